@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"wcs/dao"
@@ -21,6 +22,7 @@ func configAdminRouter(router *httprouter.Router) {
 	router.POST("/admin", AddAdmin)
 	router.GET("/admin/:argID", GetAdmin)
 	router.PUT("/admin/:argID", UpdateAdmin)
+	router.PATCH("/admin/:argID", PatchAdmin)
 	router.DELETE("/admin/:argID", DeleteAdmin)
 }
 
@@ -32,6 +34,7 @@ func configGinAdminRouter(router gin.IRoutes) {
 	router.POST("/admin", ConverHttprouterToGin(AddAdmin))
 	router.GET("/admin/:argID", ConverHttprouterToGin(GetAdmin))
 	router.PUT("/admin/:argID", ConverHttprouterToGin(UpdateAdmin))
+	router.PATCH("/admin/:argID", ConverHttprouterToGin(PatchAdmin))
 	router.DELETE("/admin/:argID", ConverHttprouterToGin(DeleteAdmin))
 }
 
@@ -88,7 +91,9 @@ func GetAllAdmin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} model.Admin
+// @Success 304 "cached copy is current"
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
 // @Router /admin/{argID} [get]
@@ -113,7 +118,7 @@ func GetAdmin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	writeVersioned(ctx, w, r, record.Version, record)
 }
 
 func IsAdminLogin(c *gin.Context) {
@@ -128,9 +133,15 @@ func AdminLogin(c *gin.Context) {
 	userName, _ := c.GetQuery("username")
 	password, _ := c.GetQuery("password")
 
-	admin, err := dao.GetAdminByName(c, userName)
+	admin, err := dao.GetAdminByName(c.Request.Context(), userName)
+	if errors.Is(err, dao.ErrNotFound) {
+		c.JSON(200, gin.H{
+			"isLogin": false,
+		})
+		return
+	}
 	if err != nil {
-		c.JSON(500, gin.H{})
+		returnError(c.Request.Context(), c.Writer, c.Request, err)
 		return
 	}
 
@@ -173,6 +184,8 @@ func AdminLogout(c *gin.Context) {
 // @Success 200 {object} model.Admin
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /admin [post]
 // echo '{"id": 33,"username": "LhvvfhYxiPROoEpSrkwbwEqIo","password": "KOadtAHGFhoOiEsTuEKPHDqbd"}' | http POST "http://localhost:8080/admin" X-Api-User:user123
 func AddAdmin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -186,12 +199,13 @@ func AddAdmin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	if err := admin.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	admin.Prepare()
 
 	if err := admin.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -207,22 +221,28 @@ func AddAdmin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(admin.Version))
 	writeJSON(ctx, w, admin)
 }
 
 // UpdateAdmin Update a single record from admin table in the wcs database
 // @Summary Update an record in table admin
-// @Description Update a single record from admin table in the wcs database
+// @Description Update a single record from admin table in the wcs database, replacing every client writable column
 // @Tags Admin
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the update is based on"
 // @Param  Admin body model.Admin true "Update Admin record"
 // @Success 200 {object} model.Admin
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Admin "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /admin/{argID} [put]
-// echo '{"id": 33,"username": "LhvvfhYxiPROoEpSrkwbwEqIo","password": "KOadtAHGFhoOiEsTuEKPHDqbd"}' | http PUT "http://localhost:8080/admin/1"  X-Api-User:user123
+// echo '{"id": 33,"username": "LhvvfhYxiPROoEpSrkwbwEqIo","password": "KOadtAHGFhoOiEsTuEKPHDqbd"}' | http PUT "http://localhost:8080/admin/1" If-Match:'"1"' X-Api-User:user123
 func UpdateAdmin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -240,12 +260,68 @@ func UpdateAdmin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	if err := admin.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	admin.Prepare()
 
 	if err := admin.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "admin", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	admin.Version = version
+
+	admin, _, err = dao.UpdateAdmin(ctx,
+		argID,
+		admin)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, admin.Version, admin)
+		return
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(admin.Version))
+	writeJSON(ctx, w, admin)
+}
+
+// PatchAdmin Patch a single record from admin table in the wcs database
+// @Summary Patch an record in table admin
+// @Description Apply a JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) document to a single record from admin table in the wcs database
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the patch is based on"
+// @Param  Admin body object true "merge patch (application/merge-patch+json) or json patch (application/json-patch+json) document"
+// @Success 200 {object} model.Admin
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Admin "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
+// @Router /admin/{argID} [patch]
+// echo '{"password": null}' | http PATCH "http://localhost:8080/admin/1" If-Match:'"1"' Content-Type:application/merge-patch+json X-Api-User:user123
+func PatchAdmin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -254,14 +330,56 @@ func UpdateAdmin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	current, err := dao.GetAdmin(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if version != 0 && version != current.Version {
+		writePreconditionFailed(ctx, w, current.Version, current)
+		return
+	}
+
+	admin := &model.Admin{}
+	if err := applyPatch(r, current, admin); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := admin.BeforeSave(); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	admin.Prepare()
+
+	if err := admin.Validate(model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	// the patch was computed against current, so the update only goes through if nobody changed it since
+	admin.Version = current.Version
 	admin, _, err = dao.UpdateAdmin(ctx,
 		argID,
 		admin)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, admin.Version, admin)
+		return
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(admin.Version))
 	writeJSON(ctx, w, admin)
 }
 
@@ -272,11 +390,14 @@ func UpdateAdmin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the delete is based on"
 // @Success 204 {object} model.Admin
 // @Failure 400 {object} api.HTTPError
+// @Failure 412 {object} model.Admin "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
 // @Failure 500 {object} api.HTTPError
 // @Router /admin/{argID} [delete]
-// http DELETE "http://localhost:8080/admin/1" X-Api-User:user123
+// http DELETE "http://localhost:8080/admin/1" If-Match:'"1"' X-Api-User:user123
 func DeleteAdmin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -291,7 +412,19 @@ func DeleteAdmin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	rowsAffected, err := dao.DeleteAdmin(ctx, argID)
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	rowsAffected, err := dao.DeleteAdmin(ctx, argID, version)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		if current, err := dao.GetAdmin(ctx, argID); err == nil {
+			writePreconditionFailed(ctx, w, current.Version, current)
+			return
+		}
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"wcs/dao"
+	"wcs/dao/daotest"
+	"wcs/model"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-contrib/sessions/cookie"
+	"github.com/gin-gonic/gin"
+)
+
+// testServer serves the gin routes of the api under /api on a fresh database
+type testServer struct {
+	*httptest.Server
+	t        *testing.T
+	database *dao.Database
+	ctx      context.Context
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	database, ctx := daotest.Open(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(sessions.Sessions("session", cookie.NewStore([]byte("test"))))
+	ConfigGinRouter(router.Group("/api"), database)
+
+	srv := &testServer{Server: httptest.NewServer(router), t: t, database: database, ctx: ctx}
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// admin returns a client signed in as a new admin
+func (s *testServer) admin() *http.Client {
+	s.t.Helper()
+	record := &model.Admin{Username: sql.NullString{String: "root", Valid: true}, Password: sql.NullString{String: "pw", Valid: true}}
+	if _, _, err := dao.AddAdmin(s.ctx, record); err != nil {
+		s.t.Fatalf("add admin: %v", err)
+	}
+
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+	status, body := s.do(client, http.MethodPost, "/api/adminLogin?username=root&password=pw", "")
+	if status != http.StatusOK || !strings.Contains(body, `"isLogin":true`) {
+		s.t.Fatalf("admin login answered %d %s", status, body)
+	}
+	return client
+}
+
+// do sends a request with body to the server, returning the status and body of the response
+func (s *testServer) do(client *http.Client, method, path, body string, headers ...string) (int, string) {
+	s.t.Helper()
+	req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		if strings.EqualFold(headers[i], "Host") {
+			req.Host = headers[i+1]
+			continue
+		}
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		s.t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	return res.StatusCode, string(data)
+}
+
+// setSiteURL sets SiteURL for the test
+func setSiteURL(t *testing.T, url string) {
+	previous := SiteURL
+	SiteURL = url
+	t.Cleanup(func() { SiteURL = previous })
+}
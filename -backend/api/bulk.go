@@ -0,0 +1,260 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	// bulkModeAtomic rolls the whole batch back when one operation fails
+	bulkModeAtomic = "atomic"
+
+	// bulkModeBestEffort commits every operation that succeeds
+	bulkModeBestEffort = "best_effort"
+
+	// maxBulkOperations upper bound of operations accepted in one bulk request
+	maxBulkOperations = 500
+)
+
+// BulkRequest batch of operations executed against one table
+type BulkRequest struct {
+	Mode       string           `json:"mode" example:"atomic"`
+	Operations []*BulkOperation `json:"operations"`
+}
+
+// BulkOperation single create, update or delete in a BulkRequest
+type BulkOperation struct {
+	Op      string          `json:"op" example:"update"`
+	ID      int32           `json:"id,omitempty"`
+	Version int32           `json:"version,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+}
+
+// BulkResponse outcome of a BulkRequest
+type BulkResponse struct {
+	Mode      string            `json:"mode"`
+	Committed bool              `json:"committed"`
+	Results   []*BulkItemResult `json:"results"`
+}
+
+// BulkItemResult outcome of a single BulkOperation, in request order
+type BulkItemResult struct {
+	Index        int         `json:"index"`
+	Op           string      `json:"op"`
+	Status       int         `json:"status"`
+	RowsAffected int64       `json:"rows_affected"`
+	Data         interface{} `json:"data,omitempty"`
+	Error        string      `json:"error,omitempty"`
+
+	// Errors field level failures of an operation rejected by validation
+	Errors []*model.FieldError `json:"errors,omitempty"`
+}
+
+var bulkActions = map[string]model.Action{
+	"create": model.Create,
+	"update": model.Update,
+	"delete": model.Delete,
+}
+
+// bulkHandler returns the handler executing bulk requests against table
+func bulkHandler(table string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		BulkTable(w, r, table)
+	}
+}
+
+// BulkTable execute a batch of create/update/delete operations against a table in the wcs database in one transaction
+// @Summary Bulk create, update and delete records of a table
+// @Description Operations run in one db transaction. In atomic mode (default) any failure rolls back the whole batch,
+// @Description in best_effort mode failed operations are rolled back individually and the rest is committed.
+// @Tags Bulk
+// @Accept  json
+// @Produce  json
+// @Param  table path string true "table name"
+// @Param  BulkRequest body api.BulkRequest true "operations"
+// @Success 200 {object} api.BulkResponse
+// @Failure 400 {object} api.HTTPError
+// @Failure 412 {object} api.BulkResponse "atomic batch rolled back, status of the first failed operation"
+// @Router /{table}/bulk [post]
+// echo '{"mode": "best_effort","operations": [{"op": "create","data": {"name": "a","intro": "b","link": ""}},{"op": "delete","id": 3,"version": 1}]}' | http POST "http://localhost:8080/projects/bulk" X-Api-User:user123
+func BulkTable(w http.ResponseWriter, r *http.Request, table string) {
+	ctx := initializeContext(r)
+
+	request := &BulkRequest{}
+	if err := readJSON(r, request); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if request.Mode == "" {
+		request.Mode = bulkModeAtomic
+	}
+
+	if request.Mode != bulkModeAtomic && request.Mode != bulkModeBestEffort {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if len(request.Operations) == 0 || len(request.Operations) > maxBulkOperations {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	atomic := request.Mode == bulkModeAtomic
+	response := &BulkResponse{Mode: request.Mode, Results: make([]*BulkItemResult, len(request.Operations))}
+
+	// validate every operation up front, only the valid ones reach the database
+	var ops []*dao.BulkOperation
+	var opIndexes []int
+	failed := false
+	for i, operation := range request.Operations {
+		result := &BulkItemResult{Index: i, Op: operation.Op}
+		response.Results[i] = result
+
+		op, err := prepareBulkOperation(ctx, r, table, operation)
+		if err != nil {
+			result.Status = errorStatus(err)
+			result.Error = errorDetail(result.Status, err)
+			if verr, ok := err.(*model.ValidationError); ok {
+				result.Errors = verr.Fields
+			}
+			failed = true
+			continue
+		}
+
+		ops = append(ops, op)
+		opIndexes = append(opIndexes, i)
+	}
+
+	if failed && atomic {
+		writeBulkResponse(w, response)
+		return
+	}
+
+	results, committed, err := dao.ExecBulk(ctx, table, ops, atomic)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	response.Committed = committed
+	for i, result := range results {
+		item := response.Results[opIndexes[i]]
+		if result == nil {
+			continue
+		}
+
+		if result.Err != nil {
+			item.Status = errorStatus(result.Err)
+			item.Error = errorDetail(item.Status, result.Err)
+			logError(ctx, r, item.Status, result.Err)
+			item.Data = result.Record
+			continue
+		}
+
+		item.Status = http.StatusOK
+		item.RowsAffected = result.RowsAffected
+		item.Data = result.Record
+	}
+
+	if committed {
+		afterBulk(ctx, ops, results)
+	}
+	writeBulkResponse(w, response)
+}
+
+// afterBulk runs the follow ups of the single record handlers for the operations of a committed batch that succeeded.
+// An updated event confirms waitlisted registrations for the places it freed.
+func afterBulk(ctx context.Context, ops []*dao.BulkOperation, results []*dao.BulkResult) {
+	for i, result := range results {
+		if result == nil || result.Err != nil || ops[i].Action != model.Update {
+			continue
+		}
+
+		if event, ok := result.Record.(*model.Events); ok {
+			promoteWaitlist(ctx, event)
+		}
+	}
+}
+
+// prepareBulkOperation decodes and validates operation the same way the single record handlers do
+func prepareBulkOperation(ctx context.Context, r *http.Request, table string, operation *BulkOperation) (*dao.BulkOperation, error) {
+	action, ok := bulkActions[operation.Op]
+	if !ok {
+		return nil, dao.ErrBadParams.Wrap(fmt.Errorf("unknown bulk op: %q", operation.Op))
+	}
+
+	if err := ValidateRequest(ctx, r, table, action); err != nil {
+		return nil, err
+	}
+
+	op := &dao.BulkOperation{Action: action, ID: operation.ID, Version: operation.Version}
+	if action != model.Create && op.ID <= 0 {
+		return nil, dao.ErrBadParams
+	}
+
+	if action == model.Delete {
+		return op, nil
+	}
+
+	record, ok := model.NewRecord(table)
+	if !ok {
+		return nil, dao.ErrBadParams
+	}
+
+	if err := json.Unmarshal(operation.Data, record); err != nil {
+		return nil, dao.ErrUnableToMarshalJSON.Wrap(err)
+	}
+
+	if err := record.BeforeSave(); err != nil {
+		return nil, dao.ErrBadParams
+	}
+
+	record.Prepare()
+
+	if err := record.Validate(action); err != nil {
+		return nil, err
+	}
+
+	op.Record = record
+	return op, nil
+}
+
+// markNotExecuted flags the operations of a rolled back batch that did not fail themselves
+func markNotExecuted(response *BulkResponse) {
+	for _, result := range response.Results {
+		if result.Error == "" {
+			result.Status = http.StatusFailedDependency
+			result.RowsAffected = 0
+			result.Data = nil
+			result.Error = "not executed, batch rolled back"
+		}
+	}
+}
+
+// writeBulkResponse answers 200 for a committed batch, otherwise the status of the first failed operation
+func writeBulkResponse(w http.ResponseWriter, response *BulkResponse) {
+	status := http.StatusOK
+	if !response.Committed {
+		for _, result := range response.Results {
+			if result.Error != "" {
+				status = result.Status
+				break
+			}
+		}
+		markNotExecuted(response)
+	}
+
+	data, _ := json.Marshal(response)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(status)
+	w.Write(data)
+}
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"wcs/cache"
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/guregu/null"
+)
+
+// bulk posts operations to the bulk endpoint of table in mode and decodes the response
+func (s *testServer) bulk(client *http.Client, table, mode string, operations ...string) (int, *BulkResponse) {
+	s.t.Helper()
+	body := fmt.Sprintf(`{"mode": %q, "operations": [`, mode)
+	for i, operation := range operations {
+		if i > 0 {
+			body += ","
+		}
+		body += operation
+	}
+	body += "]}"
+
+	status, answer := s.do(client, http.MethodPost, "/api/"+table+"/bulk", body)
+	response := &BulkResponse{}
+	if err := json.Unmarshal([]byte(answer), response); err != nil {
+		s.t.Fatalf("bulk answered %d %s", status, answer)
+	}
+	return status, response
+}
+
+// resultStatuses returns the status of every operation of response in order
+func resultStatuses(response *BulkResponse) []int {
+	var statuses []int
+	for _, result := range response.Results {
+		statuses = append(statuses, result.Status)
+	}
+	return statuses
+}
+
+func equalStatuses(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// countNews returns the number of news records
+func countNews(t *testing.T, srv *testServer) int {
+	t.Helper()
+	_, total, err := dao.GetAllNews(srv.ctx, 0, 100, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return total
+}
+
+func TestBulkAtomic(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.admin()
+
+	existing, _, err := dao.AddNews(srv.ctx, &model.News{Title: "Open day", Content: "all welcome"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, response := srv.bulk(admin, "news", bulkModeAtomic,
+		`{"op": "create", "data": {"title": "Call for papers", "content": "deadline in May"}}`,
+		fmt.Sprintf(`{"op": "update", "id": %d, "version": 1, "data": {"title": "Open day 2024", "content": "all welcome"}}`, existing.ID),
+		`{"op": "delete", "id": 999}`,
+	)
+	if status != http.StatusNotFound || response.Committed {
+		t.Fatalf("atomic batch with a missing record answered %d, committed %v, want 404 rolled back", status, response.Committed)
+	}
+	if want := []int{http.StatusFailedDependency, http.StatusFailedDependency, http.StatusNotFound}; !equalStatuses(resultStatuses(response), want) {
+		t.Errorf("operation statuses are %v, want %v", resultStatuses(response), want)
+	}
+
+	if total := countNews(t, srv); total != 1 {
+		t.Errorf("%d news after the rolled back batch, want the 1 from before", total)
+	}
+	current, err := dao.GetNews(srv.ctx, existing.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Title != "Open day" || current.Version != 1 {
+		t.Errorf("news is %q version %d after the rolled back update", current.Title, current.Version)
+	}
+
+	// invalid data fails the batch before it reaches the database
+	status, response = srv.bulk(admin, "news", bulkModeAtomic,
+		`{"op": "create", "data": {"title": "Call for papers", "content": "deadline in May"}}`,
+		`{"op": "create", "data": {"content": "no title"}}`,
+	)
+	if status != http.StatusUnprocessableEntity || response.Committed || len(response.Results[1].Errors) == 0 {
+		t.Errorf("atomic batch with an invalid record answered %d, committed %v, errors %v", status, response.Committed, response.Results[1].Errors)
+	}
+	if total := countNews(t, srv); total != 1 {
+		t.Errorf("%d news after the rejected batch, want 1", total)
+	}
+}
+
+func TestBulkBestEffort(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.admin()
+
+	existing, _, err := dao.AddNews(srv.ctx, &model.News{Title: "Open day", Content: "all welcome"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, response := srv.bulk(admin, "news", bulkModeBestEffort,
+		`{"op": "create", "data": {"title": "Call for papers", "content": "deadline in May"}}`,
+		fmt.Sprintf(`{"op": "update", "id": %d, "version": 7, "data": {"title": "Closed day", "content": "sorry"}}`, existing.ID),
+		`{"op": "create", "data": {"content": "no title"}}`,
+		fmt.Sprintf(`{"op": "delete", "id": %d, "version": 1}`, existing.ID),
+	)
+	if status != http.StatusOK || !response.Committed {
+		t.Fatalf("best effort batch answered %d, committed %v, want 200 committed", status, response.Committed)
+	}
+	want := []int{http.StatusOK, http.StatusPreconditionFailed, http.StatusUnprocessableEntity, http.StatusOK}
+	if !equalStatuses(resultStatuses(response), want) {
+		t.Errorf("operation statuses are %v, want %v", resultStatuses(response), want)
+	}
+	if response.Results[3].RowsAffected != 1 {
+		t.Errorf("delete affected %d rows, want 1", response.Results[3].RowsAffected)
+	}
+
+	records, total, err := dao.GetAllNews(srv.ctx, 0, 100, "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 || records[0].Title != "Call for papers" {
+		t.Errorf("news after the batch are %d, want only the created one", total)
+	}
+}
+
+func TestBulkEvents(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.admin()
+	srv.database.Cache = cache.New(cache.NewLRU(1 << 20))
+	setSiteURL(t, "https://wcs.example.org")
+
+	start := time.Now().Add(24 * time.Hour).UTC()
+	event := &model.Events{Title: "Workshop", Content: "hands on", Status: model.StatusPublished, RegistrationOpen: true, Capacity: 1,
+		StartTime: null.TimeFrom(start)}
+	if _, _, err := dao.AddEvents(srv.ctx, event); err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/api/events/%d/register", event.ID)
+	for _, email := range []string{"first@example.org", "second@example.org"} {
+		if status, body := srv.do(http.DefaultClient, http.MethodPost, path, fmt.Sprintf(`{"name": "Attendee", "email": %q}`, email)); status != http.StatusOK {
+			t.Fatalf("register %s answered %d %s", email, status, body)
+		}
+	}
+
+	// raising the capacity in a batch promotes the waitlist like a single update does
+	status, response := srv.bulk(admin, "events", bulkModeAtomic,
+		fmt.Sprintf(`{"op": "update", "id": %d, "data": {"title": "Workshop", "content": "hands on", "status": "published", "registration_open": true, "capacity": 2, "start_time": %q}}`,
+			event.ID, start.Format(time.RFC3339)))
+	if status != http.StatusOK || !response.Committed {
+		t.Fatalf("update batch answered %d %+v", status, response.Results[0])
+	}
+
+	confirmed, err := dao.GetRegistrations(srv.ctx, event.ID, model.RegistrationConfirmed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(confirmed) != 2 {
+		t.Errorf("%d confirmed registrations after raising the capacity to 2, want 2", len(confirmed))
+	}
+	if mails := srv.outboxTo("second@example.org"); len(mails) != 2 {
+		t.Errorf("promoted attendee got %d mails, want the waitlist notice and the promotion", len(mails))
+	}
+
+	// deleting in a batch takes the registrations along like a single delete does
+	generation := srv.database.Cache.Version("registrations")
+	status, response = srv.bulk(admin, "events", bulkModeAtomic, fmt.Sprintf(`{"op": "delete", "id": %d}`, event.ID))
+	if status != http.StatusOK || !response.Committed {
+		t.Fatalf("delete batch answered %d %+v", status, response.Results[0])
+	}
+
+	left, err := dao.GetRegistrations(srv.ctx, event.ID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 0 {
+		t.Errorf("%d registrations left of the deleted event", len(left))
+	}
+	if srv.database.Cache.Version("registrations") == generation {
+		t.Errorf("cached registrations were not invalidated by the delete")
+	}
+}
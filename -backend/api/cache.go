@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"net/http"
+	"time"
+
+	"wcs/cache"
+	"wcs/dao"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// CacheMaxAge seconds browsers and proxies may reuse a public response before revalidating it
+var CacheMaxAge = 60
+
+// cacheDependencies tables whose rows are embedded in the responses of a cached table
+var cacheDependencies = map[string][]string{
+	"news":   {"news_tags", "tags", "media", "media_variants"},
+	"events": {"events_tags", "tags", "media", "media_variants", "registrations"},
+	"phds":   {"media", "media_variants"},
+	"staffs": {"media", "media_variants"},
+	"tags":   {"news_tags", "events_tags"},
+}
+
+// configCache declares the dependencies of the cached tables on c
+func configCache(c *cache.Cache) {
+	for table, deps := range cacheDependencies {
+		c.DependOn(table, deps...)
+	}
+}
+
+// cachedResponse public response kept in the read cache
+type cachedResponse struct {
+	ContentType        string
+	ContentDisposition string
+	ETag               string
+	Body               []byte
+}
+
+// cached serves the public GET requests of h for table from the read cache of the request database, keyed by path
+// and query parameters. Requests of signed in admins bypass the cache, they see unpublished records.
+func cached(table string, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		ctx := initializeContext(r)
+		c := dao.CacheOf(ctx)
+		if c == nil || r.Method != http.MethodGet || isAdmin(ctx) {
+			h(w, r, ps)
+			return
+		}
+
+		lastModified := c.LastModified(table).UTC().Truncate(time.Second)
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", CacheMaxAge))
+		w.Header().Set("Vary", "Cookie")
+		w.Header().Set("Last-Modified", lastModified.Format(http.TimeFormat))
+
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && r.Header.Get("If-None-Match") == "" && !lastModified.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		key := r.URL.Path + "?" + r.URL.Query().Encode()
+		data, version, ok := c.Get(table, key)
+		if ok {
+			var resp cachedResponse
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&resp); err == nil {
+				w.Header().Set("X-Cache", "HIT")
+				writeCached(w, r, &resp)
+				return
+			}
+		}
+
+		w.Header().Set("X-Cache", "MISS")
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		h(rec, r, ps)
+		if rec.status != http.StatusOK {
+			return
+		}
+
+		var buf bytes.Buffer
+		resp := &cachedResponse{
+			ContentType:        w.Header().Get("Content-Type"),
+			ContentDisposition: w.Header().Get("Content-Disposition"),
+			ETag:               w.Header().Get("ETag"),
+			Body:               rec.body.Bytes(),
+		}
+		if err := gob.NewEncoder(&buf).Encode(resp); err == nil {
+			c.Set(table, key, version, buf.Bytes())
+		}
+	}
+}
+
+// writeCached writes a response of the read cache, or 304 Not Modified when the client holds its ETag
+func writeCached(w http.ResponseWriter, r *http.Request, resp *cachedResponse) {
+	if resp.ETag != "" {
+		w.Header().Set("ETag", resp.ETag)
+		if notModified(r, resp.ETag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", resp.ContentType)
+	if resp.ContentDisposition != "" {
+		w.Header().Set("Content-Disposition", resp.ContentDisposition)
+	}
+	w.Write(resp.Body)
+}
+
+// responseRecorder passes a response through to the client, keeping a copy of status and body
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(data []byte) (int, error) {
+	rec.body.Write(data)
+	return rec.ResponseWriter.Write(data)
+}
+
+// GetCacheStats is a function to get the hit and miss counts of the read cache
+// @Summary Get read cache metrics
+// @Tags Cache
+// @Description GetCacheStats returns the hits, misses and invalidations of the read cache since start, admin only
+// @Produce  json
+// @Success 200 {object} cache.Stats
+// @Failure 401 {object} api.HTTPError
+// @Router /cache/stats [get]
+// http "http://localhost:8080/cache/stats" X-Api-User:user123
+func GetCacheStats(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	writeJSON(ctx, w, dao.CacheOf(ctx).Stats())
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"wcs/cache"
+	"wcs/dao"
+	"wcs/model"
+)
+
+// withCache gives the server a read cache with the dependencies of the cached tables
+func (s *testServer) withCache() {
+	s.database.Cache = cache.New(cache.NewLRU(1 << 20))
+	configCache(s.database.Cache)
+}
+
+// get sends a GET of path for client, returning the X-Cache header and body of the response
+func (s *testServer) get(client *http.Client, path string) (xCache, body string) {
+	s.t.Helper()
+	res, err := client.Get(s.URL + path)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK {
+		s.t.Fatalf("get %s answered %d %s", path, res.StatusCode, data)
+	}
+	return res.Header.Get("X-Cache"), string(data)
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	srv := newTestServer(t)
+	srv.withCache()
+	admin := srv.admin()
+
+	news := &model.News{Title: "Open day", Content: "all welcome", Status: model.StatusPublished, Tags: model.TagList{{Name: "Seminar"}}}
+	if _, _, err := dao.AddNews(srv.ctx, news); err != nil {
+		t.Fatal(err)
+	}
+
+	if xCache, _ := srv.get(http.DefaultClient, "/api/news"); xCache != "MISS" {
+		t.Errorf("first read is %q, want MISS", xCache)
+	}
+	if xCache, _ := srv.get(http.DefaultClient, "/api/news"); xCache != "HIT" {
+		t.Errorf("second read is %q, want HIT", xCache)
+	}
+	if xCache, _ := srv.get(admin, "/api/news"); xCache != "" {
+		t.Errorf("admin read went through the cache: %q", xCache)
+	}
+
+	// renaming the tag changes the news lists embedding it
+	tag := news.Tags[0]
+	status, body := srv.do(admin, http.MethodPut, fmt.Sprintf("/api/tags/%d", tag.ID), `{"name": "Seminars", "slug": "seminar"}`, "If-Match", formatETag(tag.Version))
+	if status != http.StatusOK {
+		t.Fatalf("tag rename answered %d %s", status, body)
+	}
+	xCache, body := srv.get(http.DefaultClient, "/api/news")
+	if xCache != "MISS" || !strings.Contains(body, `"name":"Seminars"`) {
+		t.Errorf("read after the tag rename is %s %s, want a MISS with the new name", xCache, body)
+	}
+
+	// a write inside a transaction invalidates on commit only
+	err := dao.Transaction(srv.ctx, func(ctx context.Context) error {
+		if _, _, err := dao.AddNews(ctx, &model.News{Title: "Call for papers", Content: "deadline in May", Status: model.StatusPublished}); err != nil {
+			return err
+		}
+		if xCache, body := srv.get(http.DefaultClient, "/api/news"); xCache != "HIT" || strings.Contains(body, "Call for papers") {
+			t.Errorf("read during the transaction is %s, want the cached list", xCache)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if xCache, body := srv.get(http.DefaultClient, "/api/news"); xCache != "MISS" || !strings.Contains(body, "Call for papers") {
+		t.Errorf("read after the commit is %s, want a MISS listing the new news", xCache)
+	}
+}
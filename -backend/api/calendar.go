@@ -0,0 +1,409 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	// TimeZone zone calendar clients show the events in, event times are always sent in UTC
+	TimeZone = time.UTC
+
+	// EventDuration length of events without end_time in calendars
+	EventDuration = time.Hour
+
+	// CalendarSize number of the latest events listed in the calendar feed
+	CalendarSize int64 = 500
+)
+
+// layouts of UTC and local date times and of dates in iCalendar
+const (
+	icsTime  = "20060102T150405Z"
+	icsLocal = "20060102T150405"
+	icsDate  = "20060102"
+)
+
+func configCalendarRouter(router *httprouter.Router) {
+	router.GET("/calendar/events.ics", cached("events", GetEventsCalendar))
+}
+
+func configGinCalendarRouter(router gin.IRoutes) {
+	router.GET("/calendar/events.ics", ConverHttprouterToGin(cached("events", GetEventsCalendar)))
+}
+
+// withICS serves /events/{id}.ics with GetEventICS and every other id with h, the router can not route on the suffix
+func withICS(h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if strings.HasSuffix(ps.ByName("argID"), ".ics") {
+			GetEventICS(w, r, ps)
+			return
+		}
+		h(w, r, ps)
+	}
+}
+
+// GetEventsCalendar is a function to get the published events as iCalendar subscription feed
+// @Summary Get the iCalendar feed of events
+// @Tags Events
+// @Description GetEventsCalendar lists the published events with a start_time, latest first, for calendar subscriptions.
+// @Description Recurring events are listed once with their RRULE.
+// @Produce  text/calendar
+// @Param   tag   query    string  false  "only events carrying the tag with this slug"
+// @Param   If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {string} string "text/calendar"
+// @Success 304 "cached copy is current"
+// @Failure 400 {object} api.HTTPError
+// @Failure 503 {object} api.HTTPError "no --site-url is configured"
+// @Router /calendar/events.ics [get]
+// http "http://localhost:8080/calendar/events.ics?tag=seminar"
+func GetEventsCalendar(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	if err := ValidateRequest(ctx, r, "events", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	base, err := publicSiteURL()
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	filters := []dao.QueryFilter{dao.Published(time.Now())}
+	tag := r.FormValue("tag")
+	if tag != "" {
+		filters = append(filters, dao.WithTag("events", tag))
+	}
+
+	records, _, err := dao.GetAllEvents(ctx, 0, CalendarSize, "start_time DESC, id DESC", filters...)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	name := SiteName + " Events"
+	if tag != "" {
+		label := tag
+		for _, record := range records {
+			for _, t := range record.Tags {
+				if t.Slug == tag {
+					label = t.Name
+				}
+			}
+		}
+		name += " - " + label
+	}
+
+	var events []*model.Events
+	var updated time.Time
+	for _, record := range records {
+		if !record.StartTime.Valid {
+			continue
+		}
+		events = append(events, record)
+		if record.UpdateTime.After(updated) {
+			updated = record.UpdateTime
+		}
+	}
+
+	cal := &icsWriter{}
+	cal.calendar(name, base, events)
+
+	w.Header().Set("Content-Disposition", `inline; filename="events.ics"`)
+	writeDocument(w, r, "text/calendar; charset=utf-8", cal.Bytes(), updated)
+}
+
+// GetEventICS is a function to download a single event as iCalendar file
+// @Summary Get an event as .ics file
+// @Tags Events
+// @Description GetEventICS returns the event for import into a calendar. Records that are not published are only returned to signed in admins.
+// @Produce  text/calendar
+// @Param  argID path int true "id"
+// @Success 200 {string} string "text/calendar"
+// @Success 304 "cached copy is current"
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError "no such event, or the event has no start_time"
+// @Failure 503 {object} api.HTTPError "no --site-url is configured"
+// @Router /events/{argID}.ics [get]
+// http "http://localhost:8080/events/1.ics"
+func GetEventICS(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(httprouter.Params{{Key: "argID", Value: strings.TrimSuffix(ps.ByName("argID"), ".ics")}}, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "events", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	base, err := publicSiteURL()
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetEvents(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if !isAdmin(ctx) && !record.IsPublic(time.Now()) {
+		returnError(ctx, w, r, dao.ErrNotFound)
+		return
+	}
+
+	if !record.StartTime.Valid {
+		returnError(ctx, w, r, dao.ErrNotFound.Wrap(fmt.Errorf("event %d has no start_time", argID)))
+		return
+	}
+
+	cal := &icsWriter{}
+	cal.calendar(record.Title, base, []*model.Events{record})
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="event-%d.ics"`, record.ID))
+	writeDocument(w, r, "text/calendar; charset=utf-8", cal.Bytes(), record.UpdateTime)
+}
+
+// icsWriter builds an iCalendar document, RFC 5545
+type icsWriter struct {
+	bytes.Buffer
+}
+
+// calendar writes the calendar named name with events, linking to their pages on the site at base
+func (c *icsWriter) calendar(name, base string, events []*model.Events) {
+	c.prop("BEGIN", "VCALENDAR")
+	c.prop("VERSION", "2.0")
+	c.prop("PRODID", "-//"+icsText(SiteName)+"//Events//EN")
+	c.prop("CALSCALE", "GREGORIAN")
+	c.prop("METHOD", "PUBLISH")
+	c.prop("X-WR-CALNAME", icsText(name))
+	c.prop("X-WR-TIMEZONE", TimeZone.String())
+	c.prop("REFRESH-INTERVAL;VALUE=DURATION", "PT1H")
+	c.prop("X-PUBLISHED-TTL", "PT1H")
+
+	// timed events outside UTC are written in the wall clock time of their zone, so recurrences follow daylight saving
+	zones := make(map[string][2]time.Time)
+	var names []string
+	for _, event := range events {
+		loc := event.Zone()
+		if event.AllDay || loc == time.UTC {
+			continue
+		}
+
+		span, ok := zones[loc.String()]
+		if !ok {
+			names = append(names, loc.String())
+			span = [2]time.Time{event.StartTime.Time, event.StartTime.Time}
+		}
+		if event.StartTime.Time.Before(span[0]) {
+			span[0] = event.StartTime.Time
+		}
+		if event.StartTime.Time.After(span[1]) {
+			span[1] = event.StartTime.Time
+		}
+		zones[loc.String()] = span
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		loc, _ := model.LoadZone(name)
+		span := zones[name]
+		// recurring events continue after their first start, cover the years ahead
+		if horizon := time.Now().AddDate(2, 0, 0); span[1].Before(horizon) {
+			span[1] = horizon
+		}
+		c.timezone(loc, span[0].AddDate(-1, 0, 0), span[1].AddDate(1, 0, 0))
+	}
+
+	for _, event := range events {
+		c.event(base, event)
+	}
+	c.prop("END", "VCALENDAR")
+}
+
+// event writes record as VEVENT linking to its page on the site at base. The UID is built from the host of base,
+// the configured site url, so it stays the same in every copy of the calendar a subscriber loads.
+func (c *icsWriter) event(base string, record *model.Events) {
+	loc := record.Zone()
+	start := record.StartTime.Time.In(loc)
+	end := start.Add(EventDuration)
+	if record.EndTime.Valid {
+		end = record.EndTime.Time.In(loc)
+	}
+
+	host := base
+	if u, err := url.Parse(base); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	c.prop("BEGIN", "VEVENT")
+	c.prop("UID", fmt.Sprintf("events-%d@%s", record.ID, host))
+	c.prop("DTSTAMP", record.UpdateTime.UTC().Format(icsTime))
+	switch {
+	case record.AllDay:
+		c.prop("DTSTART;VALUE=DATE", start.Format(icsDate))
+		c.prop("DTEND;VALUE=DATE", end.Format(icsDate))
+	case loc == time.UTC:
+		c.prop("DTSTART", start.Format(icsTime))
+		c.prop("DTEND", end.Format(icsTime))
+	default:
+		c.prop("DTSTART;TZID="+loc.String(), start.Format(icsLocal))
+		c.prop("DTEND;TZID="+loc.String(), end.Format(icsLocal))
+	}
+	if record.RRule != "" {
+		if rule, err := model.ParseRRule(record.RRule, loc); err == nil {
+			c.prop("RRULE", rule.Format(record.AllDay))
+		}
+	}
+	c.prop("SUMMARY", icsText(record.Title))
+
+	description := plainText(record.Content)
+	if record.OnlineURL != "" {
+		description = strings.TrimSpace(description + "\n\nJoin online: " + record.OnlineURL)
+	}
+	if description != "" {
+		c.prop("DESCRIPTION", icsText(description))
+	}
+	if record.Location != "" {
+		c.prop("LOCATION", icsText(record.Location))
+	}
+	if record.OnlineURL != "" {
+		c.prop("CONFERENCE;VALUE=URI;FEATURE=VIDEO", record.OnlineURL)
+	}
+	c.prop("URL", itemLink(base, "events", record.ID))
+	if len(record.Tags) > 0 {
+		names := make([]string, len(record.Tags))
+		for i, name := range record.Tags.Names() {
+			names[i] = icsText(name)
+		}
+		c.prop("CATEGORIES", strings.Join(names, ","))
+	}
+	c.prop("CREATED", record.CreateTime.UTC().Format(icsTime))
+	c.prop("LAST-MODIFIED", record.UpdateTime.UTC().Format(icsTime))
+	c.prop("SEQUENCE", fmt.Sprint(record.Version-1))
+	c.prop("END", "VEVENT")
+}
+
+// timezone writes the VTIMEZONE of loc with the offset changes between from and to, read from the zone database
+func (c *icsWriter) timezone(loc *time.Location, from, to time.Time) {
+	c.prop("BEGIN", "VTIMEZONE")
+	c.prop("TZID", loc.String())
+
+	name, offset := from.In(loc).Zone()
+	c.observance(from.In(loc).IsDST(), "19700101T000000", offset, offset, name)
+	for _, at := range zoneTransitions(loc, from, to) {
+		before := offset
+		name, offset = at.In(loc).Zone()
+		c.observance(at.In(loc).IsDST(), at.UTC().Add(time.Duration(before)*time.Second).Format(icsLocal), before, offset, name)
+	}
+
+	c.prop("END", "VTIMEZONE")
+}
+
+// observance writes a STANDARD or DAYLIGHT component switching from offset from to offset to at the local time start
+func (c *icsWriter) observance(dst bool, start string, from, to int, name string) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+
+	c.prop("BEGIN", kind)
+	c.prop("DTSTART", start)
+	c.prop("TZOFFSETFROM", icsOffset(from))
+	c.prop("TZOFFSETTO", icsOffset(to))
+	c.prop("TZNAME", icsText(name))
+	c.prop("END", kind)
+}
+
+// zoneTransitions returns the instants between from and to at which the offset of loc changes, to the minute
+func zoneTransitions(loc *time.Location, from, to time.Time) []time.Time {
+	var transitions []time.Time
+	_, offset := from.In(loc).Zone()
+	for t := from; t.Before(to); t = t.Add(24 * time.Hour) {
+		next := t.Add(24 * time.Hour)
+		if _, o := next.In(loc).Zone(); o == offset {
+			continue
+		}
+
+		lo, hi := t, next
+		for hi.Sub(lo) > time.Minute {
+			mid := lo.Add(hi.Sub(lo) / 2)
+			if _, o := mid.In(loc).Zone(); o == offset {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		hi = hi.Truncate(time.Minute)
+		transitions = append(transitions, hi)
+		_, offset = hi.In(loc).Zone()
+	}
+	return transitions
+}
+
+// icsOffset formats a UTC offset in seconds as +HHMM
+func icsOffset(offset int) string {
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	return fmt.Sprintf("%c%02d%02d", sign, offset/3600, offset%3600/60)
+}
+
+// prop writes a content line, folded after 75 octets without splitting characters
+func (c *icsWriter) prop(name, value string) {
+	line := name + ":" + value
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		c.WriteString(line[:cut])
+		c.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines start with the folding space
+		limit = 74
+	}
+	c.WriteString(line)
+	c.WriteString("\r\n")
+}
+
+// icsText escapes s as iCalendar TEXT value
+func icsText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", "").Replace(s)
+}
+
+// blockEnd html tags ending a line of text
+var blockEnd = regexp.MustCompile(`(?i)<br\s*/?>|</(p|div|li|h[1-6]|tr|blockquote)>`)
+
+// plainText returns the text of rich text html, one line per block
+func plainText(content string) string {
+	text := model.Sanitize(model.SanitizeStrict, blockEnd.ReplaceAllString(content, "\n"))
+	lines := strings.Split(html.UnescapeString(text), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
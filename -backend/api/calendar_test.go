@@ -0,0 +1,49 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"testing"
+	"time"
+
+	"wcs/cache"
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/guregu/null"
+)
+
+var uidRegexp = regexp.MustCompile(`UID:(\S+)`)
+
+func TestCalendarIgnoresHostHeader(t *testing.T) {
+	srv := newTestServer(t)
+	srv.database.Cache = cache.New(cache.NewLRU(1 << 20))
+	setSiteURL(t, "https://wcs.example.org")
+
+	event := &model.Events{Title: "Colloquium", Content: "talk", Status: model.StatusPublished, StartTime: null.TimeFrom(time.Now().Add(24 * time.Hour))}
+	if _, _, err := dao.AddEvents(srv.ctx, event); err != nil {
+		t.Fatal(err)
+	}
+
+	want := fmt.Sprintf("events-%d@wcs.example.org", event.ID)
+	for _, path := range []string{"/api/calendar/events.ics", fmt.Sprintf("/api/events/%d.ics", event.ID)} {
+		for _, host := range []string{"evil.example", "other.example"} {
+			status, body := srv.do(http.DefaultClient, http.MethodGet, path, "", "Host", host)
+			if status != http.StatusOK {
+				t.Fatalf("%s answered %d %s", path, status, body)
+			}
+			uids := uidRegexp.FindAllStringSubmatch(body, -1)
+			if len(uids) != 1 || uids[0][1] != want {
+				t.Errorf("%s for Host %s has UIDs %v, want %s", path, host, uids, want)
+			}
+		}
+	}
+
+	// cached copies stay valid, only new renderings need the site url
+	srv.database.Cache = nil
+	setSiteURL(t, "")
+	if status, _ := srv.do(http.DefaultClient, http.MethodGet, fmt.Sprintf("/api/events/%d.ics", event.ID), ""); status != http.StatusServiceUnavailable {
+		t.Errorf("event .ics without a site url answered %d, want 503", status)
+	}
+}
@@ -2,58 +2,212 @@ package api
 
 import (
 	"context"
-	"fmt"
+	"crypto/hmac"
+	"log"
+	"math"
+	netmail "net/mail"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
+
+	"wcs/dao"
+	"wcs/mail"
+	"wcs/model"
+	"wcs/spam"
 
 	"github.com/gin-gonic/gin"
-	"github.com/mailgun/mailgun-go/v4"
 )
 
+// feedbackEmail address contact messages are sent to
+const feedbackEmail = "wcs399@proton.me"
+
+// Mailer sends the emails of the site, messages are only logged until one is configured
+var Mailer mail.Mailer = &mail.File{}
+
+// ContactLimiter limits the contact messages accepted per client address
+var ContactLimiter = NewRateLimiter(5, 10*time.Minute)
+
+// contactTokenUses counts the messages sent with a contact form token, each token is good for one message.
+// The window outlasts the token, so a token can not be used again after its window ends.
+var contactTokenUses = NewRateLimiter(1, contactTokenMaxAge)
+
+// SpamFilter scores contact messages, the ones reaching its threshold are quarantined instead of mailed
+var SpamFilter = spam.NewFilter(nil, nil, spam.DefaultThreshold)
+
+// ages of a contact form token for a message to be accepted. Bots post faster than people type,
+// and a form left open for longer has to be reloaded.
 const (
-	mailgunDomain          = "sandboxb6c2fb30d44b41e495272143b5d5c41f.mailgun.org"
-	privateAPIKeyOfMailgun = "6bac0046ac203622eb58e7c7fe1ae5fe-381f2624-91da3481"
-	sender                 = "postmaster@sandboxb6c2fb30d44b41e495272143b5d5c41f.mailgun.org"
-	msgTemplate            = `
-	user name: %s
-	user email: %s
-	user feedback: %s
-	`
-	feedbackEmail = "wcs399@proton.me"
+	contactTokenMinAge = 3 * time.Second
+	contactTokenMaxAge = 2 * time.Hour
 )
 
+// contactHoneypot query parameter of a form field hidden from people, messages filling it in are dropped
+const contactHoneypot = "website"
+
+// errContactToken the contact form token is missing, forged, too fresh, expired or used already
+var errContactToken = &dao.Error{Kind: dao.KindBadRequest, Message: "invalid or expired form token, reload the page and send the message again"}
+
+// ContactToken form token the contact form is sent with
+type ContactToken struct {
+	Token string `json:"token" example:"1714568400.q2V0bWJ5Y2hlY2tpbmcxMjM"`
+}
+
 func configGinContactRouter(router gin.IRoutes) {
+	router.GET("/contactToken", getContactToken)
 	router.POST("/notifyContact", notifyContact)
 }
 
+// getContactToken is a function to get the form token a contact message is sent with
+// @Summary Get a contact form token
+// @Tags Contact
+// @Description getContactToken issues the signed, time stamped token notifyContact expects.
+// @Description A token is accepted for one message, from a few seconds after it was issued for two hours.
+// @Produce  json
+// @Success 200 {object} api.ContactToken
+// @Router /contactToken [get]
+// http "http://localhost:8080/contactToken"
+func getContactToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.JSON(200, &ContactToken{Token: newContactToken(time.Now())})
+}
+
+// notifyContact stores a message of the contact form in the inbox of the admins and mails it to feedbackEmail.
+// The message is kept when the mail can not be queued. Messages filling in the honeypot are dropped, messages
+// the SpamFilter finds suspicious are quarantined without a mail; the visitor is told the message was sent either way.
+// @Summary Send a contact message
+// @Tags Contact
+// @Produce  json
+// @Param   name     query    string  true   "name of the visitor"
+// @Param   email    query    string  true   "email address of the visitor"
+// @Param   feedback query    string  true   "the message"
+// @Param   token    query    string  true   "form token from /contactToken"
+// @Param   website  query    string  false  "honeypot, left empty by people"
+// @Success 200 {object} object
+// @Failure 400 {object} api.HTTPError "missing parameters, invalid or used form token"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 429 {object} api.HTTPError "too many messages from the address, see Retry-After"
+// @Router /notifyContact [post]
+// http POST "http://localhost:8080/notifyContact?name=Ada&email=ada@example.org&feedback=Hello&token=1714568400.q2V0bWJ5Y2hlY2tpbmcxMjM"
 func notifyContact(c *gin.Context) {
-	// create an instance of the Mailgun Client
-	mg := mailgun.NewMailgun(mailgunDomain, privateAPIKeyOfMailgun)
+	ctx := c.Request.Context()
+	ip := clientIP(c.Request)
+	now := time.Now()
+
+	if ok, retryAfter := ContactLimiter.Allow(ip, now); !ok {
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		returnError(ctx, c.Writer, c.Request, errTooManyRequests)
+		return
+	}
 
 	name, _ := c.GetQuery("name")
 	email, _ := c.GetQuery("email")
 	feedback, _ := c.GetQuery("feedback")
 	if name == "" || email == "" || feedback == "" {
-		c.JSON(400, gin.H{
-			"message": "invalid request",
-		})
+		returnError(ctx, c.Writer, c.Request, dao.ErrBadParams)
 		return
 	}
 
-	subject := "New Contact Message!"
-	body := fmt.Sprintf(msgTemplate, name, email, feedback)
+	if c.Query(contactHoneypot) != "" {
+		log.Printf("Dropping contact message from %s, the honeypot was filled in", ip)
+		c.JSON(200, gin.H{})
+		return
+	}
 
-	message := mg.NewMessage(sender, subject, body, feedbackEmail)
+	if !validContactToken(c.Query("token"), now) {
+		returnError(ctx, c.Writer, c.Request, errContactToken)
+		return
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
-	defer cancel()
+	record := &model.ContactMessages{
+		Name:      name,
+		Email:     email,
+		Feedback:  feedback,
+		IPAddress: ip,
+		UserAgent: c.Request.UserAgent(),
+	}
+	record.Prepare()
+	if err := record.Validate(model.Create); err != nil {
+		returnError(ctx, c.Writer, c.Request, err)
+		return
+	}
 
-	// send the message with a 10 second timeout
-	_, _, err := mg.Send(ctx, message)
-	if err != nil {
-		c.JSON(500, gin.H{
-			"message": "failed to send email,err" + err.Error(),
-		})
+	// spent only once the message is accepted, so a visitor correcting a field keeps the token
+	if ok, _ := contactTokenUses.Allow(c.Query("token"), now); !ok {
+		returnError(ctx, c.Writer, c.Request, errContactToken)
+		return
+	}
+
+	verdict := SpamFilter.Check(record.Name, record.Email, record.Feedback)
+	record.SpamScore = int32(verdict.Score)
+	record.SpamReasons = truncate(strings.Join(verdict.Reasons, ", "), 255)
+	if verdict.Quarantine {
+		record.Status = model.ContactQuarantined
+	}
+
+	if _, err := dao.AddContactMessage(ctx, record); err != nil {
+		returnError(ctx, c.Writer, c.Request, err)
+		return
+	}
+
+	if verdict.Quarantine {
+		log.Printf("Quarantined contact message %d from %s, spam score %d: %s", record.ID, ip, verdict.Score, record.SpamReasons)
+	} else if err := mailContact(ctx, record); err != nil {
+		log.Printf("Got error when mailing contact message %d, the error is '%v'", record.ID, err)
 	}
 
 	c.JSON(200, gin.H{})
 }
+
+// newContactToken returns a contact form token issued at now, the unix time signed with TicketKey
+func newContactToken(now time.Time) string {
+	issued := strconv.FormatInt(now.Unix(), 10)
+	return issued + "." + sign("contact", issued)
+}
+
+// validContactToken reports whether token is a contact form token whose signature holds and whose age at now is accepted
+func validContactToken(token string, now time.Time) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(sign("contact", parts[0]))) {
+		return false
+	}
+
+	issued, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return false
+	}
+	age := now.Sub(time.Unix(issued, 0))
+	return age >= contactTokenMinAge && age <= contactTokenMaxAge
+}
+
+// truncate cuts s to at most n characters
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// mailContact queues the notification of a new contact message for feedbackEmail, replies go to the visitor
+func mailContact(ctx context.Context, record *model.ContactMessages) error {
+	msg, err := mail.Render("contact", feedbackEmail, map[string]string{"Name": record.Name, "Email": record.Email, "Feedback": record.Feedback})
+	if err != nil {
+		return err
+	}
+	if addr, err := netmail.ParseAddress(record.Email); err == nil {
+		msg.ReplyTo = addr.Address
+	}
+
+	_, err = queueMail(ctx, "contact", msg)
+	return err
+}
+
+// sendMail renders the mail template name for data and queues it for to in the outbox
+func sendMail(ctx context.Context, to, name string, data interface{}) error {
+	msg, err := mail.Render(name, to, data)
+	if err != nil {
+		return err
+	}
+	_, err = queueMail(ctx, name, msg)
+	return err
+}
@@ -0,0 +1,263 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guregu/null"
+	"github.com/julienschmidt/httprouter"
+)
+
+// contactStatusRequest body of a status change of a contact message
+type contactStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// contactAssignRequest body of an assignment of a contact message, a null admin_id unassigns it
+type contactAssignRequest struct {
+	AdminID null.Int `json:"admin_id"`
+}
+
+func configContactMessagesRouter(router *httprouter.Router) {
+	router.GET("/contactMessages", GetAllContactMessages)
+	router.GET("/contactMessages/:argID", GetContactMessage)
+	router.POST("/contactMessages/:argID/status", SetContactStatus)
+	router.POST("/contactMessages/:argID/assign", AssignContactMessage)
+}
+
+func configGinContactMessagesRouter(router gin.IRoutes) {
+	router.GET("/contactMessages", ConverHttprouterToGin(GetAllContactMessages))
+	router.GET("/contactMessages/:argID", ConverHttprouterToGin(GetContactMessage))
+	router.POST("/contactMessages/:argID/status", ConverHttprouterToGin(SetContactStatus))
+	router.POST("/contactMessages/:argID/assign", ConverHttprouterToGin(AssignContactMessage))
+}
+
+// GetAllContactMessages is a function to get a slice of record(s) from contact_messages table in the wcs database
+// @Summary Get the contact inbox
+// @Tags ContactMessages
+// @Description GetAllContactMessages lists the messages of the contact form, newest first, admin only.
+// @Description Archived and quarantined messages are left out unless status asks for them or is all.
+// @Produce  json
+// @Param   page        query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize    query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   status      query    string  false        "one of new, read, replied, archived, quarantined, all"
+// @Param   q           query    string  false        "text the name, email or message contains"
+// @Param   assigned_to query    string  false        "id of an admin, me, or none for the unassigned messages"
+// @Success 200 {object} api.PagedResults{data=[]model.ContactMessages}
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Router /contactMessages [get]
+// http "http://localhost:8080/contactMessages?status=new&assigned_to=me&q=seminar" X-Api-User:user123
+func GetAllContactMessages(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "contact_messages", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	var filters []dao.QueryFilter
+	switch status := r.FormValue("status"); {
+	case status == "":
+		filters = append(filters, dao.WithoutStatus(model.ContactArchived, model.ContactQuarantined))
+	case status == "all":
+	case model.IsContactStatus(status):
+		filters = append(filters, dao.WithStatus(status))
+	default:
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if q := strings.TrimSpace(r.FormValue("q")); q != "" {
+		filters = append(filters, dao.ContactMatching(q))
+	}
+
+	switch assignedTo := r.FormValue("assigned_to"); assignedTo {
+	case "":
+	case "none":
+		filters = append(filters, dao.AssignedTo(null.Int{}))
+	case "me":
+		adminID, _ := dao.CurrentAdminID(ctx)
+		filters = append(filters, dao.AssignedTo(null.IntFrom(int64(adminID))))
+	default:
+		adminID, err := strconv.ParseInt(assignedTo, 10, 32)
+		if err != nil {
+			returnError(ctx, w, r, dao.ErrBadParams)
+			return
+		}
+		filters = append(filters, dao.AssignedTo(null.IntFrom(adminID)))
+	}
+
+	records, totalRows, err := dao.GetAllContactMessages(ctx, page, pagesize, "id desc", filters...)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	writeJSON(ctx, w, result)
+}
+
+// GetContactMessage is a function to get a single record from the contact_messages table in the wcs database
+// @Summary Get a contact message
+// @Tags ContactMessages
+// @Description GetContactMessage returns a message of the contact form, admin only
+// @Produce  json
+// @Param  argID path int true "id"
+// @Success 200 {object} model.ContactMessages
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /contactMessages/{argID} [get]
+// http "http://localhost:8080/contactMessages/7" X-Api-User:user123
+func GetContactMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "contact_messages", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetContactMessage(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// SetContactStatus is a function to mark a contact message read, replied or archived
+// @Summary Set the status of a contact message
+// @Tags ContactMessages
+// @Description SetContactStatus moves a message of the contact form to status new, read, replied, archived or quarantined, admin only.
+// @Description Moving a quarantined message to another status releases it into the inbox, it is not mailed.
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  body body api.contactStatusRequest true "new status"
+// @Success 200 {object} model.ContactMessages
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /contactMessages/{argID}/status [post]
+// echo '{"status": "replied"}' | http POST "http://localhost:8080/contactMessages/7/status" X-Api-User:user123
+func SetContactStatus(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "contact_messages", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	body := &contactStatusRequest{}
+	if err := readJSON(r, body); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	if !model.IsContactStatus(body.Status) {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	record, err := dao.SetContactStatus(ctx, argID, body.Status, time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// AssignContactMessage is a function to hand a contact message to an admin
+// @Summary Assign a contact message
+// @Tags ContactMessages
+// @Description AssignContactMessage assigns a message of the contact form to the admin admin_id, or unassigns it for a null admin_id, admin only
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  body body api.contactAssignRequest true "admin handling the message"
+// @Success 200 {object} model.ContactMessages
+// @Failure 400 {object} api.HTTPError "no such admin"
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /contactMessages/{argID}/assign [post]
+// echo '{"admin_id": 2}' | http POST "http://localhost:8080/contactMessages/7/assign" X-Api-User:user123
+func AssignContactMessage(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "contact_messages", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	body := &contactAssignRequest{}
+	if err := readJSON(r, body); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.AssignContactMessage(ctx, argID, body.AdminID, time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
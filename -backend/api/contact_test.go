@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// setContactLimiter replaces ContactLimiter and the spent form tokens for the test
+func setContactLimiter(t *testing.T, limiter *RateLimiter) {
+	previous, uses := ContactLimiter, contactTokenUses
+	ContactLimiter, contactTokenUses = limiter, NewRateLimiter(1, contactTokenMaxAge)
+	t.Cleanup(func() { ContactLimiter, contactTokenUses = previous, uses })
+}
+
+// contactQuery returns the query of a contact message sent with token
+func contactQuery(token string) string {
+	return "/api/notifyContact?" + url.Values{
+		"name":     {"Ada"},
+		"email":    {"ada@example.org"},
+		"feedback": {"Is the colloquium recorded?"},
+		"token":    {token},
+	}.Encode()
+}
+
+func TestContactTokenSingleUse(t *testing.T) {
+	srv := newTestServer(t)
+	setContactLimiter(t, NewRateLimiter(0, time.Minute))
+
+	token := newContactToken(time.Now().Add(-time.Minute))
+	if status, body := srv.do(http.DefaultClient, http.MethodPost, contactQuery(token), ""); status != http.StatusOK {
+		t.Fatalf("first message answered %d %s", status, body)
+	}
+	if status, _ := srv.do(http.DefaultClient, http.MethodPost, contactQuery(token), ""); status != http.StatusBadRequest {
+		t.Errorf("message with a used token answered %d, want 400", status)
+	}
+
+	fresh := newContactToken(time.Now().Add(-30 * time.Second))
+	if status, body := srv.do(http.DefaultClient, http.MethodPost, contactQuery(fresh), ""); status != http.StatusOK {
+		t.Errorf("message with a new token answered %d %s", status, body)
+	}
+}
+
+func TestContactLimitIgnoresForwardedFor(t *testing.T) {
+	srv := newTestServer(t)
+	setContactLimiter(t, NewRateLimiter(1, time.Minute))
+
+	// a new token per message, tokens issued in different seconds differ
+	issued := time.Now().Add(-time.Minute)
+	send := func(forwardedFor string) int {
+		issued = issued.Add(-time.Second)
+		token := newContactToken(issued)
+		status, _ := srv.do(http.DefaultClient, http.MethodPost, contactQuery(token), "", "X-Forwarded-For", forwardedFor)
+		return status
+	}
+
+	if status := send("203.0.113.1"); status != http.StatusOK {
+		t.Fatalf("first message answered %d", status)
+	}
+	if status := send("203.0.113.22"); status != http.StatusTooManyRequests {
+		t.Errorf("message with a forged X-Forwarded-For answered %d, want 429", status)
+	}
+
+	previous := TrustedProxies
+	TrustedProxies = []*net.IPNet{{IP: net.IPv4(127, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 32)}}
+	t.Cleanup(func() { TrustedProxies = previous })
+	if status := send("203.0.113.123"); status != http.StatusOK {
+		t.Errorf("message of another client behind a trusted proxy answered %d, want 200", status)
+	}
+}
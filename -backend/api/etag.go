@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formatETag returns the entity tag of a record at the given row version.
+func formatETag(version int32) string {
+	return fmt.Sprintf(`"%d"`, version)
+}
+
+// errPreconditionRequired error when an update or delete is sent without the If-Match header
+var errPreconditionRequired = errors.New("If-Match header with the ETag of the record is required")
+
+// readIfMatch returns the row version the client expects from the If-Match header. Updates and deletes must
+// name the version they are based on, so two admins editing a record can not overwrite each other unnoticed:
+// errPreconditionRequired is returned when the header is absent. "*" matches any version and yields 0, a header that
+// names no usable version yields -1 so the update is rejected as a version conflict.
+func readIfMatch(r *http.Request) (version int32, err error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" {
+		return 0, errPreconditionRequired
+	}
+
+	if header == "*" {
+		return 0, nil
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		// weak tags never match in If-Match, see RFC 7232 section 3.1
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+
+		v, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 32)
+		if err == nil && v > 0 {
+			return int32(v), nil
+		}
+	}
+
+	return -1, nil
+}
+
+// notModified reports whether the If-None-Match header of r matches etag.
+func notModified(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writeVersioned writes v with the ETag of version, or 304 Not Modified when the client already holds it.
+func writeVersioned(ctx context.Context, w http.ResponseWriter, r *http.Request, version int32, v interface{}) {
+	etag := formatETag(version)
+	w.Header().Set("ETag", etag)
+	if notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeJSON(ctx, w, v)
+}
+
+// writePreconditionFailed answers 412 Precondition Failed with the current representation of the record.
+func writePreconditionFailed(ctx context.Context, w http.ResponseWriter, version int32, current interface{}) {
+	data, _ := json.Marshal(current)
+	w.Header().Set("ETag", formatETag(version))
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusPreconditionFailed)
+	w.Write(data)
+}
+
+// writeDocument writes a generated public document such as a feed with an ETag derived from its content.
+// Without the read cache, which dates responses itself, updated is sent as Last-Modified.
+// Clients holding the current copy get 304 Not Modified.
+func writeDocument(w http.ResponseWriter, r *http.Request, contentType string, body []byte, updated time.Time) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:12]) + `"`
+	w.Header().Set("ETag", etag)
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", CacheMaxAge))
+	}
+
+	if w.Header().Get("Last-Modified") == "" && !updated.IsZero() {
+		updated = updated.UTC().Truncate(time.Second)
+		w.Header().Set("Last-Modified", updated.Format(http.TimeFormat))
+		if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && r.Header.Get("If-None-Match") == "" && !updated.After(since) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+
+	if notModified(r, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body)
+}
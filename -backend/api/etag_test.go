@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"wcs/model"
+)
+
+func TestNewsVersions(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.admin()
+
+	status, body := srv.do(admin, http.MethodPost, "/api/news", `{"title": "Open day", "content": "<p>all welcome</p>"}`)
+	news := &model.News{}
+	if status != http.StatusOK || json.Unmarshal([]byte(body), news) != nil || news.Version != 1 {
+		t.Fatalf("add answered %d %s", status, body)
+	}
+	path := fmt.Sprintf("/api/news/%d", news.ID)
+
+	if status, _ := srv.do(admin, http.MethodGet, path, "", "If-None-Match", `"1"`); status != http.StatusNotModified {
+		t.Errorf("get with the current ETag answered %d, want 304", status)
+	}
+	if status, _ := srv.do(admin, http.MethodGet, path, "", "If-None-Match", `"7"`); status != http.StatusOK {
+		t.Errorf("get with another ETag answered %d, want 200", status)
+	}
+
+	update := `{"title": "Open day", "content": "<p>all welcome, 10am</p>"}`
+	patch := `{"title": "Open day 2024"}`
+	for _, test := range []struct {
+		method, body string
+	}{
+		{http.MethodPut, update},
+		{http.MethodPatch, patch},
+		{http.MethodDelete, ""},
+	} {
+		status, body := srv.do(admin, test.method, path, test.body, "Content-Type", "application/merge-patch+json")
+		if status != http.StatusPreconditionRequired {
+			t.Errorf("%s without If-Match answered %d %s, want 428", test.method, status, body)
+		}
+	}
+
+	status, body = srv.do(admin, http.MethodPut, path, update, "If-Match", `"1"`)
+	if status != http.StatusOK || json.Unmarshal([]byte(body), news) != nil || news.Version != 2 {
+		t.Fatalf("update of version 1 answered %d %s", status, body)
+	}
+
+	// a second admin still holding version 1
+	for _, test := range []struct {
+		method, body string
+	}{
+		{http.MethodPut, `{"title": "Closed day", "content": "<p>sorry</p>"}`},
+		{http.MethodPatch, patch},
+		{http.MethodDelete, ""},
+	} {
+		status, body := srv.do(admin, test.method, path, test.body, "If-Match", `"1"`, "Content-Type", "application/merge-patch+json")
+		current := &model.News{}
+		if status != http.StatusPreconditionFailed || json.Unmarshal([]byte(body), current) != nil || current.Version != 2 || current.Content != "<p>all welcome, 10am</p>" {
+			t.Errorf("%s of stale version 1 answered %d %s, want 412 with version 2", test.method, status, body)
+		}
+	}
+
+	if status, body := srv.do(admin, http.MethodDelete, path, "", "If-Match", `"2"`); status != http.StatusOK {
+		t.Errorf("delete of the current version answered %d %s", status, body)
+	}
+}
@@ -1,7 +1,10 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"wcs/dao"
 	"wcs/model"
@@ -16,18 +19,24 @@ var (
 )
 
 func configEventsRouter(router *httprouter.Router) {
-	router.GET("/events", GetAllEvents)
+	router.GET("/events", cached("events", GetAllEvents))
 	router.POST("/events", AddEvents)
-	router.GET("/events/:argID", GetEvents)
+	router.POST("/events/:argID", fixedArg("argID", "bulk", bulkHandler("events")))
+	router.GET("/events/:argID", cached("events", withICS(GetEvents)))
+	router.GET("/events/:argID/history", statusHistoryHandler("events"))
 	router.PUT("/events/:argID", UpdateEvents)
+	router.PATCH("/events/:argID", PatchEvents)
 	router.DELETE("/events/:argID", DeleteEvents)
 }
 
 func configGinEventsRouter(router gin.IRoutes) {
-	router.GET("/events", ConverHttprouterToGin(GetAllEvents))
+	router.GET("/events", ConverHttprouterToGin(cached("events", GetAllEvents)))
 	router.POST("/events", ConverHttprouterToGin(AddEvents))
-	router.GET("/events/:argID", ConverHttprouterToGin(GetEvents))
+	router.POST("/events/bulk", ConverHttprouterToGin(bulkHandler("events")))
+	router.GET("/events/:argID", ConverHttprouterToGin(cached("events", withICS(GetEvents))))
+	router.GET("/events/:argID/history", ConverHttprouterToGin(statusHistoryHandler("events")))
 	router.PUT("/events/:argID", ConverHttprouterToGin(UpdateEvents))
+	router.PATCH("/events/:argID", ConverHttprouterToGin(PatchEvents))
 	router.DELETE("/events/:argID", ConverHttprouterToGin(DeleteEvents))
 }
 
@@ -35,16 +44,23 @@ func configGinEventsRouter(router gin.IRoutes) {
 // @Summary Get list of Events
 // @Tags Events
 // @Description GetAllEvents is a handler to get a slice of record(s) from events table in the wcs database
+// @Description Only published records are listed unless the request comes from a signed in admin.
 // @Accept  json
 // @Produce  json
 // @Param   page     query    int     false        "page requested (defaults to 0)"
 // @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
 // @Param   order    query    string  false        "db sort order column"
+// @Param   tag      query    string  false        "only records carrying the tag with this slug"
+// @Param   status   query    string  false        "admin only, one of draft, scheduled, published, archived"
+// @Param   from     query    string  false        "list the occurrences from this time on, RFC 3339 or date, defaults to now when to is given"
+// @Param   to       query    string  false        "list the occurrences before this time, RFC 3339 or date, defaults to 30 days after from"
+// @Description With from or to the occurrences in the range are listed by start, recurring events once per occurrence
+// @Description with the occurrence field set. order is ignored then.
 // @Success 200 {object} api.PagedResults{data=[]model.Events}
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
 // @Router /events [get]
-// http "http://localhost:8080/events?page=0&pagesize=20" X-Api-User:user123
+// http "http://localhost:8080/events?page=0&pagesize=20&tag=seminar" X-Api-User:user123
 func GetAllEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 	page, err := readInt(r, "page", 0)
@@ -66,7 +82,28 @@ func GetAllEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	records, totalRows, err := dao.GetAllEvents(ctx, page, pagesize, order)
+	filters, err := publishingFilters(ctx, r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if tag := r.FormValue("tag"); tag != "" {
+		filters = append(filters, dao.WithTag("events", tag))
+	}
+
+	var records []*model.Events
+	var totalRows int
+	if r.FormValue("from") != "" || r.FormValue("to") != "" {
+		from, to, err := readTimeRange(r)
+		if err != nil {
+			returnError(ctx, w, r, err)
+			return
+		}
+		records, totalRows, err = dao.GetEventOccurrences(ctx, from, to, page, pagesize, filters...)
+	} else {
+		records, totalRows, err = dao.GetAllEvents(ctx, page, pagesize, order, filters...)
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -81,10 +118,13 @@ func GetAllEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 // @Tags Events
 // @ID argID
 // @Description GetEvents is a function to get a single record from the events table in the wcs database
+// @Description Records that are not published are only returned to signed in admins.
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} model.Events
+// @Success 304 "cached copy is current"
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
 // @Router /events/{argID} [get]
@@ -109,7 +149,12 @@ func GetEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	if !isAdmin(ctx) && !record.IsPublic(time.Now()) {
+		returnError(ctx, w, r, dao.ErrNotFound)
+		return
+	}
+
+	writeVersioned(ctx, w, r, record.Version, record)
 }
 
 // AddEvents add to add a single record to events table in the wcs database
@@ -122,8 +167,10 @@ func GetEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Success 200 {object} model.Events
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /events [post]
-// echo '{"cover": "PXvcMZAaVtykxdkaiPnFcLfhu","tags": "dymRLWPnwryPHEVWAKyjptSUC","update_time": "2208-11-03T15:07:41.739514237+08:00","create_time": "2057-01-07T00:22:49.758092343+08:00","content": "NctfhDQebYWmpAGapMOhaLiCk","title": "BVtvmnvgRGjXXVYoTuIjUZPqV","id": 35,"event_time": 55}' | http POST "http://localhost:8080/events" X-Api-User:user123
+// echo '{"cover": "PXvcMZAaVtykxdkaiPnFcLfhu","tags": ["seminar","ai"],"update_time": "2208-11-03T15:07:41.739514237+08:00","create_time": "2057-01-07T00:22:49.758092343+08:00","content": "NctfhDQebYWmpAGapMOhaLiCk","title": "BVtvmnvgRGjXXVYoTuIjUZPqV","id": 35,"start_time": "2024-05-02T14:00:00Z","time_zone": "Europe/London"}' | http POST "http://localhost:8080/events" X-Api-User:user123
 func AddEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 	events := &model.Events{}
@@ -135,12 +182,13 @@ func AddEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	if err := events.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	events.Prepare()
 
 	if err := events.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -156,22 +204,28 @@ func AddEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(events.Version))
 	writeJSON(ctx, w, events)
 }
 
 // UpdateEvents Update a single record from events table in the wcs database
 // @Summary Update an record in table events
-// @Description Update a single record from events table in the wcs database
+// @Description Update a single record from events table in the wcs database, replacing every client writable column
 // @Tags Events
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the update is based on"
 // @Param  Events body model.Events true "Update Events record"
 // @Success 200 {object} model.Events
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Events "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /events/{argID} [put]
-// echo '{"cover": "PXvcMZAaVtykxdkaiPnFcLfhu","tags": "dymRLWPnwryPHEVWAKyjptSUC","update_time": "2208-11-03T15:07:41.739514237+08:00","create_time": "2057-01-07T00:22:49.758092343+08:00","content": "NctfhDQebYWmpAGapMOhaLiCk","title": "BVtvmnvgRGjXXVYoTuIjUZPqV","id": 35,"event_time": 55}' | http PUT "http://localhost:8080/events/1"  X-Api-User:user123
+// echo '{"cover": "PXvcMZAaVtykxdkaiPnFcLfhu","tags": ["seminar","ai"],"update_time": "2208-11-03T15:07:41.739514237+08:00","create_time": "2057-01-07T00:22:49.758092343+08:00","content": "NctfhDQebYWmpAGapMOhaLiCk","title": "BVtvmnvgRGjXXVYoTuIjUZPqV","id": 35,"start_time": "2024-05-02T14:00:00Z","time_zone": "Europe/London"}' | http PUT "http://localhost:8080/events/1" If-Match:'"1"' X-Api-User:user123
 func UpdateEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -189,12 +243,13 @@ func UpdateEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 
 	if err := events.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	events.Prepare()
 
 	if err := events.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -203,14 +258,113 @@ func UpdateEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	events.Version = version
+
 	events, _, err = dao.UpdateEvents(ctx,
 		argID,
 		events)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, events.Version, events)
+		return
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
+	promoteWaitlist(ctx, events)
+	w.Header().Set("ETag", formatETag(events.Version))
+	writeJSON(ctx, w, events)
+}
+
+// PatchEvents Patch a single record from events table in the wcs database
+// @Summary Patch an record in table events
+// @Description Apply a JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) document to a single record from events table in the wcs database
+// @Tags Events
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the patch is based on"
+// @Param  Events body object true "merge patch (application/merge-patch+json) or json patch (application/json-patch+json) document"
+// @Success 200 {object} model.Events
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Events "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
+// @Router /events/{argID} [patch]
+// echo '{"tags": ["seminar"]}' | http PATCH "http://localhost:8080/events/1" If-Match:'"1"' Content-Type:application/merge-patch+json X-Api-User:user123
+func PatchEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "events", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	current, err := dao.GetEvents(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if version != 0 && version != current.Version {
+		writePreconditionFailed(ctx, w, current.Version, current)
+		return
+	}
+
+	events := &model.Events{}
+	if err := applyPatch(r, current, events); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := events.BeforeSave(); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	events.Prepare()
+
+	if err := events.Validate(model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	// the patch was computed against current, so the update only goes through if nobody changed it since
+	events.Version = current.Version
+	events, _, err = dao.UpdateEvents(ctx,
+		argID,
+		events)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, events.Version, events)
+		return
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	promoteWaitlist(ctx, events)
+	w.Header().Set("ETag", formatETag(events.Version))
 	writeJSON(ctx, w, events)
 }
 
@@ -221,11 +375,14 @@ func UpdateEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the delete is based on"
 // @Success 204 {object} model.Events
 // @Failure 400 {object} api.HTTPError
+// @Failure 412 {object} model.Events "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
 // @Failure 500 {object} api.HTTPError
 // @Router /events/{argID} [delete]
-// http DELETE "http://localhost:8080/events/1" X-Api-User:user123
+// http DELETE "http://localhost:8080/events/1" If-Match:'"1"' X-Api-User:user123
 func DeleteEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -240,7 +397,19 @@ func DeleteEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	rowsAffected, err := dao.DeleteEvents(ctx, argID)
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	rowsAffected, err := dao.DeleteEvents(ctx, argID, version)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		if current, err := dao.GetEvents(ctx, argID); err == nil {
+			writePreconditionFailed(ctx, w, current.Version, current)
+			return
+		}
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -248,3 +417,41 @@ func DeleteEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 
 	writeRowsAffected(w, rowsAffected)
 }
+
+// maxEventRange longest time range occurrences are listed for in one request
+const maxEventRange = 3 * 366 * 24 * time.Hour
+
+// readTimeRange returns the from and to query parameters, RFC 3339 times or dates in TimeZone.
+// from defaults to now and to to 30 days after from.
+func readTimeRange(r *http.Request) (from, to time.Time, err error) {
+	from, err = readTime(r, "from", time.Now())
+	if err != nil {
+		return from, to, err
+	}
+
+	to, err = readTime(r, "to", from.AddDate(0, 0, 30))
+	if err != nil {
+		return from, to, err
+	}
+
+	if !to.After(from) || to.Sub(from) > maxEventRange {
+		return from, to, dao.ErrBadParams.Wrap(fmt.Errorf("to must be after from and at most %d days later", maxEventRange/(24*time.Hour)))
+	}
+	return from, to, nil
+}
+
+// readTime returns the time in query parameter param, v when it is absent
+func readTime(r *http.Request, param string, v time.Time) (time.Time, error) {
+	value := r.FormValue(param)
+	if value == "" {
+		return v, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, TimeZone); err == nil {
+		return t, nil
+	}
+	return v, dao.ErrBadParams.Wrap(fmt.Errorf("%s must be an RFC 3339 time or a date such as 2024-01-31", param))
+}
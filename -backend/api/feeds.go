@@ -0,0 +1,387 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	// SiteURL base url of the public site feed links point to, e.g. https://wcs.example.org. Feeds are not served without it.
+	SiteURL = ""
+
+	// SiteName title of the site used in feeds
+	SiteName = "WCS"
+
+	// FeedSize number of the latest records listed in a feed
+	FeedSize int64 = 20
+)
+
+const (
+	feedRSS  = "rss"
+	feedAtom = "atom"
+)
+
+func configFeedsRouter(router *httprouter.Router) {
+	router.GET("/feeds/news.rss", cached("news", feedHandler("news", feedRSS)))
+	router.GET("/feeds/news.atom", cached("news", feedHandler("news", feedAtom)))
+	router.GET("/feeds/events.rss", cached("events", feedHandler("events", feedRSS)))
+	router.GET("/feeds/events.atom", cached("events", feedHandler("events", feedAtom)))
+}
+
+func configGinFeedsRouter(router gin.IRoutes) {
+	router.GET("/feeds/news.rss", ConverHttprouterToGin(cached("news", feedHandler("news", feedRSS))))
+	router.GET("/feeds/news.atom", ConverHttprouterToGin(cached("news", feedHandler("news", feedAtom))))
+	router.GET("/feeds/events.rss", ConverHttprouterToGin(cached("events", feedHandler("events", feedRSS))))
+	router.GET("/feeds/events.atom", ConverHttprouterToGin(cached("events", feedHandler("events", feedAtom))))
+}
+
+// feedItem record of a feed, read from a news or events record
+type feedItem struct {
+	ID         int32
+	Title      string
+	Content    string
+	CreateTime time.Time
+	UpdateTime time.Time
+	Cover      string
+	CoverMedia *model.Media
+	Tags       model.TagList
+}
+
+// feedHandler returns the handler writing the feed of the published records of table in format
+func feedHandler(table, format string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		GetFeed(w, r, ps, table, format)
+	}
+}
+
+// GetFeed is a function to get the latest published news or events as RSS 2.0 or Atom feed
+// @Summary Get the RSS or Atom feed of news or events
+// @Tags Feeds
+// @Description GetFeed lists the latest published records, newest first, with their tags as categories and their cover as enclosure.
+// @Description Drafts are never listed, also not to signed in admins.
+// @Produce  xml
+// @Param   tag   query    string  false  "only records carrying the tag with this slug"
+// @Param   If-None-Match header string false "ETag of a cached copy"
+// @Param   If-Modified-Since header string false "Last-Modified of a cached copy"
+// @Success 200 {string} string "application/rss+xml or application/atom+xml"
+// @Success 304 "cached copy is current"
+// @Failure 400 {object} api.HTTPError
+// @Failure 503 {object} api.HTTPError "no --site-url is configured"
+// @Router /feeds/news.rss [get]
+// @Router /feeds/news.atom [get]
+// @Router /feeds/events.rss [get]
+// @Router /feeds/events.atom [get]
+// http "http://localhost:8080/feeds/news.rss?tag=seminar"
+func GetFeed(w http.ResponseWriter, r *http.Request, ps httprouter.Params, table, format string) {
+	ctx := initializeContext(r)
+	if err := ValidateRequest(ctx, r, table, model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	base, err := publicSiteURL()
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	tag := r.FormValue("tag")
+	items, err := feedItems(ctx, table, tag)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	title := SiteName + " " + strings.Title(table)
+	if tag != "" {
+		title += " - " + tagName(items, tag)
+	}
+
+	var (
+		v           interface{}
+		contentType string
+	)
+	switch format {
+	case feedAtom:
+		v, contentType = atomOf(base, absURL(base, r.URL.RequestURI()), table, title, items), "application/atom+xml; charset=utf-8"
+	default:
+		v, contentType = rssOf(base, absURL(base, r.URL.RequestURI()), table, title, items), "application/rss+xml; charset=utf-8"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(xml.Header)
+	if err = xml.NewEncoder(&buf).Encode(v); err != nil {
+		returnError(ctx, w, r, dao.ErrUnableToMarshalJSON.Wrap(err))
+		return
+	}
+
+	writeDocument(w, r, contentType, buf.Bytes(), feedUpdated(items))
+}
+
+// feedItems returns the latest published records of table, optionally only those carrying the tag with slug
+func feedItems(ctx context.Context, table, slug string) ([]*feedItem, error) {
+	filters := []dao.QueryFilter{dao.Published(time.Now())}
+	if slug != "" {
+		filters = append(filters, dao.WithTag(table, slug))
+	}
+
+	var items []*feedItem
+	switch table {
+	case "news":
+		records, _, err := dao.GetAllNews(ctx, 0, FeedSize, "create_time DESC, id DESC", filters...)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			items = append(items, &feedItem{ID: record.ID, Title: record.Title, Content: record.Content, CreateTime: record.CreateTime,
+				UpdateTime: record.UpdateTime, Cover: record.Cover, CoverMedia: record.CoverMedia, Tags: record.Tags})
+		}
+	case "events":
+		records, _, err := dao.GetAllEvents(ctx, 0, FeedSize, "create_time DESC, id DESC", filters...)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			items = append(items, &feedItem{ID: record.ID, Title: record.Title, Content: record.Content, CreateTime: record.CreateTime,
+				UpdateTime: record.UpdateTime, Cover: record.Cover, CoverMedia: record.CoverMedia, Tags: record.Tags})
+		}
+	default:
+		return nil, dao.ErrNotFound
+	}
+
+	// rows written before the sanitizer existed may still carry unsafe markup
+	for _, item := range items {
+		item.Content = model.Sanitize(model.SanitizeRichText, item.Content)
+	}
+	return items, nil
+}
+
+// feedUpdated returns the latest update_time of items, zero without items
+func feedUpdated(items []*feedItem) time.Time {
+	var updated time.Time
+	for _, item := range items {
+		if item.UpdateTime.After(updated) {
+			updated = item.UpdateTime
+		}
+	}
+	return updated
+}
+
+// tagName returns the name of the tag with slug as carried by items, the slug when no item carries it
+func tagName(items []*feedItem, slug string) string {
+	for _, item := range items {
+		for _, tag := range item.Tags {
+			if tag.Slug == slug {
+				return tag.Name
+			}
+		}
+	}
+	return slug
+}
+
+// itemLink returns the url of the public page of a record
+func itemLink(base, table string, id int32) string {
+	return fmt.Sprintf("%s/%s?id=%d", base, table, id)
+}
+
+// enclosure returns url, type and size of the cover of item, empty url without cover
+func (item *feedItem) enclosure(base string) (url, mimeType string, length int64) {
+	if item.CoverMedia != nil {
+		return absURL(base, item.CoverMedia.URL), item.CoverMedia.MimeType, item.CoverMedia.Size
+	}
+	if item.Cover == "" {
+		return "", "", 0
+	}
+
+	mimeType = mime.TypeByExtension(path.Ext(item.Cover))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return absURL(base, item.Cover), mimeType, 0
+}
+
+// publicSiteURL returns SiteURL, the base url of links in cached documents and in mails. It is never taken from the Host
+// header of a request: the client chooses it, and a cached copy or a mail pointing to its host would send readers there.
+// error - ErrUnavailable, no SiteURL is configured
+func publicSiteURL() (string, error) {
+	if SiteURL == "" {
+		return "", dao.ErrUnavailable.Wrap(errors.New("no site url is configured, set --site-url"))
+	}
+	return strings.TrimSuffix(SiteURL, "/"), nil
+}
+
+// absURL resolves u against base unless it is absolute already
+func absURL(base, u string) string {
+	if strings.Contains(u, "://") {
+		return u
+	}
+	if !strings.HasPrefix(u, "/") {
+		u = "/" + u
+	}
+	return base + u
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string     `xml:"title"`
+	Link          string     `xml:"link"`
+	Description   string     `xml:"description"`
+	Self          atomLink   `xml:"atom:link"`
+	LastBuildDate string     `xml:"lastBuildDate,omitempty"`
+	Items         []*rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	Description string        `xml:"description"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Categories  []string      `xml:"category"`
+	Enclosure   *rssEnclosure `xml:"enclosure"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int64  `xml:"length,attr"`
+}
+
+// rssOf returns the RSS 2.0 feed of items
+func rssOf(base, self, table, title string, items []*feedItem) *rssFeed {
+	feed := &rssFeed{
+		Version: "2.0",
+		AtomNS:  "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        base + "/" + table,
+			Description: title,
+			Self:        atomLink{Href: self, Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	if len(items) > 0 {
+		feed.Channel.LastBuildDate = feedUpdated(items).UTC().Format(time.RFC1123Z)
+	}
+
+	for _, item := range items {
+		link := itemLink(base, table, item.ID)
+		entry := &rssItem{
+			Title:       item.Title,
+			Link:        link,
+			Description: item.Content,
+			GUID:        rssGUID{IsPermaLink: true, Value: link},
+			PubDate:     item.CreateTime.UTC().Format(time.RFC1123Z),
+			Categories:  item.Tags.Names(),
+		}
+		if url, mimeType, length := item.enclosure(base); url != "" {
+			entry.Enclosure = &rssEnclosure{URL: url, Type: mimeType, Length: length}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, entry)
+	}
+	return feed
+}
+
+type atomFeed struct {
+	XMLName xml.Name     `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string       `xml:"title"`
+	ID      string       `xml:"id"`
+	Updated string       `xml:"updated"`
+	Author  atomAuthor   `xml:"author"`
+	Links   []atomLink   `xml:"link"`
+	Entries []*atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr,omitempty"`
+	Type   string `xml:"type,attr,omitempty"`
+	Length int64  `xml:"length,attr,omitempty"`
+}
+
+type atomCategory struct {
+	Term  string `xml:"term,attr"`
+	Label string `xml:"label,attr,omitempty"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// atomOf returns the Atom feed of items
+func atomOf(base, self, table, title string, items []*feedItem) *atomFeed {
+	updated := time.Now()
+	if len(items) > 0 {
+		updated = feedUpdated(items)
+	}
+
+	feed := &atomFeed{
+		Title:   title,
+		ID:      self,
+		Updated: updated.UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: SiteName},
+		Links: []atomLink{
+			{Href: self, Rel: "self", Type: "application/atom+xml"},
+			{Href: base + "/" + table, Rel: "alternate", Type: "text/html"},
+		},
+	}
+
+	for _, item := range items {
+		link := itemLink(base, table, item.ID)
+		entry := &atomEntry{
+			Title:     item.Title,
+			ID:        link,
+			Published: item.CreateTime.UTC().Format(time.RFC3339),
+			Updated:   item.UpdateTime.UTC().Format(time.RFC3339),
+			Links:     []atomLink{{Href: link, Rel: "alternate", Type: "text/html"}},
+			Content:   atomContent{Type: "html", Value: item.Content},
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag.Slug, Label: tag.Name})
+		}
+		if url, mimeType, length := item.enclosure(base); url != "" {
+			entry.Links = append(entry.Links, atomLink{Href: url, Rel: "enclosure", Type: mimeType, Length: length})
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return feed
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"wcs/cache"
+	"wcs/dao"
+	"wcs/model"
+)
+
+func TestFeedNeedsSiteURL(t *testing.T) {
+	srv := newTestServer(t)
+	setSiteURL(t, "")
+
+	if status, body := srv.do(http.DefaultClient, http.MethodGet, "/api/feeds/news.rss", ""); status != http.StatusServiceUnavailable {
+		t.Fatalf("feed without a site url answered %d %s, want 503", status, body)
+	}
+}
+
+func TestFeedIgnoresHostHeader(t *testing.T) {
+	srv := newTestServer(t)
+	srv.database.Cache = cache.New(cache.NewLRU(1 << 20))
+	setSiteURL(t, "https://wcs.example.org/")
+
+	news := &model.News{Title: "Seminar", Content: "<p>talk</p>", Status: model.StatusPublished}
+	if _, _, err := dao.AddNews(srv.ctx, news); err != nil {
+		t.Fatal(err)
+	}
+
+	// the forged request fills the cache, the second one is served from it
+	for _, host := range []string{"evil.example", ""} {
+		var headers []string
+		if host != "" {
+			headers = []string{"Host", host, "X-Forwarded-Proto", "https"}
+		}
+		status, body := srv.do(http.DefaultClient, http.MethodGet, "/api/feeds/news.rss", "", headers...)
+		if status != http.StatusOK {
+			t.Fatalf("feed answered %d %s", status, body)
+		}
+		if strings.Contains(body, "evil.example") {
+			t.Errorf("feed links to the Host header of the request: %s", body)
+		}
+		if !strings.Contains(body, "<link>https://wcs.example.org/news?id=1</link>") {
+			t.Errorf("feed does not link to the site url: %s", body)
+		}
+	}
+}
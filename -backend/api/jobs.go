@@ -0,0 +1,121 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+func configJobsRouter(router *httprouter.Router) {
+	router.GET("/jobs", GetAllJobs)
+	router.POST("/jobs/:argID/retry", RetryJob)
+}
+
+func configGinJobsRouter(router gin.IRoutes) {
+	router.GET("/jobs", ConverHttprouterToGin(GetAllJobs))
+	router.POST("/jobs/:argID/retry", ConverHttprouterToGin(RetryJob))
+}
+
+// GetAllJobs is a function to get a slice of record(s) from jobs table in the wcs database
+// @Summary Get list of background jobs
+// @Tags Jobs
+// @Description GetAllJobs lists the background jobs, most recently due first, admin only
+// @Produce  json
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   status   query    string  false        "one of pending, running, done, failed"
+// @Success 200 {object} api.PagedResults{data=[]model.Jobs}
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Router /jobs [get]
+// http "http://localhost:8080/jobs?status=failed" X-Api-User:user123
+func GetAllJobs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "jobs", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	var filters []dao.QueryFilter
+	switch status := r.FormValue("status"); status {
+	case "":
+	case model.JobPending, model.JobRunning, model.JobDone, model.JobFailed:
+		filters = append(filters, dao.WithStatus(status))
+	default:
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllJobs(ctx, page, pagesize, "run_at desc, id desc", filters...)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	writeJSON(ctx, w, result)
+}
+
+// RetryJob is a function to run a failed background job again
+// @Summary Retry a failed background job
+// @Tags Jobs
+// @Description RetryJob gives a failed job a fresh set of attempts, due now, admin only
+// @Produce  json
+// @Param  argID path int true "id of the job"
+// @Success 200 {object} model.Jobs
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "job not failed"
+// @Router /jobs/{argID}/retry [post]
+// http POST "http://localhost:8080/jobs/7/retry" X-Api-User:user123
+func RetryJob(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "jobs", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	job, err := dao.RetryJob(ctx, argID, time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, job)
+}
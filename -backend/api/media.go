@@ -0,0 +1,356 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+
+	"wcs/dao"
+	"wcs/images"
+	"wcs/model"
+	"wcs/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guregu/null"
+	"github.com/julienschmidt/httprouter"
+)
+
+// MaxUploadSize upper bound in bytes of a file uploaded to the media library
+var MaxUploadSize int64 = 10 << 20
+
+// uploadTypes content types accepted by the media library, mapped to the file extension they are stored with
+var uploadTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+var (
+	// errUploadTooLarge error when an upload exceeds MaxUploadSize
+	errUploadTooLarge = fmt.Errorf("upload exceeds size limit")
+
+	// errUnsupportedMediaType error when the sniffed content type of an upload is not accepted
+	errUnsupportedMediaType = fmt.Errorf("unsupported media type")
+)
+
+func configMediaRouter(router *httprouter.Router) {
+	router.GET("/media", GetAllMedia)
+	router.POST("/media", UploadMedia)
+	router.GET("/media/:argID", GetMedia)
+	router.DELETE("/media/:argID", DeleteMedia)
+}
+
+func configGinMediaRouter(router gin.IRoutes) {
+	router.GET("/media", ConverHttprouterToGin(GetAllMedia))
+	router.POST("/media", ConverHttprouterToGin(UploadMedia))
+	router.GET("/media/:argID", ConverHttprouterToGin(GetMedia))
+	router.DELETE("/media/:argID", ConverHttprouterToGin(DeleteMedia))
+}
+
+// GetAllMedia is a function to get a slice of record(s) from media table in the wcs database
+// @Summary Get list of Media
+// @Tags Media
+// @Description GetAllMedia is a handler to get a slice of record(s) from media table in the wcs database
+// @Accept  json
+// @Produce  json
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "db sort order column"
+// @Success 200 {object} api.PagedResults{data=[]model.Media}
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /media [get]
+// http "http://localhost:8080/media?page=0&pagesize=20" X-Api-User:user123
+func GetAllMedia(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	order := r.FormValue("order")
+
+	if err := ValidateRequest(ctx, r, "media", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllMedia(ctx, page, pagesize, order)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	writeJSON(ctx, w, result)
+}
+
+// GetMedia is a function to get a single record from the media table in the wcs database
+// @Summary Get record from table Media by  argID
+// @Tags Media
+// @ID argID
+// @Description GetMedia is a function to get a single record from the media table in the wcs database
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {object} model.Media
+// @Success 304 "cached copy is current"
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
+// @Router /media/{argID} [get]
+// http "http://localhost:8080/media/1" X-Api-User:user123
+func GetMedia(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "media", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetMedia(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeVersioned(ctx, w, r, record.Version, record)
+}
+
+// UploadMedia upload a file to the media library
+// @Summary Upload a file to the media library
+// @Description Admin only. The content type is sniffed from the file content, jpeg, png, gif, webp and pdf are accepted.
+// @Description Files are stored by content hash, uploading a file that is stored already returns the existing record.
+// @Description Images are stripped of EXIF, GPS, XMP and comment metadata and get a blurhash placeholder and resized variants,
+// @Description jpeg or png for images with transparency. No webp variants are rendered, webp uploads are kept as originals.
+// @Tags Media
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param file formData file true "file to upload"
+// @Success 200 {object} model.Media "file was stored already"
+// @Success 201 {object} model.Media
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 413 {object} api.HTTPError
+// @Failure 415 {object} api.HTTPError
+// @Router /media [post]
+// http -f POST "http://localhost:8080/media" file@cover.jpg X-Api-User:user123
+func UploadMedia(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "media", model.Create); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	var part io.Reader
+	var filename string
+	for {
+		p, err := reader.NextPart()
+		if err != nil {
+			returnError(ctx, w, r, dao.ErrBadParams)
+			return
+		}
+
+		if p.FormName() == "file" {
+			part, filename = p, p.FileName()
+			break
+		}
+	}
+
+	// spool to disk, hashing on the way, reading one byte past the limit to detect oversized uploads
+	tmp, err := os.CreateTemp("", "wcs-upload-*")
+	if err != nil {
+		returnError(ctx, w, r, dao.ErrInsertFailed.Wrap(err))
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(part, MaxUploadSize+1))
+	if err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if size > MaxUploadSize {
+		returnError(ctx, w, r, errUploadTooLarge)
+		return
+	}
+
+	head := make([]byte, 512)
+	n, _ := tmp.ReadAt(head, 0)
+	mimeType := http.DetectContentType(head[:n])
+	ext, ok := uploadTypes[mimeType]
+	if !ok {
+		returnError(ctx, w, r, errUnsupportedMediaType)
+		return
+	}
+
+	digest := hex.EncodeToString(hash.Sum(nil))
+	record := &model.Media{
+		Digest:     digest,
+		StorageKey: storage.ContentKey(digest, ext),
+		Filename:   uploadFilename(filename),
+		MimeType:   mimeType,
+		Size:       size,
+	}
+
+	if adminID, ok := dao.CurrentAdminID(ctx); ok {
+		record.AdminID = null.IntFrom(int64(adminID))
+	}
+
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil {
+		returnError(ctx, w, r, dao.ErrInsertFailed.Wrap(err))
+		return
+	}
+
+	var content io.Reader = tmp
+	var variants []dao.MediaVariantUpload
+	if images.Processable(mimeType) {
+		processed, err := images.Process(tmp, mimeType)
+		if errors.Is(err, images.ErrTooManyPixels) {
+			returnError(ctx, w, r, errUploadTooLarge)
+			return
+		}
+		if err != nil {
+			returnError(ctx, w, r, errUnsupportedMediaType)
+			return
+		}
+
+		content = bytes.NewReader(processed.Data)
+		record.Size = int64(len(processed.Data))
+		record.Width, record.Height = int32(processed.Width), int32(processed.Height)
+		record.Blurhash = processed.Blurhash
+
+		for _, variant := range processed.Variants {
+			variants = append(variants, dao.MediaVariantUpload{
+				MediaVariants: &model.MediaVariants{
+					Width:      int32(variant.Width),
+					Height:     int32(variant.Height),
+					MimeType:   variant.MimeType,
+					StorageKey: storage.ContentKey(digest, fmt.Sprintf("-%dw%s", variant.Width, variant.Ext)),
+					Size:       int64(len(variant.Data)),
+				},
+				Content: variant.Data,
+			})
+		}
+	}
+
+	result, created, err := dao.AddMedia(ctx, record, content, variants...)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(result.Version))
+	if created {
+		w.Header().Set("Location", fmt.Sprintf("%s/%d", r.URL.Path, result.ID))
+		w.WriteHeader(http.StatusCreated)
+	}
+	writeJSON(ctx, w, result)
+}
+
+// DeleteMedia Delete a single record from media table in the wcs database and its stored file
+// @Summary Delete a record from media
+// @Description Admin only. Records referencing the file as cover or avatar lose the reference.
+// @Tags Media
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag of the version being deleted"
+// @Success 204 {object} model.Media
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 412 {object} model.Media "record was modified since the If-Match version"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 500 {object} api.HTTPError
+// @Router /media/{argID} [delete]
+// http DELETE "http://localhost:8080/media/1" If-Match:'"1"' X-Api-User:user123
+func DeleteMedia(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "media", model.Delete); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	rowsAffected, err := dao.DeleteMedia(ctx, argID, version)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		if current, err := dao.GetMedia(ctx, argID); err == nil {
+			writePreconditionFailed(ctx, w, current.Version, current)
+			return
+		}
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeRowsAffected(w, rowsAffected)
+}
+
+// uploadFilename base name of an uploaded file, truncated to fit the filename column
+func uploadFilename(name string) string {
+	name = filepath.Base(filepath.ToSlash(name))
+	if name == "." || name == "/" {
+		return ""
+	}
+
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	return name
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"errors"
 	"net/http"
+	"time"
 
 	"wcs/dao"
 	"wcs/model"
@@ -16,18 +18,24 @@ var (
 )
 
 func configNewsRouter(router *httprouter.Router) {
-	router.GET("/news", GetAllNews)
+	router.GET("/news", cached("news", GetAllNews))
 	router.POST("/news", AddNews)
-	router.GET("/news/:argID", GetNews)
+	router.POST("/news/bulk", bulkHandler("news"))
+	router.GET("/news/:argID", cached("news", GetNews))
+	router.GET("/news/:argID/history", statusHistoryHandler("news"))
 	router.PUT("/news/:argID", UpdateNews)
+	router.PATCH("/news/:argID", PatchNews)
 	router.DELETE("/news/:argID", DeleteNews)
 }
 
 func configGinNewsRouter(router gin.IRoutes) {
-	router.GET("/news", ConverHttprouterToGin(GetAllNews))
+	router.GET("/news", ConverHttprouterToGin(cached("news", GetAllNews)))
 	router.POST("/news", ConverHttprouterToGin(AddNews))
-	router.GET("/news/:argID", ConverHttprouterToGin(GetNews))
+	router.POST("/news/bulk", ConverHttprouterToGin(bulkHandler("news")))
+	router.GET("/news/:argID", ConverHttprouterToGin(cached("news", GetNews)))
+	router.GET("/news/:argID/history", ConverHttprouterToGin(statusHistoryHandler("news")))
 	router.PUT("/news/:argID", ConverHttprouterToGin(UpdateNews))
+	router.PATCH("/news/:argID", ConverHttprouterToGin(PatchNews))
 	router.DELETE("/news/:argID", ConverHttprouterToGin(DeleteNews))
 }
 
@@ -35,11 +43,14 @@ func configGinNewsRouter(router gin.IRoutes) {
 // @Summary Get list of News
 // @Tags News
 // @Description GetAllNews is a handler to get a slice of record(s) from news table in the wcs database
+// @Description Only published records are listed unless the request comes from a signed in admin.
 // @Accept  json
 // @Produce  json
 // @Param   page     query    int     false        "page requested (defaults to 0)"
 // @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
 // @Param   order    query    string  false        "db sort order column"
+// @Param   tag      query    string  false        "only records carrying the tag with this slug"
+// @Param   status   query    string  false        "admin only, one of draft, scheduled, published, archived"
 // @Success 200 {object} api.PagedResults{data=[]model.News}
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
@@ -66,7 +77,17 @@ func GetAllNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	records, totalRows, err := dao.GetAllNews(ctx, page, pagesize, order)
+	filters, err := publishingFilters(ctx, r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if tag := r.FormValue("tag"); tag != "" {
+		filters = append(filters, dao.WithTag("news", tag))
+	}
+
+	records, totalRows, err := dao.GetAllNews(ctx, page, pagesize, order, filters...)
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -81,10 +102,13 @@ func GetAllNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Tags News
 // @ID argID
 // @Description GetNews is a function to get a single record from the news table in the wcs database
+// @Description Records that are not published are only returned to signed in admins.
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} model.News
+// @Success 304 "cached copy is current"
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
 // @Router /wcs/{argID} [get]
@@ -109,7 +133,12 @@ func GetNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	if !isAdmin(ctx) && !record.IsPublic(time.Now()) {
+		returnError(ctx, w, r, dao.ErrNotFound)
+		return
+	}
+
+	writeVersioned(ctx, w, r, record.Version, record)
 }
 
 // AddNews add to add a single record to news table in the wcs database
@@ -122,8 +151,10 @@ func GetNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Success 200 {object} model.News
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /news [post]
-// echo '{"id": 76,"title": "cGSfstycEikZjCWZYJhEuWwWC","content": "YkQrALQfQfpqwiSLOctWUkrOs","create_time": "2311-07-11T12:25:43.563373812+08:00","update_time": "2177-04-07T01:41:28.623684615+08:00","tags": "erGBbmpFXmOvpNmVsAOvLkCuF","cover": "kljHXlIKVdfpvdiQDEksfgyqH"}' | http POST "http://localhost:8080/news" X-Api-User:user123
+// echo '{"id": 76,"title": "cGSfstycEikZjCWZYJhEuWwWC","content": "YkQrALQfQfpqwiSLOctWUkrOs","create_time": "2311-07-11T12:25:43.563373812+08:00","update_time": "2177-04-07T01:41:28.623684615+08:00","tags": ["seminar","ai"],"cover": "kljHXlIKVdfpvdiQDEksfgyqH"}' | http POST "http://localhost:8080/news" X-Api-User:user123
 func AddNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 	news := &model.News{}
@@ -135,12 +166,13 @@ func AddNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	if err := news.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	news.Prepare()
 
 	if err := news.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -156,22 +188,28 @@ func AddNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(news.Version))
 	writeJSON(ctx, w, news)
 }
 
 // UpdateNews Update a single record from news table in the wcs database
 // @Summary Update an record in table news
-// @Description Update a single record from news table in the wcs database
+// @Description Update a single record from news table in the wcs database, replacing every client writable column
 // @Tags News
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the update is based on"
 // @Param  News body model.News true "Update News record"
 // @Success 200 {object} model.News
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.News "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /news/{argID} [put]
-// echo '{"id": 76,"title": "cGSfstycEikZjCWZYJhEuWwWC","content": "YkQrALQfQfpqwiSLOctWUkrOs","create_time": "2311-07-11T12:25:43.563373812+08:00","update_time": "2177-04-07T01:41:28.623684615+08:00","tags": "erGBbmpFXmOvpNmVsAOvLkCuF","cover": "kljHXlIKVdfpvdiQDEksfgyqH"}' | http PUT "http://localhost:8080/news/1"  X-Api-User:user123
+// echo '{"id": 76,"title": "cGSfstycEikZjCWZYJhEuWwWC","content": "YkQrALQfQfpqwiSLOctWUkrOs","create_time": "2311-07-11T12:25:43.563373812+08:00","update_time": "2177-04-07T01:41:28.623684615+08:00","tags": ["seminar","ai"],"cover": "kljHXlIKVdfpvdiQDEksfgyqH"}' | http PUT "http://localhost:8080/news/1" If-Match:'"1"' X-Api-User:user123
 func UpdateNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -189,12 +227,68 @@ func UpdateNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	if err := news.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	news.Prepare()
 
 	if err := news.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "news", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	news.Version = version
+
+	news, _, err = dao.UpdateNews(ctx,
+		argID,
+		news)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, news.Version, news)
+		return
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(news.Version))
+	writeJSON(ctx, w, news)
+}
+
+// PatchNews Patch a single record from news table in the wcs database
+// @Summary Patch an record in table news
+// @Description Apply a JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) document to a single record from news table in the wcs database
+// @Tags News
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the patch is based on"
+// @Param  News body object true "merge patch (application/merge-patch+json) or json patch (application/json-patch+json) document"
+// @Success 200 {object} model.News
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.News "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
+// @Router /news/{argID} [patch]
+// echo '{"tags": ["seminar"]}' | http PATCH "http://localhost:8080/news/1" If-Match:'"1"' Content-Type:application/merge-patch+json X-Api-User:user123
+func PatchNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -203,14 +297,56 @@ func UpdateNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	current, err := dao.GetNews(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if version != 0 && version != current.Version {
+		writePreconditionFailed(ctx, w, current.Version, current)
+		return
+	}
+
+	news := &model.News{}
+	if err := applyPatch(r, current, news); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := news.BeforeSave(); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	news.Prepare()
+
+	if err := news.Validate(model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	// the patch was computed against current, so the update only goes through if nobody changed it since
+	news.Version = current.Version
 	news, _, err = dao.UpdateNews(ctx,
 		argID,
 		news)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, news.Version, news)
+		return
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(news.Version))
 	writeJSON(ctx, w, news)
 }
 
@@ -221,11 +357,14 @@ func UpdateNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the delete is based on"
 // @Success 204 {object} model.News
 // @Failure 400 {object} api.HTTPError
+// @Failure 412 {object} model.News "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
 // @Failure 500 {object} api.HTTPError
 // @Router /news/{argID} [delete]
-// http DELETE "http://localhost:8080/news/1" X-Api-User:user123
+// http DELETE "http://localhost:8080/news/1" If-Match:'"1"' X-Api-User:user123
 func DeleteNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -240,7 +379,19 @@ func DeleteNews(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	rowsAffected, err := dao.DeleteNews(ctx, argID)
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	rowsAffected, err := dao.DeleteNews(ctx, argID, version)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		if current, err := dao.GetNews(ctx, argID); err == nil {
+			writePreconditionFailed(ctx, w, current.Version, current)
+			return
+		}
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -0,0 +1,854 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"wcs/dao"
+	"wcs/mail"
+	"wcs/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guregu/null"
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	// SubscribeLimiter limits the newsletter subscriptions requested per client address, each one sends a confirmation email
+	SubscribeLimiter = NewRateLimiter(5, 10*time.Minute)
+
+	// DigestPeriod time between scheduled digests, 0 leaves digests to the admins
+	DigestPeriod time.Duration
+
+	// DigestBatchSize subscribers mailed per batch of a digest
+	DigestBatchSize = 50
+
+	// DigestBatchInterval time between the batches of a digest, throttling how fast its mails are queued
+	DigestBatchInterval = time.Minute
+)
+
+// kinds of the newsletter jobs
+const (
+	createDigestJob = "create_digest"
+	digestBatchJob  = "digest_batch"
+)
+
+const (
+	// confirmTokenTTL time a confirmation link of a subscription works
+	confirmTokenTTL = 7 * 24 * time.Hour
+
+	// digestNewsSince age of the news in the first digest, later digests have the news since the previous one
+	digestNewsSince = 7 * 24 * time.Hour
+
+	// digestEventsAhead time ahead of now a digest lists upcoming events for
+	digestEventsAhead = 30 * 24 * time.Hour
+
+	// maxDigestItems news and events listed per digest each
+	maxDigestItems = 10
+
+	// digestSummaryLength characters of a news kept as its summary
+	digestSummaryLength = 240
+)
+
+var (
+	// errSubscriptionToken the token of a confirmation or unsubscribe link is forged or expired
+	errSubscriptionToken = &dao.Error{Kind: dao.KindBadRequest, Message: "invalid or expired link"}
+
+	// errDigestMoved a batch of a digest was recorded by another worker meanwhile
+	errDigestMoved = errors.New("digest batch recorded already")
+)
+
+// SubscribeRequest body of a newsletter subscription
+type SubscribeRequest struct {
+	Email string `json:"email" example:"ada@example.org"`
+	Name  string `json:"name" example:"Ada"`
+}
+
+// SubscriptionRequest body carrying the token of a confirmation or unsubscribe link
+type SubscriptionRequest struct {
+	Token string `json:"token" example:"4.1715000000.q2V0bWJ5Y2hlY2tpbmcxMjM"`
+}
+
+// SubscriptionStatus state of a subscription after it was confirmed or cancelled
+type SubscriptionStatus struct {
+	Email  string `json:"email" example:"ada@example.org"`
+	Status string `json:"status" example:"active"`
+}
+
+// DigestRequest body of a digest started by an admin
+type DigestRequest struct {
+	// Subject of the mails, defaults to a count of the news and events
+	Subject string `json:"subject" example:"WCS digest: 2 news, 3 upcoming events"`
+}
+
+// digestBatch payload of a digest_batch job, the batch mails the subscribers after After
+type digestBatch struct {
+	DigestID int32 `json:"digest_id"`
+	After    int32 `json:"after"`
+}
+
+func configNewsletterRouter(router *httprouter.Router) {
+	router.POST("/subscribe", Subscribe)
+	router.POST("/subscribe/confirm", ConfirmSubscription)
+	router.POST("/unsubscribe", Unsubscribe)
+	router.GET("/subscribers", GetAllSubscribers)
+	router.GET("/digests", GetAllDigests)
+	router.POST("/digests", AddDigest)
+	router.GET("/digests/:argID", GetDigest)
+	router.POST("/digests/:argID/cancel", CancelDigest)
+	router.GET("/digestPreview", GetDigestPreview)
+}
+
+func configGinNewsletterRouter(router gin.IRoutes) {
+	router.POST("/subscribe", ConverHttprouterToGin(Subscribe))
+	router.POST("/subscribe/confirm", ConverHttprouterToGin(ConfirmSubscription))
+	router.POST("/unsubscribe", ConverHttprouterToGin(Unsubscribe))
+	router.GET("/subscribers", ConverHttprouterToGin(GetAllSubscribers))
+	router.GET("/digests", ConverHttprouterToGin(GetAllDigests))
+	router.POST("/digests", ConverHttprouterToGin(AddDigest))
+	router.GET("/digests/:argID", ConverHttprouterToGin(GetDigest))
+	router.POST("/digests/:argID/cancel", ConverHttprouterToGin(CancelDigest))
+	router.GET("/digestPreview", ConverHttprouterToGin(GetDigestPreview))
+}
+
+// Subscribe is a function to subscribe an email address to the newsletter
+// @Summary Subscribe to the newsletter
+// @Tags Newsletter
+// @Description Subscribe asks the address to confirm the subscription by email (double opt-in), it gets the newsletter once confirmed.
+// @Description The answer is the same whether the address is new, pending or subscribed already.
+// @Accept  json
+// @Produce  json
+// @Param  Subscribe body api.SubscribeRequest true "email and optional name"
+// @Success 202 {object} object
+// @Failure 400 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 429 {object} api.HTTPError "too many subscriptions from the address, see Retry-After"
+// @Failure 503 {object} api.HTTPError "no --site-url is configured"
+// @Router /subscribe [post]
+// echo '{"email": "ada@example.org","name": "Ada"}' | http POST "http://localhost:8080/subscribe"
+func Subscribe(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	ip := clientIP(r)
+	now := time.Now()
+
+	if ok, retryAfter := SubscribeLimiter.Allow(ip, now); !ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		returnError(ctx, w, r, errTooManyRequests)
+		return
+	}
+
+	body := &SubscribeRequest{}
+	if err := readJSON(r, body); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record := &model.Subscribers{Email: body.Email, Name: body.Name, IPAddress: ip}
+	record.Prepare()
+	if err := record.Validate(model.Create); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	base, err := publicSiteURL()
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	err = dao.Transaction(ctx, func(ctx context.Context) error {
+		subscriber, confirm, err := dao.Subscribe(ctx, record, now)
+		if err != nil || !confirm {
+			return err
+		}
+
+		data := map[string]string{
+			"Site":       SiteName,
+			"Name":       subscriber.Name,
+			"ConfirmURL": base + "/newsletter/confirm?token=" + url.QueryEscape(confirmToken(subscriber, now)),
+		}
+		return sendMail(ctx, subscriber.Email, "newsletter_confirm", data)
+	})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	writeJSON(ctx, w, map[string]string{})
+}
+
+// ConfirmSubscription is a function to confirm a newsletter subscription
+// @Summary Confirm a newsletter subscription
+// @Tags Newsletter
+// @Description ConfirmSubscription activates the subscription of the token of a confirmation link, confirming twice is not an error
+// @Accept  json
+// @Produce  json
+// @Param  Confirm body api.SubscriptionRequest true "token of the confirmation link"
+// @Success 200 {object} api.SubscriptionStatus
+// @Failure 400 {object} api.HTTPError "invalid or expired link"
+// @Failure 404 {object} api.HTTPError
+// @Router /subscribe/confirm [post]
+// echo '{"token": "4.1715000000.q2V0bWJ5Y2hlY2tpbmcxMjM"}' | http POST "http://localhost:8080/subscribe/confirm"
+func ConfirmSubscription(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	body := &SubscriptionRequest{}
+	if err := readJSON(r, body); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	now := time.Now()
+	subscriberID, expires, err := parseConfirmToken(body.Token, now)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	subscriber, err := dao.GetSubscriber(ctx, subscriberID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	if !hmac.Equal([]byte(body.Token), []byte(signConfirmToken(subscriber, expires))) {
+		returnError(ctx, w, r, errSubscriptionToken)
+		return
+	}
+
+	subscriber, err = dao.ConfirmSubscriber(ctx, subscriber.ID, subscriber.Email, now)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, &SubscriptionStatus{Email: subscriber.Email, Status: subscriber.Status})
+}
+
+// Unsubscribe is a function to unsubscribe from the newsletter
+// @Summary Unsubscribe from the newsletter
+// @Tags Newsletter
+// @Description Unsubscribe cancels the subscription of the token of an unsubscribe link, unsubscribing twice is not an error.
+// @Description Mail clients unsubscribing in one click (RFC 8058) post to the link with the token in the query, pages send it in the body.
+// @Accept  json
+// @Produce  json
+// @Param  token query string false "token of the unsubscribe link"
+// @Param  Unsubscribe body api.SubscriptionRequest false "token of the unsubscribe link"
+// @Success 200 {object} api.SubscriptionStatus
+// @Failure 400 {object} api.HTTPError "invalid link"
+// @Failure 404 {object} api.HTTPError
+// @Router /unsubscribe [post]
+// echo '{"token": "4.q2V0bWJ5Y2hlY2tpbmcxMjM"}' | http POST "http://localhost:8080/unsubscribe"
+func Unsubscribe(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		body := &SubscriptionRequest{}
+		if err := readJSON(r, body); err != nil {
+			returnError(ctx, w, r, err)
+			return
+		}
+		token = body.Token
+	}
+
+	subscriberID, err := parseUnsubscribeToken(token)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	subscriber, err := dao.Unsubscribe(ctx, subscriberID, time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, &SubscriptionStatus{Email: subscriber.Email, Status: subscriber.Status})
+}
+
+// GetAllSubscribers is a function to get a slice of record(s) from subscribers table in the wcs database
+// @Summary Get list of newsletter subscribers
+// @Tags Newsletter
+// @Description GetAllSubscribers lists the newsletter subscribers, newest first, admin only
+// @Produce  json
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   status   query    string  false        "one of pending, active, unsubscribed"
+// @Success 200 {object} api.PagedResults{data=[]model.Subscribers}
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Router /subscribers [get]
+// http "http://localhost:8080/subscribers?status=active" X-Api-User:user123
+func GetAllSubscribers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "subscribers", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	var filters []dao.QueryFilter
+	switch status := r.FormValue("status"); status {
+	case "":
+	case model.SubscriberPending, model.SubscriberActive, model.SubscriberUnsubscribed:
+		filters = append(filters, dao.WithStatus(status))
+	default:
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllSubscribers(ctx, page, pagesize, "id desc", filters...)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	writeJSON(ctx, w, result)
+}
+
+// GetAllDigests is a function to get a slice of record(s) from digests table in the wcs database
+// @Summary Get list of newsletter digests
+// @Tags Newsletter
+// @Description GetAllDigests lists the digests with the progress of sending them, newest first, admin only
+// @Produce  json
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Success 200 {object} api.PagedResults{data=[]model.Digests}
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Router /digests [get]
+// http "http://localhost:8080/digests" X-Api-User:user123
+func GetAllDigests(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "digests", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllDigests(ctx, page, pagesize, "id desc")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	writeJSON(ctx, w, result)
+}
+
+// GetDigest is a function to get a single record from the digests table in the wcs database
+// @Summary Get a newsletter digest
+// @Tags Newsletter
+// @Description GetDigest returns a digest with its content and the progress of sending it, admin only
+// @Produce  json
+// @Param  argID path int true "id"
+// @Success 200 {object} model.Digests
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /digests/{argID} [get]
+// http "http://localhost:8080/digests/3" X-Api-User:user123
+func GetDigest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "digests", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetDigest(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// AddDigest is a function to compose a digest and start sending it
+// @Summary Send a newsletter digest
+// @Tags Newsletter
+// @Description AddDigest composes a digest of the news since the previous digest and the upcoming events and starts sending it
+// @Description to the active subscribers in batches, admin only
+// @Accept  json
+// @Produce  json
+// @Param  Digest body api.DigestRequest false "optional subject"
+// @Success 200 {object} model.Digests
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "nothing new for a digest"
+// @Router /digests [post]
+// echo '{"subject": "Spring news"}' | http POST "http://localhost:8080/digests" X-Api-User:user123
+func AddDigest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "digests", model.Create); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	body := &DigestRequest{}
+	if r.ContentLength != 0 {
+		if err := readJSON(r, body); err != nil {
+			returnError(ctx, w, r, err)
+			return
+		}
+	}
+
+	record, err := createDigest(ctx, strings.TrimSpace(body.Subject), time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// CancelDigest is a function to stop sending a digest
+// @Summary Cancel a newsletter digest
+// @Tags Newsletter
+// @Description CancelDigest stops sending a digest, the subscribers it was queued for already still get it, admin only
+// @Produce  json
+// @Param  argID path int true "id"
+// @Success 200 {object} model.Digests
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "digest not sending"
+// @Router /digests/{argID}/cancel [post]
+// http POST "http://localhost:8080/digests/3/cancel" X-Api-User:user123
+func CancelDigest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "digests", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.CancelDigest(ctx, argID, time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// GetDigestPreview is a function to preview the digest that would be sent now
+// @Summary Preview a newsletter digest
+// @Tags Newsletter
+// @Description GetDigestPreview returns the html mail of the digest AddDigest would send now, nothing is saved or sent, admin only
+// @Produce  html
+// @Success 200 {string} string "html of the digest mail"
+// @Failure 401 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "nothing new for a digest"
+// @Router /digestPreview [get]
+// http "http://localhost:8080/digestPreview" X-Api-User:user123
+func GetDigestPreview(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	content, err := composeDigest(ctx, time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	digest := &model.Digests{Subject: digestSubject(content), Content: *content}
+	msg, err := renderDigest(digest, &model.Subscribers{Email: "subscriber@example.org"})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+	w.Write([]byte(msg.HTML))
+}
+
+// composeDigest returns the news published since the previous digest and the events coming up within digestEventsAhead of now
+// error - ErrDigestEmpty, there is neither
+func composeDigest(ctx context.Context, now time.Time) (*model.DigestContent, error) {
+	since := now.Add(-digestNewsSince)
+	latest, err := dao.LatestDigest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		since = latest.CreateTime
+	}
+
+	base, err := publicSiteURL()
+	if err != nil {
+		return nil, err
+	}
+
+	content := &model.DigestContent{News: []*model.DigestItem{}, Events: []*model.DigestItem{}}
+
+	news, _, err := dao.GetAllNews(ctx, 0, maxDigestItems, "create_time DESC, id DESC", dao.Published(now), dao.PublishedSince(since))
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range news {
+		content.News = append(content.News, &model.DigestItem{
+			ID:      record.ID,
+			Title:   record.Title,
+			Summary: summary(record.Content, digestSummaryLength),
+			URL:     itemLink(base, "news", record.ID),
+		})
+	}
+
+	events, _, err := dao.GetEventOccurrences(ctx, now, now.Add(digestEventsAhead), 0, maxPlanned, dao.Published(now))
+	if err != nil {
+		return nil, err
+	}
+	listed := make(map[int32]bool)
+	for _, event := range events {
+		if len(content.Events) == maxDigestItems {
+			break
+		}
+		// a series is listed at its next occurrence only
+		if listed[event.ID] {
+			continue
+		}
+		listed[event.ID] = true
+
+		content.Events = append(content.Events, &model.DigestItem{
+			ID:       event.ID,
+			Title:    event.Title,
+			When:     occurrenceWhen(event, event.Occurrence.Start),
+			Location: event.Location,
+			URL:      itemLink(base, "events", event.ID),
+		})
+	}
+
+	if content.Empty() {
+		return nil, dao.ErrDigestEmpty
+	}
+	return content, nil
+}
+
+// createDigest composes a digest at now and enqueues its first batch, subject defaults to digestSubject
+func createDigest(ctx context.Context, subject string, now time.Time) (*model.Digests, error) {
+	content, err := composeDigest(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	if subject == "" {
+		subject = digestSubject(content)
+	}
+
+	record := &model.Digests{Subject: truncate(subject, 255), Content: *content, CreateTime: now.UTC(), UpdateTime: now.UTC()}
+	err = dao.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := dao.AddDigest(ctx, record); err != nil {
+			return err
+		}
+		return enqueueDigestBatch(ctx, digestBatch{DigestID: record.ID}, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	wakeJobs()
+	return record, nil
+}
+
+// digestSubject returns the default subject of a digest with content
+func digestSubject(content *model.DigestContent) string {
+	return fmt.Sprintf("%s digest: %d news, %d upcoming events", SiteName, len(content.News), len(content.Events))
+}
+
+func enqueueDigestBatch(ctx context.Context, batch digestBatch, runAt time.Time) error {
+	job, err := model.NewJob(digestBatchJob, batch, runAt)
+	if err != nil {
+		return err
+	}
+	job.UniqueKey = null.StringFrom(fmt.Sprintf("%s:%d:%d", digestBatchJob, batch.DigestID, batch.After))
+
+	_, _, err = dao.EnqueueJob(ctx, job)
+	return err
+}
+
+// sendDigestBatch is the handler of a digest_batch job. It queues the digest for the next DigestBatchSize active subscribers,
+// records how far it got and enqueues the next batch DigestBatchInterval later, all in one transaction, so a batch
+// interrupted half way is redone from the start and nobody is mailed twice.
+func sendDigestBatch(ctx context.Context, job *model.Jobs) error {
+	batch := digestBatch{}
+	if err := job.Decode(&batch); err != nil {
+		return err
+	}
+
+	digest, err := dao.GetDigest(ctx, batch.DigestID)
+	if dao.KindOf(err) == dao.KindNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if digest.Status != model.DigestSending || digest.LastSubscriberID != batch.After {
+		return nil
+	}
+
+	subscribers, err := dao.GetSubscriberBatch(ctx, batch.After, DigestBatchSize)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	last, done := batch.After, len(subscribers) < DigestBatchSize
+	err = dao.Transaction(ctx, func(ctx context.Context) error {
+		for _, subscriber := range subscribers {
+			msg, err := renderDigest(digest, subscriber)
+			if err != nil {
+				return err
+			}
+			if _, err = queueMail(ctx, "digest", msg); err != nil {
+				return err
+			}
+			last = subscriber.ID
+		}
+
+		advanced, err := dao.AdvanceDigest(ctx, digest.ID, batch.After, last, len(subscribers), done, now)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return errDigestMoved
+		}
+
+		if done {
+			return nil
+		}
+		return enqueueDigestBatch(ctx, digestBatch{DigestID: digest.ID, After: last}, now.Add(DigestBatchInterval))
+	})
+	if errors.Is(err, errDigestMoved) {
+		return nil
+	}
+	if err == nil && done {
+		log.Printf("Digest %d sent to %d subscribers", digest.ID, digest.Recipients+int32(len(subscribers)))
+	}
+	return err
+}
+
+// renderDigest renders the mail of digest for subscriber, with its unsubscribe links
+func renderDigest(digest *model.Digests, subscriber *model.Subscribers) (*mail.Message, error) {
+	base, err := publicSiteURL()
+	if err != nil {
+		return nil, err
+	}
+
+	token := url.QueryEscape(unsubscribeToken(subscriber))
+
+	data := map[string]interface{}{
+		"Site":           SiteName,
+		"Name":           subscriber.Name,
+		"Subject":        digest.Subject,
+		"Content":        digest.Content,
+		"UnsubscribeURL": base + "/newsletter/unsubscribe?token=" + token,
+	}
+	msg, err := mail.Render("digest", subscriber.Email, data)
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Unsubscribe = base + "/api/unsubscribe?token=" + token
+	return msg, nil
+}
+
+// planDigest is a planner enqueueing a digest when DigestPeriod passed since the previous one. The unique key of the job
+// names the previous digest and the day, so servers polling together enqueue it once and an empty digest is retried the next day.
+func planDigest(ctx context.Context, now time.Time) error {
+	if DigestPeriod <= 0 {
+		return nil
+	}
+
+	latest, err := dao.LatestDigest(ctx)
+	if err != nil {
+		return err
+	}
+
+	var latestID int32
+	if latest != nil {
+		if now.Sub(latest.CreateTime) < DigestPeriod {
+			return nil
+		}
+		latestID = latest.ID
+	}
+
+	job, err := model.NewJob(createDigestJob, struct{}{}, now)
+	if err != nil {
+		return err
+	}
+	job.UniqueKey = null.StringFrom(fmt.Sprintf("%s:%d:%s", createDigestJob, latestID, now.UTC().Format("2006-01-02")))
+
+	_, _, err = dao.EnqueueJob(ctx, job)
+	return err
+}
+
+// createScheduledDigest is the handler of a create_digest job
+func createScheduledDigest(ctx context.Context, job *model.Jobs) error {
+	now := time.Now()
+
+	// an admin may have sent one since the job was enqueued
+	latest, err := dao.LatestDigest(ctx)
+	if err != nil {
+		return err
+	}
+	if latest != nil && now.Sub(latest.CreateTime) < DigestPeriod {
+		return nil
+	}
+
+	digest, err := createDigest(ctx, "", now)
+	if errors.Is(err, dao.ErrDigestEmpty) {
+		log.Printf("Skipping the scheduled digest, there is nothing new")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Sending scheduled digest %d", digest.ID)
+	return nil
+}
+
+// confirmToken returns the token of the confirmation link of subscriber, valid for confirmTokenTTL from now
+func confirmToken(subscriber *model.Subscribers, now time.Time) string {
+	return signConfirmToken(subscriber, now.Add(confirmTokenTTL).Unix())
+}
+
+// signConfirmToken returns the confirmation token of subscriber expiring at expires, signing its address
+// so the link stops working when the address changes
+func signConfirmToken(subscriber *model.Subscribers, expires int64) string {
+	id, exp := strconv.Itoa(int(subscriber.ID)), strconv.FormatInt(expires, 10)
+	return id + "." + exp + "." + sign("confirm", id, exp, subscriber.Email)
+}
+
+// parseConfirmToken returns the subscriber id and expiry of a confirmation token that did not expire at now,
+// the signature is checked against the subscriber by the caller
+func parseConfirmToken(token string, now time.Time) (subscriberID int32, expires int64, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, 0, errSubscriptionToken
+	}
+
+	id, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, errSubscriptionToken
+	}
+	expires, err = strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || now.Unix() > expires {
+		return 0, 0, errSubscriptionToken
+	}
+	return int32(id), expires, nil
+}
+
+// unsubscribeToken returns the token of the unsubscribe links of subscriber, it does not expire
+func unsubscribeToken(subscriber *model.Subscribers) string {
+	id := strconv.Itoa(int(subscriber.ID))
+	return id + "." + sign("unsubscribe", id)
+}
+
+// parseUnsubscribeToken returns the subscriber id of an unsubscribe token whose signature holds
+func parseUnsubscribeToken(token string) (subscriberID int32, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 || !hmac.Equal([]byte(parts[1]), []byte(sign("unsubscribe", parts[0]))) {
+		return 0, errSubscriptionToken
+	}
+
+	id, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return 0, errSubscriptionToken
+	}
+	return int32(id), nil
+}
+
+// summary returns the text of the html content cut to about n characters at a word boundary
+func summary(content string, n int) string {
+	text := strings.Join(strings.Fields(html.UnescapeString(model.Sanitize(model.SanitizeStrict, content))), " ")
+	if utf8.RuneCountInString(text) <= n {
+		return text
+	}
+
+	text = string([]rune(text)[:n])
+	if i := strings.LastIndex(text, " "); i > n/2 {
+		text = text[:i]
+	}
+	return text + "…"
+}
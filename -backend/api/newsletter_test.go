@@ -0,0 +1,60 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+var confirmTokenRegexp = regexp.MustCompile(`(\S+)/newsletter/confirm\?token=(\S+)`)
+
+func TestSubscribeConfirmLink(t *testing.T) {
+	srv := newTestServer(t)
+	previous := SubscribeLimiter
+	SubscribeLimiter = NewRateLimiter(0, time.Minute)
+	t.Cleanup(func() { SubscribeLimiter = previous })
+
+	// without a site url the link can only come from the request, so nothing is sent
+	setSiteURL(t, "")
+	if status, body := srv.do(http.DefaultClient, http.MethodPost, "/api/subscribe", `{"email": "ada@example.org", "name": "Ada"}`,
+		"Host", "evil.example"); status != http.StatusServiceUnavailable || len(srv.outboxTo("ada@example.org")) != 0 {
+		t.Fatalf("subscribe without a site url answered %d %s, want 503 and no mail", status, body)
+	}
+	setSiteURL(t, "https://wcs.example.org")
+
+	status, body := srv.do(http.DefaultClient, http.MethodPost, "/api/subscribe", `{"email": "ada@example.org", "name": "Ada"}`,
+		"Host", "evil.example", "X-Forwarded-Proto", "https")
+	if status != http.StatusAccepted {
+		t.Fatalf("subscribe answered %d %s", status, body)
+	}
+
+	mails := srv.outboxTo("ada@example.org")
+	if len(mails) != 1 {
+		t.Fatalf("got %d mails, want the confirmation", len(mails))
+	}
+	link := confirmTokenRegexp.FindStringSubmatch(mails[0])
+	if link == nil || link[1] != "https://wcs.example.org" {
+		t.Fatalf("confirmation link does not point to the site url:\n%s", mails[0])
+	}
+	token, err := url.QueryUnescape(link[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	forged := token[:len(token)-1] + "A"
+	if strings.HasSuffix(token, "A") {
+		forged = token[:len(token)-1] + "B"
+	}
+	if status, _ := srv.do(http.DefaultClient, http.MethodPost, "/api/subscribe/confirm", fmt.Sprintf(`{"token": %q}`, forged)); status != http.StatusBadRequest {
+		t.Errorf("forged token answered %d, want 400", status)
+	}
+
+	status, body = srv.do(http.DefaultClient, http.MethodPost, "/api/subscribe/confirm", fmt.Sprintf(`{"token": %q}`, token))
+	if status != http.StatusOK || !strings.Contains(body, `"status":"active"`) {
+		t.Errorf("confirm answered %d %s", status, body)
+	}
+}
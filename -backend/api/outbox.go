@@ -0,0 +1,287 @@
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"wcs/dao"
+	"wcs/jobs"
+	"wcs/mail"
+	"wcs/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+// sendMailJob kind of the job delivering a mail of the outbox
+const sendMailJob = "send_mail"
+
+// outboxAttempts delivery attempts of a mail before it is dead, about four hours of retries with the backoff of the jobs
+const outboxAttempts = 9
+
+// outboxRetention time sent mails are kept in the outbox
+const outboxRetention = 90 * 24 * time.Hour
+
+// jobRunner runner of the background jobs, woken up when a mail is queued so it goes out right away
+var jobRunner *jobs.Runner
+
+// sendMailPayload payload of a send_mail job
+type sendMailPayload struct {
+	OutboxID int32 `json:"outbox_id"`
+}
+
+func configOutboxRouter(router *httprouter.Router) {
+	router.GET("/outbox", GetAllOutbox)
+	router.GET("/outbox/:argID", GetOutbox)
+	router.POST("/outbox/:argID/resend", ResendOutbox)
+}
+
+func configGinOutboxRouter(router gin.IRoutes) {
+	router.GET("/outbox", ConverHttprouterToGin(GetAllOutbox))
+	router.GET("/outbox/:argID", ConverHttprouterToGin(GetOutbox))
+	router.POST("/outbox/:argID/resend", ConverHttprouterToGin(ResendOutbox))
+}
+
+// GetAllOutbox is a function to get a slice of record(s) from outbox table in the wcs database
+// @Summary Get list of outgoing mails
+// @Tags Outbox
+// @Description GetAllOutbox lists the outgoing mails with their delivery log, newest first, admin only
+// @Produce  json
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   status   query    string  false        "one of queued, sent, dead"
+// @Success 200 {object} api.PagedResults{data=[]model.Outbox}
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Router /outbox [get]
+// http "http://localhost:8080/outbox?status=dead" X-Api-User:user123
+func GetAllOutbox(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "outbox", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	var filters []dao.QueryFilter
+	switch status := r.FormValue("status"); status {
+	case "":
+	case model.MailQueued, model.MailSent, model.MailDead:
+		filters = append(filters, dao.WithStatus(status))
+	default:
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllOutbox(ctx, page, pagesize, "id desc", filters...)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	writeJSON(ctx, w, result)
+}
+
+// GetOutbox is a function to get a single record from the outbox table in the wcs database
+// @Summary Get an outgoing mail
+// @Tags Outbox
+// @Description GetOutbox returns an outgoing mail with its bodies and delivery log, admin only
+// @Produce  json
+// @Param  argID path int true "id"
+// @Success 200 {object} model.Outbox
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /outbox/{argID} [get]
+// http "http://localhost:8080/outbox/18" X-Api-User:user123
+func GetOutbox(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "outbox", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetOutbox(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// ResendOutbox is a function to send a sent or dead outgoing mail again
+// @Summary Resend an outgoing mail
+// @Tags Outbox
+// @Description ResendOutbox queues a sent or dead mail for delivery again, with a fresh set of attempts, admin only
+// @Produce  json
+// @Param  argID path int true "id"
+// @Success 200 {object} model.Outbox
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "mail already queued"
+// @Router /outbox/{argID}/resend [post]
+// http POST "http://localhost:8080/outbox/18/resend" X-Api-User:user123
+func ResendOutbox(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "outbox", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	var record *model.Outbox
+	err = dao.Transaction(ctx, func(ctx context.Context) (err error) {
+		if record, err = dao.RequeueOutbox(ctx, argID, time.Now()); err != nil {
+			return err
+		}
+		return enqueueSendMail(ctx, record.ID)
+	})
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	wakeJobs()
+	writeJSON(ctx, w, record)
+}
+
+// queueMail persists msg, rendered from template, in the outbox together with the job delivering it
+func queueMail(ctx context.Context, template string, msg *mail.Message) (*model.Outbox, error) {
+	record := &model.Outbox{
+		Template:    template,
+		ToAddress:   msg.To,
+		ReplyTo:     msg.ReplyTo,
+		Subject:     msg.Subject,
+		TextBody:    msg.Text,
+		HTMLBody:    msg.HTML,
+		Unsubscribe: msg.Unsubscribe,
+	}
+
+	err := dao.Transaction(ctx, func(ctx context.Context) error {
+		if _, err := dao.AddOutbox(ctx, record); err != nil {
+			return err
+		}
+		return enqueueSendMail(ctx, record.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	wakeJobs()
+	return record, nil
+}
+
+func enqueueSendMail(ctx context.Context, outboxID int32) error {
+	job, err := model.NewJob(sendMailJob, sendMailPayload{OutboxID: outboxID}, time.Now())
+	if err != nil {
+		return err
+	}
+	job.MaxAttempts = outboxAttempts
+
+	_, _, err = dao.EnqueueJob(ctx, job)
+	return err
+}
+
+// wakeJobs has the job runner poll now instead of at its next tick
+func wakeJobs() {
+	if jobRunner != nil {
+		jobRunner.Wake()
+	}
+}
+
+// deliverMail is the handler of a send_mail job: it hands the mail to the Mailer and logs the attempt.
+// A failed attempt is retried by the job with backoff, the mail is dead when the job used up its attempts.
+func deliverMail(ctx context.Context, job *model.Jobs) error {
+	payload := sendMailPayload{}
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	record, err := dao.GetOutbox(ctx, payload.OutboxID)
+	if dao.KindOf(err) == dao.KindNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if record.Status != model.MailQueued {
+		return nil
+	}
+
+	sendErr := Mailer.Send(ctx, &mail.Message{
+		To:          record.ToAddress,
+		ReplyTo:     record.ReplyTo,
+		Subject:     record.Subject,
+		Text:        record.TextBody,
+		HTML:        record.HTMLBody,
+		Unsubscribe: record.Unsubscribe,
+	})
+
+	delivery, status := &model.Delivery{At: time.Now().UTC()}, model.MailSent
+	if sendErr != nil {
+		delivery.Error, status = sendErr.Error(), model.MailQueued
+		if job.Attempts >= job.MaxAttempts {
+			status = model.MailDead
+		}
+	}
+
+	if _, err := dao.RecordDelivery(ctx, record.ID, delivery, status); err != nil {
+		log.Printf("Got error when logging the delivery of mail %d, the error is '%v'", record.ID, err)
+		if sendErr == nil {
+			// the mail went out, retrying would send it twice
+			return nil
+		}
+	}
+	return sendErr
+}
+
+// purgeOutbox is a planner deleting the mails sent longer than outboxRetention ago
+func purgeOutbox(ctx context.Context, now time.Time) error {
+	_, err := dao.PurgeOutbox(ctx, now.Add(-outboxRetention))
+	return err
+}
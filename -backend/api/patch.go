@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"wcs/dao"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+const (
+	// mergePatchContentType media type of a JSON Merge Patch document, see RFC 7386
+	mergePatchContentType = "application/merge-patch+json"
+
+	// jsonPatchContentType media type of a JSON Patch document, see RFC 6902
+	jsonPatchContentType = "application/json-patch+json"
+)
+
+// applyPatch applies the patch document in the body of r to the JSON form of current and decodes the result into patched.
+// application/json-patch+json bodies are applied as JSON Patch, application/merge-patch+json and
+// application/json bodies as JSON Merge Patch.
+func applyPatch(r *http.Request, current interface{}, patched interface{}) error {
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+
+	patch, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return dao.ErrBadParams.Wrap(err)
+	}
+
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		mediaType = mergePatchContentType
+	}
+
+	switch mediaType {
+	case jsonPatchContentType:
+		operations, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return dao.ErrUnableToMarshalJSON.Wrap(err)
+		}
+
+		if doc, err = operations.Apply(doc); err != nil {
+			return dao.ErrBadParams.Wrap(err)
+		}
+
+	case mergePatchContentType, "application/json":
+		if doc, err = jsonpatch.MergePatch(doc, patch); err != nil {
+			return dao.ErrUnableToMarshalJSON.Wrap(err)
+		}
+
+	default:
+		return fmt.Errorf("%w: %s", errUnsupportedMediaType, mediaType)
+	}
+
+	if err := json.Unmarshal(doc, patched); err != nil {
+		return dao.ErrUnableToMarshalJSON.Wrap(err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"wcs/model"
+)
+
+// tagNames returns the names of the tags of news in order
+func tagNames(news *model.News) []string {
+	var names []string
+	for _, tag := range news.Tags {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+func TestNewsPatchSemantics(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.admin()
+
+	unpublishAt := time.Now().Add(30 * 24 * time.Hour).UTC().Format(time.RFC3339)
+	status, body := srv.do(admin, http.MethodPost, "/api/news",
+		fmt.Sprintf(`{"title": "Open day", "content": "<p>all welcome</p>", "cover": "/upload/a.jpg", "tags": ["seminar", "ai"], "unpublish_at": %q}`, unpublishAt))
+	news := &model.News{}
+	if status != http.StatusOK || json.Unmarshal([]byte(body), news) != nil {
+		t.Fatalf("add answered %d %s", status, body)
+	}
+	path := fmt.Sprintf("/api/news/%d", news.ID)
+
+	send := func(method, contentType, patch string) (int, *model.News, string) {
+		t.Helper()
+		status, body := srv.do(admin, method, path, patch, "Content-Type", contentType, "If-Match", formatETag(news.Version))
+		patched := &model.News{}
+		if status == http.StatusOK {
+			if err := json.Unmarshal([]byte(body), patched); err != nil {
+				t.Fatal(err)
+			}
+			news = patched
+		}
+		return status, patched, body
+	}
+
+	// merge patch: null removes a field, arrays replace the stored one, absent fields stay
+	status, patched, body := send(http.MethodPatch, mergePatchContentType, `{"unpublish_at": null, "tags": ["workshop"]}`)
+	if status != http.StatusOK {
+		t.Fatalf("merge patch answered %d %s", status, body)
+	}
+	if patched.UnpublishAt.Valid {
+		t.Errorf("unpublish_at is %v after patching it to null", patched.UnpublishAt.Time)
+	}
+	if names := tagNames(patched); len(names) != 1 || names[0] != "workshop" {
+		t.Errorf("tags are %v after patching them to [workshop]", names)
+	}
+	if patched.Title != "Open day" || patched.Cover != "/upload/a.jpg" || patched.Content != "<p>all welcome</p>" {
+		t.Errorf("fields left out of the patch changed: %+v", patched)
+	}
+
+	// a plain json body is a merge patch too
+	if status, patched, body = send(http.MethodPatch, "application/json", `{"cover": ""}`); status != http.StatusOK || patched.Cover != "" || len(patched.Tags) != 1 {
+		t.Errorf("json merge patch answered %d %s", status, body)
+	}
+
+	// json patch: operations apply in order and a failed test rejects the whole document
+	status, _, body = send(http.MethodPatch, jsonPatchContentType, `[{"op": "test", "path": "/title", "value": "Closed day"}, {"op": "replace", "path": "/title", "value": "Never"}]`)
+	if status != http.StatusBadRequest {
+		t.Errorf("json patch with a failing test answered %d %s, want 400", status, body)
+	}
+	status, patched, body = send(http.MethodPatch, jsonPatchContentType, `[{"op": "test", "path": "/title", "value": "Open day"}, {"op": "replace", "path": "/title", "value": "Open day 2024"}, {"op": "add", "path": "/tags/-", "value": {"name": "ai"}}]`)
+	if status != http.StatusOK || patched.Title != "Open day 2024" {
+		t.Fatalf("json patch answered %d %s", status, body)
+	}
+	if names := tagNames(patched); len(names) != 2 || names[0] != "workshop" || names[1] != "ai" {
+		t.Errorf("tags are %v after appending ai", names)
+	}
+
+	if status, _, _ = send(http.MethodPatch, "text/plain", `title=x`); status != http.StatusUnsupportedMediaType {
+		t.Errorf("patch of text/plain answered %d, want 415", status)
+	}
+
+	// put replaces the whole record, fields left out are cleared
+	status, patched, body = send(http.MethodPut, "application/json", `{"title": "Open day", "content": "<p>moved online</p>"}`)
+	if status != http.StatusOK {
+		t.Fatalf("put answered %d %s", status, body)
+	}
+	if patched.Cover != "" || len(patched.Tags) != 0 || patched.UnpublishAt.Valid {
+		t.Errorf("put kept fields it left out: cover %q, tags %v", patched.Cover, tagNames(patched))
+	}
+}
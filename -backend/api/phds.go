@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"wcs/dao"
@@ -16,18 +17,22 @@ var (
 )
 
 func configPhdsRouter(router *httprouter.Router) {
-	router.GET("/phds", GetAllPhds)
+	router.GET("/phds", cached("phds", GetAllPhds))
 	router.POST("/phds", AddPhds)
-	router.GET("/phds/:argID", GetPhds)
+	router.POST("/phds/bulk", bulkHandler("phds"))
+	router.GET("/phds/:argID", cached("phds", GetPhds))
 	router.PUT("/phds/:argID", UpdatePhds)
+	router.PATCH("/phds/:argID", PatchPhds)
 	router.DELETE("/phds/:argID", DeletePhds)
 }
 
 func configGinPhdsRouter(router gin.IRoutes) {
-	router.GET("/phds", ConverHttprouterToGin(GetAllPhds))
+	router.GET("/phds", ConverHttprouterToGin(cached("phds", GetAllPhds)))
 	router.POST("/phds", ConverHttprouterToGin(AddPhds))
-	router.GET("/phds/:argID", ConverHttprouterToGin(GetPhds))
+	router.POST("/phds/bulk", ConverHttprouterToGin(bulkHandler("phds")))
+	router.GET("/phds/:argID", ConverHttprouterToGin(cached("phds", GetPhds)))
 	router.PUT("/phds/:argID", ConverHttprouterToGin(UpdatePhds))
+	router.PATCH("/phds/:argID", ConverHttprouterToGin(PatchPhds))
 	router.DELETE("/phds/:argID", ConverHttprouterToGin(DeletePhds))
 }
 
@@ -84,7 +89,9 @@ func GetAllPhds(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} model.Phds
+// @Success 304 "cached copy is current"
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
 // @Router /phds/{argID} [get]
@@ -109,7 +116,7 @@ func GetPhds(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	writeVersioned(ctx, w, r, record.Version, record)
 }
 
 // AddPhds add to add a single record to phds table in the wcs database
@@ -122,6 +129,8 @@ func GetPhds(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Success 200 {object} model.Phds
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /phds [post]
 // echo '{"id": 51,"name": "iptrUeYYumwGPNtqxbwXxhAXn","job": "TmSLqCHJTseAlgRrANZBjBvHw","intro": "FnfwXHBltWDjbebJfRnbCWXns","avatar": "OuiNsicSMHnSSfyaHXkVcbBTC"}' | http POST "http://localhost:8080/phds" X-Api-User:user123
 func AddPhds(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -135,12 +144,13 @@ func AddPhds(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	if err := phds.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	phds.Prepare()
 
 	if err := phds.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -156,22 +166,28 @@ func AddPhds(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(phds.Version))
 	writeJSON(ctx, w, phds)
 }
 
 // UpdatePhds Update a single record from phds table in the wcs database
 // @Summary Update an record in table phds
-// @Description Update a single record from phds table in the wcs database
+// @Description Update a single record from phds table in the wcs database, replacing every client writable column
 // @Tags Phds
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the update is based on"
 // @Param  Phds body model.Phds true "Update Phds record"
 // @Success 200 {object} model.Phds
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Phds "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /phds/{argID} [put]
-// echo '{"id": 51,"name": "iptrUeYYumwGPNtqxbwXxhAXn","job": "TmSLqCHJTseAlgRrANZBjBvHw","intro": "FnfwXHBltWDjbebJfRnbCWXns","avatar": "OuiNsicSMHnSSfyaHXkVcbBTC"}' | http PUT "http://localhost:8080/phds/1"  X-Api-User:user123
+// echo '{"id": 51,"name": "iptrUeYYumwGPNtqxbwXxhAXn","job": "TmSLqCHJTseAlgRrANZBjBvHw","intro": "FnfwXHBltWDjbebJfRnbCWXns","avatar": "OuiNsicSMHnSSfyaHXkVcbBTC"}' | http PUT "http://localhost:8080/phds/1" If-Match:'"1"' X-Api-User:user123
 func UpdatePhds(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -189,12 +205,68 @@ func UpdatePhds(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	if err := phds.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	phds.Prepare()
 
 	if err := phds.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "phds", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	phds.Version = version
+
+	phds, _, err = dao.UpdatePhds(ctx,
+		argID,
+		phds)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, phds.Version, phds)
+		return
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(phds.Version))
+	writeJSON(ctx, w, phds)
+}
+
+// PatchPhds Patch a single record from phds table in the wcs database
+// @Summary Patch an record in table phds
+// @Description Apply a JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) document to a single record from phds table in the wcs database
+// @Tags Phds
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the patch is based on"
+// @Param  Phds body object true "merge patch (application/merge-patch+json) or json patch (application/json-patch+json) document"
+// @Success 200 {object} model.Phds
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Phds "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
+// @Router /phds/{argID} [patch]
+// echo '{"avatar": null}' | http PATCH "http://localhost:8080/phds/1" If-Match:'"1"' Content-Type:application/merge-patch+json X-Api-User:user123
+func PatchPhds(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -203,14 +275,56 @@ func UpdatePhds(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	current, err := dao.GetPhds(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if version != 0 && version != current.Version {
+		writePreconditionFailed(ctx, w, current.Version, current)
+		return
+	}
+
+	phds := &model.Phds{}
+	if err := applyPatch(r, current, phds); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := phds.BeforeSave(); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	phds.Prepare()
+
+	if err := phds.Validate(model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	// the patch was computed against current, so the update only goes through if nobody changed it since
+	phds.Version = current.Version
 	phds, _, err = dao.UpdatePhds(ctx,
 		argID,
 		phds)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, phds.Version, phds)
+		return
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(phds.Version))
 	writeJSON(ctx, w, phds)
 }
 
@@ -221,11 +335,14 @@ func UpdatePhds(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the delete is based on"
 // @Success 204 {object} model.Phds
 // @Failure 400 {object} api.HTTPError
+// @Failure 412 {object} model.Phds "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
 // @Failure 500 {object} api.HTTPError
 // @Router /phds/{argID} [delete]
-// http DELETE "http://localhost:8080/phds/1" X-Api-User:user123
+// http DELETE "http://localhost:8080/phds/1" If-Match:'"1"' X-Api-User:user123
 func DeletePhds(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -240,7 +357,19 @@ func DeletePhds(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	rowsAffected, err := dao.DeletePhds(ctx, argID)
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	rowsAffected, err := dao.DeletePhds(ctx, argID, version)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		if current, err := dao.GetPhds(ctx, argID); err == nil {
+			writePreconditionFailed(ctx, w, current.Version, current)
+			return
+		}
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"wcs/dao"
@@ -16,18 +17,22 @@ var (
 )
 
 func configProjectsRouter(router *httprouter.Router) {
-	router.GET("/projects", GetAllProjects)
+	router.GET("/projects", cached("projects", GetAllProjects))
 	router.POST("/projects", AddProjects)
-	router.GET("/projects/:argID", GetProjects)
+	router.POST("/projects/bulk", bulkHandler("projects"))
+	router.GET("/projects/:argID", cached("projects", GetProjects))
 	router.PUT("/projects/:argID", UpdateProjects)
+	router.PATCH("/projects/:argID", PatchProjects)
 	router.DELETE("/projects/:argID", DeleteProjects)
 }
 
 func configGinProjectsRouter(router gin.IRoutes) {
-	router.GET("/projects", ConverHttprouterToGin(GetAllProjects))
+	router.GET("/projects", ConverHttprouterToGin(cached("projects", GetAllProjects)))
 	router.POST("/projects", ConverHttprouterToGin(AddProjects))
-	router.GET("/projects/:argID", ConverHttprouterToGin(GetProjects))
+	router.POST("/projects/bulk", ConverHttprouterToGin(bulkHandler("projects")))
+	router.GET("/projects/:argID", ConverHttprouterToGin(cached("projects", GetProjects)))
 	router.PUT("/projects/:argID", ConverHttprouterToGin(UpdateProjects))
+	router.PATCH("/projects/:argID", ConverHttprouterToGin(PatchProjects))
 	router.DELETE("/projects/:argID", ConverHttprouterToGin(DeleteProjects))
 }
 
@@ -84,7 +89,9 @@ func GetAllProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} model.Projects
+// @Success 304 "cached copy is current"
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
 // @Router /projects/{argID} [get]
@@ -109,7 +116,7 @@ func GetProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	writeVersioned(ctx, w, r, record.Version, record)
 }
 
 // AddProjects add to add a single record to projects table in the wcs database
@@ -122,6 +129,8 @@ func GetProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Success 200 {object} model.Projects
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /projects [post]
 // echo '{"id": 19,"name": "FQQabeNJaBNUtMTGgDPyyvDIJ","intro": "DhNHNTSdVPtKtMshMfUjnoGKa","link": "AabjFhdLeHVFKapYMEPumLtUU"}' | http POST "http://localhost:8080/projects" X-Api-User:user123
 func AddProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -135,12 +144,13 @@ func AddProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	if err := projects.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	projects.Prepare()
 
 	if err := projects.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -156,22 +166,28 @@ func AddProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(projects.Version))
 	writeJSON(ctx, w, projects)
 }
 
 // UpdateProjects Update a single record from projects table in the wcs database
 // @Summary Update an record in table projects
-// @Description Update a single record from projects table in the wcs database
+// @Description Update a single record from projects table in the wcs database, replacing every client writable column
 // @Tags Projects
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the update is based on"
 // @Param  Projects body model.Projects true "Update Projects record"
 // @Success 200 {object} model.Projects
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Projects "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /projects/{argID} [put]
-// echo '{"id": 19,"name": "FQQabeNJaBNUtMTGgDPyyvDIJ","intro": "DhNHNTSdVPtKtMshMfUjnoGKa","link": "AabjFhdLeHVFKapYMEPumLtUU"}' | http PUT "http://localhost:8080/projects/1"  X-Api-User:user123
+// echo '{"id": 19,"name": "FQQabeNJaBNUtMTGgDPyyvDIJ","intro": "DhNHNTSdVPtKtMshMfUjnoGKa","link": "AabjFhdLeHVFKapYMEPumLtUU"}' | http PUT "http://localhost:8080/projects/1" If-Match:'"1"' X-Api-User:user123
 func UpdateProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -189,12 +205,68 @@ func UpdateProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params
 
 	if err := projects.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	projects.Prepare()
 
 	if err := projects.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "projects", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	projects.Version = version
+
+	projects, _, err = dao.UpdateProjects(ctx,
+		argID,
+		projects)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, projects.Version, projects)
+		return
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(projects.Version))
+	writeJSON(ctx, w, projects)
+}
+
+// PatchProjects Patch a single record from projects table in the wcs database
+// @Summary Patch an record in table projects
+// @Description Apply a JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) document to a single record from projects table in the wcs database
+// @Tags Projects
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the patch is based on"
+// @Param  Projects body object true "merge patch (application/merge-patch+json) or json patch (application/json-patch+json) document"
+// @Success 200 {object} model.Projects
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Projects "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
+// @Router /projects/{argID} [patch]
+// echo '{"link": null}' | http PATCH "http://localhost:8080/projects/1" If-Match:'"1"' Content-Type:application/merge-patch+json X-Api-User:user123
+func PatchProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -203,14 +275,56 @@ func UpdateProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params
 		return
 	}
 
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	current, err := dao.GetProjects(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if version != 0 && version != current.Version {
+		writePreconditionFailed(ctx, w, current.Version, current)
+		return
+	}
+
+	projects := &model.Projects{}
+	if err := applyPatch(r, current, projects); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := projects.BeforeSave(); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	projects.Prepare()
+
+	if err := projects.Validate(model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	// the patch was computed against current, so the update only goes through if nobody changed it since
+	projects.Version = current.Version
 	projects, _, err = dao.UpdateProjects(ctx,
 		argID,
 		projects)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, projects.Version, projects)
+		return
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(projects.Version))
 	writeJSON(ctx, w, projects)
 }
 
@@ -221,11 +335,14 @@ func UpdateProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the delete is based on"
 // @Success 204 {object} model.Projects
 // @Failure 400 {object} api.HTTPError
+// @Failure 412 {object} model.Projects "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
 // @Failure 500 {object} api.HTTPError
 // @Router /projects/{argID} [delete]
-// http DELETE "http://localhost:8080/projects/1" X-Api-User:user123
+// http DELETE "http://localhost:8080/projects/1" If-Match:'"1"' X-Api-User:user123
 func DeleteProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -240,7 +357,19 @@ func DeleteProjects(w http.ResponseWriter, r *http.Request, ps httprouter.Params
 		return
 	}
 
-	rowsAffected, err := dao.DeleteProjects(ctx, argID)
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	rowsAffected, err := dao.DeleteProjects(ctx, argID, version)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		if current, err := dao.GetProjects(ctx, argID); err == nil {
+			writePreconditionFailed(ctx, w, current.Version, current)
+			return
+		}
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
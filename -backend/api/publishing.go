@@ -0,0 +1,97 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/gin-contrib/sessions"
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+// adminContext gin middleware exposing the admin signed in on the session to handlers through the request context
+func adminContext(c *gin.Context) {
+	if _, ok := c.Get(sessions.DefaultKey); ok {
+		if adminID, ok := sessions.Default(c).Get("currentAdmin").(int32); ok {
+			c.Request = c.Request.WithContext(dao.WithAdminID(c.Request.Context(), adminID))
+		}
+	}
+
+	c.Next()
+}
+
+// isAdmin reports whether the request was made by a signed in admin
+func isAdmin(ctx context.Context) bool {
+	_, ok := dao.CurrentAdminID(ctx)
+	return ok
+}
+
+// publishingFilters returns the filters applied to list requests of a published table.
+// The public only sees published records, admins see every record and can narrow the list down with ?status=.
+func publishingFilters(ctx context.Context, r *http.Request) ([]dao.QueryFilter, error) {
+	if !isAdmin(ctx) {
+		return []dao.QueryFilter{dao.Published(time.Now())}, nil
+	}
+
+	status := r.FormValue("status")
+	if status == "" {
+		return nil, nil
+	}
+
+	if !model.IsPublishStatus(status) {
+		return nil, dao.ErrBadParams
+	}
+
+	return []dao.QueryFilter{dao.WithStatus(status)}, nil
+}
+
+// statusHistoryHandler returns the handler listing the publishing status changes of a record of table
+func statusHistoryHandler(table string) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		GetStatusHistory(w, r, ps, table)
+	}
+}
+
+// GetStatusHistory is a function to get the publishing status changes of a news or events record
+// @Summary Get the publishing status history of a record
+// @Description Admin only. Changes made by the scheduler have no admin_id.
+// @Tags Publishing
+// @Produce  json
+// @Param  table path string true "news or events"
+// @Param  argID path int true "id"
+// @Success 200 {array} model.StatusHistory
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Router /{table}/{argID}/history [get]
+// http "http://localhost:8080/events/1/history" X-Api-User:user123
+func GetStatusHistory(w http.ResponseWriter, r *http.Request, ps httprouter.Params, table string) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "status_history", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, err := dao.GetStatusHistory(ctx, table, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, records)
+}
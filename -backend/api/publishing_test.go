@@ -0,0 +1,148 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/guregu/null"
+)
+
+// listedTitles returns the titles of the news listed at path for client
+func (s *testServer) listedTitles(client *http.Client, path string) (int, map[string]bool) {
+	s.t.Helper()
+	status, body := s.do(client, http.MethodGet, path, "")
+	if status != http.StatusOK {
+		return status, nil
+	}
+
+	var page struct {
+		Data []*model.News `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(body), &page); err != nil {
+		s.t.Fatal(err)
+	}
+	titles := make(map[string]bool)
+	for _, news := range page.Data {
+		titles[news.Title] = true
+	}
+	return status, titles
+}
+
+func TestPublishingVisibility(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.admin()
+
+	now := time.Now()
+	hour := time.Hour
+	records := map[string]*model.News{
+		"draft":     {Status: model.StatusDraft},
+		"published": {Status: model.StatusPublished},
+		"upcoming":  {Status: model.StatusScheduled, PublishAt: null.TimeFrom(now.Add(hour))},
+		"due":       {Status: model.StatusScheduled, PublishAt: null.TimeFrom(now.Add(-hour))},
+		"expired":   {Status: model.StatusPublished, UnpublishAt: null.TimeFrom(now.Add(-hour))},
+		"expiring":  {Status: model.StatusPublished, UnpublishAt: null.TimeFrom(now.Add(hour))},
+		"archived":  {Status: model.StatusArchived},
+	}
+	public := map[string]bool{"published": true, "due": true, "expiring": true}
+
+	for title, news := range records {
+		news.Title, news.Content = title, title
+		if _, _, err := dao.AddNews(srv.ctx, news); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, listed := srv.listedTitles(http.DefaultClient, "/api/news")
+	for title, news := range records {
+		if listed[title] != public[title] {
+			t.Errorf("public list shows %s: %v, want %v", title, listed[title], public[title])
+		}
+
+		want := http.StatusNotFound
+		if public[title] {
+			want = http.StatusOK
+		}
+		path := fmt.Sprintf("/api/news/%d", news.ID)
+		if status, _ := srv.do(http.DefaultClient, http.MethodGet, path, ""); status != want {
+			t.Errorf("public get of %s answered %d, want %d", title, status, want)
+		}
+		if status, _ := srv.do(admin, http.MethodGet, path, ""); status != http.StatusOK {
+			t.Errorf("admin get of %s answered %d, want 200", title, status)
+		}
+	}
+
+	if _, listed := srv.listedTitles(admin, "/api/news"); len(listed) != len(records) {
+		t.Errorf("admin list shows %d news, want all %d", len(listed), len(records))
+	}
+	if _, listed := srv.listedTitles(admin, "/api/news?status=scheduled"); len(listed) != 2 || !listed["upcoming"] || !listed["due"] {
+		t.Errorf("admin list of scheduled news shows %v", listed)
+	}
+	if status, _ := srv.listedTitles(admin, "/api/news?status=hidden"); status != http.StatusBadRequest {
+		t.Errorf("admin list of an unknown status answered %d, want 400", status)
+	}
+
+	// the status filter is an admin tool, the public still only sees public records
+	if _, listed := srv.listedTitles(http.DefaultClient, "/api/news?status=draft"); listed["draft"] || len(listed) != len(public) {
+		t.Errorf("public list filtered by draft shows %v", listed)
+	}
+}
+
+func TestStatusSchedule(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.admin()
+
+	now := time.Now()
+	due := &model.News{Title: "due", Content: "due", Status: model.StatusScheduled, PublishAt: null.TimeFrom(now.Add(-time.Minute))}
+	upcoming := &model.News{Title: "upcoming", Content: "upcoming", Status: model.StatusScheduled, PublishAt: null.TimeFrom(now.Add(time.Hour))}
+	expired := &model.News{Title: "expired", Content: "expired", Status: model.StatusPublished, UnpublishAt: null.TimeFrom(now.Add(-time.Minute))}
+	for _, news := range []*model.News{due, upcoming, expired} {
+		if _, _, err := dao.AddNews(srv.ctx, news); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changed, err := dao.ApplyStatusSchedule(srv.ctx, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 {
+		t.Errorf("schedule changed %d records, want the due and the expired one", changed)
+	}
+
+	for _, test := range []struct {
+		news    *model.News
+		status  string
+		version int32
+	}{
+		{due, model.StatusPublished, 2},
+		{upcoming, model.StatusScheduled, 1},
+		{expired, model.StatusArchived, 2},
+	} {
+		current, err := dao.GetNews(srv.ctx, test.news.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if current.Status != test.status || current.Version != test.version {
+			t.Errorf("%s is %s version %d, want %s version %d", current.Title, current.Status, current.Version, test.status, test.version)
+		}
+	}
+
+	status, body := srv.do(admin, http.MethodGet, fmt.Sprintf("/api/news/%d/history", due.ID), "")
+	var history []*model.StatusHistory
+	if status != http.StatusOK || json.Unmarshal([]byte(body), &history) != nil {
+		t.Fatalf("history answered %d %s", status, body)
+	}
+	if len(history) != 2 || history[1].FromStatus != model.StatusScheduled || history[1].ToStatus != model.StatusPublished || history[1].AdminID.Valid {
+		t.Errorf("history of the published news is %s, want its creation then scheduled to published without an admin", body)
+	}
+
+	if changed, err = dao.ApplyStatusSchedule(srv.ctx, now); err != nil || changed != 0 {
+		t.Errorf("second run changed %d records with %v, want none", changed, err)
+	}
+}
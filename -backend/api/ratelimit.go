@@ -0,0 +1,154 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errTooManyRequests error when a client exceeded a RateLimiter
+var errTooManyRequests = fmt.Errorf("too many requests")
+
+// maxRateWindows clients a RateLimiter keeps count of. When that many windows are running new clients are
+// refused until windows end, so a flood of addresses can neither exhaust memory nor push out the counts of others.
+const maxRateWindows = 100000
+
+// TrustedProxies address ranges of the reverse proxies in front of the server. Only requests arriving from
+// one of them have their client address taken from X-Forwarded-For or X-Real-Ip, the headers of everybody else are ignored.
+var TrustedProxies []*net.IPNet
+
+// RateLimiter allows a number of requests per client key in fixed windows of time, kept in memory.
+// Each server counts on its own, so behind several servers a client gets the limit once per server.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+
+	mu        sync.Mutex
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+}
+
+// rateWindow requests of a client in the window starting at start
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewRateLimiter returns a RateLimiter allowing limit requests per window, a limit of 0 allows all requests
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{limit: limit, window: window, windows: make(map[string]*rateWindow)}
+}
+
+// Allow counts a request of key at now, retryAfter is the time until key may send again when it is over the limit
+func (l *RateLimiter) Allow(key string, now time.Time) (ok bool, retryAfter time.Duration) {
+	if l == nil || l.limit <= 0 {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// forget the clients whose windows ended, so the map does not grow with every address ever seen
+	if now.Sub(l.lastSweep) >= l.window {
+		l.sweep(now)
+	}
+
+	w, found := l.windows[key]
+	if !found && len(l.windows) >= maxRateWindows {
+		l.sweep(now)
+		if len(l.windows) >= maxRateWindows {
+			return false, l.window
+		}
+	}
+	if !found || now.Sub(w.start) >= l.window {
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// sweep drops the windows that ended by now, l.mu must be held
+func (l *RateLimiter) sweep(now time.Time) {
+	for k, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, k)
+		}
+	}
+	l.lastSweep = now
+}
+
+// ParseTrustedProxies parses addresses and CIDR ranges, e.g. "10.0.0.0/8" or "127.0.0.1", for TrustedProxies
+func ParseTrustedProxies(list []string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, item := range list {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid proxy address %q", item)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy range %q", item)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// clientIP returns the address rate limits count r against: the peer address of the connection, or, when the peer
+// is a trusted proxy, the last address of X-Forwarded-For that is not one of TrustedProxies, then X-Real-Ip
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !trustedProxy(ip) {
+		return ip
+	}
+
+	forwarded := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		if !trustedProxy(hop) {
+			return hop
+		}
+	}
+
+	if real := strings.TrimSpace(r.Header.Get("X-Real-Ip")); net.ParseIP(real) != nil {
+		return real
+	}
+	return ip
+}
+
+// trustedProxy reports whether the address ip is in TrustedProxies
+func trustedProxy(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, ipNet := range TrustedProxies {
+		if ipNet.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := TrustedProxies
+	TrustedProxies = proxies
+	t.Cleanup(func() { TrustedProxies = previous })
+
+	for _, test := range []struct {
+		remote, forwardedFor, realIP, want string
+	}{
+		{"198.51.100.9:4242", "", "", "198.51.100.9"},
+		{"198.51.100.9:4242", "203.0.113.5", "203.0.113.6", "198.51.100.9"},
+		{"10.1.2.3:4242", "203.0.113.5", "", "203.0.113.5"},
+		{"10.1.2.3:4242", "1.1.1.1, 203.0.113.5, 192.0.2.7", "", "203.0.113.5"},
+		{"192.0.2.7:4242", "", "203.0.113.6", "203.0.113.6"},
+		{"10.1.2.3:4242", "10.0.0.9", "", "10.1.2.3"},
+		{"10.1.2.3:4242", "not an address", "", "10.1.2.3"},
+	} {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = test.remote
+		r.Header.Set("X-Forwarded-For", test.forwardedFor)
+		r.Header.Set("X-Real-Ip", test.realIP)
+		if got := clientIP(r); got != test.want {
+			t.Errorf("clientIP from %s forwarded for %q real ip %q is %s, want %s", test.remote, test.forwardedFor, test.realIP, got, test.want)
+		}
+	}
+
+	if _, err := ParseTrustedProxies([]string{"proxy.example"}); err == nil {
+		t.Errorf("host name accepted as proxy address")
+	}
+}
+
+func TestRateLimiterBounded(t *testing.T) {
+	l := NewRateLimiter(1, time.Minute)
+	now := time.Now()
+	for i := 0; i < maxRateWindows; i++ {
+		if ok, _ := l.Allow(fmt.Sprint(i), now); !ok {
+			t.Fatalf("client %d refused", i)
+		}
+	}
+
+	if ok, _ := l.Allow("one more", now.Add(time.Second)); ok {
+		t.Errorf("new client allowed with %d windows running", len(l.windows))
+	}
+	if len(l.windows) > maxRateWindows {
+		t.Errorf("limiter keeps %d windows, at most %d", len(l.windows), maxRateWindows)
+	}
+
+	if ok, _ := l.Allow("one more", now.Add(time.Minute)); !ok {
+		t.Errorf("new client refused after the windows ended")
+	}
+	if len(l.windows) != 1 {
+		t.Errorf("limiter keeps %d windows after the others ended, want 1", len(l.windows))
+	}
+}
@@ -0,0 +1,407 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+func configRegistrationsRouter(router *httprouter.Router) {
+	router.POST("/events/:argID/register", RegisterForEvent)
+	router.GET("/events/:argID/registrations", GetEventRegistrations)
+	router.DELETE("/events/:argID/registrations/:registrationID", DeleteEventRegistration)
+	router.POST("/registrations/cancel", CancelRegistration)
+}
+
+func configGinRegistrationsRouter(router gin.IRoutes) {
+	router.POST("/events/:argID/register", ConverHttprouterToGin(RegisterForEvent))
+	router.GET("/events/:argID/registrations", ConverHttprouterToGin(GetEventRegistrations))
+	router.DELETE("/events/:argID/registrations/:registrationID", ConverHttprouterToGin(DeleteEventRegistration))
+	router.POST("/registrations/cancel", ConverHttprouterToGin(CancelRegistration))
+}
+
+// CancelRequest body of a cancellation through the link sent to an attendee
+type CancelRequest struct {
+	// Token token of the cancel link
+	Token string `json:"token" example:"4f1c0e9a7b2d..."`
+}
+
+// RegisterForEvent is a function to register an attendee for an event
+// @Summary Register for an event
+// @Tags Registrations
+// @Description RegisterForEvent registers name and email for a public event open for registration, with the answers to its custom questions.
+// @Description The registration is confirmed while places are left and waitlisted once the event is full, the attendee is sent an email with a cancel link either way.
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id of the event"
+// @Param  Registration body model.Registrations true "name, email and answers keyed by question id"
+// @Success 200 {object} model.Registrations
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "registration closed or email registered already"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Router /events/{argID}/register [post]
+// echo '{"name": "Ada Lovelace","email": "ada@example.org","answers": {"dietary-requirements": "vegetarian"}}' | http POST "http://localhost:8080/events/1/register"
+func RegisterForEvent(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	registration := &model.Registrations{}
+	if err := readJSON(r, registration); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	registration.Prepare()
+
+	if err := registration.Validate(model.Create); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "registrations", model.Create); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	event, registration, err := dao.RegisterForEvent(ctx, argID, registration, time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	mailRegistrations(ctx, event, registration)
+	if registration.Status == model.RegistrationConfirmed {
+		registration.Ticket = ticketCode(registration)
+	}
+	writeJSON(ctx, w, registration)
+}
+
+// CancelRegistration is a function to cancel a registration through the link sent to the attendee
+// @Summary Cancel a registration
+// @Tags Registrations
+// @Description CancelRegistration cancels the registration of the token of a cancel link. The place it frees goes to the first attendee on the waitlist, who is notified by email.
+// @Description Cancelling twice is not an error.
+// @Accept  json
+// @Produce  json
+// @Param  Cancel body api.CancelRequest true "token of the cancel link"
+// @Success 200 {object} model.Registrations
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError "unknown token"
+// @Router /registrations/cancel [post]
+// echo '{"token": "4f1c0e9a7b2d"}' | http POST "http://localhost:8080/registrations/cancel"
+func CancelRegistration(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	request := &CancelRequest{}
+	if err := readJSON(r, request); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "registrations", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	registration, err := dao.GetRegistrationByToken(ctx, request.Token)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	registration, promoted, err := dao.CancelRegistration(ctx, registration.EventID, registration.ID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	notifyPromoted(ctx, registration.EventID, promoted)
+	writeJSON(ctx, w, registration)
+}
+
+// GetEventRegistrations is a function to list or export the registrations for an event
+// @Summary Get the registrations for an event
+// @Tags Registrations
+// @Description GetEventRegistrations returns the registrations for an event in order of registration, admin only.
+// @Description With format=csv they are exported as a spreadsheet with a column per custom question.
+// @Produce  json,text/csv
+// @Param  argID path int true "id of the event"
+// @Param  status query string false "confirmed, waitlisted or cancelled"
+// @Param  format query string false "json (default) or csv"
+// @Success 200 {array} model.Registrations
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /events/{argID}/registrations [get]
+// http "http://localhost:8080/events/1/registrations?format=csv" X-Api-User:user123
+func GetEventRegistrations(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	status := r.FormValue("status")
+	switch status {
+	case "", model.RegistrationConfirmed, model.RegistrationWaitlisted, model.RegistrationCancelled:
+	default:
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	format := r.FormValue("format")
+	if format != "" && format != "json" && format != "csv" {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "registrations", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	event, err := dao.GetEvents(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, err := dao.GetRegistrations(ctx, argID, status)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if format == "csv" {
+		writeRegistrationsCSV(w, event, records)
+		return
+	}
+
+	for _, record := range records {
+		if record.Status == model.RegistrationConfirmed {
+			record.Ticket = ticketCode(record)
+		}
+	}
+
+	writeJSON(ctx, w, records)
+}
+
+// DeleteEventRegistration is a function to cancel a registration for an event on behalf of the attendee
+// @Summary Cancel a registration for an event
+// @Tags Registrations
+// @Description DeleteEventRegistration cancels a registration, admin only. The place it frees goes to the first attendee on the waitlist, who is notified by email.
+// @Produce  json
+// @Param  argID path int true "id of the event"
+// @Param  registrationID path int true "id of the registration"
+// @Success 200 {object} model.Registrations
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /events/{argID}/registrations/{registrationID} [delete]
+// http DELETE "http://localhost:8080/events/1/registrations/3" X-Api-User:user123
+func DeleteEventRegistration(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	registrationID, err := parseInt32(ps, "registrationID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "registrations", model.Delete); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	registration, promoted, err := dao.CancelRegistration(ctx, argID, registrationID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	notifyPromoted(ctx, argID, promoted)
+	writeJSON(ctx, w, registration)
+}
+
+// promoteWaitlist confirms waitlisted registrations for the places an update of event freed and notifies the attendees.
+// Failures are logged, the update of the event stands.
+func promoteWaitlist(ctx context.Context, event *model.Events) {
+	if !event.RegistrationOpen {
+		return
+	}
+
+	promoted, err := dao.PromoteWaitlist(ctx, event.ID)
+	if err != nil {
+		log.Printf("Got error when promoting the waitlist of event %d, the error is '%v'", event.ID, err)
+		return
+	}
+
+	if len(promoted) > 0 {
+		mailRegistrations(ctx, event, promoted...)
+	}
+}
+
+// notifyPromoted mails the registrations promoted from the waitlist of an event
+func notifyPromoted(ctx context.Context, eventID int32, promoted []*model.Registrations) {
+	if len(promoted) == 0 {
+		return
+	}
+
+	event, err := dao.GetEvents(ctx, eventID)
+	if err != nil {
+		log.Printf("Got error when loading event %d to notify its waitlist, the error is '%v'", eventID, err)
+		return
+	}
+
+	mailRegistrations(ctx, event, promoted...)
+}
+
+// eventMailData data of the mail templates about an event sent to an attendee
+type eventMailData struct {
+	Name        string
+	Event       *model.Events
+	When        string
+	Waitlisted  bool
+	Position    int
+	TicketURL   string
+	CancelURL   string
+	FeedbackURL string
+}
+
+// newEventMailData returns the template data of a mail to the attendee of registration about event, links pointing to base
+func newEventMailData(base string, event *model.Events, registration *model.Registrations) *eventMailData {
+	data := &eventMailData{
+		Name:       registration.Name,
+		Event:      event,
+		When:       eventWhen(event),
+		Waitlisted: registration.Status == model.RegistrationWaitlisted,
+		Position:   registration.Position,
+		CancelURL:  fmt.Sprintf("%s/registrations/cancel?token=%s", base, registration.Token),
+	}
+	if registration.Status == model.RegistrationConfirmed {
+		data.TicketURL = fmt.Sprintf("%s/api/tickets/%s.png", base, ticketCode(registration))
+	}
+	return data
+}
+
+// mailRegistrations queues the confirmation or waitlist notice with the cancel link for the attendees of registrations.
+// Links point to the configured site, never to the host of the request. The registrations stand when a mail can not be queued
+// or no site is configured, failures are logged.
+func mailRegistrations(ctx context.Context, event *model.Events, registrations ...*model.Registrations) {
+	base, err := publicSiteURL()
+	if err != nil {
+		log.Printf("Got error when mailing the registrations for event %d, the error is '%v'", event.ID, err)
+		return
+	}
+
+	for _, registration := range registrations {
+		data := newEventMailData(base, event, registration)
+		if err := sendMail(ctx, registration.Email, "registration", data); err != nil {
+			log.Printf("Got error when mailing registration %d for event %d, the error is '%v'", registration.ID, event.ID, err)
+		}
+	}
+}
+
+// eventWhen describes the start of event in its time zone
+func eventWhen(event *model.Events) string {
+	if !event.StartTime.Valid {
+		return "to be announced"
+	}
+
+	when := occurrenceWhen(event, event.StartTime.Time)
+	if event.RRule != "" {
+		when += ", first of a series"
+	}
+	return when
+}
+
+// occurrenceWhen describes start, the start of an occurrence of event, in the time zone of event
+func occurrenceWhen(event *model.Events, start time.Time) string {
+	start = start.In(event.Zone())
+	if event.AllDay {
+		return start.Format("Monday 2 January 2006")
+	}
+	return start.Format("Monday 2 January 2006, 15:04 MST")
+}
+
+// writeRegistrationsCSV writes the registrations for event as a csv attachment, one column per custom question of the event
+func writeRegistrationsCSV(w http.ResponseWriter, event *model.Events, records []*model.Registrations) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="event-%d-registrations.csv"`, event.ID))
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	header := []string{"id", "status", "waitlist_position", "name", "email", "registered_at", "checked_in_at"}
+	for _, q := range event.Questions {
+		header = append(header, q.Label)
+	}
+
+	out := csv.NewWriter(w)
+	out.Write(header)
+	for _, record := range records {
+		position := ""
+		if record.Position > 0 {
+			position = strconv.Itoa(record.Position)
+		}
+		checkedIn := ""
+		if record.CheckedInAt.Valid {
+			checkedIn = record.CheckedInAt.Time.UTC().Format(time.RFC3339)
+		}
+
+		row := []string{
+			strconv.Itoa(int(record.ID)),
+			record.Status,
+			position,
+			csvSafe(record.Name),
+			csvSafe(record.Email),
+			record.CreateTime.UTC().Format(time.RFC3339),
+			checkedIn,
+		}
+		for _, q := range event.Questions {
+			row = append(row, csvSafe(record.Answers[q.ID]))
+		}
+		out.Write(row)
+	}
+	out.Flush()
+}
+
+// csvSafe defuses values spreadsheets would run as formulas, see CSV injection
+func csvSafe(s string) string {
+	if s != "" && (s[0] == '=' || s[0] == '+' || s[0] == '-' || s[0] == '@' || s[0] == '\t' || s[0] == '\r') {
+		return "'" + s
+	}
+	return s
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/guregu/null"
+)
+
+var cancelTokenRegexp = regexp.MustCompile(`/registrations/cancel\?token=(\w+)`)
+
+// outboxTo returns the text bodies of the mails queued for to, oldest first
+func (s *testServer) outboxTo(to string) []string {
+	s.t.Helper()
+	records, _, err := dao.GetAllOutbox(s.ctx, 0, 100, "id")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	var bodies []string
+	for _, record := range records {
+		if record.ToAddress == to {
+			bodies = append(bodies, record.TextBody)
+		}
+	}
+	return bodies
+}
+
+func TestWaitlistPromotion(t *testing.T) {
+	srv := newTestServer(t)
+	setSiteURL(t, "https://wcs.example.org")
+
+	event := &model.Events{Title: "Workshop", Content: "hands on", Status: model.StatusPublished, RegistrationOpen: true, Capacity: 1,
+		StartTime: null.TimeFrom(time.Now().Add(24 * time.Hour))}
+	if _, _, err := dao.AddEvents(srv.ctx, event); err != nil {
+		t.Fatal(err)
+	}
+
+	path := fmt.Sprintf("/api/events/%d/register", event.ID)
+	for _, email := range []string{"first@example.org", "second@example.org"} {
+		body := fmt.Sprintf(`{"name": "Attendee", "email": %q}`, email)
+		if status, body := srv.do(http.DefaultClient, http.MethodPost, path, body, "Host", "evil.example"); status != http.StatusOK {
+			t.Fatalf("register %s answered %d %s", email, status, body)
+		}
+	}
+
+	first := srv.outboxTo("first@example.org")
+	if len(first) != 1 {
+		t.Fatalf("first attendee got %d mails, want 1", len(first))
+	}
+	token := cancelTokenRegexp.FindStringSubmatch(first[0])
+	if token == nil {
+		t.Fatalf("confirmation has no cancel link:\n%s", first[0])
+	}
+
+	second := srv.outboxTo("second@example.org")
+	if len(second) != 1 || !strings.Contains(second[0], "waitlist") {
+		t.Fatalf("second attendee got %q, want a waitlist notice", second)
+	}
+
+	status, body := srv.do(http.DefaultClient, http.MethodPost, "/api/registrations/cancel", fmt.Sprintf(`{"token": %q}`, token[1]), "Host", "evil.example")
+	if status != http.StatusOK || !strings.Contains(body, `"status":"cancelled"`) {
+		t.Fatalf("cancel answered %d %s", status, body)
+	}
+
+	confirmed, err := dao.GetRegistrations(srv.ctx, event.ID, model.RegistrationConfirmed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(confirmed) != 1 || confirmed[0].Email != "second@example.org" {
+		t.Fatalf("confirmed registrations are %+v, want the promoted second attendee", confirmed)
+	}
+
+	second = srv.outboxTo("second@example.org")
+	if len(second) != 2 {
+		t.Fatalf("second attendee got %d mails, want the waitlist notice and the promotion", len(second))
+	}
+	for _, text := range append(first, second...) {
+		if strings.Contains(text, "evil.example") || !strings.Contains(text, "https://wcs.example.org/registrations/cancel?token=") {
+			t.Errorf("mail links do not point to the site url:\n%s", text)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"wcs/dao"
+	"wcs/jobs"
+	"wcs/model"
+
+	"github.com/guregu/null"
+)
+
+var (
+	// ReminderLead time before an occurrence of an event its attendees are reminded of it, 0 disables reminders
+	ReminderLead = 24 * time.Hour
+
+	// FollowUpDelay time after an occurrence of an event ended its attendees are asked for feedback, 0 disables follow-ups
+	FollowUpDelay = 2 * time.Hour
+)
+
+// kinds of the jobs mailing attendees. The event jobs fan out into one mail job per attendee,
+// so a failing address is retried on its own and nobody is mailed twice.
+const (
+	eventReminderJob = "event_reminder"
+	eventFollowUpJob = "event_follow_up"
+	reminderMailJob  = "reminder_mail"
+	followUpMailJob  = "follow_up_mail"
+)
+
+// followUpWindow time after FollowUpDelay an ended occurrence still gets its follow-up, covering downtime of the server
+const followUpWindow = 24 * time.Hour
+
+// maxPlanned occurrences planned per poll
+const maxPlanned = 1000
+
+// eventMail payload of the reminder and follow-up jobs of an occurrence, RegistrationID is set on mail jobs only
+type eventMail struct {
+	EventID        int32     `json:"event_id"`
+	Start          time.Time `json:"start"`
+	RegistrationID int32     `json:"registration_id,omitempty"`
+}
+
+// ConfigJobs sets the handlers and planners of the background jobs the api enqueues on runner
+func ConfigJobs(runner *jobs.Runner) {
+	runner.Handle(eventReminderJob, fanOutEventMail(reminderMailJob))
+	runner.Handle(eventFollowUpJob, fanOutEventMail(followUpMailJob))
+	runner.Handle(reminderMailJob, sendReminder)
+	runner.Handle(followUpMailJob, sendFollowUp)
+	runner.Handle(sendMailJob, deliverMail)
+	runner.Handle(digestBatchJob, sendDigestBatch)
+	runner.Handle(createDigestJob, createScheduledDigest)
+	runner.Handle(deliverWebhookJob, deliverWebhook)
+	runner.Plan(planEventMail)
+	runner.Plan(purgeOutbox)
+	runner.Plan(planDigest)
+	runner.Plan(purgeWebhookDeliveries)
+	jobRunner = runner
+}
+
+// planEventMail enqueues the reminders of the occurrences starting within ReminderLead of now
+// and the follow-ups of the occurrences that ended FollowUpDelay before now. Unique keys keep each to one per occurrence.
+func planEventMail(ctx context.Context, now time.Time) error {
+	if ReminderLead > 0 {
+		events, _, err := dao.GetEventOccurrences(ctx, now, now.Add(ReminderLead), 0, maxPlanned, dao.Published(now), dao.WithRegistrations())
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			// already running, too late for a reminder
+			if event.Occurrence.Start.Before(now) {
+				continue
+			}
+			if err := enqueueEventMail(ctx, eventReminderJob, eventMail{EventID: event.ID, Start: event.Occurrence.Start}, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	if FollowUpDelay > 0 {
+		from, to := now.Add(-FollowUpDelay-followUpWindow), now.Add(-FollowUpDelay)
+		events, _, err := dao.GetEventOccurrences(ctx, from, to, 0, maxPlanned, dao.WithRegistrations())
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			end := event.Occurrence.Start
+			if event.Occurrence.End.Valid {
+				end = event.Occurrence.End.Time
+			}
+			if end.Before(from) || end.After(to) {
+				continue
+			}
+			if err := enqueueEventMail(ctx, eventFollowUpJob, eventMail{EventID: event.ID, Start: event.Occurrence.Start}, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// enqueueEventMail enqueues a job of kind for payload due at now, unless the occurrence or attendee had one already
+func enqueueEventMail(ctx context.Context, kind string, payload eventMail, now time.Time) error {
+	job, err := model.NewJob(kind, payload, now)
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s:%d:%d", kind, payload.EventID, payload.Start.Unix())
+	if payload.RegistrationID != 0 {
+		key += fmt.Sprintf(":%d", payload.RegistrationID)
+	}
+	job.UniqueKey = null.StringFrom(key)
+
+	_, _, err = dao.EnqueueJob(ctx, job)
+	return err
+}
+
+// fanOutEventMail returns the handler of an event job, which enqueues a mail job of kind for every confirmed attendee.
+// Follow-ups only go to the attendees who checked in when anybody did.
+func fanOutEventMail(kind string) jobs.Handler {
+	return func(ctx context.Context, job *model.Jobs) error {
+		payload := eventMail{}
+		if err := job.Decode(&payload); err != nil {
+			return err
+		}
+
+		registrations, err := dao.GetRegistrations(ctx, payload.EventID, model.RegistrationConfirmed)
+		if err != nil {
+			return err
+		}
+
+		if kind == followUpMailJob {
+			attended := make([]*model.Registrations, 0, len(registrations))
+			for _, registration := range registrations {
+				if registration.CheckedInAt.Valid {
+					attended = append(attended, registration)
+				}
+			}
+			if len(attended) > 0 {
+				registrations = attended
+			}
+		}
+
+		for _, registration := range registrations {
+			payload.RegistrationID = registration.ID
+			if err := enqueueEventMail(ctx, kind, payload, time.Now()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// sendReminder is the handler of a reminder mail job. Attendees who cancelled in the meantime and
+// reminders whose occurrence already started are dropped.
+func sendReminder(ctx context.Context, job *model.Jobs) error {
+	payload, event, registration, err := loadEventMail(ctx, job)
+	if event == nil || err != nil {
+		return err
+	}
+
+	if time.Now().After(payload.Start) {
+		return nil
+	}
+
+	base, err := publicSiteURL()
+	if err != nil {
+		return err
+	}
+
+	data := newEventMailData(base, event, registration)
+	data.When = occurrenceWhen(event, payload.Start)
+	return sendMail(ctx, registration.Email, "reminder", data)
+}
+
+// sendFollowUp is the handler of a follow-up mail job, asking an attendee for feedback on the event
+func sendFollowUp(ctx context.Context, job *model.Jobs) error {
+	payload, event, registration, err := loadEventMail(ctx, job)
+	if event == nil || err != nil {
+		return err
+	}
+
+	base, err := publicSiteURL()
+	if err != nil {
+		return err
+	}
+
+	data := newEventMailData(base, event, registration)
+	data.When = occurrenceWhen(event, payload.Start)
+	data.FeedbackURL = base + "/contact"
+	return sendMail(ctx, registration.Email, "follow_up", data)
+}
+
+// loadEventMail loads the payload of a mail job and the event and registration it is for. They are nil without error
+// when the mail is not to be sent anymore, as the event was deleted or the registration is no longer confirmed.
+func loadEventMail(ctx context.Context, job *model.Jobs) (payload eventMail, event *model.Events, registration *model.Registrations, err error) {
+	if err = job.Decode(&payload); err != nil {
+		return payload, nil, nil, err
+	}
+
+	event, err = dao.GetEvents(ctx, payload.EventID)
+	if dao.KindOf(err) == dao.KindNotFound {
+		return payload, nil, nil, nil
+	}
+	if err != nil {
+		return payload, nil, nil, err
+	}
+
+	registration, err = dao.GetRegistration(ctx, payload.EventID, payload.RegistrationID)
+	if dao.KindOf(err) == dao.KindNotFound || (err == nil && registration.Status != model.RegistrationConfirmed) {
+		log.Printf("Dropping %s job %d, registration %d is no longer confirmed", job.Kind, job.ID, payload.RegistrationID)
+		return payload, nil, nil, nil
+	}
+	if err != nil {
+		return payload, nil, nil, err
+	}
+
+	return payload, event, registration, nil
+}
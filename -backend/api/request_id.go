@@ -0,0 +1,35 @@
+package api
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	uuid "github.com/satori/go.uuid"
+)
+
+// RequestIDHeader header carrying the id of a request, accepted from proxies and echoed in every response
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID ids accepted from clients, anything else is replaced so logs can not be forged
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type requestIDKey struct{}
+
+// requestID middleware tagging every request with an id, reusing the X-Request-ID of an upstream proxy when present
+func requestID(c *gin.Context) {
+	id := c.GetHeader(RequestIDHeader)
+	if !validRequestID.MatchString(id) {
+		id = uuid.NewV4().String()
+	}
+
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestIDKey{}, id))
+	c.Header(RequestIDHeader, id)
+	c.Next()
+}
+
+// RequestID returns the id of the request ctx belongs to, empty outside of a request
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"wcs/dao"
@@ -16,18 +17,22 @@ var (
 )
 
 func configResourcesRouter(router *httprouter.Router) {
-	router.GET("/resources", GetAllResources)
+	router.GET("/resources", cached("resources", GetAllResources))
 	router.POST("/resources", AddResources)
-	router.GET("/resources/:argID", GetResources)
+	router.POST("/resources/bulk", bulkHandler("resources"))
+	router.GET("/resources/:argID", cached("resources", GetResources))
 	router.PUT("/resources/:argID", UpdateResources)
+	router.PATCH("/resources/:argID", PatchResources)
 	router.DELETE("/resources/:argID", DeleteResources)
 }
 
 func configGinResourcesRouter(router gin.IRoutes) {
-	router.GET("/resources", ConverHttprouterToGin(GetAllResources))
+	router.GET("/resources", ConverHttprouterToGin(cached("resources", GetAllResources)))
 	router.POST("/resources", ConverHttprouterToGin(AddResources))
-	router.GET("/resources/:argID", ConverHttprouterToGin(GetResources))
+	router.POST("/resources/bulk", ConverHttprouterToGin(bulkHandler("resources")))
+	router.GET("/resources/:argID", ConverHttprouterToGin(cached("resources", GetResources)))
 	router.PUT("/resources/:argID", ConverHttprouterToGin(UpdateResources))
+	router.PATCH("/resources/:argID", ConverHttprouterToGin(PatchResources))
 	router.DELETE("/resources/:argID", ConverHttprouterToGin(DeleteResources))
 }
 
@@ -84,7 +89,9 @@ func GetAllResources(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} model.Resources
+// @Success 304 "cached copy is current"
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
 // @Router /resources/{argID} [get]
@@ -109,7 +116,7 @@ func GetResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	writeVersioned(ctx, w, r, record.Version, record)
 }
 
 // AddResources add to add a single record to resources table in the wcs database
@@ -122,6 +129,8 @@ func GetResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 // @Success 200 {object} model.Resources
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /resources [post]
 // echo '{"id": 57,"name": "UvJpIyJJkjDDfKyCmQxdwbcwA","intro": "yXkmMtyeYBsJGFUtZqshRSFLB","link": "TIPRoVvioBUWsOXXxvWxmYIMY"}' | http POST "http://localhost:8080/resources" X-Api-User:user123
 func AddResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -135,12 +144,13 @@ func AddResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 
 	if err := resources.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	resources.Prepare()
 
 	if err := resources.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -156,22 +166,28 @@ func AddResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(resources.Version))
 	writeJSON(ctx, w, resources)
 }
 
 // UpdateResources Update a single record from resources table in the wcs database
 // @Summary Update an record in table resources
-// @Description Update a single record from resources table in the wcs database
+// @Description Update a single record from resources table in the wcs database, replacing every client writable column
 // @Tags Resources
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the update is based on"
 // @Param  Resources body model.Resources true "Update Resources record"
 // @Success 200 {object} model.Resources
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Resources "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /resources/{argID} [put]
-// echo '{"id": 57,"name": "UvJpIyJJkjDDfKyCmQxdwbcwA","intro": "yXkmMtyeYBsJGFUtZqshRSFLB","link": "TIPRoVvioBUWsOXXxvWxmYIMY"}' | http PUT "http://localhost:8080/resources/1"  X-Api-User:user123
+// echo '{"id": 57,"name": "UvJpIyJJkjDDfKyCmQxdwbcwA","intro": "yXkmMtyeYBsJGFUtZqshRSFLB","link": "TIPRoVvioBUWsOXXxvWxmYIMY"}' | http PUT "http://localhost:8080/resources/1" If-Match:'"1"' X-Api-User:user123
 func UpdateResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -189,12 +205,68 @@ func UpdateResources(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 
 	if err := resources.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	resources.Prepare()
 
 	if err := resources.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "resources", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	resources.Version = version
+
+	resources, _, err = dao.UpdateResources(ctx,
+		argID,
+		resources)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, resources.Version, resources)
+		return
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(resources.Version))
+	writeJSON(ctx, w, resources)
+}
+
+// PatchResources Patch a single record from resources table in the wcs database
+// @Summary Patch an record in table resources
+// @Description Apply a JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) document to a single record from resources table in the wcs database
+// @Tags Resources
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the patch is based on"
+// @Param  Resources body object true "merge patch (application/merge-patch+json) or json patch (application/json-patch+json) document"
+// @Success 200 {object} model.Resources
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Resources "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
+// @Router /resources/{argID} [patch]
+// echo '{"link": null}' | http PATCH "http://localhost:8080/resources/1" If-Match:'"1"' Content-Type:application/merge-patch+json X-Api-User:user123
+func PatchResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -203,14 +275,56 @@ func UpdateResources(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		return
 	}
 
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	current, err := dao.GetResources(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if version != 0 && version != current.Version {
+		writePreconditionFailed(ctx, w, current.Version, current)
+		return
+	}
+
+	resources := &model.Resources{}
+	if err := applyPatch(r, current, resources); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := resources.BeforeSave(); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	resources.Prepare()
+
+	if err := resources.Validate(model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	// the patch was computed against current, so the update only goes through if nobody changed it since
+	resources.Version = current.Version
 	resources, _, err = dao.UpdateResources(ctx,
 		argID,
 		resources)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, resources.Version, resources)
+		return
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(resources.Version))
 	writeJSON(ctx, w, resources)
 }
 
@@ -221,11 +335,14 @@ func UpdateResources(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the delete is based on"
 // @Success 204 {object} model.Resources
 // @Failure 400 {object} api.HTTPError
+// @Failure 412 {object} model.Resources "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
 // @Failure 500 {object} api.HTTPError
 // @Router /resources/{argID} [delete]
-// http DELETE "http://localhost:8080/resources/1" X-Api-User:user123
+// http DELETE "http://localhost:8080/resources/1" If-Match:'"1"' X-Api-User:user123
 func DeleteResources(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -240,7 +357,19 @@ func DeleteResources(w http.ResponseWriter, r *http.Request, ps httprouter.Param
 		return
 	}
 
-	rowsAffected, err := dao.DeleteResources(ctx, argID)
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	rowsAffected, err := dao.DeleteResources(ctx, argID, version)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		if current, err := dao.GetResources(ctx, argID); err == nil {
+			writePreconditionFailed(ctx, w, current.Version, current)
+			return
+		}
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -3,15 +3,15 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 	"unsafe"
 
-	_ "github.com/satori/go.uuid"
-
 	"wcs/dao"
 	"wcs/model"
 
@@ -44,14 +44,31 @@ type PagedResults struct {
 	TotalRecords int         `json:"total_records"`
 }
 
-// HTTPError example
+// HTTPError problem details of a failed request, see RFC 7807. Sent with content type application/problem+json.
 type HTTPError struct {
-	Code    int    `json:"code" example:"400"`
-	Message string `json:"message" example:"status bad request"`
+	// Type uri identifying the problem type, about:blank when the status says it all
+	Type string `json:"type" example:"about:blank"`
+
+	// Title summary of the status
+	Title string `json:"title" example:"Not Found"`
+
+	Status int `json:"status" example:"404"`
+
+	// Detail explanation of this occurrence, never carries the internal cause of a server error
+	Detail string `json:"detail,omitempty" example:"record Not Found"`
+
+	// Instance path of the failed request
+	Instance string `json:"instance,omitempty" example:"/api/news/7"`
+
+	// RequestID id of the request, also sent in the X-Request-ID header and written to the server log
+	RequestID string `json:"request_id,omitempty" example:"0b7a2c1e-4f0d-4a9e-9f57-3f2d1c0e8b6a"`
+
+	// Errors field level failures of a record rejected by validation
+	Errors []*model.FieldError `json:"errors,omitempty"`
 }
 
-// ConfigRouter configure http.Handler router
-func ConfigRouter() http.Handler {
+// ConfigRouter configure http.Handler router, the handlers run their queries on db
+func ConfigRouter(db *dao.Database) http.Handler {
 	router := httprouter.New()
 	configAdminRouter(router)
 	configEventsRouter(router)
@@ -60,14 +77,33 @@ func ConfigRouter() http.Handler {
 	configProjectsRouter(router)
 	configResourcesRouter(router)
 	configStaffsRouter(router)
+	configTagsRouter(router)
+	configMediaRouter(router)
+	configFeedsRouter(router)
+	configCalendarRouter(router)
+	configRegistrationsRouter(router)
+	configTicketsRouter(router)
+	configJobsRouter(router)
+	configOutboxRouter(router)
+	configContactMessagesRouter(router)
+	configNewsletterRouter(router)
+	configWebhooksRouter(router)
+	configStreamRouter(router)
 
 	router.GET("/ddl/:argID", GetDdl)
 	router.GET("/ddl", GetDdlEndpoints)
-	return router
+	router.GET("/cache/stats", GetCacheStats)
+	if db.Cache != nil {
+		configCache(db.Cache)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		router.ServeHTTP(w, r.WithContext(dao.WithDatabase(r.Context(), db)))
+	})
 }
 
-// ConfigGinRouter configure gin router
-func ConfigGinRouter(router gin.IRoutes) {
+// ConfigGinRouter configure gin router, the handlers run their queries on db
+func ConfigGinRouter(router gin.IRoutes, db *dao.Database) {
+	router.Use(requestID, databaseContext(db), adminContext)
 	configGinAdminRouter(router)
 	configGinContactRouter(router)
 	configGinEventsRouter(router)
@@ -76,12 +112,37 @@ func ConfigGinRouter(router gin.IRoutes) {
 	configGinProjectsRouter(router)
 	configGinResourcesRouter(router)
 	configGinStaffsRouter(router)
+	configGinTagsRouter(router)
+	configGinMediaRouter(router)
+	configGinFeedsRouter(router)
+	configGinCalendarRouter(router)
+	configGinRegistrationsRouter(router)
+	configGinTicketsRouter(router)
+	configGinJobsRouter(router)
+	configGinOutboxRouter(router)
+	configGinContactMessagesRouter(router)
+	configGinNewsletterRouter(router)
+	configGinWebhooksRouter(router)
+	configGinStreamRouter(router)
 
 	router.GET("/ddl/:argID", ConverHttprouterToGin(GetDdl))
 	router.GET("/ddl", ConverHttprouterToGin(GetDdlEndpoints))
+	router.GET("/cache/stats", ConverHttprouterToGin(GetCacheStats))
+	if db.Cache != nil {
+		configCache(db.Cache)
+	}
 	return
 }
 
+// databaseContext middleware handing db to the dao calls of a request in its context.
+// Queries run with the request context, so they are canceled when the client disconnects.
+func databaseContext(db *dao.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(dao.WithDatabase(c.Request.Context(), db))
+		c.Next()
+	}
+}
+
 // ConverHttprouterToGin wrap httprouter.Handle to gin.HandlerFunc
 func ConverHttprouterToGin(f httprouter.Handle) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -97,6 +158,18 @@ func ConverHttprouterToGin(f httprouter.Handle) gin.HandlerFunc {
 	}
 }
 
+// fixedArg serves the requests whose param is value with h and answers the others with 404 Not Found.
+// httprouter can not hold a static segment next to a wildcard one, routes such as /events/bulk go through the wildcard with it.
+func fixedArg(param, value string, h httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		if ps.ByName(param) != value {
+			http.NotFound(w, r)
+			return
+		}
+		h(w, r, ps)
+	}
+}
+
 func initializeContext(r *http.Request) (ctx context.Context) {
 	if ContextInitializer != nil {
 		ctx = ContextInitializer(r)
@@ -128,13 +201,20 @@ func readInt(r *http.Request, param string, v int64) (int64, error) {
 		return v, nil
 	}
 
-	return strconv.ParseInt(p, 10, 64)
+	i, err := strconv.ParseInt(p, 10, 64)
+	if err != nil {
+		return v, dao.ErrBadParams.Wrap(err)
+	}
+	return i, nil
 }
 
+// writeJSON writes v as json. Responses not marked cacheable by the read cache must be revalidated on every use.
 func writeJSON(ctx context.Context, w http.ResponseWriter, v interface{}) {
 	data, _ := json.Marshal(v)
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
-	w.Header().Set("Cache-Control", "no-cache")
+	if w.Header().Get("Cache-Control") == "" {
+		w.Header().Set("Cache-Control", "private, no-cache")
+	}
 	w.Write(data)
 }
 
@@ -151,41 +231,119 @@ func readJSON(r *http.Request, v interface{}) error {
 		return err
 	}
 
-	return json.Unmarshal(buf, v)
+	if err := json.Unmarshal(buf, v); err != nil {
+		return dao.ErrUnableToMarshalJSON.Wrap(err)
+	}
+	return nil
 }
 
+// returnError answers with the problem details of err. The internal cause of server errors is logged
+// with the request id and left out of the response.
 func returnError(ctx context.Context, w http.ResponseWriter, r *http.Request, err error) {
-	status := 0
-	switch err {
-	case dao.ErrNotFound:
-		status = http.StatusBadRequest
-	case dao.ErrUnableToMarshalJSON:
-		status = http.StatusBadRequest
-	case dao.ErrUpdateFailed:
-		status = http.StatusBadRequest
-	case dao.ErrInsertFailed:
-		status = http.StatusBadRequest
-	case dao.ErrDeleteFailed:
-		status = http.StatusBadRequest
-	case dao.ErrBadParams:
-		status = http.StatusBadRequest
+	status := errorStatus(err)
+	er := newProblem(ctx, r, status, err)
+
+	var verr *model.ValidationError
+	if errors.As(err, &verr) {
+		er.Errors = verr.Fields
+	}
+
+	logError(ctx, r, status, err)
+
+	w.Header().Set("Cache-Control", "no-cache, no-store")
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	data, _ := json.Marshal(er)
+	w.Write(data)
+}
+
+// logError writes the internal cause of server errors to the log, tagged with the request id
+func logError(ctx context.Context, r *http.Request, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		log.Printf("Request %s %s %s failed with %d, the error is '%v'", RequestID(ctx), r.Method, r.URL.Path, status, err)
+	}
+}
+
+// newProblem returns the problem details of err reported with status
+func newProblem(ctx context.Context, r *http.Request, status int, err error) *HTTPError {
+	return &HTTPError{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    errorDetail(status, err),
+		Instance:  r.URL.Path,
+		RequestID: RequestID(ctx),
+	}
+}
+
+// errorStatus maps an error to the http status code reported to the client
+func errorStatus(err error) int {
+	var verr *model.ValidationError
+	if errors.As(err, &verr) {
+		return http.StatusUnprocessableEntity
+	}
+
+	switch {
+	case errors.Is(err, errUploadTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, errUnsupportedMediaType):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, errTooManyRequests):
+		return http.StatusTooManyRequests
+	case errors.Is(err, errPreconditionRequired):
+		return http.StatusPreconditionRequired
+	}
+
+	var daoErr *dao.Error
+	if !errors.As(err, &daoErr) {
+		return http.StatusInternalServerError
+	}
+
+	switch daoErr.Kind {
+	case dao.KindBadRequest:
+		return http.StatusBadRequest
+	case dao.KindNotFound:
+		return http.StatusNotFound
+	case dao.KindConflict:
+		return http.StatusConflict
+	case dao.KindPrecondition:
+		return http.StatusPreconditionFailed
+	case dao.KindUnauthorized:
+		return http.StatusUnauthorized
+	case dao.KindForbidden:
+		return http.StatusForbidden
+	case dao.KindUnavailable:
+		return http.StatusServiceUnavailable
 	default:
-		status = http.StatusBadRequest
+		return http.StatusInternalServerError
 	}
-	er := HTTPError{
-		Code:    status,
-		Message: err.Error(),
+}
+
+// errorDetail message of err safe to show to clients: the message of a dao error without its cause,
+// and no more than the status text for errors of unknown origin
+func errorDetail(status int, err error) string {
+	var verr *model.ValidationError
+	if errors.As(err, &verr) {
+		return verr.Error()
+	}
+
+	var daoErr *dao.Error
+	if errors.As(err, &daoErr) {
+		return daoErr.Message
 	}
 
-	SendJSON(w, r, er.Code, er)
+	if status >= http.StatusInternalServerError {
+		return http.StatusText(status)
+	}
+	return err.Error()
 }
 
 // NewError example
 func NewError(ctx *gin.Context, status int, err error) {
-	er := HTTPError{
-		Code:    status,
-		Message: err.Error(),
-	}
+	er := newProblem(ctx.Request.Context(), ctx.Request, status, err)
+	ctx.Header("Content-Type", "application/problem+json")
 	ctx.JSON(status, er)
 }
 
@@ -193,7 +351,7 @@ func parseUint8(ps httprouter.Params, key string) (uint8, error) {
 	idStr := ps.ByName(key)
 	id, err := strconv.ParseInt(idStr, 10, 8)
 	if err != nil {
-		return uint8(id), err
+		return uint8(id), dao.ErrBadParams.Wrap(err)
 	}
 	return uint8(id), err
 }
@@ -201,7 +359,7 @@ func parseUint16(ps httprouter.Params, key string) (uint16, error) {
 	idStr := ps.ByName(key)
 	id, err := strconv.ParseInt(idStr, 10, 16)
 	if err != nil {
-		return uint16(id), err
+		return uint16(id), dao.ErrBadParams.Wrap(err)
 	}
 	return uint16(id), err
 }
@@ -209,7 +367,7 @@ func parseUint32(ps httprouter.Params, key string) (uint32, error) {
 	idStr := ps.ByName(key)
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		return uint32(id), err
+		return uint32(id), dao.ErrBadParams.Wrap(err)
 	}
 	return uint32(id), err
 }
@@ -217,7 +375,7 @@ func parseUint64(ps httprouter.Params, key string) (uint64, error) {
 	idStr := ps.ByName(key)
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		return uint64(id), err
+		return uint64(id), dao.ErrBadParams.Wrap(err)
 	}
 	return uint64(id), err
 }
@@ -225,7 +383,7 @@ func parseInt(ps httprouter.Params, key string) (int, error) {
 	idStr := ps.ByName(key)
 	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
-		return -1, err
+		return -1, dao.ErrBadParams.Wrap(err)
 	}
 	return int(id), err
 }
@@ -233,7 +391,7 @@ func parseInt8(ps httprouter.Params, key string) (int8, error) {
 	idStr := ps.ByName(key)
 	id, err := strconv.ParseInt(idStr, 10, 8)
 	if err != nil {
-		return -1, err
+		return -1, dao.ErrBadParams.Wrap(err)
 	}
 	return int8(id), err
 }
@@ -241,7 +399,7 @@ func parseInt16(ps httprouter.Params, key string) (int16, error) {
 	idStr := ps.ByName(key)
 	id, err := strconv.ParseInt(idStr, 10, 16)
 	if err != nil {
-		return -1, err
+		return -1, dao.ErrBadParams.Wrap(err)
 	}
 	return int16(id), err
 }
@@ -249,7 +407,7 @@ func parseInt32(ps httprouter.Params, key string) (int32, error) {
 	idStr := ps.ByName(key)
 	id, err := strconv.ParseInt(idStr, 10, 32)
 	if err != nil {
-		return -1, err
+		return -1, dao.ErrBadParams.Wrap(err)
 	}
 	return int32(id), err
 }
@@ -257,7 +415,7 @@ func parseInt64(ps httprouter.Params, key string) (int64, error) {
 	idStr := ps.ByName(key)
 	id, err := strconv.ParseInt(idStr, 10, 54)
 	if err != nil {
-		return -1, err
+		return -1, dao.ErrBadParams.Wrap(err)
 	}
 	return id, err
 }
@@ -295,7 +453,7 @@ func GetDdl(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	record, ok := crudEndpoints[argID]
 	if !ok {
-		returnError(ctx, w, r, fmt.Errorf("unable to find table: %s", argID))
+		returnError(ctx, w, r, dao.ErrNotFound.Wrap(fmt.Errorf("unable to find table: %s", argID)))
 		return
 	}
 
@@ -418,4 +576,30 @@ func init() {
 	tmp.TableInfo, _ = model.GetTableInfo("staffs")
 	crudEndpoints["staffs"] = tmp
 
+	tmp = &CrudAPI{
+		Name:            "tags",
+		CreateURL:       "/tags",
+		RetrieveOneURL:  "/tags",
+		RetrieveManyURL: "/tags",
+		UpdateURL:       "/tags",
+		DeleteURL:       "/tags",
+		FetchDDLURL:     "/ddl/tags",
+	}
+
+	tmp.TableInfo, _ = model.GetTableInfo("tags")
+	crudEndpoints["tags"] = tmp
+
+	tmp = &CrudAPI{
+		Name:            "media",
+		CreateURL:       "/media",
+		RetrieveOneURL:  "/media",
+		RetrieveManyURL: "/media",
+		UpdateURL:       "",
+		DeleteURL:       "/media",
+		FetchDDLURL:     "/ddl/media",
+	}
+
+	tmp.TableInfo, _ = model.GetTableInfo("media")
+	crudEndpoints["media"] = tmp
+
 }
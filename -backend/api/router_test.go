@@ -0,0 +1,135 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"wcs/dao"
+	"wcs/model"
+)
+
+func TestErrorStatus(t *testing.T) {
+	cause := errors.New("dial tcp 10.0.0.7:3306: connection refused")
+	for _, test := range []struct {
+		err    error
+		status int
+	}{
+		{dao.ErrBadParams, http.StatusBadRequest},
+		{dao.ErrUnableToMarshalJSON.Wrap(cause), http.StatusBadRequest},
+		{dao.ErrUnauthorized, http.StatusUnauthorized},
+		{dao.ErrForbidden, http.StatusForbidden},
+		{dao.ErrNotFound.Wrap(cause), http.StatusNotFound},
+		{dao.ErrDuplicate.Wrap(cause), http.StatusConflict},
+		{dao.ErrVersionConflict, http.StatusPreconditionFailed},
+		{dao.ErrUnavailable.Wrap(cause), http.StatusServiceUnavailable},
+		{dao.ErrUpdateFailed.Wrap(cause), http.StatusInternalServerError},
+		{fmt.Errorf("load news: %w", dao.ErrNotFound), http.StatusNotFound},
+		{&model.ValidationError{Fields: []*model.FieldError{{Field: "title", Code: "required"}}}, http.StatusUnprocessableEntity},
+		{fmt.Errorf("upload: %w", errUploadTooLarge), http.StatusRequestEntityTooLarge},
+		{errUnsupportedMediaType, http.StatusUnsupportedMediaType},
+		{errTooManyRequests, http.StatusTooManyRequests},
+		{errPreconditionRequired, http.StatusPreconditionRequired},
+		{cause, http.StatusInternalServerError},
+	} {
+		if status := errorStatus(test.err); status != test.status {
+			t.Errorf("status of %v is %d, want %d", test.err, status, test.status)
+		}
+	}
+}
+
+func TestErrorDetail(t *testing.T) {
+	cause := errors.New("Error 1045: Access denied for user 'wcsadmin'@'10.0.0.7'")
+	for _, test := range []struct {
+		err    error
+		detail string
+	}{
+		{dao.ErrUpdateFailed.Wrap(cause), "db update error"},
+		{dao.ErrNotFound.Wrap(cause), "record Not Found"},
+		{cause, http.StatusText(http.StatusInternalServerError)},
+		{errUnsupportedMediaType, errUnsupportedMediaType.Error()},
+	} {
+		if detail := errorDetail(errorStatus(test.err), test.err); detail != test.detail {
+			t.Errorf("detail of %v is %q, want %q", test.err, detail, test.detail)
+		}
+	}
+}
+
+// problem sends a request expected to fail and decodes the problem details answered
+func (s *testServer) problem(client *http.Client, method, path, body string, headers ...string) (*http.Response, *HTTPError) {
+	s.t.Helper()
+	req, err := http.NewRequest(method, s.URL+path, strings.NewReader(body))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, _ := io.ReadAll(res.Body)
+
+	problem := &HTTPError{}
+	if err := json.Unmarshal(data, problem); err != nil {
+		s.t.Fatalf("%s %s answered %d %s", method, path, res.StatusCode, data)
+	}
+	return res, problem
+}
+
+func TestProblemDetails(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.admin()
+
+	res, problem := srv.problem(http.DefaultClient, http.MethodGet, "/api/news/999", "", RequestIDHeader, "trace-42")
+	if res.StatusCode != http.StatusNotFound || res.Header.Get("Content-Type") != "application/problem+json" {
+		t.Errorf("missing news answered %d %s, want 404 problem+json", res.StatusCode, res.Header.Get("Content-Type"))
+	}
+	want := HTTPError{Type: "about:blank", Title: "Not Found", Status: http.StatusNotFound, Detail: "record Not Found", Instance: "/api/news/999", RequestID: "trace-42"}
+	if problem.Type != want.Type || problem.Title != want.Title || problem.Status != want.Status || problem.Detail != want.Detail ||
+		problem.Instance != want.Instance || problem.RequestID != want.RequestID {
+		t.Errorf("problem is %+v, want %+v", problem, want)
+	}
+	if res.Header.Get(RequestIDHeader) != "trace-42" {
+		t.Errorf("request id header is %q, want the one sent", res.Header.Get(RequestIDHeader))
+	}
+
+	// ids that could forge log lines are replaced
+	res, problem = srv.problem(http.DefaultClient, http.MethodGet, "/api/news/999", "", RequestIDHeader, "a b;forged")
+	if id := res.Header.Get(RequestIDHeader); id == "" || strings.ContainsAny(id, " ;") || problem.RequestID != id {
+		t.Errorf("request id of an invalid id is %q, problem carries %q", id, problem.RequestID)
+	}
+
+	for _, test := range []struct {
+		name         string
+		client       *http.Client
+		method, path string
+		body         string
+		status       int
+	}{
+		{"bad id", http.DefaultClient, http.MethodGet, "/api/news/abc", "", http.StatusBadRequest},
+		{"inbox without admin", http.DefaultClient, http.MethodGet, "/api/contactMessages", "", http.StatusUnauthorized},
+		{"corrupt json", admin, http.MethodPost, "/api/news", `{"title": `, http.StatusBadRequest},
+		{"unknown publishing status", admin, http.MethodGet, "/api/news?status=hidden", "", http.StatusBadRequest},
+	} {
+		res, problem := srv.problem(test.client, test.method, test.path, test.body)
+		if res.StatusCode != test.status || problem.Status != test.status || problem.Title != http.StatusText(test.status) {
+			t.Errorf("%s answered %d with %+v, want %d", test.name, res.StatusCode, problem, test.status)
+		}
+	}
+
+	res, problem = srv.problem(admin, http.MethodPost, "/api/news", `{"content": "no title"}`)
+	if res.StatusCode != http.StatusUnprocessableEntity || len(problem.Errors) == 0 || problem.Errors[0].Field != "title" {
+		t.Errorf("invalid news answered %d with %+v, want 422 naming the title", res.StatusCode, problem)
+	}
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 
 	"wcs/dao"
@@ -16,18 +17,22 @@ var (
 )
 
 func configStaffsRouter(router *httprouter.Router) {
-	router.GET("/staffs", GetAllStaffs)
+	router.GET("/staffs", cached("staffs", GetAllStaffs))
 	router.POST("/staffs", AddStaffs)
-	router.GET("/staffs/:argID", GetStaffs)
+	router.POST("/staffs/bulk", bulkHandler("staffs"))
+	router.GET("/staffs/:argID", cached("staffs", GetStaffs))
 	router.PUT("/staffs/:argID", UpdateStaffs)
+	router.PATCH("/staffs/:argID", PatchStaffs)
 	router.DELETE("/staffs/:argID", DeleteStaffs)
 }
 
 func configGinStaffsRouter(router gin.IRoutes) {
-	router.GET("/staffs", ConverHttprouterToGin(GetAllStaffs))
+	router.GET("/staffs", ConverHttprouterToGin(cached("staffs", GetAllStaffs)))
 	router.POST("/staffs", ConverHttprouterToGin(AddStaffs))
-	router.GET("/staffs/:argID", ConverHttprouterToGin(GetStaffs))
+	router.POST("/staffs/bulk", ConverHttprouterToGin(bulkHandler("staffs")))
+	router.GET("/staffs/:argID", ConverHttprouterToGin(cached("staffs", GetStaffs)))
 	router.PUT("/staffs/:argID", ConverHttprouterToGin(UpdateStaffs))
+	router.PATCH("/staffs/:argID", ConverHttprouterToGin(PatchStaffs))
 	router.DELETE("/staffs/:argID", ConverHttprouterToGin(DeleteStaffs))
 }
 
@@ -84,7 +89,9 @@ func GetAllStaffs(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-None-Match header string false "ETag of a cached copy"
 // @Success 200 {object} model.Staffs
+// @Success 304 "cached copy is current"
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
 // @Router /staffs/{argID} [get]
@@ -109,7 +116,7 @@ func GetStaffs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
-	writeJSON(ctx, w, record)
+	writeVersioned(ctx, w, r, record.Version, record)
 }
 
 // AddStaffs add to add a single record to staffs table in the wcs database
@@ -122,6 +129,8 @@ func GetStaffs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 // @Success 200 {object} model.Staffs
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /staffs [post]
 // echo '{"id": 22,"name": "iBVEQSDapwAoCwOickNOSsaZD","job": "bCgVsWmQGGBqdXeGyTsemysfU","intro": "yXdkLRhXGVoatuacYYZPImjBd","avatar": "EcFdqBBRjJKCmuekeSswVwbWx"}' | http POST "http://localhost:8080/staffs" X-Api-User:user123
 func AddStaffs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
@@ -135,12 +144,13 @@ func AddStaffs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 
 	if err := staffs.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	staffs.Prepare()
 
 	if err := staffs.Validate(model.Create); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -156,22 +166,28 @@ func AddStaffs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(staffs.Version))
 	writeJSON(ctx, w, staffs)
 }
 
 // UpdateStaffs Update a single record from staffs table in the wcs database
 // @Summary Update an record in table staffs
-// @Description Update a single record from staffs table in the wcs database
+// @Description Update a single record from staffs table in the wcs database, replacing every client writable column
 // @Tags Staffs
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the update is based on"
 // @Param  Staffs body model.Staffs true "Update Staffs record"
 // @Success 200 {object} model.Staffs
 // @Failure 400 {object} api.HTTPError
 // @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Staffs "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
 // @Router /staffs/{argID} [put]
-// echo '{"id": 22,"name": "iBVEQSDapwAoCwOickNOSsaZD","job": "bCgVsWmQGGBqdXeGyTsemysfU","intro": "yXdkLRhXGVoatuacYYZPImjBd","avatar": "EcFdqBBRjJKCmuekeSswVwbWx"}' | http PUT "http://localhost:8080/staffs/1"  X-Api-User:user123
+// echo '{"id": 22,"name": "iBVEQSDapwAoCwOickNOSsaZD","job": "bCgVsWmQGGBqdXeGyTsemysfU","intro": "yXdkLRhXGVoatuacYYZPImjBd","avatar": "EcFdqBBRjJKCmuekeSswVwbWx"}' | http PUT "http://localhost:8080/staffs/1" If-Match:'"1"' X-Api-User:user123
 func UpdateStaffs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -189,12 +205,68 @@ func UpdateStaffs(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 
 	if err := staffs.BeforeSave(); err != nil {
 		returnError(ctx, w, r, dao.ErrBadParams)
+		return
 	}
 
 	staffs.Prepare()
 
 	if err := staffs.Validate(model.Update); err != nil {
-		returnError(ctx, w, r, dao.ErrBadParams)
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "staffs", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	staffs.Version = version
+
+	staffs, _, err = dao.UpdateStaffs(ctx,
+		argID,
+		staffs)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, staffs.Version, staffs)
+		return
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(staffs.Version))
+	writeJSON(ctx, w, staffs)
+}
+
+// PatchStaffs Patch a single record from staffs table in the wcs database
+// @Summary Patch an record in table staffs
+// @Description Apply a JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) document to a single record from staffs table in the wcs database
+// @Tags Staffs
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the patch is based on"
+// @Param  Staffs body object true "merge patch (application/merge-patch+json) or json patch (application/json-patch+json) document"
+// @Success 200 {object} model.Staffs
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Staffs "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
+// @Router /staffs/{argID} [patch]
+// echo '{"avatar": null}' | http PATCH "http://localhost:8080/staffs/1" If-Match:'"1"' Content-Type:application/merge-patch+json X-Api-User:user123
+func PatchStaffs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
 		return
 	}
 
@@ -203,14 +275,56 @@ func UpdateStaffs(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	current, err := dao.GetStaffs(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if version != 0 && version != current.Version {
+		writePreconditionFailed(ctx, w, current.Version, current)
+		return
+	}
+
+	staffs := &model.Staffs{}
+	if err := applyPatch(r, current, staffs); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := staffs.BeforeSave(); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	staffs.Prepare()
+
+	if err := staffs.Validate(model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	// the patch was computed against current, so the update only goes through if nobody changed it since
+	staffs.Version = current.Version
 	staffs, _, err = dao.UpdateStaffs(ctx,
 		argID,
 		staffs)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, staffs.Version, staffs)
+		return
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
 	}
 
+	w.Header().Set("ETag", formatETag(staffs.Version))
 	writeJSON(ctx, w, staffs)
 }
 
@@ -221,11 +335,14 @@ func UpdateStaffs(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 // @Accept  json
 // @Produce  json
 // @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the delete is based on"
 // @Success 204 {object} model.Staffs
 // @Failure 400 {object} api.HTTPError
+// @Failure 412 {object} model.Staffs "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
 // @Failure 500 {object} api.HTTPError
 // @Router /staffs/{argID} [delete]
-// http DELETE "http://localhost:8080/staffs/1" X-Api-User:user123
+// http DELETE "http://localhost:8080/staffs/1" If-Match:'"1"' X-Api-User:user123
 func DeleteStaffs(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	ctx := initializeContext(r)
 
@@ -240,7 +357,19 @@ func DeleteStaffs(w http.ResponseWriter, r *http.Request, ps httprouter.Params)
 		return
 	}
 
-	rowsAffected, err := dao.DeleteStaffs(ctx, argID)
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	rowsAffected, err := dao.DeleteStaffs(ctx, argID, version)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		if current, err := dao.GetStaffs(ctx, argID); err == nil {
+			writePreconditionFailed(ctx, w, current.Version, current)
+			return
+		}
+	}
 	if err != nil {
 		returnError(ctx, w, r, err)
 		return
@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wcs/dao"
+	"wcs/model"
+	"wcs/stream"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Stream hub of the changes sent to the clients of GET /stream
+var Stream = stream.NewHub(512, 64)
+
+// StreamHeartbeat interval of the ping events keeping idle streams, and the proxies in front of them, open
+var StreamHeartbeat = 15 * time.Second
+
+// streamRetry milliseconds a disconnected EventSource waits before reconnecting
+const streamRetry = 3000
+
+// ChangeNotice data of a change event of the stream
+type ChangeNotice struct {
+	Table  string `json:"table" example:"news"`
+	Action string `json:"action" example:"Update"`
+	ID     int32  `json:"id" example:"12"`
+
+	// AdminID admin who made the change, absent for changes of the publish scheduler
+	AdminID    *int32    `json:"admin_id,omitempty" example:"1"`
+	OccurredAt time.Time `json:"occurred_at" example:"2024-05-01T13:00:00Z"`
+}
+
+// StreamReady data of the ready event opening a stream
+type StreamReady struct {
+	// AdminID signed in admin the stream is sent to, to tell own changes from the ones of other admins
+	AdminID int32 `json:"admin_id" example:"1"`
+	// Resumed the stream continues after the Last-Event-ID sent by the client
+	Resumed bool `json:"resumed" example:"false"`
+}
+
+func configStreamRouter(router *httprouter.Router) {
+	router.GET("/stream", GetStream)
+}
+
+func configGinStreamRouter(router gin.IRoutes) {
+	router.GET("/stream", ConverHttprouterToGin(GetStream))
+}
+
+// GetStream is a function to stream the changes of the content tables as Server-Sent Events
+// @Summary Stream live content changes
+// @Tags Stream
+// @Description GetStream keeps the connection open and sends a change event, carrying a ChangeNotice, whenever a record of
+// @Description the requested tables is created, updated or deleted. The stream opens with a ready event and sends a ping event
+// @Description when idle. A client reconnecting with the Last-Event-ID header, or last_event_id, gets the changes it missed;
+// @Description when they are no longer buffered a reset event follows ready and the client should reload what it shows.
+// @Description Clients not reading their events fast enough are disconnected and resume on reconnect. Admin only.
+// @Produce  text/event-stream
+// @Param   tables        query    string  false  "comma separated tables to watch, all content tables when empty"
+// @Param   last_event_id query    string  false  "id of the last event received, instead of the Last-Event-ID header"
+// @Success 200 {object} api.ChangeNotice
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Router /stream [get]
+// http --stream "http://localhost:8080/stream?tables=news,events" X-Api-User:user123
+func GetStream(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+	adminID, _ := dao.CurrentAdminID(ctx)
+
+	var tables []string
+	for _, table := range strings.Split(r.FormValue("tables"), ",") {
+		if table = strings.TrimSpace(table); table == "" {
+			continue
+		}
+		if _, ok := model.NewRecord(table); !ok || table == "admin" {
+			returnError(ctx, w, r, dao.ErrBadParams)
+			return
+		}
+		tables = append(tables, table)
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.FormValue("last_event_id")
+	}
+	// an id that does not parse can not be resumed, the client gets a reset
+	lastID, _ := strconv.ParseUint(lastEventID, 10, 64)
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		returnError(ctx, w, r, errors.New("response writer can not stream"))
+		return
+	}
+
+	client, resumed := Stream.Subscribe(tables, lastID)
+	defer Stream.Unsubscribe(client)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	ready := sse.Event{Event: "ready", Retry: streamRetry, Data: &StreamReady{AdminID: adminID, Resumed: resumed}}
+	if !resumed {
+		// the replayed events carry the ids a resumed client continues from
+		ready.Id = strconv.FormatUint(client.LastID, 10)
+	}
+	sse.Encode(w, ready)
+	if lastEventID != "" && !resumed {
+		sse.Encode(w, sse.Event{Event: "reset", Data: client.LastID})
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(StreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-client.Events:
+			if !ok {
+				if client.Dropped() {
+					log.Printf("Got error when streaming changes to admin %d, the error is 'client lagged behind and was dropped'", adminID)
+				}
+				return
+			}
+			sse.Encode(w, sse.Event{Event: "change", Id: strconv.FormatUint(event.ID, 10), Data: event.Data})
+		case now := <-heartbeat.C:
+			sse.Encode(w, sse.Event{Event: "ping", Data: now.Unix()})
+		}
+		flusher.Flush()
+	}
+}
+
+// NotifyChange is the dao.ChangeHook of the server: it queues the webhook deliveries of change and
+// streams it to the clients of GET /stream once its transaction committed.
+func NotifyChange(ctx context.Context, change *dao.Change) error {
+	if err := NotifyWebhooks(ctx, change); err != nil {
+		return err
+	}
+
+	notice := &ChangeNotice{
+		Table:      change.Table,
+		Action:     change.Action.String(),
+		ID:         change.ID,
+		OccurredAt: time.Now().UTC(),
+	}
+	if adminID, ok := dao.CurrentAdminID(ctx); ok {
+		notice.AdminID = &adminID
+	}
+
+	dao.AfterCommit(ctx, func() {
+		Stream.Publish(change.Table, notice)
+	})
+	return nil
+}
@@ -0,0 +1,403 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/guregu/null"
+	"github.com/julienschmidt/httprouter"
+)
+
+var (
+	_ = null.Bool{}
+)
+
+// TagWithUsage tag together with the number of records it is attached to
+type TagWithUsage struct {
+	*model.Tags
+	Usage      map[string]int `json:"usage"`
+	UsageCount int            `json:"usage_count"`
+}
+
+func configTagsRouter(router *httprouter.Router) {
+	router.GET("/tags", cached("tags", GetAllTags))
+	router.POST("/tags", AddTags)
+	router.GET("/tags/:argID", cached("tags", GetTags))
+	router.PUT("/tags/:argID", UpdateTags)
+	router.PATCH("/tags/:argID", PatchTags)
+	router.DELETE("/tags/:argID", DeleteTags)
+}
+
+func configGinTagsRouter(router gin.IRoutes) {
+	router.GET("/tags", ConverHttprouterToGin(cached("tags", GetAllTags)))
+	router.POST("/tags", ConverHttprouterToGin(AddTags))
+	router.GET("/tags/:argID", ConverHttprouterToGin(cached("tags", GetTags)))
+	router.PUT("/tags/:argID", ConverHttprouterToGin(UpdateTags))
+	router.PATCH("/tags/:argID", ConverHttprouterToGin(PatchTags))
+	router.DELETE("/tags/:argID", ConverHttprouterToGin(DeleteTags))
+}
+
+// GetAllTags is a function to get a slice of record(s) from tags table in the wcs database
+// @Summary Get list of Tags
+// @Tags Tags
+// @Description GetAllTags is a handler to get a slice of record(s) from tags table in the wcs database, with the number of news and events records each tag is attached to
+// @Accept  json
+// @Produce  json
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   order    query    string  false        "db sort order column"
+// @Success 200 {object} api.PagedResults{data=[]api.TagWithUsage}
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /tags [get]
+// http "http://localhost:8080/tags?page=0&pagesize=20" X-Api-User:user123
+func GetAllTags(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	order := r.FormValue("order")
+
+	if err := ValidateRequest(ctx, r, "tags", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllTags(ctx, page, pagesize, order)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	ids := make([]int32, len(records))
+	for i, record := range records {
+		ids[i] = record.ID
+	}
+
+	usage, err := dao.CountTagUsage(ctx, ids)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	tags := make([]*TagWithUsage, len(records))
+	for i, record := range records {
+		tags[i] = &TagWithUsage{Tags: record, Usage: usage[record.ID]}
+		for _, count := range tags[i].Usage {
+			tags[i].UsageCount += count
+		}
+	}
+
+	result := &PagedResults{Page: page, PageSize: pagesize, Data: tags, TotalRecords: totalRows}
+	writeJSON(ctx, w, result)
+}
+
+// GetTags is a function to get a single record from the tags table in the wcs database
+// @Summary Get record from table Tags by  argID
+// @Tags Tags
+// @ID argID
+// @Description GetTags is a function to get a single record from the tags table in the wcs database
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {object} model.Tags
+// @Success 304 "cached copy is current"
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError "ErrNotFound, db record for id not found - returns NotFound HTTP 404 not found error"
+// @Router /tags/{argID} [get]
+// http "http://localhost:8080/tags/1" X-Api-User:user123
+func GetTags(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "tags", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetTags(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeVersioned(ctx, w, r, record.Version, record)
+}
+
+// AddTags add to add a single record to tags table in the wcs database
+// @Summary Add an record to tags table
+// @Description add to add a single record to tags table in the wcs database
+// @Tags Tags
+// @Accept  json
+// @Produce  json
+// @Param Tags body model.Tags true "Add Tags"
+// @Success 200 {object} model.Tags
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
+// @Router /tags [post]
+// echo '{"id": 58,"name": "LOjKjNusFahBrWysvNyGfXFMG","slug": "RsLhnnKPFfvyfVONdYgIQrscl","color": "#3c8dbc"}' | http POST "http://localhost:8080/tags" X-Api-User:user123
+func AddTags(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+	tags := &model.Tags{}
+
+	if err := readJSON(r, tags); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if err := tags.BeforeSave(); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	tags.Prepare()
+
+	if err := tags.Validate(model.Create); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "tags", model.Create); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	var err error
+	tags, _, err = dao.AddTags(ctx, tags)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(tags.Version))
+	writeJSON(ctx, w, tags)
+}
+
+// UpdateTags Update a single record from tags table in the wcs database
+// @Summary Update an record in table tags
+// @Description Update a single record from tags table in the wcs database, replacing every client writable column
+// @Tags Tags
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the update is based on"
+// @Param  Tags body model.Tags true "Update Tags record"
+// @Success 200 {object} model.Tags
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Tags "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
+// @Router /tags/{argID} [put]
+// echo '{"id": 58,"name": "LOjKjNusFahBrWysvNyGfXFMG","slug": "RsLhnnKPFfvyfVONdYgIQrscl","color": "#3c8dbc"}' | http PUT "http://localhost:8080/tags/1" If-Match:'"1"' X-Api-User:user123
+func UpdateTags(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	tags := &model.Tags{}
+	if err := readJSON(r, tags); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if err := tags.BeforeSave(); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	tags.Prepare()
+
+	if err := tags.Validate(model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "tags", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	tags.Version = version
+
+	tags, _, err = dao.UpdateTags(ctx,
+		argID,
+		tags)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, tags.Version, tags)
+		return
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(tags.Version))
+	writeJSON(ctx, w, tags)
+}
+
+// PatchTags Patch a single record from tags table in the wcs database
+// @Summary Patch an record in table tags
+// @Description Apply a JSON Merge Patch (RFC 7386) or JSON Patch (RFC 6902) document to a single record from tags table in the wcs database
+// @Tags Tags
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the patch is based on"
+// @Param  Tags body object true "merge patch (application/merge-patch+json) or json patch (application/json-patch+json) document"
+// @Success 200 {object} model.Tags
+// @Failure 400 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 412 {object} model.Tags "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Failure 409 {object} api.HTTPError "record clashes with a unique key of a stored record"
+// @Router /tags/{argID} [patch]
+// echo '{"color": "#f39c12"}' | http PATCH "http://localhost:8080/tags/1" If-Match:'"1"' Content-Type:application/merge-patch+json X-Api-User:user123
+func PatchTags(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "tags", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	current, err := dao.GetTags(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if version != 0 && version != current.Version {
+		writePreconditionFailed(ctx, w, current.Version, current)
+		return
+	}
+
+	tags := &model.Tags{}
+	if err := applyPatch(r, current, tags); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := tags.BeforeSave(); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	tags.Prepare()
+
+	if err := tags.Validate(model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	// the patch was computed against current, so the update only goes through if nobody changed it since
+	tags.Version = current.Version
+	tags, _, err = dao.UpdateTags(ctx,
+		argID,
+		tags)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		writePreconditionFailed(ctx, w, tags.Version, tags)
+		return
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("ETag", formatETag(tags.Version))
+	writeJSON(ctx, w, tags)
+}
+
+// DeleteTags Delete a single record from tags table in the wcs database
+// @Summary Delete a record from tags
+// @Description Delete a single record from tags table in the wcs database
+// @Tags Tags
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  If-Match header string true "ETag the delete is based on"
+// @Success 204 {object} model.Tags
+// @Failure 400 {object} api.HTTPError
+// @Failure 412 {object} model.Tags "record was modified, current representation returned"
+// @Failure 428 {object} api.HTTPError "If-Match header missing"
+// @Failure 500 {object} api.HTTPError
+// @Router /tags/{argID} [delete]
+// http DELETE "http://localhost:8080/tags/1" If-Match:'"1"' X-Api-User:user123
+func DeleteTags(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "tags", model.Delete); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	version, err := readIfMatch(r)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	rowsAffected, err := dao.DeleteTags(ctx, argID, version)
+	if errors.Is(err, dao.ErrVersionConflict) {
+		if current, err := dao.GetTags(ctx, argID); err == nil {
+			writePreconditionFailed(ctx, w, current.Version, current)
+			return
+		}
+	}
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeRowsAffected(w, rowsAffected)
+}
@@ -0,0 +1,299 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// TicketKey secret the ticket codes, the check-in keys of volunteers and the contact form tokens are signed with.
+// Changing it invalidates every ticket handed out.
+var TicketKey []byte
+
+// ticketSize side of the qr code of a ticket in pixels
+const ticketSize = 320
+
+func configTicketsRouter(router *httprouter.Router) {
+	router.GET("/tickets/:code", GetTicket)
+	router.POST("/events/:argID/checkin", CheckIn)
+	router.GET("/events/:argID/checkin-key", GetCheckInKey)
+	router.GET("/events/:argID/attendance", GetAttendanceStats)
+}
+
+func configGinTicketsRouter(router gin.IRoutes) {
+	router.GET("/tickets/:code", ConverHttprouterToGin(GetTicket))
+	router.POST("/events/:argID/checkin", ConverHttprouterToGin(CheckIn))
+	router.GET("/events/:argID/checkin-key", ConverHttprouterToGin(GetCheckInKey))
+	router.GET("/events/:argID/attendance", ConverHttprouterToGin(GetAttendanceStats))
+}
+
+// CheckInRequest body of a ticket scan
+type CheckInRequest struct {
+	// Code ticket code read from the qr code
+	Code string `json:"code" example:"35.12.q2V0bWJ5Y2hlY2tpbg"`
+}
+
+// CheckInResult outcome of a ticket scan, as much as the volunteer at the door needs to know
+type CheckInResult struct {
+	// Duplicate the ticket was checked in before, CheckedInAt is the time of the first scan
+	Duplicate bool `json:"duplicate" example:"false"`
+
+	RegistrationID int32     `json:"registration_id" example:"12"`
+	Name           string    `json:"name" example:"Ada Lovelace"`
+	CheckedInAt    time.Time `json:"checked_in_at" example:"2024-05-02T13:41:07Z"`
+	ScanCount      int32     `json:"scan_count" example:"1"`
+}
+
+// CheckInKey key volunteers check tickets of an event in with, and the scanner page carrying it
+type CheckInKey struct {
+	EventID int32  `json:"event_id" example:"35"`
+	Key     string `json:"key" example:"Yp1mO0bqVh3c2QzZ8n3WbA"`
+	URL     string `json:"url" example:"https://wcs.example.org/checkin?event=35&key=Yp1mO0bqVh3c2QzZ8n3WbA"`
+}
+
+// GetTicket is a function to get the qr code of a ticket
+// @Summary Get the qr code of a ticket
+// @Tags Registrations
+// @Description GetTicket renders a ticket code as a PNG qr code, to be shown at the door. Codes are checked when scanned, not here.
+// @Produce  png
+// @Param  code path string true "ticket code, optionally followed by .png"
+// @Success 200 {file} file
+// @Failure 400 {object} api.HTTPError
+// @Router /tickets/{code} [get]
+// http "http://localhost:8080/tickets/35.12.q2V0bWJ5Y2hlY2tpbg.png"
+func GetTicket(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	code := strings.TrimSuffix(ps.ByName("code"), ".png")
+	if _, _, err := parseTicket(code); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	png, err := qrcode.Encode(code, qrcode.Medium, ticketSize)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	writeDocument(w, r, "image/png", png, time.Time{})
+}
+
+// CheckIn is a function to check an attendee in by the code of their ticket
+// @Summary Check in a ticket
+// @Tags Registrations
+// @Description CheckIn verifies the signature of a scanned ticket code and marks its registration as attended.
+// @Description Scanning a ticket again is reported as duplicate with the time of the first scan.
+// @Description Signed in admins may check in, as may volunteers presenting the check-in key of the event in the X-Checkin-Key header or the key parameter.
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id of the event"
+// @Param  key query string false "check-in key of the event"
+// @Param  CheckIn body api.CheckInRequest true "scanned ticket code"
+// @Success 200 {object} api.CheckInResult
+// @Failure 400 {object} api.HTTPError "ticket forged or for another event"
+// @Failure 401 {object} api.HTTPError
+// @Failure 403 {object} api.HTTPError "wrong check-in key"
+// @Failure 404 {object} api.HTTPError
+// @Failure 409 {object} api.HTTPError "registration waitlisted or cancelled"
+// @Router /events/{argID}/checkin [post]
+// echo '{"code": "35.12.q2V0bWJ5Y2hlY2tpbg"}' | http POST "http://localhost:8080/events/35/checkin" X-Checkin-Key:Yp1mO0bqVh3c2QzZ8n3WbA
+func CheckIn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if !isAdmin(ctx) {
+		key := r.Header.Get("X-Checkin-Key")
+		if key == "" {
+			key = r.URL.Query().Get("key")
+		}
+		if key == "" {
+			returnError(ctx, w, r, dao.ErrUnauthorized)
+			return
+		}
+		if !hmac.Equal([]byte(key), []byte(checkInKey(argID))) {
+			returnError(ctx, w, r, dao.ErrForbidden)
+			return
+		}
+	}
+
+	request := &CheckInRequest{}
+	if err := readJSON(r, request); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "registrations", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	eventID, registrationID, err := parseTicket(strings.TrimSpace(request.Code))
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+	if eventID != argID {
+		returnError(ctx, w, r, dao.ErrInvalidTicket)
+		return
+	}
+
+	registration, duplicate, err := dao.CheckIn(ctx, eventID, registrationID, time.Now())
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, &CheckInResult{
+		Duplicate:      duplicate,
+		RegistrationID: registration.ID,
+		Name:           registration.Name,
+		CheckedInAt:    registration.CheckedInAt.Time,
+		ScanCount:      registration.ScanCount,
+	})
+}
+
+// GetCheckInKey is a function to get the key volunteers check in the tickets of an event with
+// @Summary Get the check-in key of an event
+// @Tags Registrations
+// @Description GetCheckInKey returns the check-in key of an event and the link to the scanner page carrying it, admin only.
+// @Description Whoever holds the key can check in tickets of this event and no other.
+// @Produce  json
+// @Param  argID path int true "id of the event"
+// @Success 200 {object} api.CheckInKey
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 503 {object} api.HTTPError "no --site-url is configured"
+// @Router /events/{argID}/checkin-key [get]
+// http "http://localhost:8080/events/35/checkin-key" X-Api-User:user123
+func GetCheckInKey(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if _, err := dao.GetEvents(ctx, argID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	base, err := publicSiteURL()
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	key := checkInKey(argID)
+	writeJSON(ctx, w, &CheckInKey{
+		EventID: argID,
+		Key:     key,
+		URL:     fmt.Sprintf("%s/checkin?event=%d&key=%s", base, argID, key),
+	})
+}
+
+// GetAttendanceStats is a function to get the registration and check-in counts of an event
+// @Summary Get the attendance of an event
+// @Tags Registrations
+// @Description GetAttendanceStats returns the registrations, check-ins, no-shows and duplicate scans of an event, admin only
+// @Produce  json
+// @Param  argID path int true "id of the event"
+// @Success 200 {object} model.AttendanceStats
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /events/{argID}/attendance [get]
+// http "http://localhost:8080/events/35/attendance" X-Api-User:user123
+func GetAttendanceStats(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "registrations", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	stats, err := dao.GetAttendanceStats(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, stats)
+}
+
+// ticketCode returns the ticket code of a registration: event id, registration id and their signature
+func ticketCode(registration *model.Registrations) string {
+	eventID, registrationID := strconv.Itoa(int(registration.EventID)), strconv.Itoa(int(registration.ID))
+	return eventID + "." + registrationID + "." + sign("ticket", eventID, registrationID)
+}
+
+// parseTicket returns the event and registration ids of a ticket code whose signature holds
+func parseTicket(code string) (eventID, registrationID int32, err error) {
+	parts := strings.Split(code, ".")
+	if len(parts) != 3 {
+		return 0, 0, dao.ErrInvalidTicket
+	}
+
+	event, err := strconv.ParseInt(parts[0], 10, 32)
+	if err != nil {
+		return 0, 0, dao.ErrInvalidTicket
+	}
+	registration, err := strconv.ParseInt(parts[1], 10, 32)
+	if err != nil {
+		return 0, 0, dao.ErrInvalidTicket
+	}
+
+	if !hmac.Equal([]byte(parts[2]), []byte(sign("ticket", parts[0], parts[1]))) {
+		return 0, 0, dao.ErrInvalidTicket
+	}
+	return int32(event), int32(registration), nil
+}
+
+// checkInKey returns the key volunteers check in the tickets of an event with
+func checkInKey(eventID int32) string {
+	return sign("checkin", strconv.Itoa(int(eventID)))
+}
+
+// sign returns the url safe HMAC-SHA256 signature of parts under TicketKey, truncated to 128 bits
+func sign(parts ...string) string {
+	mac := hmac.New(sha256.New, TicketKey)
+	mac.Write([]byte(strings.Join(parts, "\x00")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
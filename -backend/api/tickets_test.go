@@ -0,0 +1,41 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/guregu/null"
+)
+
+func TestCheckInKeyLink(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.admin()
+
+	event := &model.Events{Title: "Workshop", Content: "hands on", StartTime: null.TimeFrom(time.Now().Add(24 * time.Hour))}
+	if _, _, err := dao.AddEvents(srv.ctx, event); err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/events/%d/checkin-key", event.ID)
+
+	// the scanner link is never built from the host the request was sent to
+	setSiteURL(t, "")
+	if status, body := srv.do(admin, http.MethodGet, path, ""); status != http.StatusServiceUnavailable {
+		t.Errorf("check-in key without a site url answered %d %s, want 503", status, body)
+	}
+
+	setSiteURL(t, "https://wcs.example.org/")
+	status, body := srv.do(admin, http.MethodGet, path, "")
+	key := &CheckInKey{}
+	if status != http.StatusOK || json.Unmarshal([]byte(body), key) != nil {
+		t.Fatalf("check-in key answered %d %s", status, body)
+	}
+	if want := fmt.Sprintf("https://wcs.example.org/checkin?event=%d&key=%s", event.ID, key.Key); key.URL != want {
+		t.Errorf("check-in link is %s, want %s", key.URL, want)
+	}
+}
@@ -0,0 +1,654 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/julienschmidt/httprouter"
+)
+
+// deliverWebhookJob kind of the job posting a delivery to its webhook
+const deliverWebhookJob = "deliver_webhook"
+
+// webhookAttempts delivery attempts of a change before it is dead, about two hours of retries with the backoff of the jobs
+const webhookAttempts = 8
+
+// webhookRetention time delivered changes are kept in the delivery history
+const webhookRetention = 30 * 24 * time.Hour
+
+// webhookTestEvent event of the deliveries sent by TestWebhook
+const webhookTestEvent = "webhook.Test"
+
+// WebhookClient http client the deliveries are posted with. Redirects are not followed, a webhook has to answer
+// at its url.
+var WebhookClient = &http.Client{
+	Timeout: 10 * time.Second,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// WebhookEvent body posted to a webhook, signed with its secret
+type WebhookEvent struct {
+	// Event table and action of the change, e.g. news.Update
+	Event    string `json:"event" example:"news.Update"`
+	Table    string `json:"table" example:"news"`
+	Action   string `json:"action" example:"Update"`
+	RecordID int32  `json:"record_id" example:"12"`
+
+	// Record state of the record after the change, the deleted record for Delete
+	Record interface{} `json:"record"`
+
+	// AdminID admin who made the change, absent for changes of the publish scheduler
+	AdminID    *int32    `json:"admin_id,omitempty" example:"1"`
+	OccurredAt time.Time `json:"occurred_at" example:"2024-05-01T13:00:00Z"`
+}
+
+// deliverWebhookPayload payload of a deliver_webhook job
+type deliverWebhookPayload struct {
+	DeliveryID int32 `json:"delivery_id"`
+}
+
+func configWebhooksRouter(router *httprouter.Router) {
+	router.GET("/webhooks", GetAllWebhooks)
+	router.POST("/webhooks", AddWebhook)
+	router.GET("/webhooks/:argID", GetWebhook)
+	router.PUT("/webhooks/:argID", UpdateWebhook)
+	router.DELETE("/webhooks/:argID", DeleteWebhook)
+	router.POST("/webhooks/:argID/test", TestWebhook)
+	router.GET("/webhooks/:argID/deliveries", GetWebhookDeliveries)
+}
+
+func configGinWebhooksRouter(router gin.IRoutes) {
+	router.GET("/webhooks", ConverHttprouterToGin(GetAllWebhooks))
+	router.POST("/webhooks", ConverHttprouterToGin(AddWebhook))
+	router.GET("/webhooks/:argID", ConverHttprouterToGin(GetWebhook))
+	router.PUT("/webhooks/:argID", ConverHttprouterToGin(UpdateWebhook))
+	router.DELETE("/webhooks/:argID", ConverHttprouterToGin(DeleteWebhook))
+	router.POST("/webhooks/:argID/test", ConverHttprouterToGin(TestWebhook))
+	router.GET("/webhooks/:argID/deliveries", ConverHttprouterToGin(GetWebhookDeliveries))
+}
+
+// GetAllWebhooks is a function to get a slice of record(s) from webhooks table in the wcs database
+// @Summary Get list of webhooks
+// @Tags Webhooks
+// @Description GetAllWebhooks lists the webhooks called on changes of the content tables, admin only
+// @Produce  json
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Success 200 {object} api.PagedResults{data=[]model.Webhooks}
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Router /webhooks [get]
+// http "http://localhost:8080/webhooks" X-Api-User:user123
+func GetAllWebhooks(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "webhooks", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllWebhooks(ctx, page, pagesize, "id")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	writeJSON(ctx, w, result)
+}
+
+// GetWebhook is a function to get a single record from the webhooks table in the wcs database
+// @Summary Get a webhook
+// @Tags Webhooks
+// @Description GetWebhook returns a webhook with its secret, admin only
+// @Produce  json
+// @Param  argID path int true "id"
+// @Success 200 {object} model.Webhooks
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /webhooks/{argID} [get]
+// http "http://localhost:8080/webhooks/2" X-Api-User:user123
+func GetWebhook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "webhooks", model.RetrieveOne); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	record, err := dao.GetWebhook(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// AddWebhook is a function to add a single record to webhooks table in the wcs database
+// @Summary Add a webhook
+// @Tags Webhooks
+// @Description AddWebhook registers a url that is posted a WebhookEvent whenever a record of the listed tables is changed
+// @Description by one of the listed actions, empty lists match everything. A secret is generated when none is given.
+// @Description Each delivery carries the headers X-Wcs-Event, X-Wcs-Delivery, X-Wcs-Timestamp and X-Wcs-Signature,
+// @Description the signature is sha256= followed by the hex HMAC-SHA256 under the secret of the timestamp, a dot and the body.
+// @Description Deliveries not answered with a 2xx status are retried with backoff. Admin only.
+// @Accept  json
+// @Produce  json
+// @Param  Webhook body model.Webhooks true "url, secret, tables, actions, description, paused"
+// @Success 200 {object} model.Webhooks
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Router /webhooks [post]
+// echo '{"url": "https://faculty.example.org/hooks/wcs","tables": "news,events","actions": "Create,Update"}' | http POST "http://localhost:8080/webhooks" X-Api-User:user123
+func AddWebhook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	record := &model.Webhooks{}
+	if err := readJSON(r, record); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	record.Prepare()
+	if err := record.Validate(model.Create); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "webhooks", model.Create); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if record.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			returnError(ctx, w, r, err)
+			return
+		}
+		record.Secret = secret
+	}
+
+	record, err := dao.AddWebhook(ctx, record)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// UpdateWebhook is a function to update a single record from webhooks table in the wcs database
+// @Summary Update a webhook
+// @Tags Webhooks
+// @Description UpdateWebhook replaces the url, tables, actions, description and paused flag of a webhook,
+// @Description its secret is kept when none is given. Admin only.
+// @Accept  json
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param  Webhook body model.Webhooks true "url, secret, tables, actions, description, paused"
+// @Success 200 {object} model.Webhooks
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Failure 422 {object} api.HTTPError "field level validation errors"
+// @Router /webhooks/{argID} [put]
+// echo '{"url": "https://faculty.example.org/hooks/wcs","tables": "news","paused": true}' | http PUT "http://localhost:8080/webhooks/2" X-Api-User:user123
+func UpdateWebhook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	updated := &model.Webhooks{}
+	if err := readJSON(r, updated); err != nil {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	updated.Prepare()
+	if err := updated.Validate(model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "webhooks", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if updated.Secret == "" {
+		current, err := dao.GetWebhook(ctx, argID)
+		if err != nil {
+			returnError(ctx, w, r, err)
+			return
+		}
+		updated.Secret = current.Secret
+	}
+
+	record, err := dao.UpdateWebhook(ctx, argID, updated)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, record)
+}
+
+// DeleteWebhook is a function to delete a single record from webhooks table in the wcs database
+// @Summary Delete a webhook
+// @Tags Webhooks
+// @Description DeleteWebhook deletes a webhook with its delivery history, pending deliveries are dropped. Admin only.
+// @Produce  json
+// @Param  argID path int true "id"
+// @Success 200 {object} int "rows affected"
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /webhooks/{argID} [delete]
+// http DELETE "http://localhost:8080/webhooks/2" X-Api-User:user123
+func DeleteWebhook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "webhooks", model.Delete); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	rowsAffected, err := dao.DeleteWebhook(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeRowsAffected(w, rowsAffected)
+}
+
+// TestWebhook is a function to send a test delivery to a webhook
+// @Summary Send a test delivery to a webhook
+// @Tags Webhooks
+// @Description TestWebhook posts a signed webhook.Test event describing the webhook to its url right away, paused or not,
+// @Description and returns the delivery with the outcome. Test deliveries are not retried. Admin only.
+// @Produce  json
+// @Param  argID path int true "id"
+// @Success 200 {object} model.WebhookDeliveries
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /webhooks/{argID}/test [post]
+// http POST "http://localhost:8080/webhooks/2/test" X-Api-User:user123
+func TestWebhook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "webhooks", model.Update); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	webhook, err := dao.GetWebhook(ctx, argID)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	event := &WebhookEvent{
+		Event:    webhookTestEvent,
+		Table:    "webhooks",
+		Action:   "Test",
+		RecordID: webhook.ID,
+		Record: map[string]interface{}{
+			"id":          webhook.ID,
+			"url":         webhook.URL,
+			"description": webhook.Description,
+		},
+		OccurredAt: time.Now().UTC(),
+	}
+	if adminID, ok := dao.CurrentAdminID(ctx); ok {
+		event.AdminID = &adminID
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	delivery := &model.WebhookDeliveries{WebhookID: webhook.ID, Event: event.Event, RecordID: webhook.ID, Payload: string(payload)}
+	if delivery, err = dao.AddWebhookDelivery(ctx, delivery); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	attempt, postErr := postWebhook(ctx, webhook, delivery)
+	status := model.WebhookDelivered
+	if postErr != nil {
+		status = model.WebhookDead
+	}
+
+	delivery, err = dao.RecordWebhookAttempt(ctx, delivery.ID, attempt, status)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	writeJSON(ctx, w, delivery)
+}
+
+// GetWebhookDeliveries is a function to get the deliveries of a webhook from the webhook_deliveries table in the wcs database
+// @Summary Get the delivery history of a webhook
+// @Tags Webhooks
+// @Description GetWebhookDeliveries lists the deliveries of a webhook with their payload and attempts, newest first, admin only
+// @Produce  json
+// @Param  argID path int true "id"
+// @Param   page     query    int     false        "page requested (defaults to 0)"
+// @Param   pagesize query    int     false        "number of records in a page  (defaults to 20)"
+// @Param   status   query    string  false        "one of pending, delivered, dead"
+// @Success 200 {object} api.PagedResults{data=[]model.WebhookDeliveries}
+// @Failure 400 {object} api.HTTPError
+// @Failure 401 {object} api.HTTPError
+// @Failure 404 {object} api.HTTPError
+// @Router /webhooks/{argID}/deliveries [get]
+// http "http://localhost:8080/webhooks/2/deliveries?status=dead" X-Api-User:user123
+func GetWebhookDeliveries(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	ctx := initializeContext(r)
+
+	if !isAdmin(ctx) {
+		returnError(ctx, w, r, dao.ErrUnauthorized)
+		return
+	}
+
+	argID, err := parseInt32(ps, "argID")
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	page, err := readInt(r, "page", 0)
+	if err != nil || page < 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	pagesize, err := readInt(r, "pagesize", 20)
+	if err != nil || pagesize <= 0 {
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if err := ValidateRequest(ctx, r, "webhook_deliveries", model.RetrieveMany); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	filters := []dao.QueryFilter{dao.ForWebhook(argID)}
+	switch status := r.FormValue("status"); status {
+	case "":
+	case model.WebhookPending, model.WebhookDelivered, model.WebhookDead:
+		filters = append(filters, dao.WithStatus(status))
+	default:
+		returnError(ctx, w, r, dao.ErrBadParams)
+		return
+	}
+
+	if _, err := dao.GetWebhook(ctx, argID); err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	records, totalRows, err := dao.GetAllWebhookDeliveries(ctx, page, pagesize, "id desc", filters...)
+	if err != nil {
+		returnError(ctx, w, r, err)
+		return
+	}
+
+	result := &PagedResults{Page: page, PageSize: pagesize, Data: records, TotalRecords: totalRows}
+	writeJSON(ctx, w, result)
+}
+
+// NotifyWebhooks is the dao.ChangeHook queueing a delivery of change for every webhook watching it. The deliveries and
+// their jobs are saved in the transaction of the change, so a change that rolls back is never announced.
+func NotifyWebhooks(ctx context.Context, change *dao.Change) error {
+	webhooks, err := dao.GetWatchingWebhooks(ctx, change.Table, change.Action)
+	if err != nil || len(webhooks) == 0 {
+		return err
+	}
+
+	event := &WebhookEvent{
+		Event:      change.Table + "." + change.Action.String(),
+		Table:      change.Table,
+		Action:     change.Action.String(),
+		RecordID:   change.ID,
+		Record:     change.Record,
+		OccurredAt: time.Now().UTC(),
+	}
+	if adminID, ok := dao.CurrentAdminID(ctx); ok {
+		event.AdminID = &adminID
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range webhooks {
+		delivery := &model.WebhookDeliveries{WebhookID: webhook.ID, Event: event.Event, RecordID: change.ID, Payload: string(payload)}
+		if _, err := dao.AddWebhookDelivery(ctx, delivery); err != nil {
+			return err
+		}
+		if err := enqueueWebhookDelivery(ctx, delivery.ID); err != nil {
+			return err
+		}
+	}
+
+	dao.AfterCommit(ctx, wakeJobs)
+	return nil
+}
+
+func enqueueWebhookDelivery(ctx context.Context, deliveryID int32) error {
+	job, err := model.NewJob(deliverWebhookJob, deliverWebhookPayload{DeliveryID: deliveryID}, time.Now())
+	if err != nil {
+		return err
+	}
+	job.MaxAttempts = webhookAttempts
+
+	_, _, err = dao.EnqueueJob(ctx, job)
+	return err
+}
+
+// deliverWebhook is the handler of a deliver_webhook job: it posts the delivery to its webhook and logs the attempt.
+// A failed attempt is retried by the job with backoff, the delivery is dead when the job used up its attempts.
+func deliverWebhook(ctx context.Context, job *model.Jobs) error {
+	payload := deliverWebhookPayload{}
+	if err := job.Decode(&payload); err != nil {
+		return err
+	}
+
+	delivery, err := dao.GetWebhookDelivery(ctx, payload.DeliveryID)
+	if dao.KindOf(err) == dao.KindNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if delivery.Status != model.WebhookPending {
+		return nil
+	}
+
+	webhook, err := dao.GetWebhook(ctx, delivery.WebhookID)
+	if dao.KindOf(err) == dao.KindNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	attempt, postErr := postWebhook(ctx, webhook, delivery)
+	status := model.WebhookDelivered
+	if postErr != nil {
+		status = model.WebhookPending
+		if job.Attempts >= job.MaxAttempts {
+			status = model.WebhookDead
+		}
+	}
+
+	if _, err := dao.RecordWebhookAttempt(ctx, delivery.ID, attempt, status); err != nil {
+		log.Printf("Got error when logging the delivery %d to webhook %d, the error is '%v'", delivery.ID, webhook.ID, err)
+		if postErr == nil {
+			// the webhook got it, retrying would deliver it twice
+			return nil
+		}
+	}
+	return postErr
+}
+
+// postWebhook posts the payload of delivery to webhook, signed with its secret. It returns the attempt to log
+// and an error unless the webhook answered with a 2xx status.
+func postWebhook(ctx context.Context, webhook *model.Webhooks, delivery *model.WebhookDeliveries) (*model.WebhookAttempt, error) {
+	start := time.Now()
+	attempt := &model.WebhookAttempt{At: start.UTC()}
+	fail := func(err error) (*model.WebhookAttempt, error) {
+		attempt.Error = err.Error()
+		attempt.DurationMS = time.Since(start).Milliseconds()
+		return attempt, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return fail(err)
+	}
+
+	timestamp := strconv.FormatInt(start.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "WCS-Webhooks/1.0")
+	req.Header.Set("X-Wcs-Event", delivery.Event)
+	req.Header.Set("X-Wcs-Delivery", strconv.Itoa(int(delivery.ID)))
+	req.Header.Set("X-Wcs-Timestamp", timestamp)
+	req.Header.Set("X-Wcs-Signature", "sha256="+signWebhook(webhook.Secret, timestamp, delivery.Payload))
+
+	res, err := WebhookClient.Do(req)
+	if err != nil {
+		return fail(err)
+	}
+	defer res.Body.Close()
+
+	// a short excerpt of the answer helps to tell why a webhook refused a delivery
+	excerpt, _ := io.ReadAll(io.LimitReader(res.Body, 256))
+	attempt.StatusCode = res.StatusCode
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message := fmt.Sprintf("webhook answered %s", res.Status)
+		if text := strings.TrimSpace(string(excerpt)); text != "" {
+			message += ": " + text
+		}
+		return fail(fmt.Errorf("%s", message))
+	}
+
+	attempt.DurationMS = time.Since(start).Milliseconds()
+	return attempt, nil
+}
+
+// signWebhook returns the hex HMAC-SHA256 under secret of the timestamp and the body of a delivery, joined by a dot
+func signWebhook(secret, timestamp, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookSecret returns a random secret for a webhook created without one
+func newWebhookSecret() (string, error) {
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// purgeWebhookDeliveries is a planner deleting the deliveries delivered longer than webhookRetention ago
+func purgeWebhookDeliveries(ctx context.Context, now time.Time) error {
+	_, err := dao.PurgeWebhookDeliveries(ctx, now.Add(-webhookRetention))
+	return err
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"wcs/dao"
+	"wcs/jobs"
+	"wcs/model"
+)
+
+const testWebhookSecret = "s3cret"
+
+// webhookReceiver local endpoint of a webhook, answering with status and checking the signature of every delivery
+type webhookReceiver struct {
+	*httptest.Server
+	t *testing.T
+
+	mu         sync.Mutex
+	status     int
+	deliveries []*http.Request
+	bodies     []string
+}
+
+func newWebhookReceiver(t *testing.T) *webhookReceiver {
+	receiver := &webhookReceiver{t: t, status: http.StatusNoContent}
+	receiver.Server = httptest.NewServer(http.HandlerFunc(receiver.serve))
+	t.Cleanup(receiver.Close)
+	return receiver
+}
+
+func (rc *webhookReceiver) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	mac := hmac.New(sha256.New, []byte(testWebhookSecret))
+	mac.Write([]byte(r.Header.Get("X-Wcs-Timestamp") + "." + string(body)))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get("X-Wcs-Signature") != want {
+		rc.t.Errorf("signature is %q, want %q", r.Header.Get("X-Wcs-Signature"), want)
+	}
+	if timestamp, err := strconv.ParseInt(r.Header.Get("X-Wcs-Timestamp"), 10, 64); err != nil || time.Since(time.Unix(timestamp, 0)) > time.Minute {
+		rc.t.Errorf("timestamp %q is not the unix time of the delivery", r.Header.Get("X-Wcs-Timestamp"))
+	}
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+		rc.t.Errorf("delivery is a %s of %s", r.Method, r.Header.Get("Content-Type"))
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.deliveries = append(rc.deliveries, r)
+	rc.bodies = append(rc.bodies, string(body))
+	w.WriteHeader(rc.status)
+	fmt.Fprint(w, http.StatusText(rc.status))
+}
+
+func (rc *webhookReceiver) answer(status int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.status = status
+}
+
+func (rc *webhookReceiver) received() int {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return len(rc.deliveries)
+}
+
+// addWebhook adds a webhook of the news table posting to url
+func addWebhook(t *testing.T, srv *testServer, url string) *model.Webhooks {
+	t.Helper()
+	webhook, err := dao.AddWebhook(srv.ctx, &model.Webhooks{URL: url, Secret: testWebhookSecret, Tables: "news"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return webhook
+}
+
+// webhookDelivery returns the only delivery of webhook
+func webhookDelivery(t *testing.T, srv *testServer, webhook *model.Webhooks) *model.WebhookDeliveries {
+	t.Helper()
+	records, _, err := dao.GetAllWebhookDeliveries(srv.ctx, 0, 10, "id", dao.ForWebhook(webhook.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("webhook has %d deliveries, want 1", len(records))
+	}
+	return records[0]
+}
+
+// newsChange adds a news record, queueing a delivery for the webhooks of news, and returns a runner delivering it
+func newsChange(t *testing.T, srv *testServer) *jobs.Runner {
+	t.Helper()
+	srv.database.OnChange = NotifyWebhooks
+	if _, _, err := dao.AddNews(srv.ctx, &model.News{Title: "Call for papers", Content: "deadline in May"}); err != nil {
+		t.Fatal(err)
+	}
+
+	runner := jobs.NewRunner()
+	runner.Handle(deliverWebhookJob, deliverWebhook)
+	return runner
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	srv := newTestServer(t)
+	receiver := newWebhookReceiver(t)
+	webhook := addWebhook(t, srv, receiver.URL+"/hooks")
+	runner := newsChange(t, srv)
+
+	runner.Poll(srv.ctx, time.Now())
+	if receiver.received() != 1 {
+		t.Fatalf("receiver got %d deliveries, want 1", receiver.received())
+	}
+
+	delivery := webhookDelivery(t, srv, webhook)
+	if delivery.Status != model.WebhookDelivered || delivery.Attempts != 1 || delivery.ResponseCode != http.StatusNoContent {
+		t.Errorf("delivery is %s after %d attempts with %d, want delivered after 1 with 204", delivery.Status, delivery.Attempts, delivery.ResponseCode)
+	}
+
+	req := receiver.deliveries[0]
+	if req.Header.Get("X-Wcs-Event") != "news.Create" || req.Header.Get("X-Wcs-Delivery") != strconv.Itoa(int(delivery.ID)) {
+		t.Errorf("delivery headers are event %q, delivery %q", req.Header.Get("X-Wcs-Event"), req.Header.Get("X-Wcs-Delivery"))
+	}
+
+	event := &WebhookEvent{}
+	if err := json.Unmarshal([]byte(receiver.bodies[0]), event); err != nil {
+		t.Fatal(err)
+	}
+	if event.Event != "news.Create" || event.Table != "news" || event.RecordID != delivery.RecordID {
+		t.Errorf("posted event is %+v", event)
+	}
+
+	runner.Poll(srv.ctx, time.Now().Add(time.Hour))
+	if receiver.received() != 1 {
+		t.Errorf("delivered change was posted again")
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	srv := newTestServer(t)
+	receiver := newWebhookReceiver(t)
+	receiver.answer(http.StatusInternalServerError)
+	webhook := addWebhook(t, srv, receiver.URL)
+	runner := newsChange(t, srv)
+
+	now := time.Now()
+	runner.Poll(srv.ctx, now)
+	delivery := webhookDelivery(t, srv, webhook)
+	if delivery.Status != model.WebhookPending || delivery.ResponseCode != http.StatusInternalServerError || !strings.Contains(delivery.LastError, "500") {
+		t.Errorf("delivery answered 500 is %s with %d %q, want pending", delivery.Status, delivery.ResponseCode, delivery.LastError)
+	}
+
+	// the retry waits for its backoff
+	runner.Poll(srv.ctx, now.Add(30*time.Second))
+	if receiver.received() != 1 {
+		t.Errorf("failed delivery was retried before its backoff")
+	}
+
+	receiver.answer(http.StatusOK)
+	runner.Poll(srv.ctx, now.Add(2*time.Minute))
+	delivery = webhookDelivery(t, srv, webhook)
+	if receiver.received() != 2 || delivery.Status != model.WebhookDelivered || len(delivery.History) != 2 {
+		t.Errorf("retried delivery is %s after %d posts with %d logged attempts, want delivered after 2", delivery.Status, receiver.received(), len(delivery.History))
+	}
+}
+
+func TestWebhookDead(t *testing.T) {
+	srv := newTestServer(t)
+	receiver := newWebhookReceiver(t)
+	receiver.answer(http.StatusBadGateway)
+	webhook := addWebhook(t, srv, receiver.URL)
+	runner := newsChange(t, srv)
+
+	// backoff is at most six hours, polling a day apart makes every attempt due
+	now := time.Now()
+	for i := 0; i < webhookAttempts+2; i++ {
+		runner.Poll(srv.ctx, now.Add(time.Duration(i)*24*time.Hour))
+	}
+
+	if receiver.received() != webhookAttempts {
+		t.Errorf("receiver got %d posts, want %d", receiver.received(), webhookAttempts)
+	}
+	delivery := webhookDelivery(t, srv, webhook)
+	if delivery.Status != model.WebhookDead || int(delivery.Attempts) != webhookAttempts || delivery.ResponseCode != http.StatusBadGateway {
+		t.Errorf("delivery is %s after %d attempts with %d, want dead after %d", delivery.Status, delivery.Attempts, delivery.ResponseCode, webhookAttempts)
+	}
+}
+
+func TestWebhookTestSend(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.admin()
+	receiver := newWebhookReceiver(t)
+	webhook := addWebhook(t, srv, receiver.URL)
+	path := fmt.Sprintf("/api/webhooks/%d/test", webhook.ID)
+
+	if status, _ := srv.do(http.DefaultClient, http.MethodPost, path, ""); status != http.StatusUnauthorized {
+		t.Errorf("test send without admin answered %d, want 401", status)
+	}
+
+	status, body := srv.do(admin, http.MethodPost, path, "")
+	delivery := &model.WebhookDeliveries{}
+	if status != http.StatusOK || json.Unmarshal([]byte(body), delivery) != nil {
+		t.Fatalf("test send answered %d %s", status, body)
+	}
+	if delivery.Status != model.WebhookDelivered || delivery.Event != webhookTestEvent {
+		t.Errorf("test delivery is %s %s, want delivered %s", delivery.Event, delivery.Status, webhookTestEvent)
+	}
+	if receiver.received() != 1 || receiver.deliveries[0].Header.Get("X-Wcs-Event") != webhookTestEvent {
+		t.Fatalf("receiver got %d posts", receiver.received())
+	}
+
+	// test deliveries are not retried
+	receiver.answer(http.StatusServiceUnavailable)
+	status, body = srv.do(admin, http.MethodPost, path, "")
+	if status != http.StatusOK || json.Unmarshal([]byte(body), delivery) != nil {
+		t.Fatalf("test send answered %d %s", status, body)
+	}
+	if delivery.Status != model.WebhookDead || delivery.ResponseCode != http.StatusServiceUnavailable {
+		t.Errorf("refused test delivery is %s with %d, want dead with 503", delivery.Status, delivery.ResponseCode)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+
+	"github.com/droundy/goopt"
+	"github.com/jinzhu/gorm"
+
+	"wcs/dao"
+	"wcs/model"
+)
+
+var (
+	dsn    = goopt.String([]string{"--dsn"}, "wcsadmin:cs399@tcp(127.0.0.1:3306)/wcs?parseTime=true", "mysql data source name")
+	tables = goopt.String([]string{"--tables"}, "", "comma separated tables to sanitize, defaults to every table with a sanitized column")
+	dryRun = goopt.Flag([]string{"--dry-run"}, nil, "report the rows that would change without saving them", "")
+)
+
+// sanitize cleans the rich text columns of the rows already stored with the html policies of the model package,
+// run it after changing a policy or to clean rows saved before sanitization existed.
+func main() {
+	goopt.Summary = "re-sanitize the html columns of stored rows"
+	goopt.Parse(nil)
+
+	db, err := gorm.Open("mysql", *dsn)
+	if err != nil {
+		log.Fatalf("Got error when connect database, the error is '%v'", err)
+	}
+	defer db.Close()
+
+	names := model.SanitizedTables()
+	if *tables != "" {
+		names = strings.Split(*tables, ",")
+	}
+
+	ctx := dao.WithDatabase(context.Background(), dao.NewDatabase(db, 0, 0))
+	for _, table := range names {
+		table = strings.TrimSpace(table)
+		changed, err := dao.SanitizeRows(ctx, table, *dryRun)
+		if err != nil {
+			log.Fatalf("Got error when sanitizing %s, the error is '%v'", table, err)
+		}
+
+		verb := "sanitized"
+		if *dryRun {
+			verb = "would sanitize"
+		}
+		fmt.Printf("%s: %s %d row(s) %v\n", table, verb, len(changed), changed)
+	}
+}
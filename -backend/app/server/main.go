@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
+	_ "time/tzdata" // IANA zones of events on hosts without a zone database
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-contrib/sessions"
@@ -19,8 +24,14 @@ import (
 	ginSwagger "github.com/swaggo/gin-swagger" // gin-swagger middleware
 
 	"wcs/api"
+	"wcs/cache"
 	"wcs/dao"
+	"wcs/jobs"
+	"wcs/mail"
 	"wcs/model"
+	"wcs/spam"
+	"wcs/storage"
+	"wcs/stream"
 )
 
 var (
@@ -44,10 +55,63 @@ var (
 
 	// OsSignal signal used to shutdown
 	OsSignal chan os.Signal
+
+	dbReadTimeout  = goopt.Int([]string{"--db-read-timeout"}, 5, "seconds a single database read may take, 0 disables the limit")
+	dbWriteTimeout = goopt.Int([]string{"--db-write-timeout"}, 15, "seconds a single database write or transaction may take, 0 disables the limit")
+
+	cacheSize   = goopt.Int([]string{"--cache-mb"}, 32, "size of the read cache of public list and detail responses in MiB, 0 disables it")
+	cacheMaxAge = goopt.Int([]string{"--cache-max-age"}, 60, "seconds browsers and proxies may reuse a public response before revalidating it")
+
+	siteURL  = goopt.String([]string{"--site-url"}, "http://localhost:3000", "base url of the public site used in feed, calendar and mail links, feeds, calendars and mails are unavailable when empty")
+	siteName = goopt.String([]string{"--site-name"}, "WCS", "name of the site used as feed title")
+	timeZone = goopt.String([]string{"--timezone"}, "UTC", "IANA time zone calendar clients show the events in, e.g. Europe/London")
+
+	ticketKey = goopt.String([]string{"--ticket-key"}, "", "secret event tickets, check-in keys, contact form tokens and newsletter links are signed with, defaults to $TICKET_KEY")
+
+	contactRate        = goopt.Int([]string{"--contact-rate"}, 5, "contact messages and newsletter subscriptions accepted per client address every --contact-rate-minutes, 0 disables the limit")
+	contactRateMinutes = goopt.Int([]string{"--contact-rate-minutes"}, 10, "minutes of the window --contact-rate counts in")
+	trustedProxies     = goopt.String([]string{"--trusted-proxies"}, "", "comma separated addresses or CIDR ranges of the reverse proxies whose X-Forwarded-For gives the client address --contact-rate counts, empty trusts none")
+	spamThreshold      = goopt.Int([]string{"--spam-threshold"}, spam.DefaultThreshold, "spam score from which contact messages are quarantined instead of mailed, 0 disables quarantine")
+	blockedWords       = goopt.String([]string{"--blocked-words"}, "", "comma separated words and phrases adding to the spam score of contact messages")
+	blockedDomains     = goopt.String([]string{"--blocked-domains"}, "", "comma separated email domains whose contact messages are quarantined")
+
+	digestDays         = goopt.Int([]string{"--digest-days"}, 0, "days between newsletter digests sent automatically, 0 leaves sending them to the admins")
+	digestBatch        = goopt.Int([]string{"--digest-batch"}, 50, "subscribers a newsletter digest is queued for per batch")
+	digestBatchSeconds = goopt.Int([]string{"--digest-batch-seconds"}, 60, "seconds between the batches of a newsletter digest")
+
+	streamBuffer    = goopt.Int([]string{"--stream-buffer"}, 512, "changes kept for clients of /api/stream resuming after a reconnect")
+	streamQueue     = goopt.Int([]string{"--stream-queue"}, 64, "changes a client of /api/stream may fall behind before it is disconnected")
+	streamHeartbeat = goopt.Int([]string{"--stream-heartbeat"}, 15, "seconds between the pings of an idle /api/stream connection")
+
+	publishInterval = goopt.Int([]string{"--publish-interval"}, 60, "seconds between runs of the news and events publish scheduler")
+
+	mailer        = goopt.String([]string{"--mailer"}, "mailgun", "how emails are sent: mailgun, smtp, file (written to --mail-dir) or log")
+	mailFrom      = goopt.String([]string{"--mail-from"}, "postmaster@sandboxb6c2fb30d44b41e495272143b5d5c41f.mailgun.org", "sender address of emails, e.g. \"WCS <events@example.org>\"")
+	mailgunDomain = goopt.String([]string{"--mailgun-domain"}, "sandboxb6c2fb30d44b41e495272143b5d5c41f.mailgun.org", "Mailgun sending domain")
+	mailgunKey    = goopt.String([]string{"--mailgun-key"}, "", "Mailgun api key, defaults to $MAILGUN_API_KEY")
+	mailgunAPI    = goopt.String([]string{"--mailgun-api"}, "", "Mailgun api base url, e.g. https://api.eu.mailgun.net/v3 for the EU region")
+	smtpAddr      = goopt.String([]string{"--smtp-addr"}, "127.0.0.1:1025", "host:port of the SMTP server emails are sent through")
+	smtpUser      = goopt.String([]string{"--smtp-user"}, "", "SMTP username, no authentication when empty")
+	smtpPassword  = goopt.String([]string{"--smtp-password"}, "", "SMTP password, defaults to $SMTP_PASSWORD")
+	mailDir       = goopt.String([]string{"--mail-dir"}, "./sent-mail", "directory the file mailer writes emails to as .eml files")
+
+	jobInterval   = goopt.Int([]string{"--job-interval"}, 30, "seconds between polls of the background job scheduler")
+	reminderHours = goopt.Int([]string{"--reminder-hours"}, 24, "hours before an event its attendees get a reminder email, 0 disables reminders")
+	followUpHours = goopt.Int([]string{"--follow-up-hours"}, 2, "hours after an event its attendees are asked for feedback, 0 disables follow-ups")
+
+	mediaDir      = goopt.String([]string{"--media-dir"}, "./uploads", "directory uploaded media is stored in")
+	mediaURL      = goopt.String([]string{"--media-url"}, "/uploads", "base url uploaded media is served at")
+	maxUploadSize = goopt.Int([]string{"--max-upload-mb"}, 10, "size limit of uploaded media in MiB")
+	s3Endpoint    = goopt.String([]string{"--s3-endpoint"}, "", "S3 compatible endpoint to store media in instead of --media-dir, e.g. http://127.0.0.1:9000")
+	s3Bucket      = goopt.String([]string{"--s3-bucket"}, "wcs-media", "S3 bucket media is stored in")
+	s3Region      = goopt.String([]string{"--s3-region"}, "us-east-1", "S3 region of the bucket")
+	s3AccessKey   = goopt.String([]string{"--s3-access-key"}, "", "S3 access key, defaults to $S3_ACCESS_KEY")
+	s3SecretKey   = goopt.String([]string{"--s3-secret-key"}, "", "S3 secret key, defaults to $S3_SECRET_KEY")
+	s3PublicURL   = goopt.String([]string{"--s3-public-url"}, "", "base url media in the bucket is served at, defaults to <endpoint>/<bucket>")
 )
 
 // GinServer launch gin server
-func GinServer() (err error) {
+func GinServer(database *dao.Database) (err error) {
 	url := ginSwagger.URL("http://localhost:8080/swagger/doc.json") // The url pointing to API definition
 
 	router := gin.Default()
@@ -62,8 +126,12 @@ func GinServer() (err error) {
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, url))
 
+	if *s3Endpoint == "" {
+		router.Static(*mediaURL, *mediaDir)
+	}
+
 	apiGroup := router.Group("/api")
-	api.ConfigGinRouter(apiGroup)
+	api.ConfigGinRouter(apiGroup, database)
 	router.Run(":8080")
 	if err != nil {
 		log.Fatalf("Error starting server, the error is '%v'", err)
@@ -106,7 +174,37 @@ func main() {
 	}
 
 	db.LogMode(true)
-	dao.DB = db
+	database := dao.NewDatabase(db, time.Duration(*dbReadTimeout)*time.Second, time.Duration(*dbWriteTimeout)*time.Second)
+	database.LogSQL = true
+	if *cacheSize > 0 {
+		database.Cache = cache.New(cache.NewLRU(*cacheSize << 20))
+	}
+	database.OnChange = api.NotifyChange
+	api.CacheMaxAge = *cacheMaxAge
+	api.SiteURL = *siteURL
+	api.SiteName = *siteName
+	if api.TimeZone, err = time.LoadLocation(*timeZone); err != nil {
+		log.Fatalf("Got error when loading time zone %s, the error is '%v'", *timeZone, err)
+	}
+	if api.TicketKey, err = loadTicketKey(); err != nil {
+		log.Fatalf("Got error when generating a ticket key, the error is '%v'", err)
+	}
+	api.ContactLimiter = api.NewRateLimiter(*contactRate, time.Duration(*contactRateMinutes)*time.Minute)
+	if api.TrustedProxies, err = api.ParseTrustedProxies(splitList(*trustedProxies)); err != nil {
+		log.Fatalf("Got error when parsing --trusted-proxies, the error is '%v'", err)
+	}
+	api.SpamFilter = spam.NewFilter(splitList(*blockedWords), splitList(*blockedDomains), *spamThreshold)
+	api.SubscribeLimiter = api.NewRateLimiter(*contactRate, time.Duration(*contactRateMinutes)*time.Minute)
+	api.DigestPeriod = time.Duration(*digestDays) * 24 * time.Hour
+	if *digestBatch > 0 {
+		api.DigestBatchSize = *digestBatch
+	}
+	api.DigestBatchInterval = time.Duration(*digestBatchSeconds) * time.Second
+	api.Stream = stream.NewHub(*streamBuffer, *streamQueue)
+	if *streamHeartbeat > 0 {
+		api.StreamHeartbeat = time.Duration(*streamHeartbeat) * time.Second
+	}
+	ctx := dao.WithDatabase(context.Background(), database)
 
 	db.AutoMigrate(
 		&model.Admin{},
@@ -116,16 +214,162 @@ func main() {
 		&model.Projects{},
 		&model.Resources{},
 		&model.Staffs{},
+		&model.Tags{},
+		&model.EventsTags{},
+		&model.NewsTags{},
+		&model.StatusHistory{},
+		&model.Media{},
+		&model.MediaVariants{},
+		&model.Registrations{},
+		&model.Jobs{},
+		&model.Outbox{},
+		&model.ContactMessages{},
+		&model.Subscribers{},
+		&model.Digests{},
+		&model.Webhooks{},
+		&model.WebhookDeliveries{},
 	)
 
+	skipped, err := dao.MigrateTags(ctx)
+	for _, tag := range skipped {
+		log.Printf("Skipped legacy tag %s, it has no letter or digit to derive a slug from", tag)
+	}
+	if err != nil {
+		log.Fatalf("Got error when migrating tags, the error is '%v'", err)
+	}
+
+	if err = dao.MigrateEvents(ctx); err != nil {
+		log.Fatalf("Got error when migrating event times, the error is '%v'", err)
+	}
+
 	// dao.Logger = func(ctx context.Context, sql string) {
 	// 	fmt.Printf("SQL: %s\n", sql)
 	// }
 
-	go GinServer()
+	dao.Store, err = mediaStorage()
+	if err != nil {
+		log.Fatalf("Got error when opening media storage, the error is '%v'", err)
+	}
+	api.MaxUploadSize = int64(*maxUploadSize) << 20
+
+	api.Mailer, err = newMailer()
+	if err != nil {
+		log.Fatalf("Got error when setting up the %s mailer, the error is '%v'", *mailer, err)
+	}
+
+	go PublishScheduler(ctx, time.Duration(*publishInterval)*time.Second)
+
+	api.ReminderLead = time.Duration(*reminderHours) * time.Hour
+	api.FollowUpDelay = time.Duration(*followUpHours) * time.Hour
+	runner := jobs.NewRunner()
+	api.ConfigJobs(runner)
+	go runner.Run(ctx, time.Duration(*jobInterval)*time.Second)
+	go GinServer(database)
 	LoopForever()
 }
 
+// loadTicketKey returns the key of --ticket-key or $TICKET_KEY. Without one a random key is used,
+// tickets handed out then stop working when the server restarts.
+func loadTicketKey() ([]byte, error) {
+	key := *ticketKey
+	if key == "" {
+		key = os.Getenv("TICKET_KEY")
+	}
+	if key != "" {
+		return []byte(key), nil
+	}
+
+	log.Printf("No --ticket-key set, event tickets will be invalid after a restart")
+	random := make([]byte, 32)
+	_, err := rand.Read(random)
+	return random, err
+}
+
+// splitList returns the non empty items of the comma separated list s
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// mediaStorage returns the storage selected by the command line, an S3 bucket when --s3-endpoint is set, local disk otherwise
+func mediaStorage() (storage.Storage, error) {
+	if *s3Endpoint == "" {
+		return storage.NewLocal(*mediaDir, *mediaURL)
+	}
+
+	s3 := &storage.S3{
+		Endpoint:  *s3Endpoint,
+		Bucket:    *s3Bucket,
+		Region:    *s3Region,
+		AccessKey: *s3AccessKey,
+		SecretKey: *s3SecretKey,
+		PublicURL: *s3PublicURL,
+	}
+
+	if s3.AccessKey == "" {
+		s3.AccessKey = os.Getenv("S3_ACCESS_KEY")
+	}
+
+	if s3.SecretKey == "" {
+		s3.SecretKey = os.Getenv("S3_SECRET_KEY")
+	}
+
+	return s3, nil
+}
+
+// newMailer returns the mailer selected by --mailer
+func newMailer() (mail.Mailer, error) {
+	switch *mailer {
+	case "mailgun":
+		key := *mailgunKey
+		if key == "" {
+			key = os.Getenv("MAILGUN_API_KEY")
+		}
+		if key == "" {
+			log.Printf("No --mailgun-key set, emails are written to the log instead")
+			return mail.NewFile("", *mailFrom)
+		}
+		return mail.NewMailgun(*mailgunDomain, key, *mailgunAPI, *mailFrom), nil
+
+	case "smtp":
+		password := *smtpPassword
+		if password == "" {
+			password = os.Getenv("SMTP_PASSWORD")
+		}
+		return mail.NewSMTP(*smtpAddr, *smtpUser, password, *mailFrom), nil
+
+	case "file":
+		return mail.NewFile(*mailDir, *mailFrom)
+
+	case "log":
+		return mail.NewFile("", *mailFrom)
+	}
+
+	return nil, fmt.Errorf("unknown mailer %q, use mailgun, smtp, file or log", *mailer)
+}
+
+// PublishScheduler publish scheduled and archive expired news and events every interval, ctx carries the database
+func PublishScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		changed, err := dao.ApplyStatusSchedule(ctx, time.Now())
+		if err != nil {
+			log.Printf("Got error when applying publish schedule, the error is '%v'", err)
+		} else if changed > 0 {
+			log.Printf("Publish scheduler changed the status of %d record(s)", changed)
+		}
+
+		<-ticker.C
+	}
+}
+
 // LoopForever on signal processing
 func LoopForever() {
 	fmt.Printf("Entering infinite loop\n")
@@ -0,0 +1,168 @@
+// Package cache keeps the responses of public read endpoints, invalidated per table whenever the table changes.
+package cache
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Backend stores cached values under string keys. Backends may evict values at any time.
+type Backend interface {
+	// Get returns the value stored under key
+	Get(key string) (value []byte, ok bool)
+
+	// Set stores value under key, replacing any previous value
+	Set(key string, value []byte)
+
+	// Len returns the number of values stored
+	Len() int
+}
+
+// Cache caches values per table. Each table has a generation that is part of the keys of its values,
+// Invalidate bumps it so the values read before a change are never returned again and age out of the backend.
+type Cache struct {
+	// counters first, 64 bit aligned for atomic access on 32 bit platforms
+	hits          uint64
+	misses        uint64
+	invalidations uint64
+
+	backend Backend
+	started time.Time
+
+	mu          sync.RWMutex
+	generations map[string]uint64
+	modified    map[string]time.Time
+	dependsOn   map[string][]string
+}
+
+// Stats cache metrics since start
+type Stats struct {
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	HitRatio      float64 `json:"hit_ratio"`
+	Invalidations uint64  `json:"invalidations"`
+	Entries       int     `json:"entries"`
+}
+
+// New returns a Cache storing its values in backend
+func New(backend Backend) *Cache {
+	return &Cache{
+		backend:     backend,
+		started:     time.Now(),
+		generations: make(map[string]uint64),
+		modified:    make(map[string]time.Time),
+		dependsOn:   make(map[string][]string),
+	}
+}
+
+// DependOn declares that values of table embed rows of the tables in on, e.g. news lists carry their tags,
+// so changes to those tables invalidate the values of table too
+func (c *Cache) DependOn(table string, on ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dependsOn[table] = append(c.dependsOn[table], on...)
+}
+
+// Get returns the value cached under key for table, and the version of table to pass to Set when the value is missing
+func (c *Cache) Get(table, key string) (value []byte, version string, ok bool) {
+	if c == nil {
+		return nil, "", false
+	}
+
+	version = c.Version(table)
+	value, ok = c.backend.Get(c.key(table, version, key))
+	if ok {
+		atomic.AddUint64(&c.hits, 1)
+	} else {
+		atomic.AddUint64(&c.misses, 1)
+	}
+	return value, version, ok
+}
+
+// Set caches value under key for table at the version Get returned before value was loaded.
+// A value loaded while the table changed is stored under its stale version, where it is never found.
+func (c *Cache) Set(table, key, version string, value []byte) {
+	if c == nil || version != c.Version(table) {
+		return
+	}
+	c.backend.Set(c.key(table, version, key), value)
+}
+
+// Version returns the current generations of table and the tables it depends on
+func (c *Cache) Version(table string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var b strings.Builder
+	b.WriteString(strconv.FormatUint(c.generations[table], 36))
+	for _, dep := range c.dependsOn[table] {
+		b.WriteByte('.')
+		b.WriteString(strconv.FormatUint(c.generations[dep], 36))
+	}
+	return b.String()
+}
+
+// LastModified returns the time table or a table it depends on was last invalidated,
+// the start of the cache when none changed since
+func (c *Cache) LastModified(table string) time.Time {
+	if c == nil {
+		return time.Time{}
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	last := c.started
+	if c.modified[table].After(last) {
+		last = c.modified[table]
+	}
+	for _, dep := range c.dependsOn[table] {
+		if c.modified[dep].After(last) {
+			last = c.modified[dep]
+		}
+	}
+	return last
+}
+
+// Invalidate drops the cached values of tables and of every table depending on them
+func (c *Cache) Invalidate(tables ...string) {
+	if c == nil || len(tables) == 0 {
+		return
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, table := range tables {
+		c.generations[table]++
+		c.modified[table] = now
+	}
+	atomic.AddUint64(&c.invalidations, 1)
+}
+
+// Stats returns the cache metrics
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+
+	stats := Stats{
+		Hits:          atomic.LoadUint64(&c.hits),
+		Misses:        atomic.LoadUint64(&c.misses),
+		Invalidations: atomic.LoadUint64(&c.invalidations),
+		Entries:       c.backend.Len(),
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// key returns the backend key of key for table at version
+func (c *Cache) key(table, version, key string) string {
+	return table + "@" + version + "?" + key
+}
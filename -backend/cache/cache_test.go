@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInvalidateGenerations(t *testing.T) {
+	c := New(NewLRU(1 << 10))
+
+	_, version, ok := c.Get("news", "/news?page=0")
+	if ok {
+		t.Fatal("empty cache has a value")
+	}
+	c.Set("news", "/news?page=0", version, []byte("v1"))
+	if value, _, ok := c.Get("news", "/news?page=0"); !ok || string(value) != "v1" {
+		t.Fatalf("cached value is %q %v, want v1", value, ok)
+	}
+
+	c.Invalidate("events")
+	if _, _, ok := c.Get("news", "/news?page=0"); !ok {
+		t.Errorf("change of another table dropped the news")
+	}
+
+	c.Invalidate("news")
+	_, current, ok := c.Get("news", "/news?page=0")
+	if ok || current == version {
+		t.Fatalf("value of the previous generation %s is still served at %s", version, current)
+	}
+
+	// a value loaded while the table changed is stored under its stale generation, where it is never found
+	c.Invalidate("news")
+	c.Set("news", "/news?page=0", current, []byte("stale"))
+	if value, _, ok := c.Get("news", "/news?page=0"); ok {
+		t.Errorf("value loaded before the change is served: %q", value)
+	}
+
+	if stats := c.Stats(); stats.Hits != 2 || stats.Misses != 3 || stats.Invalidations != 3 || stats.Entries != 1 {
+		t.Errorf("stats are %+v", stats)
+	}
+}
+
+func TestInvalidateDependencies(t *testing.T) {
+	c := New(NewLRU(1 << 10))
+	c.DependOn("news", "news_tags", "tags")
+	c.DependOn("tags", "news_tags")
+
+	cache := func(table string) {
+		_, version, _ := c.Get(table, "/"+table)
+		c.Set(table, "/"+table, version, []byte(table))
+	}
+	cached := func(table string) bool {
+		_, _, ok := c.Get(table, "/"+table)
+		return ok
+	}
+
+	cache("news")
+	cache("tags")
+	before := c.LastModified("news")
+	time.Sleep(time.Millisecond)
+
+	// renaming a tag changes the news lists carrying it, not the other way round
+	c.Invalidate("tags")
+	if cached("news") || cached("tags") {
+		t.Errorf("values embedding the changed tags are still served: news %v, tags %v", cached("news"), cached("tags"))
+	}
+	if !c.LastModified("news").After(before) {
+		t.Errorf("news last modified %v did not move with its tags", c.LastModified("news"))
+	}
+
+	cache("news")
+	cache("tags")
+	c.Invalidate("news")
+	if cached("news") || !cached("tags") {
+		t.Errorf("after a news change news cached %v, tags cached %v, want only the tags", cached("news"), cached("tags"))
+	}
+
+	cache("news")
+	c.Invalidate("news_tags")
+	if cached("news") || cached("tags") {
+		t.Errorf("tag links changed but news cached %v, tags cached %v", cached("news"), cached("tags"))
+	}
+}
+
+func TestNilCache(t *testing.T) {
+	var c *Cache
+	c.Set("news", "/news", "0", []byte("v"))
+	c.Invalidate("news")
+	if _, _, ok := c.Get("news", "/news"); ok || !c.LastModified("news").IsZero() || c.Stats() != (Stats{}) {
+		t.Errorf("disabled cache holds values")
+	}
+}
+
+func TestLRUEviction(t *testing.T) {
+	l := NewLRU(20)
+	l.Set("a", []byte("123456789"))
+	l.Set("b", []byte("123456789"))
+	l.Get("a")
+	l.Set("c", []byte("123456789"))
+
+	if _, ok := l.Get("b"); ok {
+		t.Errorf("least recently used value b was kept")
+	}
+	if _, ok := l.Get("a"); !ok {
+		t.Errorf("recently read value a was evicted")
+	}
+
+	l.Set("big", make([]byte, 40))
+	if _, ok := l.Get("big"); ok || l.Len() != 2 {
+		t.Errorf("value larger than the cache was stored, %d values", l.Len())
+	}
+}
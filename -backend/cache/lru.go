@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LRU in memory Backend holding up to a number of bytes, evicting the least recently used values first
+type LRU struct {
+	maxBytes int
+
+	mu      sync.Mutex
+	bytes   int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+// NewLRU returns an LRU backend holding up to maxBytes of keys and values
+func NewLRU(maxBytes int) *LRU {
+	return &LRU{
+		maxBytes: maxBytes,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+}
+
+// Get returns the value stored under key and marks it as recently used
+func (l *LRU) Get(key string) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	elem, ok := l.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	l.order.MoveToFront(elem)
+	return elem.Value.(*lruEntry).value, true
+}
+
+// Set stores value under key, evicting the least recently used values until it fits.
+// Values larger than the whole cache are not stored.
+func (l *LRU) Set(key string, value []byte) {
+	size := len(key) + len(value)
+	if size > l.maxBytes {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if elem, ok := l.entries[key]; ok {
+		l.remove(elem)
+	}
+
+	for l.bytes+size > l.maxBytes {
+		l.remove(l.order.Back())
+	}
+
+	l.entries[key] = l.order.PushFront(&lruEntry{key: key, value: value})
+	l.bytes += size
+}
+
+// Len returns the number of values stored
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.order.Len()
+}
+
+func (l *LRU) remove(elem *list.Element) {
+	entry := l.order.Remove(elem).(*lruEntry)
+	delete(l.entries, entry.key)
+	l.bytes -= len(entry.key) + len(entry.value)
+}
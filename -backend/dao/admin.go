@@ -2,6 +2,7 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"wcs/model"
@@ -22,8 +23,13 @@ var (
 // params - order    - db sort order column
 // error - ErrNotFound, db Find error
 func GetAllAdmin(ctx context.Context, page, pagesize int64, order string) (results []*model.Admin, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
 
-	resultOrm := DB.Model(&model.Admin{})
+	resultOrm := conn.Model(&model.Admin{})
 	resultOrm.Count(&totalRows)
 
 	if page > 0 {
@@ -38,7 +44,7 @@ func GetAllAdmin(ctx context.Context, page, pagesize int64, order string) (resul
 	}
 
 	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
+		err = dbError(ErrNotFound, err)
 		return nil, -1, err
 	}
 
@@ -48,9 +54,15 @@ func GetAllAdmin(ctx context.Context, page, pagesize int64, order string) (resul
 // GetAdmin is a function to get a single record from the admin table in the wcs database
 // error - ErrNotFound, db Find error
 func GetAdmin(ctx context.Context, argID int32) (record *model.Admin, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	record = &model.Admin{}
-	if err = DB.First(record, argID).Error; err != nil {
-		err = ErrNotFound
+	if err = conn.First(record, argID).Error; err != nil {
+		err = dbError(ErrNotFound, err)
 		return record, err
 	}
 
@@ -60,9 +72,15 @@ func GetAdmin(ctx context.Context, argID int32) (record *model.Admin, err error)
 // GetAdminByName is a function to get a single record by name from the admin table in the wcs database
 // error - ErrNotFound, db Find error
 func GetAdminByName(ctx context.Context, userName string) (record *model.Admin, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	record = &model.Admin{}
-	if err = DB.First(record, "username = ?", userName).Error; err != nil {
-		err = ErrNotFound
+	if err = conn.First(record, "username = ?", userName).Error; err != nil {
+		err = dbError(ErrNotFound, err)
 		return record, err
 	}
 
@@ -72,52 +90,89 @@ func GetAdminByName(ctx context.Context, userName string) (record *model.Admin,
 // AddAdmin is a function to add a single record to admin table in the wcs database
 // error - ErrInsertFailed, db save call failed
 func AddAdmin(ctx context.Context, record *model.Admin) (result *model.Admin, RowsAffected int64, err error) {
-	db := DB.Save(record)
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	db := conn.Save(record)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrInsertFailed
+		return nil, -1, dbError(ErrInsertFailed, err)
 	}
 
+	invalidate(ctx, "admin")
 	return record, db.RowsAffected, nil
 }
 
 // UpdateAdmin is a function to update a single record from admin table in the wcs database
+// Every client writable column is replaced by the value in updated, zero values included.
+// A non zero updated.Version must match the stored row version, otherwise the current record is returned with ErrVersionConflict.
 // error - ErrNotFound, db record for id not found
-// error - ErrUpdateFailed, db meta data copy failed or db.Save call failed
+// error - ErrVersionConflict, db record was modified since updated.Version was read
+// error - ErrUpdateFailed, db meta data copy failed or db update call failed
 func UpdateAdmin(ctx context.Context, argID int32, updated *model.Admin) (result *model.Admin, RowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
 
 	result = &model.Admin{}
-	db := DB.First(result, argID)
+	db := conn.First(result, argID)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrNotFound
+		return nil, -1, dbError(ErrNotFound, err)
 	}
 
-	if err = Copy(result, updated); err != nil {
-		return nil, -1, ErrUpdateFailed
+	version := result.Version
+	if updated.Version != 0 && updated.Version != version {
+		return result, -1, ErrVersionConflict
 	}
 
-	db = db.Save(result)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrUpdateFailed
+	if err = Replace(result, updated); err != nil {
+		return nil, -1, dbError(ErrUpdateFailed, err)
 	}
 
-	return result, db.RowsAffected, nil
+	RowsAffected, err = saveVersioned(conn, result, version)
+	if errors.Is(err, ErrVersionConflict) {
+		if result, err = GetAdmin(ctx, argID); err != nil {
+			return nil, -1, err
+		}
+		return result, -1, ErrVersionConflict
+	}
+	if err != nil {
+		return nil, -1, err
+	}
+
+	invalidate(ctx, "admin")
+	return result, RowsAffected, nil
 }
 
 // DeleteAdmin is a function to delete a single record from admin table in the wcs database
+// A non zero version must match the stored row version.
 // error - ErrNotFound, db Find error
+// error - ErrVersionConflict, db record was modified since version was read
 // error - ErrDeleteFailed, db Delete failed error
-func DeleteAdmin(ctx context.Context, argID int32) (rowsAffected int64, err error) {
+func DeleteAdmin(ctx context.Context, argID int32, version int32) (rowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer done()
 
 	record := &model.Admin{}
-	db := DB.First(record, argID)
+	db := conn.First(record, argID)
 	if db.Error != nil {
-		return -1, ErrNotFound
+		return -1, dbError(ErrNotFound, db.Error)
 	}
 
-	db = db.Delete(record)
-	if err = db.Error; err != nil {
-		return -1, ErrDeleteFailed
+	if version != 0 && version != record.Version {
+		return -1, ErrVersionConflict
 	}
 
-	return db.RowsAffected, nil
+	rowsAffected, err = deleteVersioned(conn, record, record.Version)
+	if err == nil {
+		invalidate(ctx, "admin")
+	}
+	return rowsAffected, err
 }
@@ -0,0 +1,212 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"wcs/model"
+
+	"github.com/jinzhu/gorm"
+)
+
+// errRolledBack aborts the transaction of an all or nothing batch once an operation failed
+var errRolledBack = errors.New("bulk operation failed")
+
+// bulkDeletes deletes the rows depending on a record of a table inside the transaction deleting it, like the single record delete does
+var bulkDeletes = map[string]func(tx *gorm.DB, id int32) error{
+	"events": deleteEventRegistrations,
+}
+
+// bulkDependents tables changed along with a table by a batch, invalidated once it committed
+var bulkDependents = map[string][]string{
+	"events": {"registrations"},
+}
+
+// BulkOperation is a single create, update or delete executed as part of a bulk request
+type BulkOperation struct {
+	// Action one of model.Create, model.Update or model.Delete
+	Action model.Action
+
+	// ID primary key of the record to update or delete
+	ID int32
+
+	// Version expected row version for update or delete, 0 skips the check
+	Version int32
+
+	// Record values to create or update, nil for delete
+	Record model.Model
+}
+
+// BulkResult is the outcome of a single BulkOperation
+type BulkResult struct {
+	// Record created or updated record, nil for delete
+	Record model.Model
+
+	// RowsAffected number of rows touched by the operation
+	RowsAffected int64
+
+	// Err reason the operation failed, nil on success
+	Err error
+}
+
+// ExecBulk is a function to execute a batch of operations against a table in the wcs database inside one transaction
+// With allOrNothing the transaction is rolled back on the first failing operation and the remaining operations are not
+// run. Otherwise each operation runs in its own savepoint, failures are rolled back individually and the rest is committed.
+// Deleted records take the rows depending on them along, the registrations of an event, as the single record deletes do.
+// committed reports whether the transaction was committed.
+// error - ErrBadParams, unknown table or action
+// error - ErrUpdateFailed, transaction could not be started or committed
+func ExecBulk(ctx context.Context, table string, ops []*BulkOperation, allOrNothing bool) (results []*BulkResult, committed bool, err error) {
+	if _, ok := model.NewRecord(table); !ok {
+		return nil, false, ErrBadParams
+	}
+
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer done()
+
+	results = make([]*BulkResult, len(ops))
+	err = transaction(conn, func(tx *gorm.DB) error {
+		for i, op := range ops {
+			if allOrNothing {
+				results[i] = execBulkOperation(ctx, tx, table, op)
+				if results[i].Err != nil {
+					return errRolledBack
+				}
+				continue
+			}
+
+			savepoint := fmt.Sprintf("bulk_%d", i)
+			if err := tx.Exec("SAVEPOINT " + savepoint).Error; err != nil {
+				return dbError(ErrUpdateFailed, err)
+			}
+
+			results[i] = execBulkOperation(ctx, tx, table, op)
+			var err error
+			if results[i].Err != nil {
+				err = tx.Exec("ROLLBACK TO SAVEPOINT " + savepoint).Error
+			} else {
+				err = tx.Exec("RELEASE SAVEPOINT " + savepoint).Error
+			}
+			if err != nil {
+				return dbError(ErrUpdateFailed, err)
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errRolledBack) {
+		return results, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	invalidate(ctx, table)
+	if join, ok := taggedTables[table]; ok {
+		invalidate(ctx, join)
+	}
+	invalidate(ctx, bulkDependents[table]...)
+	return results, true, nil
+}
+
+func execBulkOperation(ctx context.Context, tx *gorm.DB, table string, op *BulkOperation) *BulkResult {
+	switch op.Action {
+	case model.Create:
+		if err := resolveMedia(tx, table, op.Record); err != nil {
+			return &BulkResult{Err: err}
+		}
+
+		db := tx.Create(op.Record)
+		if db.Error != nil {
+			return &BulkResult{Err: dbError(ErrInsertFailed, db.Error)}
+		}
+
+		if err := trackStatus(ctx, tx, table, op.Record, ""); err != nil {
+			return &BulkResult{Err: err}
+		}
+
+		if err := syncTags(tx, table, op.Record); err != nil {
+			return &BulkResult{Err: err}
+		}
+
+		if err := recordChange(ctx, tx, table, model.Create, op.Record); err != nil {
+			return &BulkResult{Err: err}
+		}
+		return &BulkResult{Record: op.Record, RowsAffected: db.RowsAffected}
+
+	case model.Update:
+		current, _ := model.NewRecord(table)
+		if err := tx.First(current, op.ID).Error; err != nil {
+			return &BulkResult{Err: dbError(ErrNotFound, err)}
+		}
+
+		version, status := recordVersion(current), recordStatus(current)
+		if op.Version != 0 && op.Version != version {
+			return &BulkResult{Record: current, Err: ErrVersionConflict}
+		}
+
+		if err := Replace(current, op.Record); err != nil {
+			return &BulkResult{Err: dbError(ErrUpdateFailed, err)}
+		}
+		copyTags(table, current, op.Record)
+
+		if err := resolveMedia(tx, table, current); err != nil {
+			return &BulkResult{Err: err}
+		}
+
+		rowsAffected, err := saveVersioned(tx, current, version)
+		if err != nil {
+			return &BulkResult{Err: err}
+		}
+
+		if err = trackStatus(ctx, tx, table, current, status); err != nil {
+			return &BulkResult{Err: err}
+		}
+
+		if err = syncTags(tx, table, current); err != nil {
+			return &BulkResult{Err: err}
+		}
+
+		if err = recordChange(ctx, tx, table, model.Update, current); err != nil {
+			return &BulkResult{Err: err}
+		}
+		return &BulkResult{Record: current, RowsAffected: rowsAffected}
+
+	case model.Delete:
+		current, _ := model.NewRecord(table)
+		if err := tx.First(current, op.ID).Error; err != nil {
+			return &BulkResult{Err: dbError(ErrNotFound, err)}
+		}
+
+		version := recordVersion(current)
+		if op.Version != 0 && op.Version != version {
+			return &BulkResult{Record: current, Err: ErrVersionConflict}
+		}
+
+		rowsAffected, err := deleteVersioned(tx, current, version)
+		if err != nil {
+			return &BulkResult{Err: err}
+		}
+
+		if deleteDependents, ok := bulkDeletes[table]; ok {
+			if err = deleteDependents(tx, op.ID); err != nil {
+				return &BulkResult{Err: err}
+			}
+		}
+
+		if err = clearTags(tx, table, op.ID); err != nil {
+			return &BulkResult{Err: err}
+		}
+
+		if err = recordChange(ctx, tx, table, model.Delete, current); err != nil {
+			return &BulkResult{Err: err}
+		}
+		return &BulkResult{RowsAffected: rowsAffected}
+
+	default:
+		return &BulkResult{Err: ErrBadParams}
+	}
+}
@@ -0,0 +1,45 @@
+package dao
+
+import (
+	"context"
+
+	"wcs/model"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Change a record created, updated or deleted by a dao write function
+type Change struct {
+	// Table name of the table the record belongs to
+	Table string
+
+	// Action one of model.Create, model.Update or model.Delete
+	Action model.Action
+
+	// ID primary key of the record
+	ID int32
+
+	// Record state of the record after the change, the deleted record for model.Delete
+	Record model.Model
+}
+
+// ChangeHook is called for every Change within the transaction of the write. The dao functions called with ctx join
+// the transaction, an error rolls the write back. Work outside of the database waits for the commit, see AfterCommit.
+type ChangeHook func(ctx context.Context, change *Change) error
+
+// recordChange reports the change of record by action to the OnChange hook of the Database, tx is the handle the
+// record was written with
+func recordChange(ctx context.Context, tx *gorm.DB, table string, action model.Action, record model.Model) error {
+	database, ok := ctx.Value(databaseKey{}).(*Database)
+	if !ok || database.OnChange == nil {
+		return nil
+	}
+
+	if _, ok := ctx.Value(txKey{}).(*txState); !ok {
+		if state := txStateOf(tx); state != nil {
+			ctx = context.WithValue(ctx, txKey{}, state)
+		}
+	}
+
+	return database.OnChange(ctx, &Change{Table: table, Action: action, ID: recordID(record), Record: record})
+}
@@ -0,0 +1,163 @@
+package dao
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"wcs/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+// likeEscaper escapes the wildcards of LIKE patterns with !, backslashes mean different things to MySQL and SQLite
+var likeEscaper = strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`)
+
+// AddContactMessage is a function to add a message of the contact form to the contact_messages table in the wcs database,
+// with status new unless it was quarantined
+// error - ErrInsertFailed, db save call failed
+func AddContactMessage(ctx context.Context, record *model.ContactMessages) (result *model.ContactMessages, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if record.Status != model.ContactQuarantined {
+		record.Status = model.ContactNew
+	}
+	if err = conn.Create(record).Error; err != nil {
+		return nil, dbError(ErrInsertFailed, err)
+	}
+
+	return record, nil
+}
+
+// GetAllContactMessages is a function to get a slice of record(s) from contact_messages table in the wcs database
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of records in a page  (defaults to 20)
+// params - order    - db sort order column
+// params - filters  - optional query filters, e.g. WithStatus, ContactMatching, AssignedTo
+// error - ErrNotFound, db Find error
+func GetAllContactMessages(ctx context.Context, page, pagesize int64, order string, filters ...QueryFilter) (results []*model.ContactMessages, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	resultOrm := conn.Model(&model.ContactMessages{})
+	for _, filter := range filters {
+		resultOrm = filter(resultOrm)
+	}
+	resultOrm.Count(&totalRows)
+
+	if page > 0 {
+		offset := (page - 1) * pagesize
+		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
+	} else {
+		resultOrm = resultOrm.Limit(pagesize)
+	}
+
+	if order != "" {
+		resultOrm = resultOrm.Order(order)
+	}
+
+	results = make([]*model.ContactMessages, 0)
+	if err = resultOrm.Find(&results).Error; err != nil {
+		err = dbError(ErrNotFound, err)
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// GetContactMessage is a function to get a single record from the contact_messages table in the wcs database
+// error - ErrNotFound, db Find error
+func GetContactMessage(ctx context.Context, argID int32) (record *model.ContactMessages, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.ContactMessages{}
+	if err = conn.First(record, argID).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	return record, nil
+}
+
+// SetContactStatus is a function to move a message of the contact_messages table in the wcs database to status
+// error - ErrNotFound, db record for id not found
+// error - ErrUpdateFailed, db update call failed
+func SetContactStatus(ctx context.Context, argID int32, status string, now time.Time) (record *model.ContactMessages, err error) {
+	return updateContactMessage(ctx, argID, map[string]interface{}{"status": status, "update_time": now.UTC()})
+}
+
+// AssignContactMessage is a function to hand a message of the contact_messages table in the wcs database to an admin,
+// an invalid adminID unassigns it
+// error - ErrNotFound, db record for id not found
+// error - ErrBadParams, there is no admin adminID
+// error - ErrUpdateFailed, db update call failed
+func AssignContactMessage(ctx context.Context, argID int32, adminID null.Int, now time.Time) (record *model.ContactMessages, err error) {
+	if adminID.Valid {
+		if _, err := GetAdmin(ctx, int32(adminID.Int64)); err != nil {
+			if KindOf(err) == KindNotFound {
+				return nil, ErrBadParams
+			}
+			return nil, err
+		}
+	}
+
+	return updateContactMessage(ctx, argID, map[string]interface{}{"assigned_to": adminID, "update_time": now.UTC()})
+}
+
+func updateContactMessage(ctx context.Context, argID int32, fields map[string]interface{}) (record *model.ContactMessages, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.ContactMessages{}
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if err := forUpdate(tx).First(record, argID).Error; err != nil {
+			return dbError(ErrNotFound, err)
+		}
+
+		return dbError(ErrUpdateFailed, tx.Model(record).UpdateColumns(fields).Error)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// ContactMatching narrows a GetAllContactMessages query down to the messages whose name, email or text contains q
+func ContactMatching(q string) QueryFilter {
+	pattern := "%" + likeEscaper.Replace(q) + "%"
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("name LIKE ? ESCAPE '!' OR email LIKE ? ESCAPE '!' OR feedback LIKE ? ESCAPE '!'", pattern, pattern, pattern)
+	}
+}
+
+// AssignedTo narrows a GetAllContactMessages query down to the messages assigned to adminID, or the unassigned ones for an invalid adminID
+func AssignedTo(adminID null.Int) QueryFilter {
+	return func(db *gorm.DB) *gorm.DB {
+		if !adminID.Valid {
+			return db.Where("assigned_to IS NULL")
+		}
+		return db.Where("assigned_to = ?", adminID.Int64)
+	}
+}
+
+// WithoutStatus narrows a GetAll query down to the records in none of statuses
+func WithoutStatus(statuses ...string) QueryFilter {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("status NOT IN (?)", statuses)
+	}
+}
@@ -3,8 +3,10 @@ package dao
 import (
 	"context"
 	"errors"
-	"fmt"
 	"reflect"
+	"time"
+
+	"wcs/model"
 
 	"github.com/jinzhu/gorm"
 )
@@ -33,28 +35,10 @@ type BuildInfo struct {
 
 type LogSql func(ctx context.Context, sql string)
 
-var (
-	// ErrNotFound error when record not found
-	ErrNotFound = fmt.Errorf("record Not Found")
-
-	// ErrUnableToMarshalJSON error when json payload corrupt
-	ErrUnableToMarshalJSON = fmt.Errorf("json payload corrupt")
-
-	// ErrUpdateFailed error when update fails
-	ErrUpdateFailed = fmt.Errorf("db update error")
-
-	// ErrInsertFailed error when insert fails
-	ErrInsertFailed = fmt.Errorf("db insert error")
-
-	// ErrDeleteFailed error when delete fails
-	ErrDeleteFailed = fmt.Errorf("db delete error")
-
-	// ErrBadParams error when bad params passed in
-	ErrBadParams = fmt.Errorf("bad params error")
-
-	// DB reference to database
-	DB *gorm.DB
+// QueryFilter narrows down the records returned by a GetAll function
+type QueryFilter func(db *gorm.DB) *gorm.DB
 
+var (
 	// AppBuildInfo reference to build info
 	AppBuildInfo *BuildInfo
 
@@ -85,6 +69,86 @@ func Copy(dst interface{}, src interface{}) error {
 	return nil
 }
 
+// Replace overwrites every client writable column of dst with the value held by src, zero values included.
+// Server managed columns (primary key, timestamps, version) of dst are left untouched.
+func Replace(dst model.Model, src model.Model) error {
+	dstV := reflect.Indirect(reflect.ValueOf(dst))
+	srcV := reflect.Indirect(reflect.ValueOf(src))
+
+	if !dstV.CanAddr() {
+		return errors.New("replace to value is unaddressable")
+	}
+
+	if srcV.Type() != dstV.Type() {
+		return errors.New("different types can be replaced")
+	}
+
+	for _, col := range dst.TableInfo().Columns {
+		if col.IsServerManaged() {
+			continue
+		}
+		dstV.FieldByName(col.GoFieldName).Set(srcV.FieldByName(col.GoFieldName))
+	}
+
+	return nil
+}
+
 func isZeroOfUnderlyingType(x interface{}) bool {
 	return x == nil || reflect.DeepEqual(x, reflect.Zero(reflect.TypeOf(x)).Interface())
 }
+
+// saveVersioned writes every column of record back to the database, provided the stored row is still at version.
+// The row version is bumped and update_time refreshed on success, so any other writer holding the old version is rejected.
+// error - ErrVersionConflict, stored row no longer at version
+// error - ErrUpdateFailed, db update call failed
+func saveVersioned(db *gorm.DB, record model.Model, version int32) (rowsAffected int64, err error) {
+	fields := make(map[string]interface{})
+	recordV := reflect.Indirect(reflect.ValueOf(record))
+	for _, col := range record.TableInfo().Columns {
+		if col.IsPrimaryKey {
+			continue
+		}
+		fields[col.Name] = recordV.FieldByName(col.GoFieldName).Interface()
+	}
+	fields["version"] = version + 1
+	if _, ok := fields["update_time"]; ok {
+		fields["update_time"] = time.Now()
+	}
+
+	db = db.Model(record).Where("version = ?", version).Updates(fields)
+	if db.Error != nil {
+		return -1, dbError(ErrUpdateFailed, db.Error)
+	}
+
+	if db.RowsAffected == 0 {
+		return 0, ErrVersionConflict
+	}
+
+	return db.RowsAffected, nil
+}
+
+// deleteVersioned deletes record provided the stored row is still at the version held by record.
+// error - ErrVersionConflict, stored row no longer at the version of record
+// error - ErrDeleteFailed, db delete call failed
+func deleteVersioned(db *gorm.DB, record model.Model, version int32) (rowsAffected int64, err error) {
+	db = db.Where("version = ?", version).Delete(record)
+	if db.Error != nil {
+		return -1, dbError(ErrDeleteFailed, db.Error)
+	}
+
+	if db.RowsAffected == 0 {
+		return 0, ErrVersionConflict
+	}
+
+	return db.RowsAffected, nil
+}
+
+// recordVersion returns the row version held by record
+func recordVersion(record model.Model) int32 {
+	return int32(reflect.Indirect(reflect.ValueOf(record)).FieldByName("Version").Int())
+}
+
+// recordID returns the primary key held by record
+func recordID(record model.Model) int32 {
+	return int32(reflect.Indirect(reflect.ValueOf(record)).FieldByName("ID").Int())
+}
@@ -0,0 +1,112 @@
+// Package daotest opens throwaway sqlite databases holding every table of the wcs schema, for the tests of the
+// packages running queries through dao.
+package daotest
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"wcs/dao"
+	"wcs/model"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite" // register the sqlite3 dialect
+)
+
+// Models every table of the schema, in the order of the AutoMigrate of the server
+var Models = []interface{}{
+	&model.Admin{},
+	&model.Events{},
+	&model.News{},
+	&model.Phds{},
+	&model.Projects{},
+	&model.Resources{},
+	&model.Staffs{},
+	&model.Tags{},
+	&model.EventsTags{},
+	&model.NewsTags{},
+	&model.StatusHistory{},
+	&model.Media{},
+	&model.MediaVariants{},
+	&model.Registrations{},
+	&model.Jobs{},
+	&model.Outbox{},
+	&model.ContactMessages{},
+	&model.Subscribers{},
+	&model.Digests{},
+	&model.Webhooks{},
+	&model.WebhookDeliveries{},
+}
+
+// Open returns a Database on a new sqlite database in a temporary directory of t, and a context carrying it.
+// The database is closed when t ends.
+func Open(t testing.TB) (*dao.Database, context.Context) {
+	t.Helper()
+	_, database, ctx := OpenDB(t)
+	return database, ctx
+}
+
+// OpenDB is Open also returning the gorm handle of the database, for tests setting up rows or columns the models
+// do not know, like the legacy columns the migrations move.
+func OpenDB(t testing.TB) (*gorm.DB, *dao.Database, context.Context) {
+	t.Helper()
+
+	db, err := gorm.Open("sqlite3", filepath.Join(t.TempDir(), "wcs.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	// sqlite allows one writer, a single connection serializes the transactions instead of failing them as busy
+	db.DB().SetMaxOpenConns(1)
+
+	for _, m := range Models {
+		if err := createTable(db, m); err != nil {
+			t.Fatalf("create table of %T: %v", m, err)
+		}
+	}
+	if err := db.AutoMigrate(Models...).Error; err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	database := dao.NewDatabase(db, 5*time.Second, 5*time.Second)
+	return db, database, dao.WithDatabase(context.Background(), database)
+}
+
+// createTable creates the table of m with an autoincrement primary key. The AutoMigrate of the sqlite dialect
+// declares int32 keys without autoincrement, so the tables with a single key are created here first.
+func createTable(db *gorm.DB, m interface{}) error {
+	scope := db.NewScope(m)
+	if scope.Dialect().HasTable(scope.TableName()) {
+		return nil
+	}
+
+	var keys int
+	for _, f := range scope.GetModelStruct().StructFields {
+		if f.IsPrimaryKey && f.IsNormal {
+			keys++
+		}
+	}
+	if keys != 1 {
+		return nil
+	}
+
+	var columns []string
+	for _, f := range scope.GetModelStruct().StructFields {
+		if f.IsIgnored || !f.IsNormal {
+			continue
+		}
+		if f.IsPrimaryKey {
+			columns = append(columns, scope.Quote(f.DBName)+" integer primary key autoincrement")
+			continue
+		}
+		field, _ := scope.FieldByName(f.Name)
+		columns = append(columns, scope.Quote(f.DBName)+" "+scope.Dialect().DataTypeOf(field.StructField))
+	}
+
+	return db.Exec(fmt.Sprintf("CREATE TABLE %s (%s)", scope.QuotedTableName(), strings.Join(columns, ","))).Error
+}
@@ -0,0 +1,296 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"wcs/cache"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Database connection pool the dao functions run their queries on. It reaches them in the context, see WithDatabase,
+// so requests, jobs and tests can each hand the dao a database of their own.
+type Database struct {
+	db      *sql.DB
+	dialect string
+
+	// ReadTimeout deadline of a single read operation, zero leaves reads bounded by the caller context only
+	ReadTimeout time.Duration
+
+	// WriteTimeout deadline of a single write operation including its transaction, zero leaves writes bounded by the caller context only
+	WriteTimeout time.Duration
+
+	// LogSQL log every statement, like gorm LogMode
+	LogSQL bool
+
+	// Cache read cache invalidated by the dao write functions on every change of a table, nil when caching is off
+	Cache *cache.Cache
+
+	// OnChange hook the dao write functions report every record they create, update or delete to, nil when nobody listens
+	OnChange ChangeHook
+}
+
+// errNoDatabase cause reported when a dao function is called with a context lacking a Database
+var errNoDatabase = errors.New("no database in context")
+
+type databaseKey struct{}
+
+type txKey struct{}
+
+// txStateSetting gorm setting the handles of a transaction carry its txState in
+const txStateSetting = "wcs:tx_state"
+
+// txState transaction shared by the dao calls of a Transaction, with the tables they changed
+// and the functions to run once it committed
+type txState struct {
+	tx      *sql.Tx
+	changed []string
+	after   []func()
+}
+
+// NewDatabase returns a Database running its queries on the connection pool of db
+func NewDatabase(db *gorm.DB, readTimeout, writeTimeout time.Duration) *Database {
+	return &Database{
+		db:           db.DB(),
+		dialect:      db.Dialect().GetName(),
+		ReadTimeout:  readTimeout,
+		WriteTimeout: writeTimeout,
+	}
+}
+
+// WithDatabase returns a copy of ctx the dao functions run their queries on database with
+func WithDatabase(ctx context.Context, database *Database) context.Context {
+	return context.WithValue(ctx, databaseKey{}, database)
+}
+
+// Transaction runs fn in one database transaction, limited by the WriteTimeout. The dao functions called with the context
+// passed to fn share the transaction, which commits when fn returns nil and rolls back otherwise.
+// Transactions started within fn join the outer one.
+// error - ErrUpdateFailed, transaction could not be started or committed
+func Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	database, ok := ctx.Value(databaseKey{}).(*Database)
+	if !ok {
+		return ErrUnavailable.Wrap(errNoDatabase)
+	}
+
+	if _, nested := ctx.Value(txKey{}).(*txState); nested {
+		return fn(ctx)
+	}
+
+	ctx, cancel := withTimeout(ctx, database.WriteTimeout)
+	defer cancel()
+
+	db, err := database.open(ctx)
+	if err != nil {
+		return err
+	}
+
+	return transaction(db, func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, txStateOf(tx)))
+	})
+}
+
+// AfterCommit runs fn once the transaction of ctx committed, right away when ctx carries no transaction.
+// fn is dropped when the transaction rolls back.
+func AfterCommit(ctx context.Context, fn func()) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.after = append(state.after, fn)
+		return
+	}
+
+	fn()
+}
+
+// CacheOf returns the read cache of the Database of ctx, nil when there is none
+func CacheOf(ctx context.Context) *cache.Cache {
+	if database, ok := ctx.Value(databaseKey{}).(*Database); ok {
+		return database.Cache
+	}
+	return nil
+}
+
+// invalidate drops the cached reads of tables after a successful write.
+// Within a Transaction the invalidation waits for the commit, so readers can not cache uncommitted rows.
+func invalidate(ctx context.Context, tables ...string) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.changed = append(state.changed, tables...)
+		return
+	}
+
+	CacheOf(ctx).Invalidate(tables...)
+}
+
+// dbRead returns a gorm handle for a read operation, its statements are canceled with ctx or after the ReadTimeout.
+// done releases the deadline and must be called once the results are read.
+func dbRead(ctx context.Context) (db *gorm.DB, done context.CancelFunc, err error) {
+	return dbSession(ctx, func(database *Database) time.Duration { return database.ReadTimeout })
+}
+
+// dbWrite returns a gorm handle for a write operation, its statements are canceled with ctx or after the WriteTimeout.
+// done releases the deadline and must be called once the operation completed.
+func dbWrite(ctx context.Context) (db *gorm.DB, done context.CancelFunc, err error) {
+	return dbSession(ctx, func(database *Database) time.Duration { return database.WriteTimeout })
+}
+
+// dbMigrate returns a gorm handle for schema and data migrations run at startup, bounded by ctx only
+func dbMigrate(ctx context.Context) (db *gorm.DB, done context.CancelFunc, err error) {
+	return dbSession(ctx, func(*Database) time.Duration { return 0 })
+}
+
+// dbSession returns a gorm handle on the Database of ctx, joining the transaction ctx carries
+func dbSession(ctx context.Context, timeout func(*Database) time.Duration) (*gorm.DB, context.CancelFunc, error) {
+	database, ok := ctx.Value(databaseKey{}).(*Database)
+	if !ok {
+		return nil, nil, ErrUnavailable.Wrap(errNoDatabase)
+	}
+
+	ctx, cancel := withTimeout(ctx, timeout(database))
+	db, err := database.open(ctx)
+	if err != nil {
+		cancel()
+		return nil, nil, err
+	}
+
+	return db, cancel, nil
+}
+
+// open returns a gorm handle whose statements run with ctx, inside the transaction of ctx if there is one
+func (d *Database) open(ctx context.Context) (*gorm.DB, error) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return d.handle(&ctxTx{ctx: ctx, tx: state.tx})
+	}
+	return d.handle(&ctxConn{ctx: ctx, database: d})
+}
+
+// handle returns a gorm handle issuing its statements on conn
+func (d *Database) handle(conn gorm.SQLCommon) (*gorm.DB, error) {
+	db, err := gorm.Open(d.dialect, conn)
+	if err != nil {
+		return nil, dbError(ErrUnavailable, err)
+	}
+
+	db.LogMode(d.LogSQL)
+	return db, nil
+}
+
+// transaction runs fn on a handle bound to a new transaction of the connection pool db runs on,
+// or on db itself when db already runs in a transaction. After the commit the tables changed in the transaction
+// are dropped from the cache and its AfterCommit functions run.
+func transaction(db *gorm.DB, fn func(tx *gorm.DB) error) (err error) {
+	conn, ok := db.CommonDB().(*ctxConn)
+	if !ok {
+		return fn(db)
+	}
+
+	sqlTx, err := conn.database.db.BeginTx(conn.ctx, nil)
+	if err != nil {
+		return dbError(ErrUpdateFailed, err)
+	}
+
+	tx, err := conn.database.handle(&ctxTx{ctx: conn.ctx, tx: sqlTx})
+	if err != nil {
+		sqlTx.Rollback()
+		return err
+	}
+
+	panicked := true
+	defer func() {
+		if panicked || err != nil {
+			sqlTx.Rollback()
+		}
+	}()
+
+	state := &txState{tx: sqlTx}
+	err = fn(tx.Set(txStateSetting, state))
+	if err == nil {
+		err = dbError(ErrUpdateFailed, sqlTx.Commit())
+	}
+
+	panicked = false
+	if err != nil {
+		return err
+	}
+
+	conn.database.Cache.Invalidate(state.changed...)
+	for _, after := range state.after {
+		after()
+	}
+	return nil
+}
+
+// txStateOf returns the state of the transaction handle tx was derived from, nil for handles outside of a transaction
+func txStateOf(tx *gorm.DB) *txState {
+	if state, ok := tx.Get(txStateSetting); ok {
+		return state.(*txState)
+	}
+	return nil
+}
+
+// withTimeout derives a context with the deadline timeout from ctx, zero keeps the deadline of ctx
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// ctxConn gorm connection running the statements of a handle on the pool with the context of the operation,
+// so they are canceled when the client goes away or the operation deadline passes
+type ctxConn struct {
+	ctx      context.Context
+	database *Database
+}
+
+func (c *ctxConn) Exec(query string, args ...interface{}) (sql.Result, error) {
+	logSQL(c.ctx, query)
+	return c.database.db.ExecContext(c.ctx, query, args...)
+}
+
+func (c *ctxConn) Prepare(query string) (*sql.Stmt, error) {
+	return c.database.db.PrepareContext(c.ctx, query)
+}
+
+func (c *ctxConn) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	logSQL(c.ctx, query)
+	return c.database.db.QueryContext(c.ctx, query, args...)
+}
+
+func (c *ctxConn) QueryRow(query string, args ...interface{}) *sql.Row {
+	logSQL(c.ctx, query)
+	return c.database.db.QueryRowContext(c.ctx, query, args...)
+}
+
+// ctxTx gorm connection running the statements of a handle in a transaction with the context of the operation
+type ctxTx struct {
+	ctx context.Context
+	tx  *sql.Tx
+}
+
+func (c *ctxTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	logSQL(c.ctx, query)
+	return c.tx.ExecContext(c.ctx, query, args...)
+}
+
+func (c *ctxTx) Prepare(query string) (*sql.Stmt, error) {
+	return c.tx.PrepareContext(c.ctx, query)
+}
+
+func (c *ctxTx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	logSQL(c.ctx, query)
+	return c.tx.QueryContext(c.ctx, query, args...)
+}
+
+func (c *ctxTx) QueryRow(query string, args ...interface{}) *sql.Row {
+	logSQL(c.ctx, query)
+	return c.tx.QueryRowContext(c.ctx, query, args...)
+}
+
+// logSQL passes a statement to the Logger hook
+func logSQL(ctx context.Context, query string) {
+	if Logger != nil {
+		Logger(ctx, query)
+	}
+}
@@ -0,0 +1,120 @@
+package dao_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"wcs/cache"
+	"wcs/dao"
+	"wcs/dao/daotest"
+	"wcs/model"
+
+	"github.com/guregu/null"
+)
+
+var errAbort = errors.New("abort")
+
+func TestTransactionCommits(t *testing.T) {
+	database, ctx := daotest.Open(t)
+	database.Cache = cache.New(cache.NewLRU(1 << 20))
+	generation := database.Cache.Version("news")
+
+	committed := false
+	err := dao.Transaction(ctx, func(ctx context.Context) error {
+		if _, _, err := dao.AddNews(ctx, &model.News{Title: "Open day", Content: "all welcome"}); err != nil {
+			return err
+		}
+		if _, _, err := dao.AddEvents(ctx, &model.Events{Title: "Open day", Content: "campus tour", StartTime: null.TimeFrom(time.Now())}); err != nil {
+			return err
+		}
+
+		dao.AfterCommit(ctx, func() { committed = true })
+		if committed || database.Cache.Version("news") != generation {
+			t.Errorf("commit hooks ran or the cache was invalidated before the commit")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !committed || database.Cache.Version("news") == generation {
+		t.Errorf("after the commit hooks ran %v, news invalidated %v", committed, database.Cache.Version("news") != generation)
+	}
+	if _, total, _ := dao.GetAllNews(ctx, 0, 10, "id"); total != 1 {
+		t.Errorf("%d news after the commit, want 1", total)
+	}
+	if _, total, _ := dao.GetAllEvents(ctx, 0, 10, "id"); total != 1 {
+		t.Errorf("%d events after the commit, want 1", total)
+	}
+}
+
+func TestTransactionRollsBack(t *testing.T) {
+	database, ctx := daotest.Open(t)
+	database.Cache = cache.New(cache.NewLRU(1 << 20))
+	generation := database.Cache.Version("news")
+
+	committed := false
+	err := dao.Transaction(ctx, func(ctx context.Context) error {
+		if _, _, err := dao.AddNews(ctx, &model.News{Title: "Open day", Content: "all welcome"}); err != nil {
+			return err
+		}
+		dao.AfterCommit(ctx, func() { committed = true })
+
+		// a nested transaction joins the outer one and goes down with it
+		return dao.Transaction(ctx, func(ctx context.Context) error {
+			if _, _, err := dao.AddNews(ctx, &model.News{Title: "Call for papers", Content: "deadline in May"}); err != nil {
+				return err
+			}
+			return errAbort
+		})
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("transaction returned %v, want the error of fn", err)
+	}
+
+	if _, total, _ := dao.GetAllNews(ctx, 0, 10, "id"); total != 0 {
+		t.Errorf("%d news after the rollback, want none", total)
+	}
+	if committed || database.Cache.Version("news") != generation {
+		t.Errorf("rolled back transaction ran its commit hooks %v or invalidated the cache", committed)
+	}
+}
+
+func TestOperationContext(t *testing.T) {
+	database, ctx := daotest.Open(t)
+	if _, _, err := dao.AddNews(ctx, &model.News{Title: "Open day", Content: "all welcome"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// a client that went away cancels the queries of its request
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, _, err := dao.GetAllNews(canceled, 0, 10, "id"); !errors.Is(err, context.Canceled) {
+		t.Errorf("read with a canceled context returned %v, want context.Canceled", err)
+	}
+	if _, _, err := dao.AddNews(canceled, &model.News{Title: "Call for papers", Content: "deadline in May"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("write with a canceled context returned %v, want context.Canceled", err)
+	}
+
+	// the operation timeouts bound every query, however long the caller is willing to wait
+	database.ReadTimeout = time.Nanosecond
+	if _, _, err := dao.GetAllNews(ctx, 0, 10, "id"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("read past the read timeout returned %v, want context.DeadlineExceeded", err)
+	}
+	database.ReadTimeout, database.WriteTimeout = 0, time.Nanosecond
+	if _, _, err := dao.AddNews(ctx, &model.News{Title: "Call for papers", Content: "deadline in May"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("write past the write timeout returned %v, want context.DeadlineExceeded", err)
+	}
+
+	database.WriteTimeout = 0
+	if _, total, err := dao.GetAllNews(ctx, 0, 10, "id"); err != nil || total != 1 {
+		t.Errorf("read without timeouts returned %d news with %v, want the 1 stored", total, err)
+	}
+
+	if _, err := dao.GetNews(context.Background(), 1); !errors.Is(err, dao.ErrUnavailable) {
+		t.Errorf("call without a database in the context returned %v, want ErrUnavailable", err)
+	}
+}
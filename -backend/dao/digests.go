@@ -0,0 +1,176 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"wcs/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrDigestEmpty composing a digest without news or upcoming events
+	ErrDigestEmpty = &Error{Kind: KindConflict, Message: "nothing new for a digest"}
+
+	// ErrDigestNotSending cancelling a digest that is sent or cancelled already
+	ErrDigestNotSending = &Error{Kind: KindConflict, Message: "digest not sending"}
+)
+
+// AddDigest is a function to add a digest to the digests table in the wcs database, in status sending
+// error - ErrInsertFailed, db save call failed
+func AddDigest(ctx context.Context, record *model.Digests) (result *model.Digests, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record.Status = model.DigestSending
+	if err = conn.Create(record).Error; err != nil {
+		return nil, dbError(ErrInsertFailed, err)
+	}
+
+	return record, nil
+}
+
+// GetAllDigests is a function to get a slice of record(s) from digests table in the wcs database
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of records in a page  (defaults to 20)
+// params - order    - db sort order column
+// params - filters  - optional query filters, e.g. WithStatus
+// error - ErrNotFound, db Find error
+func GetAllDigests(ctx context.Context, page, pagesize int64, order string, filters ...QueryFilter) (results []*model.Digests, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	resultOrm := conn.Model(&model.Digests{})
+	for _, filter := range filters {
+		resultOrm = filter(resultOrm)
+	}
+	resultOrm.Count(&totalRows)
+
+	if page > 0 {
+		offset := (page - 1) * pagesize
+		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
+	} else {
+		resultOrm = resultOrm.Limit(pagesize)
+	}
+
+	if order != "" {
+		resultOrm = resultOrm.Order(order)
+	}
+
+	results = make([]*model.Digests, 0)
+	if err = resultOrm.Find(&results).Error; err != nil {
+		err = dbError(ErrNotFound, err)
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// GetDigest is a function to get a single record from the digests table in the wcs database
+// error - ErrNotFound, db Find error
+func GetDigest(ctx context.Context, argID int32) (record *model.Digests, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Digests{}
+	if err = conn.First(record, argID).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	return record, nil
+}
+
+// LatestDigest is a function to get the latest digest of the digests table in the wcs database that was not cancelled,
+// nil when there is none
+// error - ErrNotFound, db Find error
+func LatestDigest(ctx context.Context) (record *model.Digests, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Digests{}
+	err = conn.Where("status <> ?", model.DigestCancelled).Order("id desc").First(record).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	return record, nil
+}
+
+// AdvanceDigest is a function to record a batch of a sending digest of the digests table in the wcs database:
+// queued mails were queued for the subscribers after from up to to, done marks the digest sent.
+// The batch only counts when the digest is still sending from from, advanced is false when another worker
+// recorded the batch already or the digest was cancelled.
+// error - ErrUpdateFailed, db update call failed
+func AdvanceDigest(ctx context.Context, argID, from, to int32, queued int, done bool, now time.Time) (advanced bool, err error) {
+	conn, release, err := dbWrite(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer release()
+
+	fields := map[string]interface{}{
+		"last_subscriber_id": to,
+		"recipients":         gorm.Expr("recipients + ?", queued),
+		"update_time":        now.UTC(),
+	}
+	if done {
+		fields["status"] = model.DigestSent
+		fields["sent_at"] = null.TimeFrom(now.UTC())
+	}
+
+	db := conn.Model(&model.Digests{}).
+		Where("id = ? AND status = ? AND last_subscriber_id = ?", argID, model.DigestSending, from).
+		UpdateColumns(fields)
+	if db.Error != nil {
+		return false, dbError(ErrUpdateFailed, db.Error)
+	}
+
+	return db.RowsAffected == 1, nil
+}
+
+// CancelDigest is a function to stop sending a digest of the digests table in the wcs database, the mails queued already still go out
+// error - ErrNotFound, db record for id not found
+// error - ErrDigestNotSending, the digest is sent or cancelled
+// error - ErrUpdateFailed, db update call failed
+func CancelDigest(ctx context.Context, argID int32, now time.Time) (record *model.Digests, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Digests{}
+	if err = conn.First(record, argID).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	db := conn.Model(&model.Digests{}).
+		Where("id = ? AND status = ?", argID, model.DigestSending).
+		UpdateColumns(map[string]interface{}{"status": model.DigestCancelled, "update_time": now.UTC()})
+	if db.Error != nil {
+		return nil, dbError(ErrUpdateFailed, db.Error)
+	}
+	if db.RowsAffected != 1 {
+		return nil, ErrDigestNotSending
+	}
+
+	record.Status, record.UpdateTime = model.DigestCancelled, now.UTC()
+	return record, nil
+}
@@ -0,0 +1,168 @@
+package dao
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+)
+
+// Kind classifies dao errors, the api reports each kind with its own http status
+type Kind int
+
+const (
+	// KindBadRequest the request carried unusable parameters
+	KindBadRequest Kind = iota
+
+	// KindNotFound the addressed record does not exist
+	KindNotFound
+
+	// KindConflict the change clashes with stored data, e.g. a duplicate unique key
+	KindConflict
+
+	// KindPrecondition the record was modified since the version the caller read
+	KindPrecondition
+
+	// KindUnauthorized the request requires a signed in admin
+	KindUnauthorized
+
+	// KindForbidden the signed in admin may not perform the request
+	KindForbidden
+
+	// KindInternal a db or storage call failed
+	KindInternal
+
+	// KindUnavailable the database or storage can not be reached
+	KindUnavailable
+)
+
+// Error dao error of a Kind, wrapping the underlying cause.
+// The message is safe to show to clients, the cause is for the logs.
+type Error struct {
+	Kind    Kind
+	Message string
+	Cause   error
+}
+
+// Error returns the message followed by the cause
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the cause
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// Is reports whether target is the sentinel e was wrapped from, so errors.Is(err, ErrNotFound) holds for wrapped errors
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Message == e.Message
+}
+
+// Wrap returns a copy of the sentinel e carrying cause
+func (e *Error) Wrap(cause error) error {
+	return &Error{Kind: e.Kind, Message: e.Message, Cause: cause}
+}
+
+var (
+	// ErrNotFound error when record not found
+	ErrNotFound = &Error{Kind: KindNotFound, Message: "record Not Found"}
+
+	// ErrUnableToMarshalJSON error when json payload corrupt
+	ErrUnableToMarshalJSON = &Error{Kind: KindBadRequest, Message: "json payload corrupt"}
+
+	// ErrUpdateFailed error when update fails
+	ErrUpdateFailed = &Error{Kind: KindInternal, Message: "db update error"}
+
+	// ErrInsertFailed error when insert fails
+	ErrInsertFailed = &Error{Kind: KindInternal, Message: "db insert error"}
+
+	// ErrDeleteFailed error when delete fails
+	ErrDeleteFailed = &Error{Kind: KindInternal, Message: "db delete error"}
+
+	// ErrQueryFailed error when a lookup fails for another reason than a missing record
+	ErrQueryFailed = &Error{Kind: KindInternal, Message: "db query error"}
+
+	// ErrBadParams error when bad params passed in
+	ErrBadParams = &Error{Kind: KindBadRequest, Message: "bad params error"}
+
+	// ErrVersionConflict error when a record was modified since the caller read it
+	ErrVersionConflict = &Error{Kind: KindPrecondition, Message: "record version conflict"}
+
+	// ErrDuplicate error when a record clashes with the unique key of a stored record
+	ErrDuplicate = &Error{Kind: KindConflict, Message: "record already exists"}
+
+	// ErrUnauthorized error when the request requires a signed in admin
+	ErrUnauthorized = &Error{Kind: KindUnauthorized, Message: "admin login required"}
+
+	// ErrForbidden error when the signed in admin may not perform the request
+	ErrForbidden = &Error{Kind: KindForbidden, Message: "forbidden"}
+
+	// ErrUnavailable error when the database or storage can not be reached
+	ErrUnavailable = &Error{Kind: KindUnavailable, Message: "service unavailable"}
+)
+
+// KindOf returns the Kind of a dao error, KindInternal for any other error
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	return KindInternal
+}
+
+// dbError classifies the cause of a failed db or storage call: missing records, duplicate keys and unreachable
+// backends get their own error, other causes are wrapped in sentinel. Dao errors pass through unchanged.
+func dbError(sentinel *Error, cause error) error {
+	var e *Error
+	switch {
+	case cause == nil:
+		return nil
+	case errors.As(cause, &e):
+		return cause
+	case gorm.IsRecordNotFoundError(cause):
+		return ErrNotFound.Wrap(cause)
+	case isDuplicate(cause):
+		return ErrDuplicate.Wrap(cause)
+	case isUnavailable(cause):
+		return ErrUnavailable.Wrap(cause)
+	case sentinel == ErrNotFound:
+		// a lookup failing for another reason than a missing record
+		return ErrQueryFailed.Wrap(cause)
+	default:
+		return sentinel.Wrap(cause)
+	}
+}
+
+// isDuplicate reports whether cause is a unique key violation
+func isDuplicate(cause error) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(cause, &mysqlErr) {
+		return mysqlErr.Number == 1062
+	}
+
+	// sqlite and postgres drivers
+	msg := cause.Error()
+	return strings.Contains(msg, "UNIQUE constraint failed") || strings.Contains(msg, "duplicate key value")
+}
+
+// isUnavailable reports whether cause is a failure to reach the database or storage,
+// or an operation that ran past its deadline or was canceled with the request
+func isUnavailable(cause error) bool {
+	var netErr net.Error
+	return errors.Is(cause, driver.ErrBadConn) ||
+		errors.Is(cause, sql.ErrConnDone) ||
+		errors.Is(cause, mysql.ErrInvalidConn) ||
+		errors.Is(cause, context.DeadlineExceeded) ||
+		errors.Is(cause, context.Canceled) ||
+		errors.As(cause, &netErr)
+}
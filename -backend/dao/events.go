@@ -2,11 +2,15 @@ package dao
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"wcs/model"
 
 	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -20,10 +24,19 @@ var (
 // params - page     - page requested (defaults to 0)
 // params - pagesize - number of records in a page  (defaults to 20)
 // params - order    - db sort order column
+// params - filters  - optional query filters, e.g. WithTag
 // error - ErrNotFound, db Find error
-func GetAllEvents(ctx context.Context, page, pagesize int64, order string) (results []*model.Events, totalRows int, err error) {
+func GetAllEvents(ctx context.Context, page, pagesize int64, order string, filters ...QueryFilter) (results []*model.Events, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
 
-	resultOrm := DB.Model(&model.Events{})
+	resultOrm := conn.Model(&model.Events{})
+	for _, filter := range filters {
+		resultOrm = filter(resultOrm)
+	}
 	resultOrm.Count(&totalRows)
 
 	if page > 0 {
@@ -38,19 +51,124 @@ func GetAllEvents(ctx context.Context, page, pagesize int64, order string) (resu
 	}
 
 	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
+		err = dbError(ErrNotFound, err)
 		return nil, -1, err
 	}
 
+	records := make([]model.Model, len(results))
+	for i, record := range results {
+		records[i] = record
+	}
+
+	if err = loadTags(conn, "events", records...); err != nil {
+		return nil, -1, dbError(ErrNotFound, err)
+	}
+
+	if err = loadMedia(conn, "events", records...); err != nil {
+		return nil, -1, dbError(ErrNotFound, err)
+	}
+
+	return results, totalRows, nil
+}
+
+// maxOccurrences bounds the occurrences a single recurring event contributes to one GetEventOccurrences call
+const maxOccurrences = 1000
+
+// GetEventOccurrences is a function to get the occurrences of events from events table in the wcs database overlapping from to to,
+// recurring events expanded by their rrule, sorted by start. Each record carries the occurrence it is listed for.
+// params - from, to - time range, to is exclusive
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of occurrences in a page
+// params - filters  - optional query filters, e.g. WithTag
+// error - ErrNotFound, db Find error
+func GetEventOccurrences(ctx context.Context, from, to time.Time, page, pagesize int64, filters ...QueryFilter) (results []*model.Events, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	resultOrm := conn.Model(&model.Events{}).
+		Where("start_time < ?", to.UTC()).
+		Where("rrule <> '' OR COALESCE(end_time, start_time) >= ?", from.UTC())
+	for _, filter := range filters {
+		resultOrm = filter(resultOrm)
+	}
+
+	var events []*model.Events
+	if err = resultOrm.Find(&events).Error; err != nil {
+		return nil, -1, dbError(ErrNotFound, err)
+	}
+
+	results = make([]*model.Events, 0)
+	for _, event := range events {
+		for _, occurrence := range event.Occurrences(from, to, maxOccurrences) {
+			record := *event
+			record.Occurrence = occurrence
+			results = append(results, &record)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if !results[i].Occurrence.Start.Equal(results[j].Occurrence.Start) {
+			return results[i].Occurrence.Start.Before(results[j].Occurrence.Start)
+		}
+		return results[i].ID < results[j].ID
+	})
+	totalRows = len(results)
+
+	offset := int64(0)
+	if page > 0 {
+		offset = (page - 1) * pagesize
+	}
+	if offset > int64(len(results)) {
+		offset = int64(len(results))
+	}
+	results = results[offset:]
+	if int64(len(results)) > pagesize {
+		results = results[:pagesize]
+	}
+
+	records := make([]model.Model, len(results))
+	for i, record := range results {
+		records[i] = record
+	}
+
+	if err = loadTags(conn, "events", records...); err != nil {
+		return nil, -1, dbError(ErrNotFound, err)
+	}
+
+	if err = loadMedia(conn, "events", records...); err != nil {
+		return nil, -1, dbError(ErrNotFound, err)
+	}
+
 	return results, totalRows, nil
 }
 
 // GetEvents is a function to get a single record from the events table in the wcs database
 // error - ErrNotFound, db Find error
 func GetEvents(ctx context.Context, argID int32) (record *model.Events, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	record = &model.Events{}
-	if err = DB.First(record, argID).Error; err != nil {
-		err = ErrNotFound
+	if err = conn.First(record, argID).Error; err != nil {
+		err = dbError(ErrNotFound, err)
+		return record, err
+	}
+
+	if err = loadTags(conn, "events", record); err != nil {
+		return record, dbError(ErrNotFound, err)
+	}
+
+	if err = loadMedia(conn, "events", record); err != nil {
+		return record, dbError(ErrNotFound, err)
+	}
+
+	if err = loadAttendance(conn, record); err != nil {
 		return record, err
 	}
 
@@ -58,54 +176,214 @@ func GetEvents(ctx context.Context, argID int32) (record *model.Events, err erro
 }
 
 // AddEvents is a function to add a single record to events table in the wcs database
+// The record and its tag links are saved in one transaction, tags given by name only are created.
+// The initial publishing status is recorded in status_history.
 // error - ErrInsertFailed, db save call failed
+// error - ErrBadParams, tag list references an unknown tag id or cover_media_id an unknown media
 func AddEvents(ctx context.Context, record *model.Events) (result *model.Events, RowsAffected int64, err error) {
-	db := DB.Save(record)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrInsertFailed
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	if err = resolveMedia(conn, "events", record); err != nil {
+		return nil, -1, err
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		db := tx.Save(record)
+		if db.Error != nil {
+			return dbError(ErrInsertFailed, db.Error)
+		}
+
+		RowsAffected = db.RowsAffected
+		if err := trackStatus(ctx, tx, "events", record, ""); err != nil {
+			return err
+		}
+		if err := syncTags(tx, "events", record); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "events", model.Create, record)
+	})
+	if err != nil {
+		return nil, -1, err
 	}
 
-	return record, db.RowsAffected, nil
+	invalidate(ctx, "events", "events_tags")
+	return record, RowsAffected, nil
 }
 
 // UpdateEvents is a function to update a single record from events table in the wcs database
+// Every client writable column and the tag list are replaced by the values in updated, zero values included.
+// A change of publishing status is recorded in status_history.
+// A non zero updated.Version must match the stored row version, otherwise the current record is returned with ErrVersionConflict.
 // error - ErrNotFound, db record for id not found
-// error - ErrUpdateFailed, db meta data copy failed or db.Save call failed
+// error - ErrVersionConflict, db record was modified since updated.Version was read
+// error - ErrUpdateFailed, db meta data copy failed or db update call failed
+// error - ErrBadParams, tag list references an unknown tag id or cover_media_id an unknown media
 func UpdateEvents(ctx context.Context, argID int32, updated *model.Events) (result *model.Events, RowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
 
 	result = &model.Events{}
-	db := DB.First(result, argID)
+	db := conn.First(result, argID)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrNotFound
+		return nil, -1, dbError(ErrNotFound, err)
 	}
 
-	if err = Copy(result, updated); err != nil {
-		return nil, -1, ErrUpdateFailed
+	version, status := result.Version, result.Status
+	if updated.Version != 0 && updated.Version != version {
+		return result, -1, ErrVersionConflict
 	}
 
-	db = db.Save(result)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrUpdateFailed
+	if err = Replace(result, updated); err != nil {
+		return nil, -1, dbError(ErrUpdateFailed, err)
 	}
+	result.Tags = updated.Tags
 
-	return result, db.RowsAffected, nil
+	if err = resolveMedia(conn, "events", result); err != nil {
+		return nil, -1, err
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if RowsAffected, err = saveVersioned(tx, result, version); err != nil {
+			return err
+		}
+		if err = trackStatus(ctx, tx, "events", result, status); err != nil {
+			return err
+		}
+		if err = syncTags(tx, "events", result); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "events", model.Update, result)
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		if result, err = GetEvents(ctx, argID); err != nil {
+			return nil, -1, err
+		}
+		return result, -1, ErrVersionConflict
+	}
+	if err != nil {
+		return nil, -1, err
+	}
+
+	invalidate(ctx, "events", "events_tags")
+	return result, RowsAffected, nil
 }
 
 // DeleteEvents is a function to delete a single record from events table in the wcs database
+// The registrations for the event are deleted with it.
+// A non zero version must match the stored row version.
 // error - ErrNotFound, db Find error
+// error - ErrVersionConflict, db record was modified since version was read
 // error - ErrDeleteFailed, db Delete failed error
-func DeleteEvents(ctx context.Context, argID int32) (rowsAffected int64, err error) {
+func DeleteEvents(ctx context.Context, argID int32, version int32) (rowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer done()
 
 	record := &model.Events{}
-	db := DB.First(record, argID)
+	db := conn.First(record, argID)
 	if db.Error != nil {
-		return -1, ErrNotFound
+		return -1, dbError(ErrNotFound, db.Error)
 	}
 
-	db = db.Delete(record)
-	if err = db.Error; err != nil {
-		return -1, ErrDeleteFailed
+	if version != 0 && version != record.Version {
+		return -1, ErrVersionConflict
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if rowsAffected, err = deleteVersioned(tx, record, record.Version); err != nil {
+			return err
+		}
+		if err = deleteEventRegistrations(tx, argID); err != nil {
+			return err
+		}
+		if err = clearTags(tx, "events", argID); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "events", model.Delete, record)
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	invalidate(ctx, "events", "events_tags", "registrations")
+	return rowsAffected, nil
+}
+
+// deleteEventRegistrations deletes the registrations for the event eventID
+func deleteEventRegistrations(tx *gorm.DB, eventID int32) error {
+	if err := tx.Where("event_id = ?", eventID).Delete(&model.Registrations{}).Error; err != nil {
+		return dbError(ErrDeleteFailed, err)
+	}
+	return nil
+}
+
+// MigrateEvents is a function to move the legacy unix event_time column of events into start_time.
+// It runs at startup after the schema migration added start_time, the event_time column is dropped once every row moved.
+// Events without event_time start at their creation, an admin can set the real start time afterwards.
+// Tables without an event_time column have been migrated already and are skipped.
+func MigrateEvents(ctx context.Context) error {
+	conn, done, err := dbMigrate(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	if !conn.Dialect().HasColumn("events", "event_time") {
+		return nil
+	}
+
+	if err = transaction(conn, migrateEventTimes); err != nil {
+		return err
+	}
+
+	if err = conn.Table("events").DropColumn("event_time").Error; err != nil {
+		return err
+	}
+	invalidate(ctx, "events")
+
+	return nil
+}
+
+// migrateEventTimes sets start_time of the events still without one from their event_time, or from create_time when
+// event_time is 0, so every event passes Validate when it is next saved
+func migrateEventTimes(tx *gorm.DB) error {
+	rows, err := tx.Table("events").Select("id, event_time").Where("event_time <> 0 AND start_time IS NULL").Rows()
+	if err != nil {
+		return err
+	}
+
+	legacy := make(map[int32]int64)
+	for rows.Next() {
+		var id int32
+		var eventTime int64
+		if err = rows.Scan(&id, &eventTime); err != nil {
+			rows.Close()
+			return err
+		}
+		legacy[id] = eventTime
+	}
+	rows.Close()
+
+	for id, eventTime := range legacy {
+		if err = tx.Table("events").Where("id = ?", id).UpdateColumn("start_time", time.Unix(eventTime, 0).UTC()).Error; err != nil {
+			return fmt.Errorf("migrate event_time of events %d: %v", id, err)
+		}
+	}
+
+	err = tx.Table("events").Where("(event_time = 0 OR event_time IS NULL) AND start_time IS NULL").
+		UpdateColumn("start_time", gorm.Expr("create_time")).Error
+	if err != nil {
+		return fmt.Errorf("migrate events without event_time: %v", err)
 	}
 
-	return db.RowsAffected, nil
+	return nil
 }
@@ -0,0 +1,55 @@
+package dao_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"wcs/dao"
+	"wcs/dao/daotest"
+	"wcs/model"
+)
+
+func TestMigrateEventsWithoutEventTime(t *testing.T) {
+	db, _, ctx := daotest.OpenDB(t)
+
+	if err := db.Exec("ALTER TABLE events ADD COLUMN event_time bigint NOT NULL DEFAULT 0").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO events (title, content, event_time, create_time) VALUES ('Open day', 'campus tour', 1717236000, '2024-05-01 09:00:00'), " +
+		"('Alumni dinner', 'date to be set', 0, '2024-05-02 18:30:00')").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// sqlite can not drop the legacy column afterwards, so the test stops short of it
+	if err := dao.MigrateEventTimes(db); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		id    int32
+		start time.Time
+	}{
+		{1, time.Unix(1717236000, 0)},
+		{2, time.Date(2024, 5, 2, 18, 30, 0, 0, time.UTC)},
+	} {
+		event, err := dao.GetEvents(ctx, test.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !event.StartTime.Valid || !event.StartTime.Time.Equal(test.start) {
+			t.Errorf("%s starts at %v, want %v", event.Title, event.StartTime, test.start)
+		}
+
+		// the migrated event can be saved by an admin without setting its start first
+		event.Prepare()
+		var invalid *model.ValidationError
+		if err := event.Validate(model.Update); errors.As(err, &invalid) {
+			for _, field := range invalid.Fields {
+				if field.Field == "start_time" {
+					t.Errorf("%s fails validation: %s", event.Title, field.Message)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,7 @@
+package dao
+
+// MigrateTableTags exposes the migration of the legacy tags column of one table to the tests
+var MigrateTableTags = migrateTableTags
+
+// MigrateEventTimes exposes the migration of the legacy event_time column of events to the tests
+var MigrateEventTimes = migrateEventTimes
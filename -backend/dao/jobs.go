@@ -0,0 +1,230 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"wcs/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrJobNotFailed retrying a job that did not fail
+	ErrJobNotFailed = &Error{Kind: KindConflict, Message: "job not failed"}
+)
+
+// maxJobError bounds the error message kept of a failed attempt
+const maxJobError = 2000
+
+// EnqueueJob is a function to add a job to the jobs table in the wcs database.
+// A job with a unique key is enqueued at most once: when a job holds the key already, that job is returned and created is false.
+// error - ErrInsertFailed, db save call failed
+func EnqueueJob(ctx context.Context, job *model.Jobs) (result *model.Jobs, created bool, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer done()
+
+	if job.UniqueKey.Valid {
+		existing := &model.Jobs{}
+		err = conn.Where("unique_key = ?", job.UniqueKey.String).First(existing).Error
+		if err == nil {
+			return existing, false, nil
+		}
+		if !gorm.IsRecordNotFoundError(err) {
+			return nil, false, dbError(ErrNotFound, err)
+		}
+	}
+
+	if err = conn.Create(job).Error; err != nil {
+		err = dbError(ErrInsertFailed, err)
+		if job.UniqueKey.Valid && KindOf(err) == KindConflict {
+			// enqueued by another worker in the meantime
+			existing := &model.Jobs{}
+			if conn.Where("unique_key = ?", job.UniqueKey.String).First(existing).Error == nil {
+				return existing, false, nil
+			}
+		}
+		return nil, false, err
+	}
+
+	return job, true, nil
+}
+
+// ClaimJobs is a function to claim up to limit jobs of the jobs table in the wcs database that are due at now.
+// Each claimed job is leased to the caller until now + lease, after which another worker may pick it up again,
+// so jobs of a worker that died are not lost. A job is claimed by a single worker even when several poll the table.
+// Jobs whose lease expired on their last attempt are marked failed.
+// error - ErrNotFound, db Find error
+// error - ErrUpdateFailed, db update call failed
+func ClaimJobs(ctx context.Context, now time.Time, limit int, lease time.Duration) (results []*model.Jobs, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	now = now.UTC()
+	err = conn.Model(&model.Jobs{}).
+		Where("status = ? AND locked_until < ? AND attempts >= max_attempts", model.JobRunning, now).
+		UpdateColumns(map[string]interface{}{"status": model.JobFailed, "locked_until": nil, "last_error": "lease expired before the job finished", "update_time": now}).Error
+	if err != nil {
+		return nil, dbError(ErrUpdateFailed, err)
+	}
+
+	var candidates []*model.Jobs
+	err = conn.Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)", model.JobPending, now, model.JobRunning, now).
+		Order("run_at, id").Limit(limit).Find(&candidates).Error
+	if err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	results = make([]*model.Jobs, 0, len(candidates))
+	lockedUntil := null.TimeFrom(now.Add(lease))
+	for _, job := range candidates {
+		// attempts acts as the version of the job, only one of the workers racing for it gets to update it
+		db := conn.Model(&model.Jobs{}).
+			Where("id = ? AND status = ? AND attempts = ?", job.ID, job.Status, job.Attempts).
+			UpdateColumns(map[string]interface{}{"status": model.JobRunning, "attempts": job.Attempts + 1, "locked_until": lockedUntil, "update_time": now})
+		if db.Error != nil {
+			return results, dbError(ErrUpdateFailed, db.Error)
+		}
+		if db.RowsAffected != 1 {
+			continue
+		}
+
+		job.Status, job.Attempts, job.LockedUntil, job.UpdateTime = model.JobRunning, job.Attempts+1, lockedUntil, now
+		results = append(results, job)
+	}
+
+	return results, nil
+}
+
+// CompleteJob is a function to mark a claimed job of the jobs table in the wcs database done.
+// A job whose lease expired and that was claimed again meanwhile is left to its new worker.
+// error - ErrUpdateFailed, db update call failed
+func CompleteJob(ctx context.Context, job *model.Jobs, now time.Time) error {
+	return finishJob(ctx, job, map[string]interface{}{"status": model.JobDone, "locked_until": nil, "last_error": "", "update_time": now.UTC()})
+}
+
+// FailJob is a function to record a failed attempt of a claimed job of the jobs table in the wcs database.
+// The job is retried with backoff, see model.Jobs.RetryAt, until it used up its attempts and is marked failed.
+// error - ErrUpdateFailed, db update call failed
+func FailJob(ctx context.Context, job *model.Jobs, cause error, now time.Time) error {
+	message := cause.Error()
+	if len(message) > maxJobError {
+		message = message[:maxJobError]
+	}
+
+	fields := map[string]interface{}{"status": model.JobPending, "run_at": job.RetryAt(now), "locked_until": nil, "last_error": message, "update_time": now.UTC()}
+	if job.Attempts >= job.MaxAttempts {
+		fields["status"] = model.JobFailed
+		delete(fields, "run_at")
+	}
+	return finishJob(ctx, job, fields)
+}
+
+func finishJob(ctx context.Context, job *model.Jobs, fields map[string]interface{}) error {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	err = conn.Model(&model.Jobs{}).
+		Where("id = ? AND status = ? AND attempts = ?", job.ID, model.JobRunning, job.Attempts).
+		UpdateColumns(fields).Error
+	return dbError(ErrUpdateFailed, err)
+}
+
+// GetAllJobs is a function to get a slice of record(s) from jobs table in the wcs database
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of records in a page  (defaults to 20)
+// params - order    - db sort order column
+// params - filters  - optional query filters, e.g. WithStatus
+// error - ErrNotFound, db Find error
+func GetAllJobs(ctx context.Context, page, pagesize int64, order string, filters ...QueryFilter) (results []*model.Jobs, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	resultOrm := conn.Model(&model.Jobs{})
+	for _, filter := range filters {
+		resultOrm = filter(resultOrm)
+	}
+	resultOrm.Count(&totalRows)
+
+	if page > 0 {
+		offset := (page - 1) * pagesize
+		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
+	} else {
+		resultOrm = resultOrm.Limit(pagesize)
+	}
+
+	if order != "" {
+		resultOrm = resultOrm.Order(order)
+	}
+
+	results = make([]*model.Jobs, 0)
+	if err = resultOrm.Find(&results).Error; err != nil {
+		err = dbError(ErrNotFound, err)
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// RetryJob is a function to give a failed job of the jobs table in the wcs database a fresh set of attempts, due at now
+// error - ErrNotFound, db record for id not found
+// error - ErrJobNotFailed, the job is not failed
+// error - ErrUpdateFailed, db update call failed
+func RetryJob(ctx context.Context, argID int32, now time.Time) (record *model.Jobs, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Jobs{}
+	if err = conn.First(record, argID).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+	if record.Status != model.JobFailed {
+		return nil, ErrJobNotFailed
+	}
+
+	record.Status, record.Attempts, record.RunAt, record.UpdateTime = model.JobPending, 0, now.UTC(), now.UTC()
+	db := conn.Model(&model.Jobs{}).
+		Where("id = ? AND status = ?", record.ID, model.JobFailed).
+		UpdateColumns(map[string]interface{}{"status": record.Status, "attempts": 0, "run_at": record.RunAt, "update_time": record.UpdateTime})
+	if db.Error != nil {
+		return nil, dbError(ErrUpdateFailed, db.Error)
+	}
+	if db.RowsAffected != 1 {
+		return nil, ErrJobNotFailed
+	}
+
+	return record, nil
+}
+
+// PurgeJobs is a function to delete the jobs of the jobs table in the wcs database that were done before before.
+// Their unique keys are released with them, so before should lie well behind any time such a job could be enqueued again.
+// error - ErrDeleteFailed, db delete call failed
+func PurgeJobs(ctx context.Context, before time.Time) (rowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer done()
+
+	db := conn.Where("status = ? AND update_time < ?", model.JobDone, before.UTC()).Delete(&model.Jobs{})
+	if db.Error != nil {
+		return -1, dbError(ErrDeleteFailed, db.Error)
+	}
+	return db.RowsAffected, nil
+}
@@ -0,0 +1,105 @@
+package dao_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"wcs/dao"
+	"wcs/dao/daotest"
+	"wcs/model"
+)
+
+func enqueueJobs(t *testing.T, ctx context.Context, n int, runAt time.Time) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		job, err := model.NewJob("test", map[string]int{"n": i}, runAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		job.MaxAttempts = 2
+		if _, _, err := dao.EnqueueJob(ctx, job); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClaimJobsOnce(t *testing.T) {
+	_, ctx := daotest.Open(t)
+	now := time.Now()
+	enqueueJobs(t, ctx, 20, now.Add(-time.Minute))
+	enqueueJobs(t, ctx, 3, now.Add(time.Hour))
+
+	var mu sync.Mutex
+	claims := make(map[int32]int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < 4; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				jobs, err := dao.ClaimJobs(ctx, now, 3, time.Minute)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if len(jobs) == 0 {
+					return
+				}
+
+				mu.Lock()
+				for _, job := range jobs {
+					claims[job.ID]++
+					if job.Status != model.JobRunning || job.Attempts != 1 {
+						t.Errorf("claimed job %d is %s after %d attempts, want running after 1", job.ID, job.Status, job.Attempts)
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(claims) != 20 {
+		t.Errorf("%d jobs were claimed, want the 20 due ones", len(claims))
+	}
+	for id, n := range claims {
+		if n != 1 {
+			t.Errorf("job %d was claimed %d times", id, n)
+		}
+	}
+}
+
+func TestClaimJobsLeaseExpired(t *testing.T) {
+	_, ctx := daotest.Open(t)
+	now := time.Now()
+	enqueueJobs(t, ctx, 1, now)
+
+	jobs, err := dao.ClaimJobs(ctx, now, 10, time.Minute)
+	if err != nil || len(jobs) != 1 {
+		t.Fatalf("first claim got %d jobs, %v", len(jobs), err)
+	}
+
+	if jobs, _ := dao.ClaimJobs(ctx, now.Add(30*time.Second), 10, time.Minute); len(jobs) != 0 {
+		t.Errorf("leased job was claimed again before its lease expired")
+	}
+
+	jobs, err = dao.ClaimJobs(ctx, now.Add(2*time.Minute), 10, time.Minute)
+	if err != nil || len(jobs) != 1 || jobs[0].Attempts != 2 {
+		t.Fatalf("claim after the lease expired got %+v, %v, want the job on its second attempt", jobs, err)
+	}
+
+	// out of attempts, the next expiry fails the job instead of handing it out again
+	if jobs, _ := dao.ClaimJobs(ctx, now.Add(4*time.Minute), 10, time.Minute); len(jobs) != 0 {
+		t.Errorf("job out of attempts was claimed again")
+	}
+
+	records, _, err := dao.GetAllJobs(ctx, 0, 10, "id")
+	if err != nil || len(records) != 1 {
+		t.Fatalf("jobs are %+v, %v", records, err)
+	}
+	if records[0].Status != model.JobFailed {
+		t.Errorf("job out of attempts is %s, want failed", records[0].Status)
+	}
+}
@@ -0,0 +1,355 @@
+package dao
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+
+	"wcs/model"
+	"wcs/storage"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+// Store media storage holding the content of the media table rows
+var Store storage.Storage
+
+// mediaRef column of a content table referencing the media table
+type mediaRef struct {
+	// column media id column, e.g. cover_media_id
+	column string
+
+	// legacy url column kept in sync with the referenced media, e.g. cover
+	legacy string
+
+	// Go fields of the media id column, the loaded media and the legacy url column
+	idField, mediaField, urlField string
+}
+
+// mediaRefs content tables referencing uploaded media
+var mediaRefs = map[string]mediaRef{
+	"events": {"cover_media_id", "cover", "CoverMediaID", "CoverMedia", "Cover"},
+	"news":   {"cover_media_id", "cover", "CoverMediaID", "CoverMedia", "Cover"},
+	"phds":   {"avatar_media_id", "avatar", "AvatarMediaID", "AvatarMedia", "Avatar"},
+	"staffs": {"avatar_media_id", "avatar", "AvatarMediaID", "AvatarMedia", "Avatar"},
+}
+
+// mediaRefTables names of the tables in mediaRefs
+func mediaRefTables() []string {
+	tables := make([]string, 0, len(mediaRefs))
+	for table := range mediaRefs {
+		tables = append(tables, table)
+	}
+	return tables
+}
+
+// MediaVariantUpload resized copy of an uploaded image, stored along with it by AddMedia
+type MediaVariantUpload struct {
+	*model.MediaVariants
+	Content []byte
+}
+
+// GetAllMedia is a function to get a slice of record(s) from media table in the wcs database
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of records in a page  (defaults to 20)
+// params - order    - db sort order column
+// error - ErrNotFound, db Find error
+func GetAllMedia(ctx context.Context, page, pagesize int64, order string) (results []*model.Media, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	resultOrm := conn.Model(&model.Media{})
+	resultOrm.Count(&totalRows)
+
+	if page > 0 {
+		offset := (page - 1) * pagesize
+		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
+	} else {
+		resultOrm = resultOrm.Limit(pagesize)
+	}
+
+	if order != "" {
+		resultOrm = resultOrm.Order(order)
+	}
+
+	if err = resultOrm.Find(&results).Error; err != nil {
+		err = dbError(ErrNotFound, err)
+		return nil, -1, err
+	}
+
+	if err = loadVariants(conn, results...); err != nil {
+		return nil, -1, dbError(ErrNotFound, err)
+	}
+
+	return results, totalRows, nil
+}
+
+// GetMedia is a function to get a single record from the media table in the wcs database
+// error - ErrNotFound, db Find error
+func GetMedia(ctx context.Context, argID int32) (record *model.Media, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Media{}
+	if err = conn.First(record, argID).Error; err != nil {
+		err = dbError(ErrNotFound, err)
+		return record, err
+	}
+
+	if err = loadVariants(conn, record); err != nil {
+		return record, dbError(ErrNotFound, err)
+	}
+	return record, nil
+}
+
+// AddMedia is a function to store uploaded content and add its record to media table in the wcs database.
+// Content is addressed by record.Digest, uploading content that is stored already returns the existing record
+// with created false. Resized variants of an image are stored and recorded in media_variants along with it.
+// error - ErrInsertFailed, storage put or db save call failed
+func AddMedia(ctx context.Context, record *model.Media, content io.Reader, variants ...MediaVariantUpload) (result *model.Media, created bool, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer done()
+
+	existing := &model.Media{}
+	err = conn.Where("digest = ?", record.Digest).First(existing).Error
+	if err == nil {
+		return existing, false, loadVariants(conn, existing)
+	}
+
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, false, dbError(ErrInsertFailed, err)
+	}
+
+	keys := []string{record.StorageKey}
+	if err = Store.Put(ctx, record.StorageKey, content, record.Size, record.MimeType); err != nil {
+		return nil, false, dbError(ErrInsertFailed, err)
+	}
+
+	for _, variant := range variants {
+		err = Store.Put(ctx, variant.StorageKey, bytes.NewReader(variant.Content), int64(len(variant.Content)), variant.MimeType)
+		if err != nil {
+			deleteBlobs(ctx, keys)
+			return nil, false, dbError(ErrInsertFailed, err)
+		}
+		keys = append(keys, variant.StorageKey)
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if err := tx.Save(record).Error; err != nil {
+			return err
+		}
+
+		record.Variants = make([]*model.MediaVariants, 0, len(variants))
+		for _, variant := range variants {
+			variant.MediaID = record.ID
+			if err := tx.Save(variant.MediaVariants).Error; err != nil {
+				return err
+			}
+			record.Variants = append(record.Variants, variant.MediaVariants)
+		}
+		return nil
+	})
+	if err != nil {
+		// lost a race against a concurrent upload of the same content, which owns the blobs now
+		if conn.Where("digest = ?", record.Digest).First(existing).Error == nil {
+			return existing, false, loadVariants(conn, existing)
+		}
+
+		deleteBlobs(ctx, keys)
+		return nil, false, dbError(ErrInsertFailed, err)
+	}
+
+	fillMediaURL(record)
+	invalidate(ctx, "media", "media_variants")
+	return record, true, nil
+}
+
+// DeleteMedia is a function to delete a single record from media table in the wcs database and its stored content.
+// Content rows referencing the media lose the reference and their cover or avatar url.
+// A non zero version must match the stored row version.
+// error - ErrNotFound, db Find error
+// error - ErrVersionConflict, db record was modified since version was read
+// error - ErrDeleteFailed, db Delete failed error
+func DeleteMedia(ctx context.Context, argID int32, version int32) (rowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer done()
+
+	record := &model.Media{}
+	db := conn.First(record, argID)
+	if db.Error != nil {
+		return -1, dbError(ErrNotFound, db.Error)
+	}
+
+	if version != 0 && version != record.Version {
+		return -1, ErrVersionConflict
+	}
+
+	if err = loadVariants(conn, record); err != nil {
+		return -1, dbError(ErrDeleteFailed, err)
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		for table, ref := range mediaRefs {
+			err := tx.Table(table).Where(ref.column+" = ?", argID).Updates(map[string]interface{}{
+				ref.column: nil,
+				ref.legacy: "",
+				"version":  gorm.Expr("version + 1"),
+			}).Error
+			if err != nil {
+				return dbError(ErrDeleteFailed, err)
+			}
+		}
+
+		if err := tx.Where("media_id = ?", argID).Delete(&model.MediaVariants{}).Error; err != nil {
+			return dbError(ErrDeleteFailed, err)
+		}
+
+		rowsAffected, err = deleteVersioned(tx, record, record.Version)
+		return err
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	keys := []string{record.StorageKey}
+	for _, variant := range record.Variants {
+		keys = append(keys, variant.StorageKey)
+	}
+	deleteBlobs(ctx, keys)
+	invalidate(ctx, append([]string{"media", "media_variants"}, mediaRefTables()...)...)
+	return rowsAffected, nil
+}
+
+// deleteBlobs removes stored blobs no row refers to, a blob left behind by a failed delete is harmless
+func deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		Store.Delete(ctx, key)
+	}
+}
+
+func fillMediaURL(record *model.Media) {
+	if Store != nil {
+		record.URL = Store.URL(record.StorageKey)
+	}
+}
+
+// loadVariants fills the urls and the resized variants of media records
+func loadVariants(db *gorm.DB, media ...*model.Media) error {
+	if len(media) == 0 {
+		return nil
+	}
+
+	ids := make([]int32, 0, len(media))
+	byID := make(map[int32]*model.Media, len(media))
+	for _, m := range media {
+		fillMediaURL(m)
+		m.Variants = []*model.MediaVariants{}
+		ids = append(ids, m.ID)
+		byID[m.ID] = m
+	}
+
+	var variants []*model.MediaVariants
+	if err := db.Where("media_id IN (?)", ids).Order("width").Find(&variants).Error; err != nil {
+		return err
+	}
+
+	for _, variant := range variants {
+		if Store != nil {
+			variant.URL = Store.URL(variant.StorageKey)
+		}
+		if m, ok := byID[variant.MediaID]; ok {
+			m.Variants = append(m.Variants, variant)
+		}
+	}
+
+	return nil
+}
+
+// mediaID returns the media id referenced by a record of a content table
+func mediaID(record model.Model, ref mediaRef) null.Int {
+	return reflect.Indirect(reflect.ValueOf(record)).FieldByName(ref.idField).Interface().(null.Int)
+}
+
+// loadMedia fills the referenced media of records of a content table
+func loadMedia(db *gorm.DB, table string, records ...model.Model) error {
+	ref, ok := mediaRefs[table]
+	if !ok || len(records) == 0 {
+		return nil
+	}
+
+	var ids []int64
+	for _, record := range records {
+		if id := mediaID(record, ref); id.Valid {
+			ids = append(ids, id.Int64)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var media []*model.Media
+	if err := db.Where("id IN (?)", ids).Find(&media).Error; err != nil {
+		return err
+	}
+
+	if err := loadVariants(db, media...); err != nil {
+		return err
+	}
+
+	byID := make(map[int64]*model.Media, len(media))
+	for _, m := range media {
+		byID[int64(m.ID)] = m
+	}
+
+	for _, record := range records {
+		id := mediaID(record, ref)
+		if m, ok := byID[id.Int64]; ok && id.Valid {
+			reflect.Indirect(reflect.ValueOf(record)).FieldByName(ref.mediaField).Set(reflect.ValueOf(m))
+		}
+	}
+
+	return nil
+}
+
+// resolveMedia checks the media referenced by record exists, fills it in and copies its url into the legacy url column
+// error - ErrBadParams, record references an unknown media id
+func resolveMedia(db *gorm.DB, table string, record model.Model) error {
+	ref, ok := mediaRefs[table]
+	if !ok {
+		return nil
+	}
+
+	id := mediaID(record, ref)
+	if !id.Valid {
+		return nil
+	}
+
+	media := &model.Media{}
+	if err := db.First(media, id.Int64).Error; err != nil {
+		return ErrBadParams
+	}
+
+	if err := loadVariants(db, media); err != nil {
+		return ErrBadParams
+	}
+
+	value := reflect.Indirect(reflect.ValueOf(record))
+	value.FieldByName(ref.mediaField).Set(reflect.ValueOf(media))
+	value.FieldByName(ref.urlField).SetString(media.URL)
+	return nil
+}
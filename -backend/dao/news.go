@@ -2,11 +2,13 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"wcs/model"
 
 	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -20,10 +22,19 @@ var (
 // params - page     - page requested (defaults to 0)
 // params - pagesize - number of records in a page  (defaults to 20)
 // params - order    - db sort order column
+// params - filters  - optional query filters, e.g. WithTag
 // error - ErrNotFound, db Find error
-func GetAllNews(ctx context.Context, page, pagesize int64, order string) (results []*model.News, totalRows int, err error) {
+func GetAllNews(ctx context.Context, page, pagesize int64, order string, filters ...QueryFilter) (results []*model.News, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
 
-	resultOrm := DB.Model(&model.News{})
+	resultOrm := conn.Model(&model.News{})
+	for _, filter := range filters {
+		resultOrm = filter(resultOrm)
+	}
 	resultOrm.Count(&totalRows)
 
 	if page > 0 {
@@ -38,74 +49,187 @@ func GetAllNews(ctx context.Context, page, pagesize int64, order string) (result
 	}
 
 	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
+		err = dbError(ErrNotFound, err)
 		return nil, -1, err
 	}
 
+	records := make([]model.Model, len(results))
+	for i, record := range results {
+		records[i] = record
+	}
+
+	if err = loadTags(conn, "news", records...); err != nil {
+		return nil, -1, dbError(ErrNotFound, err)
+	}
+
+	if err = loadMedia(conn, "news", records...); err != nil {
+		return nil, -1, dbError(ErrNotFound, err)
+	}
+
 	return results, totalRows, nil
 }
 
 // GetNews is a function to get a single record from the news table in the wcs database
 // error - ErrNotFound, db Find error
 func GetNews(ctx context.Context, argID int32) (record *model.News, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	record = &model.News{}
-	if err = DB.First(record, argID).Error; err != nil {
-		err = ErrNotFound
+	if err = conn.First(record, argID).Error; err != nil {
+		err = dbError(ErrNotFound, err)
 		return record, err
 	}
 
+	if err = loadTags(conn, "news", record); err != nil {
+		return record, dbError(ErrNotFound, err)
+	}
+
+	if err = loadMedia(conn, "news", record); err != nil {
+		return record, dbError(ErrNotFound, err)
+	}
+
 	return record, nil
 }
 
 // AddNews is a function to add a single record to news table in the wcs database
+// The record and its tag links are saved in one transaction, tags given by name only are created.
+// The initial publishing status is recorded in status_history.
 // error - ErrInsertFailed, db save call failed
+// error - ErrBadParams, tag list references an unknown tag id or cover_media_id an unknown media
 func AddNews(ctx context.Context, record *model.News) (result *model.News, RowsAffected int64, err error) {
-	db := DB.Save(record)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrInsertFailed
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
 	}
+	defer done()
 
-	return record, db.RowsAffected, nil
+	if err = resolveMedia(conn, "news", record); err != nil {
+		return nil, -1, err
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		db := tx.Save(record)
+		if db.Error != nil {
+			return dbError(ErrInsertFailed, db.Error)
+		}
+
+		RowsAffected = db.RowsAffected
+		if err := trackStatus(ctx, tx, "news", record, ""); err != nil {
+			return err
+		}
+		if err := syncTags(tx, "news", record); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "news", model.Create, record)
+	})
+	if err != nil {
+		return nil, -1, err
+	}
+
+	invalidate(ctx, "news", "news_tags")
+	return record, RowsAffected, nil
 }
 
 // UpdateNews is a function to update a single record from news table in the wcs database
+// Every client writable column and the tag list are replaced by the values in updated, zero values included.
+// A change of publishing status is recorded in status_history.
+// A non zero updated.Version must match the stored row version, otherwise the current record is returned with ErrVersionConflict.
 // error - ErrNotFound, db record for id not found
-// error - ErrUpdateFailed, db meta data copy failed or db.Save call failed
+// error - ErrVersionConflict, db record was modified since updated.Version was read
+// error - ErrUpdateFailed, db meta data copy failed or db update call failed
+// error - ErrBadParams, tag list references an unknown tag id or cover_media_id an unknown media
 func UpdateNews(ctx context.Context, argID int32, updated *model.News) (result *model.News, RowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
 
 	result = &model.News{}
-	db := DB.First(result, argID)
+	db := conn.First(result, argID)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrNotFound
+		return nil, -1, dbError(ErrNotFound, err)
 	}
 
-	if err = Copy(result, updated); err != nil {
-		return nil, -1, ErrUpdateFailed
+	version, status := result.Version, result.Status
+	if updated.Version != 0 && updated.Version != version {
+		return result, -1, ErrVersionConflict
 	}
 
-	db = db.Save(result)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrUpdateFailed
+	if err = Replace(result, updated); err != nil {
+		return nil, -1, dbError(ErrUpdateFailed, err)
+	}
+	result.Tags = updated.Tags
+
+	if err = resolveMedia(conn, "news", result); err != nil {
+		return nil, -1, err
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if RowsAffected, err = saveVersioned(tx, result, version); err != nil {
+			return err
+		}
+		if err = trackStatus(ctx, tx, "news", result, status); err != nil {
+			return err
+		}
+		if err = syncTags(tx, "news", result); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "news", model.Update, result)
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		if result, err = GetNews(ctx, argID); err != nil {
+			return nil, -1, err
+		}
+		return result, -1, ErrVersionConflict
+	}
+	if err != nil {
+		return nil, -1, err
 	}
 
-	return result, db.RowsAffected, nil
+	invalidate(ctx, "news", "news_tags")
+	return result, RowsAffected, nil
 }
 
 // DeleteNews is a function to delete a single record from news table in the wcs database
+// A non zero version must match the stored row version.
 // error - ErrNotFound, db Find error
+// error - ErrVersionConflict, db record was modified since version was read
 // error - ErrDeleteFailed, db Delete failed error
-func DeleteNews(ctx context.Context, argID int32) (rowsAffected int64, err error) {
+func DeleteNews(ctx context.Context, argID int32, version int32) (rowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer done()
 
 	record := &model.News{}
-	db := DB.First(record, argID)
+	db := conn.First(record, argID)
 	if db.Error != nil {
-		return -1, ErrNotFound
+		return -1, dbError(ErrNotFound, db.Error)
 	}
 
-	db = db.Delete(record)
-	if err = db.Error; err != nil {
-		return -1, ErrDeleteFailed
+	if version != 0 && version != record.Version {
+		return -1, ErrVersionConflict
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if rowsAffected, err = deleteVersioned(tx, record, record.Version); err != nil {
+			return err
+		}
+		if err = clearTags(tx, "news", argID); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "news", model.Delete, record)
+	})
+	if err != nil {
+		return -1, err
 	}
 
-	return db.RowsAffected, nil
+	invalidate(ctx, "news", "news_tags")
+	return rowsAffected, nil
 }
@@ -0,0 +1,181 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"wcs/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrMailQueued resending a mail that is waiting to be sent
+	ErrMailQueued = &Error{Kind: KindConflict, Message: "mail already queued"}
+)
+
+// AddOutbox is a function to add an outgoing mail to the outbox table in the wcs database
+// error - ErrInsertFailed, db save call failed
+func AddOutbox(ctx context.Context, record *model.Outbox) (result *model.Outbox, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record.Status = model.MailQueued
+	if err = conn.Create(record).Error; err != nil {
+		return nil, dbError(ErrInsertFailed, err)
+	}
+
+	return record, nil
+}
+
+// GetAllOutbox is a function to get a slice of record(s) from outbox table in the wcs database
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of records in a page  (defaults to 20)
+// params - order    - db sort order column
+// params - filters  - optional query filters, e.g. WithStatus
+// error - ErrNotFound, db Find error
+func GetAllOutbox(ctx context.Context, page, pagesize int64, order string, filters ...QueryFilter) (results []*model.Outbox, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	resultOrm := conn.Model(&model.Outbox{})
+	for _, filter := range filters {
+		resultOrm = filter(resultOrm)
+	}
+	resultOrm.Count(&totalRows)
+
+	if page > 0 {
+		offset := (page - 1) * pagesize
+		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
+	} else {
+		resultOrm = resultOrm.Limit(pagesize)
+	}
+
+	if order != "" {
+		resultOrm = resultOrm.Order(order)
+	}
+
+	results = make([]*model.Outbox, 0)
+	if err = resultOrm.Find(&results).Error; err != nil {
+		err = dbError(ErrNotFound, err)
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// GetOutbox is a function to get a single record from the outbox table in the wcs database
+// error - ErrNotFound, db Find error
+func GetOutbox(ctx context.Context, argID int32) (record *model.Outbox, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Outbox{}
+	if err = conn.First(record, argID).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	return record, nil
+}
+
+// RecordDelivery is a function to add a delivery attempt to the log of a mail of the outbox table in the wcs database
+// and move the mail to status: sent when the attempt succeeded, dead when it was the last one, queued otherwise
+// error - ErrNotFound, db record for id not found
+// error - ErrUpdateFailed, db update call failed
+func RecordDelivery(ctx context.Context, argID int32, delivery *model.Delivery, status string) (record *model.Outbox, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Outbox{}
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if err := forUpdate(tx).First(record, argID).Error; err != nil {
+			return dbError(ErrNotFound, err)
+		}
+
+		record.Deliveries = append(record.Deliveries, delivery)
+		record.Attempts++
+		record.LastError = delivery.Error
+		record.Status = status
+		record.UpdateTime = delivery.At
+		if status == model.MailSent {
+			record.SentAt = null.TimeFrom(delivery.At)
+		}
+
+		err := tx.Model(record).UpdateColumns(map[string]interface{}{
+			"deliveries":  record.Deliveries,
+			"attempts":    record.Attempts,
+			"last_error":  record.LastError,
+			"status":      record.Status,
+			"sent_at":     record.SentAt,
+			"update_time": record.UpdateTime,
+		}).Error
+		return dbError(ErrUpdateFailed, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// RequeueOutbox is a function to queue a sent or dead mail of the outbox table in the wcs database again
+// error - ErrNotFound, db record for id not found
+// error - ErrMailQueued, the mail is queued already
+// error - ErrUpdateFailed, db update call failed
+func RequeueOutbox(ctx context.Context, argID int32, now time.Time) (record *model.Outbox, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Outbox{}
+	if err = conn.First(record, argID).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+	if record.Status == model.MailQueued {
+		return nil, ErrMailQueued
+	}
+
+	db := conn.Model(&model.Outbox{}).
+		Where("id = ? AND status = ?", record.ID, record.Status).
+		UpdateColumns(map[string]interface{}{"status": model.MailQueued, "update_time": now.UTC()})
+	if db.Error != nil {
+		return nil, dbError(ErrUpdateFailed, db.Error)
+	}
+	if db.RowsAffected != 1 {
+		return nil, ErrMailQueued
+	}
+
+	record.Status, record.UpdateTime = model.MailQueued, now.UTC()
+	return record, nil
+}
+
+// PurgeOutbox is a function to delete the mails of the outbox table in the wcs database that were sent before before
+// error - ErrDeleteFailed, db delete call failed
+func PurgeOutbox(ctx context.Context, before time.Time) (rowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer done()
+
+	db := conn.Where("status = ? AND sent_at < ?", model.MailSent, before.UTC()).Delete(&model.Outbox{})
+	if db.Error != nil {
+		return -1, dbError(ErrDeleteFailed, db.Error)
+	}
+	return db.RowsAffected, nil
+}
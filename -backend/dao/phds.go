@@ -2,11 +2,13 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"wcs/model"
 
 	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -22,8 +24,13 @@ var (
 // params - order    - db sort order column
 // error - ErrNotFound, db Find error
 func GetAllPhds(ctx context.Context, page, pagesize int64, order string) (results []*model.Phds, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
 
-	resultOrm := DB.Model(&model.Phds{})
+	resultOrm := conn.Model(&model.Phds{})
 	resultOrm.Count(&totalRows)
 
 	if page > 0 {
@@ -38,74 +45,160 @@ func GetAllPhds(ctx context.Context, page, pagesize int64, order string) (result
 	}
 
 	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
+		err = dbError(ErrNotFound, err)
 		return nil, -1, err
 	}
 
+	records := make([]model.Model, len(results))
+	for i, record := range results {
+		records[i] = record
+	}
+
+	if err = loadMedia(conn, "phds", records...); err != nil {
+		return nil, -1, dbError(ErrNotFound, err)
+	}
+
 	return results, totalRows, nil
 }
 
 // GetPhds is a function to get a single record from the phds table in the wcs database
 // error - ErrNotFound, db Find error
 func GetPhds(ctx context.Context, argID int32) (record *model.Phds, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	record = &model.Phds{}
-	if err = DB.First(record, argID).Error; err != nil {
-		err = ErrNotFound
+	if err = conn.First(record, argID).Error; err != nil {
+		err = dbError(ErrNotFound, err)
 		return record, err
 	}
 
+	if err = loadMedia(conn, "phds", record); err != nil {
+		return record, dbError(ErrNotFound, err)
+	}
+
 	return record, nil
 }
 
 // AddPhds is a function to add a single record to phds table in the wcs database
 // error - ErrInsertFailed, db save call failed
+// error - ErrBadParams, avatar_media_id references an unknown media
 func AddPhds(ctx context.Context, record *model.Phds) (result *model.Phds, RowsAffected int64, err error) {
-	db := DB.Save(record)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrInsertFailed
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	if err = resolveMedia(conn, "phds", record); err != nil {
+		return nil, -1, err
 	}
 
-	return record, db.RowsAffected, nil
+	err = transaction(conn, func(tx *gorm.DB) error {
+		db := tx.Save(record)
+		if db.Error != nil {
+			return dbError(ErrInsertFailed, db.Error)
+		}
+
+		RowsAffected = db.RowsAffected
+		return recordChange(ctx, tx, "phds", model.Create, record)
+	})
+	if err != nil {
+		return nil, -1, err
+	}
+
+	invalidate(ctx, "phds")
+	return record, RowsAffected, nil
 }
 
 // UpdatePhds is a function to update a single record from phds table in the wcs database
+// Every client writable column is replaced by the value in updated, zero values included.
+// A non zero updated.Version must match the stored row version, otherwise the current record is returned with ErrVersionConflict.
 // error - ErrNotFound, db record for id not found
-// error - ErrUpdateFailed, db meta data copy failed or db.Save call failed
+// error - ErrVersionConflict, db record was modified since updated.Version was read
+// error - ErrUpdateFailed, db meta data copy failed or db update call failed
+// error - ErrBadParams, avatar_media_id references an unknown media
 func UpdatePhds(ctx context.Context, argID int32, updated *model.Phds) (result *model.Phds, RowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
 
 	result = &model.Phds{}
-	db := DB.First(result, argID)
+	db := conn.First(result, argID)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrNotFound
+		return nil, -1, dbError(ErrNotFound, err)
 	}
 
-	if err = Copy(result, updated); err != nil {
-		return nil, -1, ErrUpdateFailed
+	version := result.Version
+	if updated.Version != 0 && updated.Version != version {
+		return result, -1, ErrVersionConflict
 	}
 
-	db = db.Save(result)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrUpdateFailed
+	if err = Replace(result, updated); err != nil {
+		return nil, -1, dbError(ErrUpdateFailed, err)
+	}
+
+	if err = resolveMedia(conn, "phds", result); err != nil {
+		return nil, -1, err
 	}
 
-	return result, db.RowsAffected, nil
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if RowsAffected, err = saveVersioned(tx, result, version); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "phds", model.Update, result)
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		if result, err = GetPhds(ctx, argID); err != nil {
+			return nil, -1, err
+		}
+		return result, -1, ErrVersionConflict
+	}
+	if err != nil {
+		return nil, -1, err
+	}
+
+	invalidate(ctx, "phds")
+	return result, RowsAffected, nil
 }
 
 // DeletePhds is a function to delete a single record from phds table in the wcs database
+// A non zero version must match the stored row version.
 // error - ErrNotFound, db Find error
+// error - ErrVersionConflict, db record was modified since version was read
 // error - ErrDeleteFailed, db Delete failed error
-func DeletePhds(ctx context.Context, argID int32) (rowsAffected int64, err error) {
+func DeletePhds(ctx context.Context, argID int32, version int32) (rowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer done()
 
 	record := &model.Phds{}
-	db := DB.First(record, argID)
+	db := conn.First(record, argID)
 	if db.Error != nil {
-		return -1, ErrNotFound
+		return -1, dbError(ErrNotFound, db.Error)
 	}
 
-	db = db.Delete(record)
-	if err = db.Error; err != nil {
-		return -1, ErrDeleteFailed
+	if version != 0 && version != record.Version {
+		return -1, ErrVersionConflict
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if rowsAffected, err = deleteVersioned(tx, record, record.Version); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "phds", model.Delete, record)
+	})
+	if err != nil {
+		return -1, err
 	}
 
-	return db.RowsAffected, nil
+	invalidate(ctx, "phds")
+	return rowsAffected, nil
 }
@@ -2,11 +2,13 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"wcs/model"
 
 	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -22,8 +24,13 @@ var (
 // params - order    - db sort order column
 // error - ErrNotFound, db Find error
 func GetAllProjects(ctx context.Context, page, pagesize int64, order string) (results []*model.Projects, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
 
-	resultOrm := DB.Model(&model.Projects{})
+	resultOrm := conn.Model(&model.Projects{})
 	resultOrm.Count(&totalRows)
 
 	if page > 0 {
@@ -38,7 +45,7 @@ func GetAllProjects(ctx context.Context, page, pagesize int64, order string) (re
 	}
 
 	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
+		err = dbError(ErrNotFound, err)
 		return nil, -1, err
 	}
 
@@ -48,9 +55,15 @@ func GetAllProjects(ctx context.Context, page, pagesize int64, order string) (re
 // GetProjects is a function to get a single record from the projects table in the wcs database
 // error - ErrNotFound, db Find error
 func GetProjects(ctx context.Context, argID int32) (record *model.Projects, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	record = &model.Projects{}
-	if err = DB.First(record, argID).Error; err != nil {
-		err = ErrNotFound
+	if err = conn.First(record, argID).Error; err != nil {
+		err = dbError(ErrNotFound, err)
 		return record, err
 	}
 
@@ -60,52 +73,109 @@ func GetProjects(ctx context.Context, argID int32) (record *model.Projects, err
 // AddProjects is a function to add a single record to projects table in the wcs database
 // error - ErrInsertFailed, db save call failed
 func AddProjects(ctx context.Context, record *model.Projects) (result *model.Projects, RowsAffected int64, err error) {
-	db := DB.Save(record)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrInsertFailed
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		db := tx.Save(record)
+		if db.Error != nil {
+			return dbError(ErrInsertFailed, db.Error)
+		}
+
+		RowsAffected = db.RowsAffected
+		return recordChange(ctx, tx, "projects", model.Create, record)
+	})
+	if err != nil {
+		return nil, -1, err
 	}
 
-	return record, db.RowsAffected, nil
+	invalidate(ctx, "projects")
+	return record, RowsAffected, nil
 }
 
 // UpdateProjects is a function to update a single record from projects table in the wcs database
+// Every client writable column is replaced by the value in updated, zero values included.
+// A non zero updated.Version must match the stored row version, otherwise the current record is returned with ErrVersionConflict.
 // error - ErrNotFound, db record for id not found
-// error - ErrUpdateFailed, db meta data copy failed or db.Save call failed
+// error - ErrVersionConflict, db record was modified since updated.Version was read
+// error - ErrUpdateFailed, db meta data copy failed or db update call failed
 func UpdateProjects(ctx context.Context, argID int32, updated *model.Projects) (result *model.Projects, RowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
 
 	result = &model.Projects{}
-	db := DB.First(result, argID)
+	db := conn.First(result, argID)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrNotFound
+		return nil, -1, dbError(ErrNotFound, err)
 	}
 
-	if err = Copy(result, updated); err != nil {
-		return nil, -1, ErrUpdateFailed
+	version := result.Version
+	if updated.Version != 0 && updated.Version != version {
+		return result, -1, ErrVersionConflict
 	}
 
-	db = db.Save(result)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrUpdateFailed
+	if err = Replace(result, updated); err != nil {
+		return nil, -1, dbError(ErrUpdateFailed, err)
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if RowsAffected, err = saveVersioned(tx, result, version); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "projects", model.Update, result)
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		if result, err = GetProjects(ctx, argID); err != nil {
+			return nil, -1, err
+		}
+		return result, -1, ErrVersionConflict
+	}
+	if err != nil {
+		return nil, -1, err
 	}
 
-	return result, db.RowsAffected, nil
+	invalidate(ctx, "projects")
+	return result, RowsAffected, nil
 }
 
 // DeleteProjects is a function to delete a single record from projects table in the wcs database
+// A non zero version must match the stored row version.
 // error - ErrNotFound, db Find error
+// error - ErrVersionConflict, db record was modified since version was read
 // error - ErrDeleteFailed, db Delete failed error
-func DeleteProjects(ctx context.Context, argID int32) (rowsAffected int64, err error) {
+func DeleteProjects(ctx context.Context, argID int32, version int32) (rowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer done()
 
 	record := &model.Projects{}
-	db := DB.First(record, argID)
+	db := conn.First(record, argID)
 	if db.Error != nil {
-		return -1, ErrNotFound
+		return -1, dbError(ErrNotFound, db.Error)
 	}
 
-	db = db.Delete(record)
-	if err = db.Error; err != nil {
-		return -1, ErrDeleteFailed
+	if version != 0 && version != record.Version {
+		return -1, ErrVersionConflict
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if rowsAffected, err = deleteVersioned(tx, record, record.Version); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "projects", model.Delete, record)
+	})
+	if err != nil {
+		return -1, err
 	}
 
-	return db.RowsAffected, nil
+	invalidate(ctx, "projects")
+	return rowsAffected, nil
 }
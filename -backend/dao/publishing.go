@@ -0,0 +1,189 @@
+package dao
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"wcs/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+type contextKey int
+
+const adminIDKey contextKey = iota
+
+// publishedTables tables whose records go through the draft / scheduled / published / archived workflow
+var publishedTables = map[string]bool{
+	"events": true,
+	"news":   true,
+}
+
+// WithAdminID returns a copy of ctx carrying the id of the signed in admin
+func WithAdminID(ctx context.Context, adminID int32) context.Context {
+	return context.WithValue(ctx, adminIDKey, adminID)
+}
+
+// CurrentAdminID returns the id of the signed in admin carried by ctx, ok is false for anonymous requests
+func CurrentAdminID(ctx context.Context) (adminID int32, ok bool) {
+	adminID, ok = ctx.Value(adminIDKey).(int32)
+	return adminID, ok
+}
+
+// Published narrows a GetAll query of a published table down to records visible to the public at now
+func Published(now time.Time) QueryFilter {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(status = ? OR (status = ? AND publish_at <= ?)) AND (unpublish_at IS NULL OR unpublish_at > ?)",
+			model.StatusPublished, model.StatusScheduled, now, now)
+	}
+}
+
+// PublishedSince narrows a GetAll query of a published table down to the records that went public after since
+func PublishedSince(since time.Time) QueryFilter {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("COALESCE(publish_at, create_time) > ?", since)
+	}
+}
+
+// WithStatus narrows a GetAll query of a published table down to records in the given publishing state
+func WithStatus(status string) QueryFilter {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("status = ?", status)
+	}
+}
+
+// trackStatus records a status_history row when the publishing state of record moved away from from.
+// The change is attributed to the admin carried by ctx.
+func trackStatus(ctx context.Context, db *gorm.DB, table string, record model.Model, from string) error {
+	if !publishedTables[table] {
+		return nil
+	}
+
+	return recordStatusChange(ctx, db, table, recordID(record), from, recordStatus(record))
+}
+
+// recordStatus returns the publishing state of record, empty for tables without a publishing workflow
+func recordStatus(record model.Model) string {
+	field := reflect.Indirect(reflect.ValueOf(record)).FieldByName("Status")
+	if !field.IsValid() {
+		return ""
+	}
+	return field.String()
+}
+
+func recordStatusChange(ctx context.Context, db *gorm.DB, table string, id int32, from, to string) error {
+	if from == to {
+		return nil
+	}
+
+	history := &model.StatusHistory{Table: table, RecordID: id, FromStatus: from, ToStatus: to, CreateTime: time.Now()}
+	if adminID, ok := CurrentAdminID(ctx); ok {
+		history.AdminID = null.IntFrom(int64(adminID))
+	}
+
+	if err := db.Create(history).Error; err != nil {
+		return dbError(ErrInsertFailed, err)
+	}
+
+	return nil
+}
+
+// GetStatusHistory is a function to get the publishing status changes of a news or events record, oldest first
+// error - ErrBadParams, table has no publishing workflow
+// error - ErrNotFound, db Find error
+func GetStatusHistory(ctx context.Context, table string, argID int32) (results []*model.StatusHistory, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if !publishedTables[table] {
+		return nil, ErrBadParams
+	}
+
+	results = []*model.StatusHistory{}
+	if err = conn.Where("table_name = ? AND record_id = ?", table, argID).Order("id").Find(&results).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	return results, nil
+}
+
+// ApplyStatusSchedule is a function to publish scheduled records whose publish_at has passed and archive published records
+// whose unpublish_at has passed. Each change bumps the record version and is recorded in status_history without an admin,
+// it is reported to the OnChange hook as an update.
+// changed - number of records whose status was flipped
+func ApplyStatusSchedule(ctx context.Context, now time.Time) (changed int, err error) {
+	transitions := []struct{ from, to, column string }{
+		{model.StatusScheduled, model.StatusPublished, "publish_at"},
+		{model.StatusPublished, model.StatusArchived, "unpublish_at"},
+	}
+
+	for table := range publishedTables {
+		for _, t := range transitions {
+			conn, done, err := dbRead(ctx)
+			if err != nil {
+				return changed, err
+			}
+
+			var ids []int32
+			err = conn.Table(table).Where("status = ? AND "+t.column+" <= ?", t.from, now).Pluck("id", &ids).Error
+			done()
+			if err != nil {
+				return changed, dbError(ErrUpdateFailed, err)
+			}
+
+			for _, id := range ids {
+				conn, done, err := dbWrite(ctx)
+				if err != nil {
+					return changed, err
+				}
+
+				flipped := false
+				err = transaction(conn, func(tx *gorm.DB) error {
+					db := tx.Table(table).Where("id = ? AND status = ?", id, t.from).Updates(map[string]interface{}{
+						"status":      t.to,
+						"version":     gorm.Expr("version + 1"),
+						"update_time": now,
+					})
+					if db.Error != nil {
+						return dbError(ErrUpdateFailed, db.Error)
+					}
+
+					// changed by an admin since the ids were read
+					if db.RowsAffected == 0 {
+						return nil
+					}
+
+					flipped = true
+					if err := recordStatusChange(ctx, tx, table, id, t.from, t.to); err != nil {
+						return err
+					}
+
+					record, _ := model.NewRecord(table)
+					if err := tx.First(record, id).Error; err != nil {
+						return dbError(ErrNotFound, err)
+					}
+					if err := loadTags(tx, table, record); err != nil {
+						return dbError(ErrNotFound, err)
+					}
+					return recordChange(ctx, tx, table, model.Update, record)
+				})
+				done()
+				if err != nil {
+					return changed, err
+				}
+
+				if flipped {
+					changed++
+					invalidate(ctx, table)
+				}
+			}
+		}
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,422 @@
+package dao
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"wcs/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	// ErrRegistrationClosed the event does not take registrations, or no longer
+	ErrRegistrationClosed = &Error{Kind: KindConflict, Message: "registration closed"}
+
+	// ErrAlreadyRegistered the email address holds a registration for the event already
+	ErrAlreadyRegistered = &Error{Kind: KindConflict, Message: "already registered"}
+
+	// ErrInvalidTicket the ticket code is malformed, forged or for another event
+	ErrInvalidTicket = &Error{Kind: KindBadRequest, Message: "invalid ticket"}
+
+	// ErrNotConfirmed the registration of a ticket is waitlisted or cancelled
+	ErrNotConfirmed = &Error{Kind: KindConflict, Message: "registration not confirmed"}
+)
+
+// RegisterForEvent is a function to add a registration for an event to the registrations table in the wcs database.
+// The registration is confirmed while the event has places left and waitlisted once it is full.
+// The event row is locked for the duration, so concurrent registrations can not overbook it.
+// error - ErrNotFound, event not found
+// error - ErrRegistrationClosed, event not public, not open for registration or over
+// error - ErrAlreadyRegistered, email registered for the event already
+// error - model.ValidationError, answers miss a required question
+// error - ErrInsertFailed, db save call failed
+func RegisterForEvent(ctx context.Context, eventID int32, record *model.Registrations, now time.Time) (event *model.Events, result *model.Registrations, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer done()
+
+	token, err := newToken()
+	if err != nil {
+		return nil, nil, dbError(ErrInsertFailed, err)
+	}
+
+	event = &model.Events{}
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if err := forUpdate(tx).First(event, eventID).Error; err != nil {
+			return dbError(ErrNotFound, err)
+		}
+
+		if !event.AcceptsRegistrations(now) {
+			return ErrRegistrationClosed
+		}
+
+		if err := model.ValidateAnswers(record, event.Questions); err != nil {
+			return err
+		}
+
+		var registered int
+		err := tx.Model(&model.Registrations{}).
+			Where("event_id = ? AND email = ? AND status <> ?", eventID, record.Email, model.RegistrationCancelled).
+			Count(&registered).Error
+		if err != nil {
+			return dbError(ErrQueryFailed, err)
+		}
+		if registered > 0 {
+			return ErrAlreadyRegistered
+		}
+
+		confirmed, err := countRegistrations(tx, eventID, model.RegistrationConfirmed)
+		if err != nil {
+			return err
+		}
+
+		record.ID = 0
+		record.EventID = eventID
+		record.Token = token
+		record.CheckedInAt, record.ScanCount = null.Time{}, 0
+		record.Status = model.RegistrationConfirmed
+		if event.Capacity > 0 && confirmed >= int(event.Capacity) {
+			record.Status = model.RegistrationWaitlisted
+		}
+
+		if err := tx.Save(record).Error; err != nil {
+			return dbError(ErrInsertFailed, err)
+		}
+		return loadPosition(tx, record)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invalidate(ctx, "registrations")
+	return event, record, nil
+}
+
+// GetRegistrations is a function to get the registrations for an event from the registrations table in the wcs database,
+// in order of registration. Waitlisted registrations carry their position.
+// params - status - only registrations of status, every registration when empty
+// error - ErrNotFound, db Find error
+func GetRegistrations(ctx context.Context, eventID int32, status string) (results []*model.Registrations, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	db := conn.Where("event_id = ?", eventID)
+	if status != "" {
+		db = db.Where("status = ?", status)
+	}
+
+	results = make([]*model.Registrations, 0)
+	if err = db.Order("id").Find(&results).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	position := 0
+	if status == "" || status == model.RegistrationWaitlisted {
+		for _, record := range results {
+			if record.Status == model.RegistrationWaitlisted {
+				position++
+				record.Position = position
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// GetRegistration is a function to get a single registration for an event from the registrations table in the wcs database
+// error - ErrNotFound, db record for id not found or registered for another event
+func GetRegistration(ctx context.Context, eventID, argID int32) (record *model.Registrations, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Registrations{}
+	if err = conn.Where("event_id = ?", eventID).First(record, argID).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	return record, nil
+}
+
+// WithRegistrations narrows a query of the events table down to events with confirmed registrations
+func WithRegistrations() QueryFilter {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("id IN (SELECT event_id FROM registrations WHERE status = ?)", model.RegistrationConfirmed)
+	}
+}
+
+// GetRegistrationByToken is a function to get the registration of a cancel link token from the registrations table in the wcs database
+// error - ErrNotFound, no registration holds token
+func GetRegistrationByToken(ctx context.Context, token string) (record *model.Registrations, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Registrations{}
+	if token == "" {
+		return nil, ErrNotFound
+	}
+	if err = conn.Where("token = ?", token).First(record).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	return record, nil
+}
+
+// CancelRegistration is a function to cancel a registration of the registrations table in the wcs database.
+// The place it frees goes to the first registrations of the waitlist, which are returned as promoted.
+// Cancelling a cancelled registration changes nothing.
+// error - ErrNotFound, db record for id not found or registered for another event
+// error - ErrUpdateFailed, db update call failed
+func CancelRegistration(ctx context.Context, eventID, argID int32) (record *model.Registrations, promoted []*model.Registrations, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer done()
+
+	record = &model.Registrations{}
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if err := tx.Where("event_id = ?", eventID).First(record, argID).Error; err != nil {
+			return dbError(ErrNotFound, err)
+		}
+
+		event := &model.Events{}
+		if err := forUpdate(tx).First(event, record.EventID).Error; err != nil {
+			return dbError(ErrNotFound, err)
+		}
+
+		if record.Status == model.RegistrationCancelled {
+			return nil
+		}
+
+		if err := setRegistrationStatus(tx, record, model.RegistrationCancelled); err != nil {
+			return err
+		}
+
+		promoted, err = promoteWaitlist(tx, event)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	invalidate(ctx, "registrations")
+	return record, promoted, nil
+}
+
+// PromoteWaitlist is a function to confirm registrations of the waitlist of an event in the registrations table in the wcs database
+// while the event has places left, first come first served. It runs after the capacity of an event changed.
+// error - ErrNotFound, event not found
+// error - ErrUpdateFailed, db update call failed
+func PromoteWaitlist(ctx context.Context, eventID int32) (promoted []*model.Registrations, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		event := &model.Events{}
+		if err := forUpdate(tx).First(event, eventID).Error; err != nil {
+			return dbError(ErrNotFound, err)
+		}
+
+		promoted, err = promoteWaitlist(tx, event)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(promoted) > 0 {
+		invalidate(ctx, "registrations")
+	}
+	return promoted, nil
+}
+
+// CheckIn is a function to record the scan of the ticket of a registration for an event in the registrations table in the wcs database.
+// The first scan checks the attendee in at now, later scans are counted and reported as duplicate.
+// error - ErrNotFound, db record for id not found or registered for another event
+// error - ErrNotConfirmed, registration waitlisted or cancelled
+// error - ErrUpdateFailed, db update call failed
+func CheckIn(ctx context.Context, eventID, argID int32, now time.Time) (record *model.Registrations, duplicate bool, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer done()
+
+	record = &model.Registrations{}
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if err := forUpdate(tx).Where("event_id = ?", eventID).First(record, argID).Error; err != nil {
+			return dbError(ErrNotFound, err)
+		}
+
+		if record.Status != model.RegistrationConfirmed {
+			return ErrNotConfirmed
+		}
+
+		duplicate = record.CheckedInAt.Valid
+		record.ScanCount++
+		if !duplicate {
+			record.CheckedInAt = null.TimeFrom(now.UTC())
+		}
+		fields := map[string]interface{}{"scan_count": record.ScanCount, "checked_in_at": record.CheckedInAt}
+		if err := tx.Model(record).UpdateColumns(fields).Error; err != nil {
+			return dbError(ErrUpdateFailed, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	invalidate(ctx, "registrations")
+	return record, duplicate, nil
+}
+
+// GetAttendanceStats is a function to get the registration and check-in counts of an event from the registrations table in the wcs database
+// error - ErrNotFound, event not found or db Find error
+func GetAttendanceStats(ctx context.Context, eventID int32) (stats *model.AttendanceStats, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	event := &model.Events{}
+	if err = conn.Select("id, capacity").First(event, eventID).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	var records []*model.Registrations
+	err = conn.Select("status, checked_in_at, scan_count").Where("event_id = ?", eventID).Find(&records).Error
+	if err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	stats = &model.AttendanceStats{EventID: eventID, Capacity: event.Capacity}
+	for _, record := range records {
+		stats.Add(record)
+	}
+	return stats, nil
+}
+
+// promoteWaitlist confirms the oldest waitlisted registrations of event for its free places, event must be locked by the caller
+func promoteWaitlist(tx *gorm.DB, event *model.Events) ([]*model.Registrations, error) {
+	db := tx.Where("event_id = ? AND status = ?", event.ID, model.RegistrationWaitlisted).Order("id")
+	if event.Capacity > 0 {
+		confirmed, err := countRegistrations(tx, event.ID, model.RegistrationConfirmed)
+		if err != nil {
+			return nil, err
+		}
+
+		free := int(event.Capacity) - confirmed
+		if free <= 0 {
+			return nil, nil
+		}
+		db = db.Limit(free)
+	}
+
+	var promoted []*model.Registrations
+	if err := db.Find(&promoted).Error; err != nil {
+		return nil, dbError(ErrQueryFailed, err)
+	}
+
+	for _, record := range promoted {
+		if err := setRegistrationStatus(tx, record, model.RegistrationConfirmed); err != nil {
+			return nil, err
+		}
+	}
+	return promoted, nil
+}
+
+// setRegistrationStatus changes the status of record
+func setRegistrationStatus(tx *gorm.DB, record *model.Registrations, status string) error {
+	now := time.Now().UTC()
+	err := tx.Model(record).UpdateColumns(map[string]interface{}{"status": status, "update_time": now}).Error
+	if err != nil {
+		return dbError(ErrUpdateFailed, err)
+	}
+
+	record.Status, record.UpdateTime, record.Position = status, now, 0
+	return nil
+}
+
+// countRegistrations returns the number of registrations for an event of status
+func countRegistrations(db *gorm.DB, eventID int32, status string) (count int, err error) {
+	err = db.Model(&model.Registrations{}).Where("event_id = ? AND status = ?", eventID, status).Count(&count).Error
+	if err != nil {
+		return 0, dbError(ErrQueryFailed, err)
+	}
+	return count, nil
+}
+
+// loadPosition fills in the waitlist position of a waitlisted record
+func loadPosition(db *gorm.DB, record *model.Registrations) error {
+	if record.Status != model.RegistrationWaitlisted {
+		return nil
+	}
+
+	var ahead int
+	err := db.Model(&model.Registrations{}).
+		Where("event_id = ? AND status = ? AND id < ?", record.EventID, model.RegistrationWaitlisted, record.ID).
+		Count(&ahead).Error
+	if err != nil {
+		return dbError(ErrQueryFailed, err)
+	}
+
+	record.Position = ahead + 1
+	return nil
+}
+
+// loadAttendance fills in the registration counts of events taking registrations
+func loadAttendance(db *gorm.DB, event *model.Events) error {
+	if !event.RegistrationOpen {
+		return nil
+	}
+
+	confirmed, err := countRegistrations(db, event.ID, model.RegistrationConfirmed)
+	if err != nil {
+		return err
+	}
+
+	waitlisted, err := countRegistrations(db, event.ID, model.RegistrationWaitlisted)
+	if err != nil {
+		return err
+	}
+
+	event.Attendance = model.NewAttendance(event.Capacity, confirmed, waitlisted)
+	return nil
+}
+
+// forUpdate locks the rows db reads until the end of its transaction, on MySQL. SQLite locks the whole database on write anyway.
+func forUpdate(db *gorm.DB) *gorm.DB {
+	if db.Dialect().GetName() == "mysql" {
+		return db.Set("gorm:query_option", "FOR UPDATE")
+	}
+	return db
+}
+
+// newToken returns a random hex token for links sent by email
+func newToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
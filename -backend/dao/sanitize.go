@@ -0,0 +1,97 @@
+package dao
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"wcs/model"
+)
+
+// sanitizeBatch number of rows read per query by SanitizeRows
+const sanitizeBatch = 100
+
+// SanitizeRows is a function to clean the sanitized columns of every row of table with the current policies,
+// e.g. after a policy change or for rows stored before sanitization existed.
+// Rows whose content changes are saved with a bumped version unless dryRun is set, rows modified concurrently are skipped.
+// changed - ids of the rows whose content changed
+// error - ErrBadParams, table has no sanitized column
+// error - ErrNotFound, db Find error
+// error - ErrUpdateFailed, db update error
+func SanitizeRows(ctx context.Context, table string, dryRun bool) (changed []int32, err error) {
+	sample, ok := model.NewRecord(table)
+	if !ok {
+		return nil, ErrBadParams
+	}
+
+	var columns []string
+	for _, col := range sample.TableInfo().Columns {
+		if col.Sanitizer != "" {
+			columns = append(columns, col.Name)
+		}
+	}
+	if len(columns) == 0 {
+		return nil, ErrBadParams
+	}
+
+	var lastID int32
+	for {
+		rows := reflect.New(reflect.SliceOf(reflect.TypeOf(sample)))
+		conn, done, err := dbRead(ctx)
+		if err != nil {
+			return changed, err
+		}
+
+		err = conn.Where("id > ?", lastID).Order("id").Limit(sanitizeBatch).Find(rows.Interface()).Error
+		done()
+		if err != nil {
+			return changed, dbError(ErrNotFound, err)
+		}
+
+		records := rows.Elem()
+		if records.Len() == 0 {
+			return changed, nil
+		}
+
+		for i := 0; i < records.Len(); i++ {
+			record := records.Index(i).Interface().(model.Model)
+			lastID = recordID(record)
+
+			cleaned := model.SanitizeColumns(record)
+			if len(cleaned) == 0 {
+				continue
+			}
+
+			changed = append(changed, lastID)
+			if dryRun {
+				continue
+			}
+
+			value := reflect.Indirect(reflect.ValueOf(record))
+			version := int32(value.FieldByName("Version").Int())
+			fields := map[string]interface{}{"version": version + 1}
+			for _, col := range record.TableInfo().Columns {
+				if col.Name == "update_time" {
+					fields[col.Name] = time.Now()
+				}
+				for _, name := range cleaned {
+					if col.Name == name {
+						fields[name] = value.FieldByName(col.GoFieldName).Interface()
+					}
+				}
+			}
+
+			conn, done, err := dbWrite(ctx)
+			if err != nil {
+				return changed, err
+			}
+
+			err = conn.Table(table).Where("id = ? AND version = ?", lastID, version).Updates(fields).Error
+			done()
+			if err != nil {
+				return changed, dbError(ErrUpdateFailed, err)
+			}
+			invalidate(ctx, table)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package dao_test
+
+import (
+	"strings"
+	"testing"
+
+	"wcs/dao"
+	"wcs/dao/daotest"
+)
+
+func TestSanitizeRows(t *testing.T) {
+	db, _, ctx := daotest.OpenDB(t)
+
+	image := `<img src="data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAIAAAACCAIAAAD91JpzAAAAEElEQVR4nGP4z8AARAwQCgAf7gP9i18U1AAAAABJRU5ErkJggg==">`
+	for _, content := range []string{
+		`<p>` + image + `</p>`,
+		`<p>` + image + `<script>alert(1)</script></p>`,
+	} {
+		// rows stored before sanitization existed
+		if err := db.Exec("INSERT INTO news (title, content, version) VALUES ('Open day', ?, 1)", content).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	changed, err := dao.SanitizeRows(ctx, "news", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changed) != 1 || changed[0] != 2 {
+		t.Errorf("dry run reports %v, want only news 2 with the script", changed)
+	}
+	if news, err := dao.GetNews(ctx, 2); err != nil || !strings.Contains(news.Content, "<script>") || news.Version != 1 {
+		t.Fatalf("dry run saved news 2: %v %+v", err, news)
+	}
+
+	if changed, err = dao.SanitizeRows(ctx, "news", false); err != nil || len(changed) != 1 {
+		t.Fatalf("sanitize changed %v with %v, want news 2", changed, err)
+	}
+	for _, id := range []int32{1, 2} {
+		news, err := dao.GetNews(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if news.Content != `<p>`+image+`</p>` {
+			t.Errorf("news %d is %q after sanitizing, want the image without the script", id, news.Content)
+		}
+	}
+}
@@ -2,11 +2,13 @@ package dao
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"wcs/model"
 
 	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
 	uuid "github.com/satori/go.uuid"
 )
 
@@ -22,8 +24,13 @@ var (
 // params - order    - db sort order column
 // error - ErrNotFound, db Find error
 func GetAllStaffs(ctx context.Context, page, pagesize int64, order string) (results []*model.Staffs, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
 
-	resultOrm := DB.Model(&model.Staffs{})
+	resultOrm := conn.Model(&model.Staffs{})
 	resultOrm.Count(&totalRows)
 
 	if page > 0 {
@@ -38,74 +45,160 @@ func GetAllStaffs(ctx context.Context, page, pagesize int64, order string) (resu
 	}
 
 	if err = resultOrm.Find(&results).Error; err != nil {
-		err = ErrNotFound
+		err = dbError(ErrNotFound, err)
 		return nil, -1, err
 	}
 
+	records := make([]model.Model, len(results))
+	for i, record := range results {
+		records[i] = record
+	}
+
+	if err = loadMedia(conn, "staffs", records...); err != nil {
+		return nil, -1, dbError(ErrNotFound, err)
+	}
+
 	return results, totalRows, nil
 }
 
 // GetStaffs is a function to get a single record from the staffs table in the wcs database
 // error - ErrNotFound, db Find error
 func GetStaffs(ctx context.Context, argID int32) (record *model.Staffs, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
 	record = &model.Staffs{}
-	if err = DB.First(record, argID).Error; err != nil {
-		err = ErrNotFound
+	if err = conn.First(record, argID).Error; err != nil {
+		err = dbError(ErrNotFound, err)
 		return record, err
 	}
 
+	if err = loadMedia(conn, "staffs", record); err != nil {
+		return record, dbError(ErrNotFound, err)
+	}
+
 	return record, nil
 }
 
 // AddStaffs is a function to add a single record to staffs table in the wcs database
 // error - ErrInsertFailed, db save call failed
+// error - ErrBadParams, avatar_media_id references an unknown media
 func AddStaffs(ctx context.Context, record *model.Staffs) (result *model.Staffs, RowsAffected int64, err error) {
-	db := DB.Save(record)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrInsertFailed
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	if err = resolveMedia(conn, "staffs", record); err != nil {
+		return nil, -1, err
 	}
 
-	return record, db.RowsAffected, nil
+	err = transaction(conn, func(tx *gorm.DB) error {
+		db := tx.Save(record)
+		if db.Error != nil {
+			return dbError(ErrInsertFailed, db.Error)
+		}
+
+		RowsAffected = db.RowsAffected
+		return recordChange(ctx, tx, "staffs", model.Create, record)
+	})
+	if err != nil {
+		return nil, -1, err
+	}
+
+	invalidate(ctx, "staffs")
+	return record, RowsAffected, nil
 }
 
 // UpdateStaffs is a function to update a single record from staffs table in the wcs database
+// Every client writable column is replaced by the value in updated, zero values included.
+// A non zero updated.Version must match the stored row version, otherwise the current record is returned with ErrVersionConflict.
 // error - ErrNotFound, db record for id not found
-// error - ErrUpdateFailed, db meta data copy failed or db.Save call failed
+// error - ErrVersionConflict, db record was modified since updated.Version was read
+// error - ErrUpdateFailed, db meta data copy failed or db update call failed
+// error - ErrBadParams, avatar_media_id references an unknown media
 func UpdateStaffs(ctx context.Context, argID int32, updated *model.Staffs) (result *model.Staffs, RowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
 
 	result = &model.Staffs{}
-	db := DB.First(result, argID)
+	db := conn.First(result, argID)
 	if err = db.Error; err != nil {
-		return nil, -1, ErrNotFound
+		return nil, -1, dbError(ErrNotFound, err)
 	}
 
-	if err = Copy(result, updated); err != nil {
-		return nil, -1, ErrUpdateFailed
+	version := result.Version
+	if updated.Version != 0 && updated.Version != version {
+		return result, -1, ErrVersionConflict
 	}
 
-	db = db.Save(result)
-	if err = db.Error; err != nil {
-		return nil, -1, ErrUpdateFailed
+	if err = Replace(result, updated); err != nil {
+		return nil, -1, dbError(ErrUpdateFailed, err)
+	}
+
+	if err = resolveMedia(conn, "staffs", result); err != nil {
+		return nil, -1, err
 	}
 
-	return result, db.RowsAffected, nil
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if RowsAffected, err = saveVersioned(tx, result, version); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "staffs", model.Update, result)
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		if result, err = GetStaffs(ctx, argID); err != nil {
+			return nil, -1, err
+		}
+		return result, -1, ErrVersionConflict
+	}
+	if err != nil {
+		return nil, -1, err
+	}
+
+	invalidate(ctx, "staffs")
+	return result, RowsAffected, nil
 }
 
 // DeleteStaffs is a function to delete a single record from staffs table in the wcs database
+// A non zero version must match the stored row version.
 // error - ErrNotFound, db Find error
+// error - ErrVersionConflict, db record was modified since version was read
 // error - ErrDeleteFailed, db Delete failed error
-func DeleteStaffs(ctx context.Context, argID int32) (rowsAffected int64, err error) {
+func DeleteStaffs(ctx context.Context, argID int32, version int32) (rowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer done()
 
 	record := &model.Staffs{}
-	db := DB.First(record, argID)
+	db := conn.First(record, argID)
 	if db.Error != nil {
-		return -1, ErrNotFound
+		return -1, dbError(ErrNotFound, db.Error)
 	}
 
-	db = db.Delete(record)
-	if err = db.Error; err != nil {
-		return -1, ErrDeleteFailed
+	if version != 0 && version != record.Version {
+		return -1, ErrVersionConflict
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if rowsAffected, err = deleteVersioned(tx, record, record.Version); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "staffs", model.Delete, record)
+	})
+	if err != nil {
+		return -1, err
 	}
 
-	return db.RowsAffected, nil
+	invalidate(ctx, "staffs")
+	return rowsAffected, nil
 }
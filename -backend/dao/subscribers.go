@@ -0,0 +1,176 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"wcs/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+// Subscribe is a function to add a subscriber to the subscribers table in the wcs database, pending until confirmed.
+// An address that unsubscribed or never confirmed is pending again and confirm is true, an active one is left as it is.
+// error - ErrInsertFailed, db save call failed
+// error - ErrUpdateFailed, db update call failed
+func Subscribe(ctx context.Context, record *model.Subscribers, now time.Time) (result *model.Subscribers, confirm bool, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	defer done()
+
+	now = now.UTC()
+	result = &model.Subscribers{}
+	err = transaction(conn, func(tx *gorm.DB) error {
+		err := forUpdate(tx).Where("email = ?", record.Email).First(result).Error
+		if gorm.IsRecordNotFoundError(err) {
+			record.Status, record.CreateTime, record.UpdateTime = model.SubscriberPending, now, now
+			if err := tx.Create(record).Error; err != nil {
+				return dbError(ErrInsertFailed, err)
+			}
+			result, confirm = record, true
+			return nil
+		}
+		if err != nil {
+			return dbError(ErrNotFound, err)
+		}
+
+		if result.Status == model.SubscriberActive {
+			return nil
+		}
+
+		confirm = true
+		fields := map[string]interface{}{"status": model.SubscriberPending, "ip_address": record.IPAddress, "update_time": now}
+		if record.Name != "" {
+			fields["name"] = record.Name
+		}
+		return dbError(ErrUpdateFailed, tx.Model(result).UpdateColumns(fields).Error)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return result, confirm, nil
+}
+
+// GetSubscriber is a function to get a single record from the subscribers table in the wcs database
+// error - ErrNotFound, db Find error
+func GetSubscriber(ctx context.Context, argID int32) (record *model.Subscribers, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Subscribers{}
+	if err = conn.First(record, argID).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	return record, nil
+}
+
+// ConfirmSubscriber is a function to activate the subscriber argID of the subscribers table in the wcs database.
+// The subscriber must still have the address email the confirmation was sent to, confirming twice is fine.
+// error - ErrNotFound, db record for id and email not found
+// error - ErrUpdateFailed, db update call failed
+func ConfirmSubscriber(ctx context.Context, argID int32, email string, now time.Time) (record *model.Subscribers, err error) {
+	return setSubscriberStatus(ctx, argID, email, model.SubscriberActive, "confirmed_at", now)
+}
+
+// Unsubscribe is a function to unsubscribe the subscriber argID of the subscribers table in the wcs database, unsubscribing twice is fine
+// error - ErrNotFound, db record for id not found
+// error - ErrUpdateFailed, db update call failed
+func Unsubscribe(ctx context.Context, argID int32, now time.Time) (record *model.Subscribers, err error) {
+	return setSubscriberStatus(ctx, argID, "", model.SubscriberUnsubscribed, "unsubscribed_at", now)
+}
+
+func setSubscriberStatus(ctx context.Context, argID int32, email, status, column string, now time.Time) (record *model.Subscribers, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Subscribers{}
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if err := forUpdate(tx).First(record, argID).Error; err != nil {
+			return dbError(ErrNotFound, err)
+		}
+		if email != "" && record.Email != email {
+			return ErrNotFound
+		}
+		if record.Status == status {
+			return nil
+		}
+
+		now := now.UTC()
+		err := tx.Model(record).UpdateColumns(map[string]interface{}{"status": status, column: null.TimeFrom(now), "update_time": now}).Error
+		return dbError(ErrUpdateFailed, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// GetAllSubscribers is a function to get a slice of record(s) from subscribers table in the wcs database
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of records in a page  (defaults to 20)
+// params - order    - db sort order column
+// params - filters  - optional query filters, e.g. WithStatus
+// error - ErrNotFound, db Find error
+func GetAllSubscribers(ctx context.Context, page, pagesize int64, order string, filters ...QueryFilter) (results []*model.Subscribers, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	resultOrm := conn.Model(&model.Subscribers{})
+	for _, filter := range filters {
+		resultOrm = filter(resultOrm)
+	}
+	resultOrm.Count(&totalRows)
+
+	if page > 0 {
+		offset := (page - 1) * pagesize
+		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
+	} else {
+		resultOrm = resultOrm.Limit(pagesize)
+	}
+
+	if order != "" {
+		resultOrm = resultOrm.Order(order)
+	}
+
+	results = make([]*model.Subscribers, 0)
+	if err = resultOrm.Find(&results).Error; err != nil {
+		err = dbError(ErrNotFound, err)
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// GetSubscriberBatch is a function to get up to limit active subscribers of the subscribers table in the wcs database
+// whose id follows afterID, in the order of their ids
+// error - ErrNotFound, db Find error
+func GetSubscriberBatch(ctx context.Context, afterID int32, limit int) (results []*model.Subscribers, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	results = make([]*model.Subscribers, 0, limit)
+	err = conn.Where("status = ? AND id > ?", model.SubscriberActive, afterID).Order("id").Limit(limit).Find(&results).Error
+	if err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,380 @@
+package dao
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"wcs/model"
+
+	"github.com/jinzhu/gorm"
+)
+
+// taggedTables tables whose records carry a model.TagList, mapped to the join table holding the links
+var taggedTables = map[string]string{
+	"events": "events_tags",
+	"news":   "news_tags",
+}
+
+// WithTag narrows a GetAll query of a tagged table down to records carrying the tag with the given slug
+func WithTag(table, slug string) QueryFilter {
+	join, column := tagJoin(table)
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(fmt.Sprintf("id IN (SELECT j.%s FROM %s j JOIN tags t ON t.id = j.tag_id WHERE t.slug = ?)", column, join), slug)
+	}
+}
+
+// CountTagUsage is a function to count the records each tag is attached to, per tagged table
+func CountTagUsage(ctx context.Context, tagIDs []int32) (usage map[int32]map[string]int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	usage = make(map[int32]map[string]int)
+	for _, id := range tagIDs {
+		usage[id] = make(map[string]int)
+	}
+
+	if len(tagIDs) == 0 {
+		return usage, nil
+	}
+
+	for table, join := range taggedTables {
+		rows, err := conn.Table(join).Select("tag_id, COUNT(*)").Where("tag_id IN (?)", tagIDs).Group("tag_id").Rows()
+		if err != nil {
+			return nil, dbError(ErrNotFound, err)
+		}
+
+		for rows.Next() {
+			var tagID int32
+			var count int
+			if err = rows.Scan(&tagID, &count); err != nil {
+				rows.Close()
+				return nil, dbError(ErrNotFound, err)
+			}
+			usage[tagID][table] = count
+		}
+		rows.Close()
+	}
+
+	return usage, nil
+}
+
+func tagJoin(table string) (join string, column string) {
+	return taggedTables[table], table + "_id"
+}
+
+// tagsOf returns the tags attached to records of a tagged table, keyed by record id
+func tagsOf(db *gorm.DB, table string, ids []int32) (map[int32]model.TagList, error) {
+	result := make(map[int32]model.TagList)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	join, column := tagJoin(table)
+	rows, err := db.Table(join).Select(column+", tag_id").Where(column+" IN (?)", ids).Order(column + ", position").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type link struct{ recordID, tagID int32 }
+	var links []link
+	var tagIDs []int32
+	for rows.Next() {
+		var l link
+		if err = rows.Scan(&l.recordID, &l.tagID); err != nil {
+			return nil, err
+		}
+		links = append(links, l)
+		tagIDs = append(tagIDs, l.tagID)
+	}
+
+	if len(links) == 0 {
+		return result, nil
+	}
+
+	var tags []*model.Tags
+	if err = db.Where("id IN (?)", tagIDs).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int32]*model.Tags, len(tags))
+	for _, tag := range tags {
+		byID[tag.ID] = tag
+	}
+
+	for _, l := range links {
+		if tag, ok := byID[l.tagID]; ok {
+			result[l.recordID] = append(result[l.recordID], tag)
+		}
+	}
+
+	return result, nil
+}
+
+// loadTags fills the tag list of records of a tagged table
+func loadTags(db *gorm.DB, table string, records ...model.Model) error {
+	if _, ok := taggedTables[table]; !ok || len(records) == 0 {
+		return nil
+	}
+
+	ids := make([]int32, 0, len(records))
+	for _, record := range records {
+		ids = append(ids, recordID(record))
+	}
+
+	tags, err := tagsOf(db, table, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		list, ok := tags[recordID(record)]
+		if !ok {
+			list = model.TagList{}
+		}
+		reflect.Indirect(reflect.ValueOf(record)).FieldByName("Tags").Set(reflect.ValueOf(list))
+	}
+
+	return nil
+}
+
+// syncTags replaces the tags linked to record with its tag list, creating tags that do not exist yet.
+// The tag list of record is replaced by the stored tags.
+// error - ErrBadParams, tag list references an unknown tag id
+// error - ErrUpdateFailed, db link update failed
+func syncTags(db *gorm.DB, table string, record model.Model) error {
+	if _, ok := taggedTables[table]; !ok {
+		return nil
+	}
+
+	field := reflect.Indirect(reflect.ValueOf(record)).FieldByName("Tags")
+	list, _ := field.Interface().(model.TagList)
+
+	resolved := model.TagList{}
+	seen := make(map[int32]bool)
+	for _, tag := range list {
+		stored, err := resolveTag(db, tag)
+		if err != nil {
+			return err
+		}
+
+		if stored == nil || seen[stored.ID] {
+			continue
+		}
+		seen[stored.ID] = true
+		resolved = append(resolved, stored)
+	}
+
+	id := recordID(record)
+	if err := clearTags(db, table, id); err != nil {
+		return err
+	}
+
+	join, column := tagJoin(table)
+	for position, tag := range resolved {
+		err := db.Exec(fmt.Sprintf("INSERT INTO %s (%s, tag_id, position) VALUES (?, ?, ?)", join, column), id, tag.ID, position).Error
+		if err != nil {
+			return dbError(ErrUpdateFailed, err)
+		}
+	}
+
+	field.Set(reflect.ValueOf(resolved))
+	return nil
+}
+
+// copyTags copies the tag list of src into dst for records of tagged tables
+func copyTags(table string, dst model.Model, src model.Model) {
+	if _, ok := taggedTables[table]; !ok {
+		return
+	}
+
+	reflect.Indirect(reflect.ValueOf(dst)).FieldByName("Tags").Set(reflect.Indirect(reflect.ValueOf(src)).FieldByName("Tags"))
+}
+
+// clearTags removes every tag link of a record of a tagged table
+func clearTags(db *gorm.DB, table string, id int32) error {
+	if _, ok := taggedTables[table]; !ok {
+		return nil
+	}
+
+	join, column := tagJoin(table)
+	if err := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", join, column), id).Error; err != nil {
+		return dbError(ErrUpdateFailed, err)
+	}
+
+	return nil
+}
+
+// resolveTag finds the stored tag referenced by id, slug or name, creating it when only a name or slug is given.
+// A name matches the tag of the same name, ignoring case, or the tag whose slug it is. A new tag whose slug derived
+// from its name is taken by a differently named tag gets a numbered slug, so "C++" and "C#" stay two tags.
+// A nil tag is returned for entries without any usable reference.
+// error - ErrBadParams, id references an unknown tag
+// error - ValidationError, name has no letter or digit to derive a slug from
+func resolveTag(db *gorm.DB, tag *model.Tags) (*model.Tags, error) {
+	if tag == nil {
+		return nil, nil
+	}
+
+	stored := &model.Tags{}
+	if tag.ID > 0 {
+		if err := db.First(stored, tag.ID).Error; err != nil {
+			return nil, ErrBadParams
+		}
+		return stored, nil
+	}
+
+	explicit := strings.TrimSpace(tag.Slug) != ""
+	tag.Prepare()
+	if tag.Name == "" && tag.Slug == "" {
+		return nil, nil
+	}
+
+	if tag.Slug == "" {
+		v := &model.ValidationError{}
+		v.Add("tags", model.ErrCodeInvalid, "tag %q needs a letter or digit", tag.Name)
+		return nil, v.Err()
+	}
+
+	var err error
+	if explicit {
+		err = db.Where("slug = ?", tag.Slug).First(stored).Error
+	} else {
+		err = db.Where("LOWER(name) = LOWER(?) OR slug = ?", tag.Name, tag.Name).Order("id").First(stored).Error
+	}
+	if err == nil {
+		return stored, nil
+	}
+
+	if !gorm.IsRecordNotFoundError(err) {
+		return nil, dbError(ErrUpdateFailed, err)
+	}
+
+	if tag.Name == "" {
+		tag.Name = tag.Slug
+	}
+
+	if !explicit {
+		if tag.Slug, err = uniqueSlug(db, tag.Name, tag.Slug); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tag.Validate(model.Create); err != nil {
+		return nil, err
+	}
+
+	stored = &model.Tags{Name: tag.Name, Slug: tag.Slug, Color: tag.Color}
+	if err = db.Create(stored).Error; err != nil {
+		return nil, dbError(ErrInsertFailed, err)
+	}
+
+	return stored, nil
+}
+
+// uniqueSlug returns slug when no tag holds it yet or the tag holding it is named name, ignoring case.
+// Otherwise the first free slug numbered from slug-2 on is returned.
+func uniqueSlug(db *gorm.DB, name, slug string) (string, error) {
+	candidate := slug
+	for n := 2; ; n++ {
+		holder := &model.Tags{}
+		err := db.Where("slug = ?", candidate).First(holder).Error
+		if gorm.IsRecordNotFoundError(err) {
+			return candidate, nil
+		}
+		if err != nil {
+			return "", dbError(ErrUpdateFailed, err)
+		}
+		if strings.EqualFold(holder.Name, name) {
+			return candidate, nil
+		}
+
+		suffix := fmt.Sprintf("-%d", n)
+		base := []rune(slug)
+		if max := 64 - len(suffix); len(base) > max {
+			base = base[:max]
+		}
+		candidate = strings.TrimSuffix(string(base), "-") + suffix
+	}
+}
+
+// MigrateTags is a function to move the legacy pipe delimited tags columns of news and events into the tags table.
+// Each tagged table is migrated in one transaction, after which its legacy tags column is dropped.
+// A legacy tag without a letter or digit has no slug to be stored under, it is dropped and returned in skipped as
+// "<table> <id>: <name>" for the caller to log.
+// The migration runs at startup and is bounded by ctx only, not by the operation timeouts.
+// Tables without a tags column have been migrated already and are skipped.
+func MigrateTags(ctx context.Context) (skipped []string, err error) {
+	conn, done, err := dbMigrate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	for table := range taggedTables {
+		if !conn.Dialect().HasColumn(table, "tags") {
+			continue
+		}
+
+		err := transaction(conn, func(tx *gorm.DB) error {
+			moved, err := migrateTableTags(tx, table)
+			skipped = append(skipped, moved...)
+			return err
+		})
+		if err != nil {
+			return skipped, err
+		}
+
+		if err = conn.Table(table).DropColumn("tags").Error; err != nil {
+			return skipped, err
+		}
+		invalidate(ctx, table, "tags", taggedTables[table])
+	}
+
+	return skipped, nil
+}
+
+// migrateTableTags links the records of table to the tags named in its legacy tags column.
+// skipped lists the legacy tags without a slug, which are left out.
+func migrateTableTags(tx *gorm.DB, table string) (skipped []string, err error) {
+	rows, err := tx.Table(table).Select("id, tags").Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	legacy := make(map[int32]string)
+	for rows.Next() {
+		var id int32
+		var tags string
+		if err = rows.Scan(&id, &tags); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		legacy[id] = tags
+	}
+	rows.Close()
+
+	for id, names := range legacy {
+		tags := model.TagList{}
+		for _, tag := range model.ParseTagNames(names) {
+			if model.Slugify(tag.Name) == "" {
+				skipped = append(skipped, fmt.Sprintf("%s %d: %q", table, id, tag.Name))
+				continue
+			}
+			tags = append(tags, tag)
+		}
+
+		record, _ := model.NewRecord(table)
+		reflect.Indirect(reflect.ValueOf(record)).FieldByName("ID").SetInt(int64(id))
+		reflect.Indirect(reflect.ValueOf(record)).FieldByName("Tags").Set(reflect.ValueOf(tags))
+		if err = syncTags(tx, table, record); err != nil {
+			return skipped, fmt.Errorf("migrate tags of %s %d: %v", table, id, err)
+		}
+	}
+	return skipped, nil
+}
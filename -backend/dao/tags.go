@@ -0,0 +1,195 @@
+package dao
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"wcs/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+// GetAllTags is a function to get a slice of record(s) from tags table in the wcs database
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of records in a page  (defaults to 20)
+// params - order    - db sort order column
+// error - ErrNotFound, db Find error
+func GetAllTags(ctx context.Context, page, pagesize int64, order string) (results []*model.Tags, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	resultOrm := conn.Model(&model.Tags{})
+	resultOrm.Count(&totalRows)
+
+	if page > 0 {
+		offset := (page - 1) * pagesize
+		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
+	} else {
+		resultOrm = resultOrm.Limit(pagesize)
+	}
+
+	if order != "" {
+		resultOrm = resultOrm.Order(order)
+	}
+
+	if err = resultOrm.Find(&results).Error; err != nil {
+		err = dbError(ErrNotFound, err)
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// GetTags is a function to get a single record from the tags table in the wcs database
+// error - ErrNotFound, db Find error
+func GetTags(ctx context.Context, argID int32) (record *model.Tags, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Tags{}
+	if err = conn.First(record, argID).Error; err != nil {
+		err = dbError(ErrNotFound, err)
+		return record, err
+	}
+
+	return record, nil
+}
+
+// AddTags is a function to add a single record to tags table in the wcs database
+// A slug derived from the name that is taken by a differently named tag is numbered, e.g. c-2.
+// error - ErrInsertFailed, db save call failed
+func AddTags(ctx context.Context, record *model.Tags) (result *model.Tags, RowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if record.Slug == model.Slugify(record.Name) {
+			if record.Slug, err = uniqueSlug(tx, record.Name, record.Slug); err != nil {
+				return err
+			}
+		}
+
+		db := tx.Save(record)
+		if db.Error != nil {
+			return dbError(ErrInsertFailed, db.Error)
+		}
+
+		RowsAffected = db.RowsAffected
+		return recordChange(ctx, tx, "tags", model.Create, record)
+	})
+	if err != nil {
+		return nil, -1, err
+	}
+
+	invalidate(ctx, "tags")
+	return record, RowsAffected, nil
+}
+
+// UpdateTags is a function to update a single record from tags table in the wcs database
+// Every client writable column is replaced by the value in updated, zero values included.
+// A non zero updated.Version must match the stored row version, otherwise the current record is returned with ErrVersionConflict.
+// error - ErrNotFound, db record for id not found
+// error - ErrVersionConflict, db record was modified since updated.Version was read
+// error - ErrUpdateFailed, db meta data copy failed or db update call failed
+func UpdateTags(ctx context.Context, argID int32, updated *model.Tags) (result *model.Tags, RowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	result = &model.Tags{}
+	db := conn.First(result, argID)
+	if err = db.Error; err != nil {
+		return nil, -1, dbError(ErrNotFound, err)
+	}
+
+	version := result.Version
+	if updated.Version != 0 && updated.Version != version {
+		return result, -1, ErrVersionConflict
+	}
+
+	if err = Replace(result, updated); err != nil {
+		return nil, -1, dbError(ErrUpdateFailed, err)
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if RowsAffected, err = saveVersioned(tx, result, version); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "tags", model.Update, result)
+	})
+	if errors.Is(err, ErrVersionConflict) {
+		if result, err = GetTags(ctx, argID); err != nil {
+			return nil, -1, err
+		}
+		return result, -1, ErrVersionConflict
+	}
+	if err != nil {
+		return nil, -1, err
+	}
+
+	invalidate(ctx, "tags")
+	return result, RowsAffected, nil
+}
+
+// DeleteTags is a function to delete a single record from tags table in the wcs database
+// The tag is detached from every news and events record it was linked to.
+// A non zero version must match the stored row version.
+// error - ErrNotFound, db Find error
+// error - ErrVersionConflict, db record was modified since version was read
+// error - ErrDeleteFailed, db Delete failed error
+func DeleteTags(ctx context.Context, argID int32, version int32) (rowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer done()
+
+	record := &model.Tags{}
+	db := conn.First(record, argID)
+	if db.Error != nil {
+		return -1, dbError(ErrNotFound, db.Error)
+	}
+
+	if version != 0 && version != record.Version {
+		return -1, ErrVersionConflict
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		for _, join := range taggedTables {
+			if err := tx.Exec("DELETE FROM "+join+" WHERE tag_id = ?", argID).Error; err != nil {
+				return dbError(ErrDeleteFailed, err)
+			}
+		}
+
+		if rowsAffected, err = deleteVersioned(tx, record, record.Version); err != nil {
+			return err
+		}
+		return recordChange(ctx, tx, "tags", model.Delete, record)
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	invalidate(ctx, "tags", "news_tags", "events_tags")
+	return rowsAffected, nil
+}
@@ -0,0 +1,309 @@
+package dao
+
+import (
+	"context"
+	"time"
+
+	"wcs/model"
+
+	"github.com/guregu/null"
+	"github.com/jinzhu/gorm"
+)
+
+// AddWebhook is a function to add a single record to webhooks table in the wcs database
+// error - ErrInsertFailed, db save call failed
+func AddWebhook(ctx context.Context, record *model.Webhooks) (result *model.Webhooks, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	if err = conn.Create(record).Error; err != nil {
+		return nil, dbError(ErrInsertFailed, err)
+	}
+
+	return record, nil
+}
+
+// GetAllWebhooks is a function to get a slice of record(s) from webhooks table in the wcs database
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of records in a page  (defaults to 20)
+// params - order    - db sort order column
+// error - ErrNotFound, db Find error
+func GetAllWebhooks(ctx context.Context, page, pagesize int64, order string) (results []*model.Webhooks, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	resultOrm := conn.Model(&model.Webhooks{})
+	resultOrm.Count(&totalRows)
+
+	if page > 0 {
+		offset := (page - 1) * pagesize
+		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
+	} else {
+		resultOrm = resultOrm.Limit(pagesize)
+	}
+
+	if order != "" {
+		resultOrm = resultOrm.Order(order)
+	}
+
+	results = make([]*model.Webhooks, 0)
+	if err = resultOrm.Find(&results).Error; err != nil {
+		err = dbError(ErrNotFound, err)
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// GetWebhook is a function to get a single record from the webhooks table in the wcs database
+// error - ErrNotFound, db Find error
+func GetWebhook(ctx context.Context, argID int32) (record *model.Webhooks, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.Webhooks{}
+	if err = conn.First(record, argID).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	return record, nil
+}
+
+// GetWatchingWebhooks is a function to get the webhooks of the webhooks table in the wcs database that are called when
+// a record of table is changed by action
+// error - ErrNotFound, db Find error
+func GetWatchingWebhooks(ctx context.Context, table string, action model.Action) (results []*model.Webhooks, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	var webhooks []*model.Webhooks
+	if err = conn.Where("paused = ?", false).Order("id").Find(&webhooks).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	results = make([]*model.Webhooks, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		if webhook.Watches(table, action) {
+			results = append(results, webhook)
+		}
+	}
+
+	return results, nil
+}
+
+// UpdateWebhook is a function to update a single record from webhooks table in the wcs database
+// Every client writable column is replaced by the value in updated, zero values included.
+// error - ErrNotFound, db record for id not found
+// error - ErrUpdateFailed, db meta data copy failed or db update call failed
+func UpdateWebhook(ctx context.Context, argID int32, updated *model.Webhooks) (result *model.Webhooks, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	result = &model.Webhooks{}
+	if err = conn.First(result, argID).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	if err = Replace(result, updated); err != nil {
+		return nil, dbError(ErrUpdateFailed, err)
+	}
+	result.UpdateTime = time.Now().UTC()
+
+	if err = conn.Save(result).Error; err != nil {
+		return nil, dbError(ErrUpdateFailed, err)
+	}
+
+	return result, nil
+}
+
+// DeleteWebhook is a function to delete a single record from webhooks table in the wcs database, with its deliveries
+// error - ErrNotFound, db Find error
+// error - ErrDeleteFailed, db Delete failed error
+func DeleteWebhook(ctx context.Context, argID int32) (rowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer done()
+
+	record := &model.Webhooks{}
+	if err = conn.First(record, argID).Error; err != nil {
+		return -1, dbError(ErrNotFound, err)
+	}
+
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", argID).Delete(&model.WebhookDeliveries{}).Error; err != nil {
+			return dbError(ErrDeleteFailed, err)
+		}
+
+		db := tx.Delete(record)
+		if db.Error != nil {
+			return dbError(ErrDeleteFailed, db.Error)
+		}
+		rowsAffected = db.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return -1, err
+	}
+
+	return rowsAffected, nil
+}
+
+// AddWebhookDelivery is a function to add a pending delivery to the webhook_deliveries table in the wcs database
+// error - ErrInsertFailed, db save call failed
+func AddWebhookDelivery(ctx context.Context, record *model.WebhookDeliveries) (result *model.WebhookDeliveries, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record.Status = model.WebhookPending
+	if err = conn.Create(record).Error; err != nil {
+		return nil, dbError(ErrInsertFailed, err)
+	}
+
+	return record, nil
+}
+
+// GetAllWebhookDeliveries is a function to get a slice of record(s) from webhook_deliveries table in the wcs database
+// params - page     - page requested (defaults to 0)
+// params - pagesize - number of records in a page  (defaults to 20)
+// params - order    - db sort order column
+// params - filters  - optional query filters, e.g. ForWebhook, WithStatus
+// error - ErrNotFound, db Find error
+func GetAllWebhookDeliveries(ctx context.Context, page, pagesize int64, order string, filters ...QueryFilter) (results []*model.WebhookDeliveries, totalRows int, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, -1, err
+	}
+	defer done()
+
+	resultOrm := conn.Model(&model.WebhookDeliveries{})
+	for _, filter := range filters {
+		resultOrm = filter(resultOrm)
+	}
+	resultOrm.Count(&totalRows)
+
+	if page > 0 {
+		offset := (page - 1) * pagesize
+		resultOrm = resultOrm.Offset(offset).Limit(pagesize)
+	} else {
+		resultOrm = resultOrm.Limit(pagesize)
+	}
+
+	if order != "" {
+		resultOrm = resultOrm.Order(order)
+	}
+
+	results = make([]*model.WebhookDeliveries, 0)
+	if err = resultOrm.Find(&results).Error; err != nil {
+		err = dbError(ErrNotFound, err)
+		return nil, -1, err
+	}
+
+	return results, totalRows, nil
+}
+
+// GetWebhookDelivery is a function to get a single record from the webhook_deliveries table in the wcs database
+// error - ErrNotFound, db Find error
+func GetWebhookDelivery(ctx context.Context, argID int32) (record *model.WebhookDeliveries, err error) {
+	conn, done, err := dbRead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.WebhookDeliveries{}
+	if err = conn.First(record, argID).Error; err != nil {
+		return nil, dbError(ErrNotFound, err)
+	}
+
+	return record, nil
+}
+
+// RecordWebhookAttempt is a function to add a delivery attempt to the history of a delivery of the webhook_deliveries table
+// in the wcs database and move the delivery to status: delivered when the attempt succeeded, dead when it was the last one,
+// pending otherwise
+// error - ErrNotFound, db record for id not found
+// error - ErrUpdateFailed, db update call failed
+func RecordWebhookAttempt(ctx context.Context, argID int32, attempt *model.WebhookAttempt, status string) (record *model.WebhookDeliveries, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+
+	record = &model.WebhookDeliveries{}
+	err = transaction(conn, func(tx *gorm.DB) error {
+		if err := forUpdate(tx).First(record, argID).Error; err != nil {
+			return dbError(ErrNotFound, err)
+		}
+
+		record.History = append(record.History, attempt)
+		record.Attempts++
+		record.ResponseCode = int32(attempt.StatusCode)
+		record.LastError = attempt.Error
+		record.Status = status
+		record.UpdateTime = attempt.At
+		if status == model.WebhookDelivered {
+			record.DeliveredAt = null.TimeFrom(attempt.At)
+		}
+
+		err := tx.Model(record).UpdateColumns(map[string]interface{}{
+			"history":       record.History,
+			"attempts":      record.Attempts,
+			"response_code": record.ResponseCode,
+			"last_error":    record.LastError,
+			"status":        record.Status,
+			"delivered_at":  record.DeliveredAt,
+			"update_time":   record.UpdateTime,
+		}).Error
+		return dbError(ErrUpdateFailed, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// PurgeWebhookDeliveries is a function to delete the deliveries of the webhook_deliveries table in the wcs database
+// that were delivered before before
+// error - ErrDeleteFailed, db delete call failed
+func PurgeWebhookDeliveries(ctx context.Context, before time.Time) (rowsAffected int64, err error) {
+	conn, done, err := dbWrite(ctx)
+	if err != nil {
+		return -1, err
+	}
+	defer done()
+
+	db := conn.Where("status = ? AND delivered_at < ?", model.WebhookDelivered, before.UTC()).Delete(&model.WebhookDeliveries{})
+	if db.Error != nil {
+		return -1, dbError(ErrDeleteFailed, db.Error)
+	}
+	return db.RowsAffected, nil
+}
+
+// ForWebhook filters the deliveries of the webhook webhookID
+func ForWebhook(webhookID int32) QueryFilter {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("webhook_id = ?", webhookID)
+	}
+}
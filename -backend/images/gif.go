@@ -0,0 +1,100 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+)
+
+var errBadGIF = errors.New("malformed gif stream")
+
+// gifLoopApps application extensions controlling animation loops, the only application extensions kept
+var gifLoopApps = map[string]bool{
+	"NETSCAPE2.0": true,
+	"ANIMEXTS1.0": true,
+}
+
+// stripGIF returns a copy of a gif file without its comment extensions and application extensions other than
+// the animation loop ones, which carry XMP, ICC profiles and editor data. Frames are copied untouched.
+func stripGIF(data []byte) ([]byte, error) {
+	if len(data) < 13 || (string(data[0:6]) != "GIF87a" && string(data[0:6]) != "GIF89a") {
+		return nil, errBadGIF
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	pos := 13
+	if flags := data[10]; flags&0x80 != 0 {
+		pos += 3 << (flags&0x07 + 1)
+	}
+	if pos > len(data) {
+		return nil, errBadGIF
+	}
+	out.Write(data[:pos])
+
+	for {
+		if pos >= len(data) {
+			return nil, errBadGIF
+		}
+
+		switch data[pos] {
+		case 0x3B: // trailer
+			out.WriteByte(0x3B)
+			return out.Bytes(), nil
+
+		case 0x21: // extension
+			if pos+2 > len(data) {
+				return nil, errBadGIF
+			}
+			end, err := skipSubBlocks(data, pos+2)
+			if err != nil {
+				return nil, err
+			}
+
+			keep := true
+			switch data[pos+1] {
+			case 0xFE: // comment
+				keep = false
+			case 0xFF: // application
+				keep = pos+14 <= len(data) && data[pos+2] == 11 && gifLoopApps[string(data[pos+3:pos+14])]
+			}
+			if keep {
+				out.Write(data[pos:end])
+			}
+			pos = end
+
+		case 0x2C: // image descriptor, local color table, lzw code size and image data
+			start := pos
+			if pos+10 > len(data) {
+				return nil, errBadGIF
+			}
+			flags := data[pos+9]
+			pos += 10
+			if flags&0x80 != 0 {
+				pos += 3 << (flags&0x07 + 1)
+			}
+			end, err := skipSubBlocks(data, pos+1)
+			if err != nil {
+				return nil, err
+			}
+			out.Write(data[start:end])
+			pos = end
+
+		default:
+			return nil, errBadGIF
+		}
+	}
+}
+
+// skipSubBlocks returns the position after the sub-blocks starting at pos and their terminator
+func skipSubBlocks(data []byte, pos int) (int, error) {
+	for {
+		if pos >= len(data) {
+			return 0, errBadGIF
+		}
+		size := int(data[pos])
+		pos++
+		if size == 0 {
+			return pos, nil
+		}
+		pos += size
+	}
+}
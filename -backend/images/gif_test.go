@@ -0,0 +1,95 @@
+package images
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"testing"
+)
+
+// testGIF returns a two frame looping gif carrying a comment and an XMP application extension before its first frame
+func testGIF(t *testing.T) []byte {
+	palette := color.Palette{color.Black, color.White}
+	anim := &gif.GIF{LoopCount: 0}
+	for i := 0; i < 2; i++ {
+		frame := image.NewPaletted(image.Rect(0, 0, 4, 4), palette)
+		frame.SetColorIndex(i, i, 1)
+		anim.Image = append(anim.Image, frame)
+		anim.Delay = append(anim.Delay, 10)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// header, screen descriptor and the global color table, if any, come first
+	head := 13
+	if data[10]&0x80 != 0 {
+		head += 3 << (data[10]&0x07 + 1)
+	}
+	var extra []byte
+	extra = append(extra, 0x21, 0xFE, 9)
+	extra = append(extra, "GPS 52,13"...)
+	extra = append(extra, 0)
+	extra = append(extra, 0x21, 0xFF, 11)
+	extra = append(extra, "XMP DataXMP"...)
+	extra = append(extra, 12)
+	extra = append(extra, "<x:xmpmeta/>"...)
+	extra = append(extra, 0)
+
+	return append(append(append([]byte(nil), data[:head]...), extra...), data[head:]...)
+}
+
+func TestStripGIF(t *testing.T) {
+	data := testGIF(t)
+	if _, err := gif.DecodeAll(bytes.NewReader(data)); err != nil {
+		t.Fatalf("test gif does not decode: %v", err)
+	}
+
+	stripped, err := stripGIF(data)
+	if err != nil {
+		t.Fatalf("stripGIF: %v", err)
+	}
+
+	for _, leak := range []string{"GPS 52,13", "XMP DataXMP", "xmpmeta"} {
+		if bytes.Contains(stripped, []byte(leak)) {
+			t.Errorf("stripped gif still contains %q", leak)
+		}
+	}
+	if !bytes.Contains(stripped, []byte("NETSCAPE2.0")) {
+		t.Error("stripped gif lost its loop extension")
+	}
+
+	anim, err := gif.DecodeAll(bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatalf("stripped gif does not decode: %v", err)
+	}
+	if len(anim.Image) != 2 || anim.Image[1].ColorIndexAt(1, 1) != 1 {
+		t.Errorf("stripped gif has %d frames or lost their pixels", len(anim.Image))
+	}
+}
+
+func TestStripGIFMalformed(t *testing.T) {
+	data := testGIF(t)
+	for _, bad := range [][]byte{nil, []byte("GIF89a"), data[:len(data)-1], append([]byte("PNG89a"), data[6:]...)} {
+		if _, err := stripGIF(bad); err == nil {
+			t.Errorf("stripGIF accepted %d malformed bytes", len(bad))
+		}
+	}
+}
+
+func TestProcessGIF(t *testing.T) {
+	result, err := Process(bytes.NewReader(testGIF(t)), "image/gif")
+	if err != nil {
+		t.Fatalf("Process: %v", err)
+	}
+	if bytes.Contains(result.Data, []byte("GPS 52,13")) {
+		t.Error("processed gif keeps its comment")
+	}
+	if result.Width != 4 || result.Height != 4 || result.Blurhash == "" {
+		t.Errorf("processed gif is %dx%d with blurhash %q", result.Width, result.Height, result.Blurhash)
+	}
+}
@@ -0,0 +1,152 @@
+// Package images cleans uploaded images and renders the resized variants pages pick from with srcset.
+// Variants are jpeg, or png for images with transparency, not webp: the standard library and x/image only decode webp,
+// and a lossless webp encoder would give photos larger variants than jpeg. Webp uploads are accepted as originals.
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"io"
+
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp" // register webp for image.Decode
+)
+
+// Widths target widths in pixels of the resized variants, images are never scaled up
+var Widths = []int{200, 600, 1200}
+
+// MaxPixels upper bound of width * height of an image accepted for processing, guards against decompression bombs
+var MaxPixels = 40_000_000
+
+// jpegQuality quality of re-encoded originals and jpeg variants
+const jpegQuality = 85
+
+// ErrTooManyPixels error when the dimensions of an image exceed MaxPixels
+var ErrTooManyPixels = errors.New("image dimensions exceed limit")
+
+// processable content types Process accepts
+var processable = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
+// Variant resized copy of an image
+type Variant struct {
+	Width, Height int
+
+	// MimeType image/jpeg, or image/png for images with transparency
+	MimeType string
+
+	// Ext file extension matching MimeType
+	Ext string
+
+	Data []byte
+}
+
+// Result cleaned original and variants of an uploaded image
+type Result struct {
+	// Data the original with metadata stripped and EXIF orientation applied to the pixels
+	Data []byte
+
+	// Width, Height dimensions of the original after orientation
+	Width, Height int
+
+	// Blurhash compact placeholder rendered while the image loads
+	Blurhash string
+
+	// Variants resized copies, smallest first
+	Variants []*Variant
+}
+
+// Processable reports whether Process accepts content of mimeType
+func Processable(mimeType string) bool {
+	return processable[mimeType]
+}
+
+// Process strips EXIF, GPS and other metadata from an image of the given sniffed content type and renders its variants.
+// Jpeg and png originals are re-encoded, which drops every metadata segment, gif originals keep their frames and
+// animation but lose their comment and XMP extensions, and webp originals lose their EXIF and XMP chunks.
+func Process(r io.Reader, mimeType string) (*Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Width*config.Height > MaxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Width: img.Bounds().Dx(), Height: img.Bounds().Dy()}
+
+	switch mimeType {
+	case "image/jpeg":
+		result.Data, err = encode(img, imaging.JPEG)
+	case "image/png":
+		result.Data, err = encode(img, imaging.PNG)
+	case "image/gif":
+		// the variants show the first frame, the original keeps its animation
+		result.Data, err = stripGIF(data)
+	case "image/webp":
+		result.Data, err = stripWebP(data)
+	default:
+		return nil, errors.New("unsupported image type " + mimeType)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, width := range Widths {
+		if width >= result.Width {
+			break
+		}
+
+		variant, err := resize(img, width)
+		if err != nil {
+			return nil, err
+		}
+		result.Variants = append(result.Variants, variant)
+	}
+
+	result.Blurhash = Blurhash(imaging.Resize(img, 32, 0, imaging.Box), 4, 3)
+	return result, nil
+}
+
+// resize renders a variant of img scaled down to width, keeping the aspect ratio
+func resize(img image.Image, width int) (*Variant, error) {
+	scaled := imaging.Resize(img, width, 0, imaging.Lanczos)
+
+	variant := &Variant{Width: scaled.Bounds().Dx(), Height: scaled.Bounds().Dy(), MimeType: "image/jpeg", Ext: ".jpg"}
+	format := imaging.JPEG
+	if !scaled.Opaque() {
+		variant.MimeType, variant.Ext, format = "image/png", ".png", imaging.PNG
+	}
+
+	data, err := encode(scaled, format)
+	if err != nil {
+		return nil, err
+	}
+
+	variant.Data = data
+	return variant, nil
+}
+
+func encode(img image.Image, format imaging.Format) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := imaging.Encode(&buf, img, format, imaging.JPEGQuality(jpegQuality)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,62 @@
+package images
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+// vp8x feature flags announcing metadata chunks
+const (
+	vp8xEXIF = 1 << 3
+	vp8xXMP  = 1 << 2
+)
+
+var errBadWebP = errors.New("malformed webp container")
+
+// stripWebP returns a copy of a webp file without its EXIF and XMP chunks.
+// The image data chunks are copied untouched, the feature flags and RIFF size are updated to match.
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, errBadWebP
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[0:12])
+
+	for rest := data[12:]; len(rest) > 0; {
+		if len(rest) < 8 {
+			return nil, errBadWebP
+		}
+
+		fourCC := string(rest[0:4])
+		size := int(binary.LittleEndian.Uint32(rest[4:8]))
+		end := 8 + size + size&1
+		if end > len(rest) {
+			// tolerate a missing pad byte on the last chunk
+			if end-1 != len(rest) {
+				return nil, errBadWebP
+			}
+			end = len(rest)
+		}
+
+		chunk := rest[:end]
+		rest = rest[end:]
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+			continue
+		case "VP8X":
+			if size < 1 {
+				return nil, errBadWebP
+			}
+			chunk = append([]byte(nil), chunk...)
+			chunk[8] &^= vp8xEXIF | vp8xXMP
+		}
+		out.Write(chunk)
+	}
+
+	stripped := out.Bytes()
+	binary.LittleEndian.PutUint32(stripped[4:8], uint32(len(stripped)-8))
+	return stripped, nil
+}
@@ -0,0 +1,143 @@
+// Package jobs runs the background jobs of the jobs table: due jobs are claimed, handed to the handler of their kind,
+// and retried with backoff when it fails. Jobs live in the database, so they survive restarts and
+// several servers may run a Runner against the same database.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"wcs/dao"
+	"wcs/model"
+)
+
+// Handler runs a claimed job, an error schedules another attempt.
+// Handlers may run more than once for the same job and should tolerate that.
+type Handler func(ctx context.Context, job *model.Jobs) error
+
+// Planner is called on every poll before due jobs are claimed, to enqueue the jobs that became due by now
+type Planner func(ctx context.Context, now time.Time) error
+
+// Runner polls the jobs table and runs due jobs one after the other
+type Runner struct {
+	// Batch jobs claimed per poll
+	Batch int
+	// Lease time a claimed job may run before another worker takes it over
+	Lease time.Duration
+	// Retention time done jobs are kept, releasing their unique keys when they are purged
+	Retention time.Duration
+
+	handlers map[string]Handler
+	planners []Planner
+	wake     chan struct{}
+}
+
+// NewRunner returns a Runner without handlers
+func NewRunner() *Runner {
+	return &Runner{
+		Batch:     20,
+		Lease:     5 * time.Minute,
+		Retention: 30 * 24 * time.Hour,
+		handlers:  make(map[string]Handler),
+		wake:      make(chan struct{}, 1),
+	}
+}
+
+// Handle sets the handler of the jobs of kind
+func (r *Runner) Handle(kind string, handler Handler) {
+	r.handlers[kind] = handler
+}
+
+// Plan adds a planner run on every poll
+func (r *Runner) Plan(planner Planner) {
+	r.planners = append(r.planners, planner)
+}
+
+// Run polls for due jobs every interval until ctx is done, ctx carries the database
+func (r *Runner) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		r.Poll(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// Wake has Run poll right away instead of at the next tick, for jobs enqueued to run now
+func (r *Runner) Wake() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Poll runs the planners, then the jobs due at now, batch by batch until none is left
+func (r *Runner) Poll(ctx context.Context, now time.Time) {
+	for _, planner := range r.planners {
+		if err := planner(ctx, now); err != nil {
+			log.Printf("Got error when planning jobs, the error is '%v'", err)
+		}
+	}
+
+	for {
+		claimed, err := dao.ClaimJobs(ctx, now, r.Batch, r.Lease)
+		if err != nil {
+			log.Printf("Got error when claiming jobs, the error is '%v'", err)
+			return
+		}
+
+		for _, job := range claimed {
+			r.run(ctx, job)
+		}
+
+		if len(claimed) < r.Batch || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if _, err := dao.PurgeJobs(ctx, now.Add(-r.Retention)); err != nil {
+		log.Printf("Got error when purging done jobs, the error is '%v'", err)
+	}
+}
+
+func (r *Runner) run(ctx context.Context, job *model.Jobs) {
+	err := r.call(ctx, job)
+	if err == nil {
+		if err = dao.CompleteJob(ctx, job, time.Now()); err != nil {
+			log.Printf("Got error when completing job %d, the error is '%v'", job.ID, err)
+		}
+		return
+	}
+
+	log.Printf("Got error when running %s job %d, attempt %d of %d, the error is '%v'", job.Kind, job.ID, job.Attempts, job.MaxAttempts, err)
+	if err = dao.FailJob(ctx, job, err, time.Now()); err != nil {
+		log.Printf("Got error when rescheduling job %d, the error is '%v'", job.ID, err)
+	}
+}
+
+// call runs the handler of job, turning a panic into an error so one bad job does not stop the runner
+func (r *Runner) call(ctx context.Context, job *model.Jobs) (err error) {
+	handler, ok := r.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("no handler for jobs of kind %q", job.Kind)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+
+	jobCtx, cancel := context.WithTimeout(ctx, r.Lease)
+	defer cancel()
+	return handler(jobCtx, job)
+}
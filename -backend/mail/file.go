@@ -0,0 +1,54 @@
+package mail
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+// File keeps messages instead of sending them, for development: each one is written to Dir as an .eml file
+// that mail clients open, and logged. Without Dir the whole text body goes to the log.
+type File struct {
+	Dir  string
+	From string
+}
+
+// NewFile returns a File mailer writing to dir, creating the directory when missing. An empty dir logs messages only.
+func NewFile(dir, from string) (*File, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &File{Dir: dir, From: from}, nil
+}
+
+// Send writes msg to a new file in Dir and logs it
+func (f *File) Send(ctx context.Context, msg *Message) error {
+	if f.Dir == "" {
+		log.Printf("Mail to %s: %s\n%s", msg.To, msg.Subject, msg.Text)
+		return nil
+	}
+
+	now := time.Now()
+	data, err := compose(f.From, msg, now)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp(f.Dir, now.Format("20060102-150405")+"-*.eml")
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	log.Printf("Mail to %s: %s, written to %s", msg.To, msg.Subject, file.Name())
+	return nil
+}
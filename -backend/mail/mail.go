@@ -0,0 +1,114 @@
+// Package mail sends the emails of the site through a Mailer: Mailgun, an SMTP server, or files and the log during development.
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+// Message email to a single recipient, with a plain text body and optionally an html alternative of it
+type Message struct {
+	To      string
+	ReplyTo string
+	Subject string
+	Text    string
+	HTML    string
+	// Unsubscribe url a POST to unsubscribes the recipient in one click (RFC 8058), set on mailing list mails
+	Unsubscribe string
+}
+
+// Mailer sends messages from the address it is configured with
+type Mailer interface {
+	// Send delivers msg, or hands it to a relay that will
+	Send(ctx context.Context, msg *Message) error
+}
+
+var headerBreaks = strings.NewReplacer("\r", "", "\n", "")
+
+// compose returns msg as an RFC 5322 message from from, multipart/alternative when it has an html body
+func compose(from string, msg *Message, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(key, value string) {
+		// addresses come from forms, line breaks in them must not start headers of their own
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, headerBreaks.Replace(value))
+	}
+
+	header("From", from)
+	header("To", msg.To)
+	if msg.ReplyTo != "" {
+		header("Reply-To", msg.ReplyTo)
+	}
+	if msg.Unsubscribe != "" {
+		header("List-Unsubscribe", "<"+msg.Unsubscribe+">")
+		header("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", messageID(from))
+	header("MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		header("Content-Type", "text/plain; charset=utf-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuoted(&buf, msg.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	parts := multipart.NewWriter(&buf)
+	header("Content-Type", "multipart/alternative; boundary="+parts.Boundary())
+	buf.WriteString("\r\n")
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err = writeQuoted(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeQuoted writes body quoted-printable encoded with CRLF line ends
+func writeQuoted(w interface{ Write([]byte) (int, error) }, body string) error {
+	q := quotedprintable.NewWriter(w)
+	if _, err := q.Write([]byte(strings.ReplaceAll(body, "\n", "\r\n"))); err != nil {
+		return err
+	}
+	return q.Close()
+}
+
+// messageID returns a unique Message-ID in the domain of the from address
+func messageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 {
+		domain = strings.TrimSuffix(from[at+1:], ">")
+	}
+
+	random := make([]byte, 12)
+	rand.Read(random)
+	return "<" + hex.EncodeToString(random) + "@" + domain + ">"
+}
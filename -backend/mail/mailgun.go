@@ -0,0 +1,46 @@
+package mail
+
+import (
+	"context"
+	"time"
+
+	"github.com/mailgun/mailgun-go/v4"
+)
+
+// Mailgun sends messages through the Mailgun api of a sending domain
+type Mailgun struct {
+	From    string
+	Timeout time.Duration
+
+	client *mailgun.MailgunImpl
+}
+
+// NewMailgun returns a Mailgun sending as from through domain, apiBase selects the region, e.g. mailgun.APIBaseEU, empty for the default
+func NewMailgun(domain, apiKey, apiBase, from string) *Mailgun {
+	client := mailgun.NewMailgun(domain, apiKey)
+	if apiBase != "" {
+		client.SetAPIBase(apiBase)
+	}
+	return &Mailgun{From: from, Timeout: 10 * time.Second, client: client}
+}
+
+// Send posts msg to the messages endpoint of the domain
+func (m *Mailgun) Send(ctx context.Context, msg *Message) error {
+	message := m.client.NewMessage(m.From, msg.Subject, msg.Text, msg.To)
+	if msg.HTML != "" {
+		message.SetHtml(msg.HTML)
+	}
+	if msg.ReplyTo != "" {
+		message.SetReplyTo(msg.ReplyTo)
+	}
+	if msg.Unsubscribe != "" {
+		message.AddHeader("List-Unsubscribe", "<"+msg.Unsubscribe+">")
+		message.AddHeader("List-Unsubscribe-Post", "List-Unsubscribe=One-Click")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	_, _, err := m.client.Send(ctx, message)
+	return err
+}
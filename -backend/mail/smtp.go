@@ -0,0 +1,99 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTP sends messages through an SMTP server, upgrading the connection with STARTTLS when the server offers it.
+// Username and Password are used for PLAIN authentication when set, which net/smtp only allows over TLS or to localhost.
+type SMTP struct {
+	// Addr host:port of the server, e.g. smtp.example.org:587 or 127.0.0.1:1025 for a local stand-in
+	Addr     string
+	Username string
+	Password string
+	From     string
+	Timeout  time.Duration
+}
+
+// NewSMTP returns an SMTP mailer sending as from through the server at addr
+func NewSMTP(addr, username, password, from string) *SMTP {
+	return &SMTP{Addr: addr, Username: username, Password: password, From: from, Timeout: 10 * time.Second}
+}
+
+// Send delivers msg in a single SMTP session
+func (s *SMTP) Send(ctx context.Context, msg *Message) error {
+	from, err := netmail.ParseAddress(s.From)
+	if err != nil {
+		return err
+	}
+	to, err := netmail.ParseAddress(msg.To)
+	if err != nil {
+		return err
+	}
+
+	data, err := compose(s.From, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+
+	if s.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", s.Username, s.Password, host)); err != nil {
+			return err
+		}
+	}
+
+	if err = client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err = client.Rcpt(to.Address); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(data); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,201 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	netmail "net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpSession envelope and data of a message received by the stand-in
+type smtpSession struct {
+	Auth string
+	From string
+	To   []string
+	Data []byte
+}
+
+// smtpStandIn accepts one SMTP session on a local port, without STARTTLS, and sends what it received on the channel
+func smtpStandIn(t *testing.T) (addr string, sessions <-chan *smtpSession) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	ch := make(chan *smtpSession, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		c.SetDeadline(time.Now().Add(5 * time.Second))
+
+		conn := textproto.NewConn(c)
+		session := &smtpSession{}
+		conn.PrintfLine("220 localhost stand-in")
+		for {
+			line, err := conn.ReadLine()
+			if err != nil {
+				return
+			}
+			verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch verb {
+			case "EHLO", "HELO":
+				conn.PrintfLine("250-localhost")
+				conn.PrintfLine("250 AUTH PLAIN")
+			case "AUTH":
+				fields := strings.Fields(line)
+				if len(fields) == 3 {
+					auth, _ := base64.StdEncoding.DecodeString(fields[2])
+					session.Auth = string(auth)
+				}
+				conn.PrintfLine("235 authenticated")
+			case "MAIL":
+				session.From = line[len("MAIL FROM:"):]
+				conn.PrintfLine("250 ok")
+			case "RCPT":
+				session.To = append(session.To, line[len("RCPT TO:"):])
+				conn.PrintfLine("250 ok")
+			case "DATA":
+				conn.PrintfLine("354 go ahead")
+				if session.Data, err = conn.ReadDotBytes(); err != nil {
+					return
+				}
+				conn.PrintfLine("250 queued")
+			case "QUIT":
+				conn.PrintfLine("221 bye")
+				ch <- session
+				return
+			default:
+				conn.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+	return l.Addr().String(), ch
+}
+
+func receive(t *testing.T, sessions <-chan *smtpSession) *smtpSession {
+	t.Helper()
+	select {
+	case session := <-sessions:
+		return session
+	case <-time.After(5 * time.Second):
+		t.Fatal("stand-in received no session")
+		return nil
+	}
+}
+
+// decodeQuoted returns the quoted-printable body of r with LF line ends
+func decodeQuoted(t *testing.T, r io.Reader) string {
+	t.Helper()
+	body, err := io.ReadAll(quotedprintable.NewReader(r))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.ReplaceAll(string(body), "\r\n", "\n")
+}
+
+func TestSMTPSendText(t *testing.T) {
+	addr, sessions := smtpStandIn(t)
+	mailer := NewSMTP(addr, "wcs", "secret", "WCS <noreply@wcs.example.org>")
+
+	text := "Hello Ada,\n\nyou are registered, the café opens at 9.\nSee you there!"
+	err := mailer.Send(context.Background(), &Message{To: "ada@example.org", Subject: "Registration confirmed", Text: text})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session := receive(t, sessions)
+	if session.Auth != "\x00wcs\x00secret" {
+		t.Errorf("auth is %q, want PLAIN credentials of wcs", session.Auth)
+	}
+	if session.From != "<noreply@wcs.example.org>" || len(session.To) != 1 || session.To[0] != "<ada@example.org>" {
+		t.Errorf("envelope is from %s to %v", session.From, session.To)
+	}
+
+	msg, err := netmail.ReadMessage(bytes.NewReader(session.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type is %q", got)
+	}
+	// the line end before the terminating dot belongs to the DATA framing, not to the body
+	if got := strings.TrimSuffix(decodeQuoted(t, msg.Body), "\n"); got != text {
+		t.Errorf("body is %q, want %q", got, text)
+	}
+}
+
+func TestSMTPSendAlternative(t *testing.T) {
+	addr, sessions := smtpStandIn(t)
+	mailer := NewSMTP(addr, "", "", "noreply@wcs.example.org")
+
+	msg := &Message{To: "ada@example.org", Subject: "Newsletter", Text: "plain news", HTML: "<p>html news</p>",
+		Unsubscribe: "https://wcs.example.org/unsubscribe?token=x"}
+	if err := mailer.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	session := receive(t, sessions)
+	if session.Auth != "" {
+		t.Errorf("authenticated without credentials")
+	}
+
+	received, err := netmail.ReadMessage(bytes.NewReader(session.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := received.Header.Get("List-Unsubscribe"); got != "<"+msg.Unsubscribe+">" {
+		t.Errorf("List-Unsubscribe is %q", got)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(received.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type is %q", received.Header.Get("Content-Type"))
+	}
+
+	var bodies []string
+	parts := multipart.NewReader(received.Body, params["boundary"])
+	for {
+		part, err := parts.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		bodies = append(bodies, part.Header.Get("Content-Type")+": "+decodeQuoted(t, part))
+	}
+
+	want := []string{"text/plain; charset=utf-8: plain news", "text/html; charset=utf-8: <p>html news</p>"}
+	if strings.Join(bodies, "\n") != strings.Join(want, "\n") {
+		t.Errorf("parts are %q, want %q", bodies, want)
+	}
+}
+
+func TestComposeHeaderBreaks(t *testing.T) {
+	data, err := compose("noreply@wcs.example.org", &Message{To: "ada@example.org\r\nBcc: eve@example.org", Subject: "hi", Text: "x"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, err := netmail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Header.Get("Bcc") != "" {
+		t.Errorf("line break in the address started a Bcc header")
+	}
+}
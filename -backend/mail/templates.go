@@ -0,0 +1,79 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"path"
+	"strings"
+	texttemplate "text/template"
+)
+
+// templates/<name>.txt defines the "subject" and the "text" body of message name,
+// templates/<name>.html, when present, its "content" in the html layout of templates/layout.html.
+// Templates of files starting with _ are shared by every message.
+//
+//go:embed templates/*
+var files embed.FS
+
+var (
+	textTemplates = map[string]*texttemplate.Template{}
+	htmlTemplates = map[string]*htmltemplate.Template{}
+)
+
+func init() {
+	text := texttemplate.Must(texttemplate.ParseFS(files, "templates/_*.txt"))
+	html := htmltemplate.Must(htmltemplate.ParseFS(files, "templates/layout.html", "templates/_*.html"))
+
+	names, _ := fs.Glob(files, "templates/*.txt")
+	for _, name := range names {
+		key := strings.TrimSuffix(path.Base(name), ".txt")
+		if strings.HasPrefix(key, "_") {
+			continue
+		}
+		textTemplates[key] = texttemplate.Must(texttemplate.Must(text.Clone()).ParseFS(files, name))
+	}
+
+	names, _ = fs.Glob(files, "templates/*.html")
+	for _, name := range names {
+		key := strings.TrimSuffix(path.Base(name), ".html")
+		if key == "layout" || strings.HasPrefix(key, "_") {
+			continue
+		}
+		htmlTemplates[key] = htmltemplate.Must(htmltemplate.Must(html.Clone()).ParseFS(files, name))
+	}
+}
+
+// Render returns message name for data, addressed to to
+func Render(name, to string, data interface{}) (*Message, error) {
+	text, ok := textTemplates[name]
+	if !ok {
+		return nil, fmt.Errorf("no mail template %q", name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := text.ExecuteTemplate(&body, "text", data); err != nil {
+		return nil, err
+	}
+
+	msg := &Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(body.String()) + "\n",
+	}
+
+	if html, ok := htmlTemplates[name]; ok {
+		var buf bytes.Buffer
+		if err := html.ExecuteTemplate(&buf, "layout", data); err != nil {
+			return nil, err
+		}
+		msg.HTML = buf.String()
+	}
+
+	return msg, nil
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/guregu/null"
@@ -24,6 +25,7 @@ CREATE TABLE `admin` (
   `id` int NOT NULL AUTO_INCREMENT COMMENT 'id',
   `username` varchar(128) DEFAULT NULL COMMENT 'user name',
   `password` varchar(256) DEFAULT NULL COMMENT 'password',
+  `version` int NOT NULL DEFAULT '1',
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB AUTO_INCREMENT=3 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='admin of system'
 
@@ -43,6 +45,8 @@ type Admin struct {
 	Username sql.NullString `gorm:"column:username;type:varchar;size:128;" json:"username"` // user name
 	//[ 2] password                                       varchar(256)         null: true   primary: false  isArray: false  auto: false  col: varchar         len: 256     default: []
 	Password sql.NullString `gorm:"column:password;type:varchar;size:256;" json:"password"` // password
+	//[ 3] version                                        int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [1]
+	Version int32 `gorm:"column:version;type:int;default:1;not null;" json:"version"` // row version used for optimistic concurrency
 
 }
 
@@ -112,6 +116,27 @@ var adminTableInfo = &TableInfo{
 			ProtobufType:       "string",
 			ProtobufPos:        3,
 		},
+
+		{
+			Index:              3,
+			Name:               "version",
+			Comment:            `row version used for optimistic concurrency`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Version",
+			GoFieldType:        "int32",
+			JSONFieldName:      "version",
+			ProtobufFieldName:  "version",
+			ProtobufType:       "int32",
+			ProtobufPos:        4,
+		},
 	},
 }
 
@@ -131,7 +156,19 @@ func (a *Admin) Prepare() {
 
 // Validate invoked before performing action, return an error if field is not populated.
 func (a *Admin) Validate(action Action) error {
-	return nil
+	if action != Create && action != Update {
+		return nil
+	}
+
+	v := ValidateColumns(a)
+	if strings.TrimSpace(a.Username.String) == "" {
+		v.Add("username", ErrCodeRequired, "username is required")
+	}
+	if a.Password.String == "" {
+		v.Add("password", ErrCodeRequired, "password is required")
+	}
+
+	return v.Err()
 }
 
 // TableInfo return table meta data
@@ -0,0 +1,396 @@
+package model
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/guregu/null"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+CREATE TABLE `contact_messages` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `name` varchar(128) NOT NULL COMMENT 'name the visitor gave',
+  `email` varchar(255) NOT NULL COMMENT 'email address replies go to',
+  `feedback` text NOT NULL COMMENT 'the message',
+  `ip_address` varchar(64) NOT NULL DEFAULT '' COMMENT 'address the message was sent from',
+  `user_agent` varchar(512) NOT NULL DEFAULT '' COMMENT 'browser the message was sent with',
+  `status` varchar(16) NOT NULL DEFAULT 'new' COMMENT 'new, read, replied, archived or quarantined',
+  `assigned_to` int DEFAULT NULL COMMENT 'id of the admin handling the message, null while unassigned',
+  `spam_score` int NOT NULL DEFAULT '0' COMMENT 'score of the spam heuristics, quarantined from the threshold on',
+  `spam_reasons` varchar(255) NOT NULL DEFAULT '' COMMENT 'heuristics adding up to the spam score',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_contact_messages_status` (`status`, `create_time`),
+  KEY `idx_contact_messages_assigned_to` (`assigned_to`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='messages sent through the contact form'
+
+JSON Sample
+-------------------------------------
+{    "id": 7,    "name": "Ada",    "email": "ada@example.org",    "feedback": "Is the seminar recorded?",    "ip_address": "203.0.113.7",    "user_agent": "Mozilla/5.0",    "status": "new",    "assigned_to": null,    "spam_score": 1,    "spam_reasons": "1 links",    "create_time": "2024-05-01T13:00:00Z",    "update_time": "2024-05-01T13:00:00Z"}
+
+
+
+*/
+
+// contact message statuses, archived and quarantined messages are hidden from the inbox unless asked for.
+// Quarantined messages looked like spam, they were not mailed to the admins.
+const (
+	ContactNew         = "new"
+	ContactRead        = "read"
+	ContactReplied     = "replied"
+	ContactArchived    = "archived"
+	ContactQuarantined = "quarantined"
+)
+
+// IsContactStatus reports whether status is a status of contact messages
+func IsContactStatus(status string) bool {
+	switch status {
+	case ContactNew, ContactRead, ContactReplied, ContactArchived, ContactQuarantined:
+		return true
+	}
+	return false
+}
+
+// ContactMessages struct is a row record of the contact_messages table in the wcs database
+type ContactMessages struct {
+	//[ 0] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
+	ID int32 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] name                                           varchar(128)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 128     default: []
+	Name string `gorm:"column:name;type:varchar(128);not null;" json:"name"` // name the visitor gave
+	//[ 2] email                                          varchar(255)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 255     default: []
+	Email string `gorm:"column:email;type:varchar(255);not null;" json:"email"` // email address replies go to
+	//[ 3] feedback                                       text(65535)          null: false  primary: false  isArray: false  auto: false  col: text            len: 65535   default: []
+	Feedback string `gorm:"column:feedback;type:text;not null;" json:"feedback"` // the message
+	//[ 4] ip_address                                     varchar(64)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 64      default: []
+	IPAddress string `gorm:"column:ip_address;type:varchar(64);default:'';not null;" json:"ip_address"` // address the message was sent from
+	//[ 5] user_agent                                     varchar(512)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 512     default: []
+	UserAgent string `gorm:"column:user_agent;type:varchar(512);default:'';not null;" json:"user_agent"` // browser the message was sent with
+	//[ 6] status                                         varchar(16)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 16      default: [new]
+	Status string `gorm:"column:status;type:varchar(16);default:'new';not null;index:idx_contact_messages_status;" json:"status"` // new, read, replied, archived or quarantined
+	//[ 7] assigned_to                                    int                  null: true   primary: false  isArray: false  auto: false  col: int             len: -1      default: []
+	AssignedTo null.Int `gorm:"column:assigned_to;type:int;index:idx_contact_messages_assigned_to;" json:"assigned_to"` // id of the admin handling the message, null while unassigned
+	//[ 8] spam_score                                     int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	SpamScore int32 `gorm:"column:spam_score;type:int;default:0;not null;" json:"spam_score"` // score of the spam heuristics, quarantined from the threshold on
+	//[ 9] spam_reasons                                   varchar(255)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 255     default: []
+	SpamReasons string `gorm:"column:spam_reasons;type:varchar(255);default:'';not null;" json:"spam_reasons"` // heuristics adding up to the spam score
+	//[10] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;index:idx_contact_messages_status;" json:"create_time"`
+	//[11] update_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	UpdateTime time.Time `gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"update_time"`
+}
+
+var contactMessagesTableInfo = &TableInfo{
+	Name: "contact_messages",
+	Columns: []*ColumnInfo{
+
+		{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		{
+			Index:              1,
+			Name:               "name",
+			Comment:            `name the visitor gave`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(128)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       128,
+			GoFieldName:        "Name",
+			GoFieldType:        "string",
+			JSONFieldName:      "name",
+			ProtobufFieldName:  "name",
+			ProtobufType:       "string",
+			ProtobufPos:        2,
+			Required:           true,
+		},
+
+		{
+			Index:              2,
+			Name:               "email",
+			Comment:            `email address replies go to`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       255,
+			GoFieldName:        "Email",
+			GoFieldType:        "string",
+			JSONFieldName:      "email",
+			ProtobufFieldName:  "email",
+			ProtobufType:       "string",
+			ProtobufPos:        3,
+			Required:           true,
+		},
+
+		{
+			Index:              3,
+			Name:               "feedback",
+			Comment:            `the message`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "text",
+			DatabaseTypePretty: "text",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "text",
+			ColumnLength:       65535,
+			GoFieldName:        "Feedback",
+			GoFieldType:        "string",
+			JSONFieldName:      "feedback",
+			ProtobufFieldName:  "feedback",
+			ProtobufType:       "string",
+			ProtobufPos:        4,
+			Required:           true,
+		},
+
+		{
+			Index:              4,
+			Name:               "ip_address",
+			Comment:            `address the message was sent from`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       64,
+			GoFieldName:        "IPAddress",
+			GoFieldType:        "string",
+			JSONFieldName:      "ip_address",
+			ProtobufFieldName:  "ip_address",
+			ProtobufType:       "string",
+			ProtobufPos:        5,
+		},
+
+		{
+			Index:              5,
+			Name:               "user_agent",
+			Comment:            `browser the message was sent with`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(512)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       512,
+			GoFieldName:        "UserAgent",
+			GoFieldType:        "string",
+			JSONFieldName:      "user_agent",
+			ProtobufFieldName:  "user_agent",
+			ProtobufType:       "string",
+			ProtobufPos:        6,
+		},
+
+		{
+			Index:              6,
+			Name:               "status",
+			Comment:            `new, read, replied, archived or quarantined`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(16)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       16,
+			GoFieldName:        "Status",
+			GoFieldType:        "string",
+			JSONFieldName:      "status",
+			ProtobufFieldName:  "status",
+			ProtobufType:       "string",
+			ProtobufPos:        7,
+		},
+
+		{
+			Index:              7,
+			Name:               "assigned_to",
+			Comment:            `id of the admin handling the message, null while unassigned`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "AssignedTo",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "assigned_to",
+			ProtobufFieldName:  "assigned_to",
+			ProtobufType:       "int32",
+			ProtobufPos:        8,
+		},
+
+		{
+			Index:              8,
+			Name:               "spam_score",
+			Comment:            `score of the spam heuristics, quarantined from the threshold on`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "SpamScore",
+			GoFieldType:        "int32",
+			JSONFieldName:      "spam_score",
+			ProtobufFieldName:  "spam_score",
+			ProtobufType:       "int32",
+			ProtobufPos:        9,
+		},
+
+		{
+			Index:              9,
+			Name:               "spam_reasons",
+			Comment:            `heuristics adding up to the spam score`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       255,
+			GoFieldName:        "SpamReasons",
+			GoFieldType:        "string",
+			JSONFieldName:      "spam_reasons",
+			ProtobufFieldName:  "spam_reasons",
+			ProtobufType:       "string",
+			ProtobufPos:        10,
+		},
+
+		{
+			Index:              10,
+			Name:               "create_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "CreateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "create_time",
+			ProtobufFieldName:  "create_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        11,
+		},
+
+		{
+			Index:              11,
+			Name:               "update_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "UpdateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "update_time",
+			ProtobufFieldName:  "update_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        12,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (c *ContactMessages) TableName() string {
+	return "contact_messages"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (c *ContactMessages) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+// The user agent is cut to its column rather than rejected, it is not the visitor's input.
+func (c *ContactMessages) Prepare() {
+	c.Name = strings.TrimSpace(c.Name)
+	c.Email = strings.TrimSpace(c.Email)
+	if utf8.RuneCountInString(c.UserAgent) > 512 {
+		c.UserAgent = string([]rune(c.UserAgent)[:512])
+	}
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (c *ContactMessages) Validate(action Action) error {
+	if action != Create {
+		return nil
+	}
+
+	v := ValidateColumns(c)
+	if c.Email != "" && !IsEmail(c.Email) {
+		v.Add("email", ErrCodeInvalid, "email must be an email address such as name@example.org")
+	}
+
+	return v.Err()
+}
+
+// TableInfo return table meta data
+func (c *ContactMessages) TableInfo() *TableInfo {
+	return contactMessagesTableInfo
+}
@@ -0,0 +1,325 @@
+package model
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/guregu/null"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+CREATE TABLE `digests` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `subject` varchar(255) NOT NULL COMMENT 'subject of the digest mails',
+  `status` varchar(16) NOT NULL DEFAULT 'sending' COMMENT 'sending, sent or cancelled',
+  `content` mediumtext COMMENT 'json of the news and events of the digest, fixed when it was composed',
+  `last_subscriber_id` int NOT NULL DEFAULT '0' COMMENT 'id of the last subscriber mailed, batches resume after it',
+  `recipients` int NOT NULL DEFAULT '0' COMMENT 'mails queued so far',
+  `sent_at` datetime DEFAULT NULL COMMENT 'time the last batch was queued, null while sending',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='newsletter digests and the progress of sending them'
+
+JSON Sample
+-------------------------------------
+{    "id": 3,    "subject": "WCS digest: 2 news, 3 upcoming events",    "status": "sending",    "content": {"news": [{"id": 12, "title": "New lab opens", "summary": "The lab ...", "url": "https://wcs.example.org/news?id=12"}], "events": [{"id": 7, "title": "Seminar", "when": "Thu 2 May 2024, 15:00 BST", "location": "Room 1", "url": "https://wcs.example.org/events?id=7"}]},    "last_subscriber_id": 150,    "recipients": 150,    "sent_at": null,    "create_time": "2024-05-01T08:00:00Z",    "update_time": "2024-05-01T08:03:00Z"}
+
+
+
+*/
+
+// digest statuses
+const (
+	DigestSending   = "sending"
+	DigestSent      = "sent"
+	DigestCancelled = "cancelled"
+)
+
+// Digests struct is a row record of the digests table in the wcs database
+type Digests struct {
+	//[ 0] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
+	ID int32 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] subject                                        varchar(255)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 255     default: []
+	Subject string `gorm:"column:subject;type:varchar(255);not null;" json:"subject"` // subject of the digest mails
+	//[ 2] status                                         varchar(16)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 16      default: [sending]
+	Status string `gorm:"column:status;type:varchar(16);default:'sending';not null;" json:"status"` // sending, sent or cancelled
+	//[ 3] content                                        mediumtext(16777215) null: true   primary: false  isArray: false  auto: false  col: mediumtext      len: 16777215default: []
+	Content DigestContent `gorm:"column:content;type:mediumtext;" json:"content"` // json of the news and events of the digest, fixed when it was composed
+	//[ 4] last_subscriber_id                             int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	LastSubscriberID int32 `gorm:"column:last_subscriber_id;type:int;default:0;not null;" json:"last_subscriber_id"` // id of the last subscriber mailed, batches resume after it
+	//[ 5] recipients                                     int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	Recipients int32 `gorm:"column:recipients;type:int;default:0;not null;" json:"recipients"` // mails queued so far
+	//[ 6] sent_at                                        datetime             null: true   primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	SentAt null.Time `gorm:"column:sent_at;type:datetime;" json:"sent_at"` // time the last batch was queued, null while sending
+	//[ 7] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"create_time"`
+	//[ 8] update_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	UpdateTime time.Time `gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"update_time"`
+}
+
+var digestsTableInfo = &TableInfo{
+	Name: "digests",
+	Columns: []*ColumnInfo{
+
+		{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		{
+			Index:              1,
+			Name:               "subject",
+			Comment:            `subject of the digest mails`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       255,
+			GoFieldName:        "Subject",
+			GoFieldType:        "string",
+			JSONFieldName:      "subject",
+			ProtobufFieldName:  "subject",
+			ProtobufType:       "string",
+			ProtobufPos:        2,
+			Required:           true,
+		},
+
+		{
+			Index:              2,
+			Name:               "status",
+			Comment:            `sending, sent or cancelled`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(16)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       16,
+			GoFieldName:        "Status",
+			GoFieldType:        "string",
+			JSONFieldName:      "status",
+			ProtobufFieldName:  "status",
+			ProtobufType:       "string",
+			ProtobufPos:        3,
+		},
+
+		{
+			Index:              3,
+			Name:               "content",
+			Comment:            `json of the news and events of the digest, fixed when it was composed`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "mediumtext",
+			DatabaseTypePretty: "mediumtext",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "mediumtext",
+			ColumnLength:       16777215,
+			GoFieldName:        "Content",
+			GoFieldType:        "DigestContent",
+			JSONFieldName:      "content",
+			ProtobufFieldName:  "content",
+			ProtobufType:       "string",
+			ProtobufPos:        4,
+		},
+
+		{
+			Index:              4,
+			Name:               "last_subscriber_id",
+			Comment:            `id of the last subscriber mailed, batches resume after it`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "LastSubscriberID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "last_subscriber_id",
+			ProtobufFieldName:  "last_subscriber_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        5,
+		},
+
+		{
+			Index:              5,
+			Name:               "recipients",
+			Comment:            `mails queued so far`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Recipients",
+			GoFieldType:        "int32",
+			JSONFieldName:      "recipients",
+			ProtobufFieldName:  "recipients",
+			ProtobufType:       "int32",
+			ProtobufPos:        6,
+		},
+
+		{
+			Index:              6,
+			Name:               "sent_at",
+			Comment:            `time the last batch was queued, null while sending`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "SentAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "sent_at",
+			ProtobufFieldName:  "sent_at",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        7,
+		},
+
+		{
+			Index:              7,
+			Name:               "create_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "CreateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "create_time",
+			ProtobufFieldName:  "create_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        8,
+		},
+
+		{
+			Index:              8,
+			Name:               "update_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "UpdateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "update_time",
+			ProtobufFieldName:  "update_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        9,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (d *Digests) TableName() string {
+	return "digests"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (d *Digests) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (d *Digests) Prepare() {
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (d *Digests) Validate(action Action) error {
+	return nil
+}
+
+// TableInfo return table meta data
+func (d *Digests) TableInfo() *TableInfo {
+	return digestsTableInfo
+}
+
+// DigestContent news and upcoming events a digest tells its subscribers about
+type DigestContent struct {
+	News   []*DigestItem `json:"news"`
+	Events []*DigestItem `json:"events"`
+}
+
+// DigestItem a news or event of a digest, with its text ready for the mail
+type DigestItem struct {
+	ID       int32  `json:"id"`
+	Title    string `json:"title"`
+	Summary  string `json:"summary,omitempty"`
+	When     string `json:"when,omitempty"`
+	Location string `json:"location,omitempty"`
+	URL      string `json:"url"`
+}
+
+// Empty reports whether there is nothing to tell
+func (c DigestContent) Empty() bool {
+	return len(c.News) == 0 && len(c.Events) == 0
+}
+
+// Value stores the content as json
+func (c DigestContent) Value() (driver.Value, error) {
+	data, err := json.Marshal(c)
+	return string(data), err
+}
+
+// Scan reads the content from json
+func (c *DigestContent) Scan(src interface{}) error {
+	return scanJSON(src, c)
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"database/sql"
+	"strings"
 	"time"
 
 	"github.com/guregu/null"
@@ -22,19 +23,32 @@ DB Table Details
 
 CREATE TABLE `events` (
   `cover` varchar(512) NOT NULL DEFAULT '',
-  `tags` varchar(1024) NOT NULL DEFAULT '',
   `update_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `content` longtext NOT NULL,
   `title` varchar(512) NOT NULL,
   `id` int NOT NULL AUTO_INCREMENT,
-  `event_time` bigint NOT NULL DEFAULT '0',
+  `start_time` datetime DEFAULT NULL COMMENT 'start of the event, or of its first occurrence when recurring',
+  `version` int NOT NULL DEFAULT '1',
+  `status` varchar(16) NOT NULL DEFAULT 'published' COMMENT 'publishing state: draft, scheduled, published or archived',
+  `publish_at` datetime DEFAULT NULL COMMENT 'time a scheduled record goes public',
+  `unpublish_at` datetime DEFAULT NULL COMMENT 'time a published record is archived',
+  `cover_media_id` int DEFAULT NULL COMMENT 'id of the uploaded cover in the media table',
+  `end_time` datetime DEFAULT NULL COMMENT 'end of the event, exclusive, or of its first occurrence when recurring',
+  `time_zone` varchar(64) NOT NULL DEFAULT 'UTC' COMMENT 'IANA time zone the event takes place in',
+  `all_day` tinyint(1) NOT NULL DEFAULT '0' COMMENT 'event lasts whole days, start and end are midnights in time_zone',
+  `location` varchar(512) NOT NULL DEFAULT '' COMMENT 'physical place of the event',
+  `online_url` varchar(512) NOT NULL DEFAULT '' COMMENT 'link to join the event online',
+  `rrule` varchar(512) NOT NULL DEFAULT '' COMMENT 'RFC 5545 recurrence rule, empty for single events',
+  `registration_open` tinyint(1) NOT NULL DEFAULT '0' COMMENT 'event takes registrations',
+  `capacity` int NOT NULL DEFAULT '0' COMMENT 'places for confirmed registrations, 0 when unlimited',
+  `questions` text COMMENT 'json list of the custom questions of the registration form',
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB AUTO_INCREMENT=16 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci
 
 JSON Sample
 -------------------------------------
-{    "cover": "PXvcMZAaVtykxdkaiPnFcLfhu",    "tags": "dymRLWPnwryPHEVWAKyjptSUC",    "update_time": "2208-11-03T15:07:41.739514237+08:00",    "create_time": "2057-01-07T00:22:49.758092343+08:00",    "content": "NctfhDQebYWmpAGapMOhaLiCk",    "title": "BVtvmnvgRGjXXVYoTuIjUZPqV",    "id": 35,    "event_time": 55}
+{    "cover": "PXvcMZAaVtykxdkaiPnFcLfhu",    "update_time": "2208-11-03T15:07:41.739514237+08:00",    "create_time": "2057-01-07T00:22:49.758092343+08:00",    "content": "NctfhDQebYWmpAGapMOhaLiCk",    "title": "BVtvmnvgRGjXXVYoTuIjUZPqV",    "id": 35,    "start_time": "2023-03-01T14:00:00Z",    "end_time": "2023-03-01T15:30:00Z",    "time_zone": "Europe/London",    "all_day": false,    "location": "Room 1.01",    "online_url": "",    "rrule": "FREQ=WEEKLY;COUNT=10",    "registration_open": true,    "capacity": 30,    "questions": [{"id": "dietary-requirements", "label": "Dietary requirements", "required": false}]}
 
 
 
@@ -44,20 +58,58 @@ JSON Sample
 type Events struct {
 	//[ 0] cover                                          varchar(512)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 512     default: []
 	Cover string `gorm:"column:cover;type:varchar;size:512;" json:"cover"`
-	//[ 1] tags                                           varchar(1024)        null: false  primary: false  isArray: false  auto: false  col: varchar         len: 1024    default: []
-	Tags string `gorm:"column:tags;type:varchar;size:1024;" json:"tags"`
-	//[ 2] update_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	//[ 1] update_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
 	UpdateTime time.Time `gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"update_time"`
-	//[ 3] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	//[ 2] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
 	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"create_time"`
-	//[ 4] content                                        text(4294967295)     null: false  primary: false  isArray: false  auto: false  col: text            len: 4294967295 default: []
+	//[ 3] content                                        text(4294967295)     null: false  primary: false  isArray: false  auto: false  col: text            len: 4294967295 default: []
 	Content string `gorm:"column:content;type:text;size:4294967295;" json:"content"`
-	//[ 5] title                                          varchar(512)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 512     default: []
+	//[ 4] title                                          varchar(512)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 512     default: []
 	Title string `gorm:"column:title;type:varchar;size:512;" json:"title"`
-	//[ 6] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
+	//[ 5] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
 	ID int32 `gorm:"primary_key;AUTO_INCREMENT;column:id;type:int;" json:"id"`
-	//[ 7] event_time                                     bigint               null: false  primary: false  isArray: false  auto: false  col: bigint          len: -1      default: [0]
-	EventTime int64 `gorm:"column:event_time;type:bigint;default:0;" json:"event_time"`
+	//[ 6] start_time                                     datetime             null: true   primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	StartTime null.Time `gorm:"column:start_time;type:datetime;index:idx_events_start_time;" json:"start_time"` // start of the event, or of its first occurrence when recurring
+	//[ 7] version                                        int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [1]
+	Version int32 `gorm:"column:version;type:int;default:1;not null;" json:"version"` // row version used for optimistic concurrency
+	//[ 8] status                                         varchar(16)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 16      default: [published]
+	Status string `gorm:"column:status;type:varchar(16);default:'published';not null;index:idx_events_status;" json:"status"` // publishing state: draft, scheduled, published or archived
+	//[ 9] publish_at                                     datetime             null: true   primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	PublishAt null.Time `gorm:"column:publish_at;type:datetime;" json:"publish_at"` // time a scheduled record goes public
+	//[10] unpublish_at                                   datetime             null: true   primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	UnpublishAt null.Time `gorm:"column:unpublish_at;type:datetime;" json:"unpublish_at"` // time a published record is archived
+	//[11] cover_media_id                                 int                  null: true   primary: false  isArray: false  auto: false  col: int             len: -1      default: []
+	CoverMediaID null.Int `gorm:"column:cover_media_id;type:int;" json:"cover_media_id"` // id of the uploaded cover in the media table
+	//[12] end_time                                       datetime             null: true   primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	EndTime null.Time `gorm:"column:end_time;type:datetime;" json:"end_time"` // end of the event, exclusive, or of its first occurrence when recurring
+	//[13] time_zone                                      varchar(64)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 64      default: [UTC]
+	TimeZone string `gorm:"column:time_zone;type:varchar(64);default:'UTC';not null;" json:"time_zone"` // IANA time zone the event takes place in
+	//[14] all_day                                        tinyint              null: false  primary: false  isArray: false  auto: false  col: tinyint         len: -1      default: [0]
+	AllDay bool `gorm:"column:all_day;type:tinyint(1);default:0;not null;" json:"all_day"` // event lasts whole days, start and end are midnights in time_zone
+	//[15] location                                       varchar(512)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 512     default: []
+	Location string `gorm:"column:location;type:varchar(512);default:'';not null;" json:"location"` // physical place of the event
+	//[16] online_url                                     varchar(512)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 512     default: []
+	OnlineURL string `gorm:"column:online_url;type:varchar(512);default:'';not null;" json:"online_url"` // link to join the event online
+	//[17] rrule                                          varchar(512)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 512     default: []
+	RRule string `gorm:"column:rrule;type:varchar(512);default:'';not null;" json:"rrule"` // RFC 5545 recurrence rule, empty for single events
+	//[18] registration_open                              tinyint              null: false  primary: false  isArray: false  auto: false  col: tinyint         len: -1      default: [0]
+	RegistrationOpen bool `gorm:"column:registration_open;type:tinyint(1);default:0;not null;" json:"registration_open"` // event takes registrations
+	//[19] capacity                                       int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	Capacity int32 `gorm:"column:capacity;type:int;default:0;not null;" json:"capacity"` // places for confirmed registrations, 0 when unlimited
+	//[20] questions                                      text(65535)          null: true   primary: false  isArray: false  auto: false  col: text            len: 65535   default: []
+	Questions QuestionList `gorm:"column:questions;type:text;" json:"questions"` // json list of the custom questions of the registration form
+
+	// Tags attached to the record, kept in the events_tags join table
+	Tags TagList `gorm:"-" json:"tags"`
+
+	// CoverMedia uploaded cover referenced by cover_media_id, filled in by the dao
+	CoverMedia *Media `gorm:"-" json:"cover_media,omitempty"`
+
+	// Occurrence the occurrence of the event a list of occurrences returned the record for, filled in by the dao
+	Occurrence *Occurrence `gorm:"-" json:"occurrence,omitempty"`
+
+	// Attendance registration counts of an event taking registrations, filled in by the dao for single records
+	Attendance *Attendance `gorm:"-" json:"attendance,omitempty"`
 }
 
 var eventsTableInfo = &TableInfo{
@@ -83,31 +135,11 @@ var eventsTableInfo = &TableInfo{
 			ProtobufFieldName:  "cover",
 			ProtobufType:       "string",
 			ProtobufPos:        1,
+			Format:             FormatURL,
 		},
 
 		{
 			Index:              1,
-			Name:               "tags",
-			Comment:            ``,
-			Notes:              ``,
-			Nullable:           false,
-			DatabaseTypeName:   "varchar",
-			DatabaseTypePretty: "varchar(1024)",
-			IsPrimaryKey:       false,
-			IsAutoIncrement:    false,
-			IsArray:            false,
-			ColumnType:         "varchar",
-			ColumnLength:       1024,
-			GoFieldName:        "Tags",
-			GoFieldType:        "string",
-			JSONFieldName:      "tags",
-			ProtobufFieldName:  "tags",
-			ProtobufType:       "string",
-			ProtobufPos:        2,
-		},
-
-		{
-			Index:              2,
 			Name:               "update_time",
 			Comment:            ``,
 			Notes:              ``,
@@ -124,11 +156,11 @@ var eventsTableInfo = &TableInfo{
 			JSONFieldName:      "update_time",
 			ProtobufFieldName:  "update_time",
 			ProtobufType:       "google.protobuf.Timestamp",
-			ProtobufPos:        3,
+			ProtobufPos:        2,
 		},
 
 		{
-			Index:              3,
+			Index:              2,
 			Name:               "create_time",
 			Comment:            ``,
 			Notes:              ``,
@@ -145,11 +177,11 @@ var eventsTableInfo = &TableInfo{
 			JSONFieldName:      "create_time",
 			ProtobufFieldName:  "create_time",
 			ProtobufType:       "google.protobuf.Timestamp",
-			ProtobufPos:        4,
+			ProtobufPos:        3,
 		},
 
 		{
-			Index:              4,
+			Index:              3,
 			Name:               "content",
 			Comment:            ``,
 			Notes:              ``,
@@ -166,11 +198,12 @@ var eventsTableInfo = &TableInfo{
 			JSONFieldName:      "content",
 			ProtobufFieldName:  "content",
 			ProtobufType:       "string",
-			ProtobufPos:        5,
+			ProtobufPos:        4,
+			Sanitizer:          SanitizeRichText,
 		},
 
 		{
-			Index:              5,
+			Index:              4,
 			Name:               "title",
 			Comment:            ``,
 			Notes:              ``,
@@ -187,11 +220,12 @@ var eventsTableInfo = &TableInfo{
 			JSONFieldName:      "title",
 			ProtobufFieldName:  "title",
 			ProtobufType:       "string",
-			ProtobufPos:        6,
+			ProtobufPos:        5,
+			Required:           true,
 		},
 
 		{
-			Index:              6,
+			Index:              5,
 			Name:               "id",
 			Comment:            ``,
 			Notes:              ``,
@@ -208,29 +242,324 @@ var eventsTableInfo = &TableInfo{
 			JSONFieldName:      "id",
 			ProtobufFieldName:  "id",
 			ProtobufType:       "int32",
+			ProtobufPos:        6,
+		},
+
+		{
+			Index:              6,
+			Name:               "start_time",
+			Comment:            `start of the event, or of its first occurrence when recurring`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "StartTime",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "start_time",
+			ProtobufFieldName:  "start_time",
+			ProtobufType:       "google.protobuf.Timestamp",
 			ProtobufPos:        7,
 		},
 
 		{
 			Index:              7,
-			Name:               "event_time",
-			Comment:            ``,
+			Name:               "version",
+			Comment:            `row version used for optimistic concurrency`,
 			Notes:              ``,
 			Nullable:           false,
-			DatabaseTypeName:   "bigint",
-			DatabaseTypePretty: "bigint",
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
 			IsPrimaryKey:       false,
 			IsAutoIncrement:    false,
 			IsArray:            false,
-			ColumnType:         "bigint",
+			ColumnType:         "int",
 			ColumnLength:       -1,
-			GoFieldName:        "EventTime",
-			GoFieldType:        "int64",
-			JSONFieldName:      "event_time",
-			ProtobufFieldName:  "event_time",
-			ProtobufType:       "int64",
+			GoFieldName:        "Version",
+			GoFieldType:        "int32",
+			JSONFieldName:      "version",
+			ProtobufFieldName:  "version",
+			ProtobufType:       "int32",
 			ProtobufPos:        8,
 		},
+
+		{
+			Index:              8,
+			Name:               "status",
+			Comment:            `publishing state: draft, scheduled, published or archived`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(16)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       16,
+			GoFieldName:        "Status",
+			GoFieldType:        "string",
+			JSONFieldName:      "status",
+			ProtobufFieldName:  "status",
+			ProtobufType:       "string",
+			ProtobufPos:        9,
+		},
+
+		{
+			Index:              9,
+			Name:               "publish_at",
+			Comment:            `time a scheduled record goes public`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "PublishAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "publish_at",
+			ProtobufFieldName:  "publish_at",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        10,
+		},
+
+		{
+			Index:              10,
+			Name:               "unpublish_at",
+			Comment:            `time a published record is archived`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "UnpublishAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "unpublish_at",
+			ProtobufFieldName:  "unpublish_at",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        11,
+		},
+
+		{
+			Index:              11,
+			Name:               "cover_media_id",
+			Comment:            `id of the uploaded cover in the media table`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "CoverMediaID",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "cover_media_id",
+			ProtobufFieldName:  "cover_media_id",
+			ProtobufType:       "int64",
+			ProtobufPos:        12,
+		},
+
+		{
+			Index:              12,
+			Name:               "end_time",
+			Comment:            `end of the event, exclusive, or of its first occurrence when recurring`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "EndTime",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "end_time",
+			ProtobufFieldName:  "end_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        13,
+		},
+
+		{
+			Index:              13,
+			Name:               "time_zone",
+			Comment:            `IANA time zone the event takes place in`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       64,
+			GoFieldName:        "TimeZone",
+			GoFieldType:        "string",
+			JSONFieldName:      "time_zone",
+			ProtobufFieldName:  "time_zone",
+			ProtobufType:       "string",
+			ProtobufPos:        14,
+		},
+
+		{
+			Index:              14,
+			Name:               "all_day",
+			Comment:            `event lasts whole days, start and end are midnights in time_zone`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "tinyint",
+			DatabaseTypePretty: "tinyint(1)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "tinyint",
+			ColumnLength:       -1,
+			GoFieldName:        "AllDay",
+			GoFieldType:        "bool",
+			JSONFieldName:      "all_day",
+			ProtobufFieldName:  "all_day",
+			ProtobufType:       "bool",
+			ProtobufPos:        15,
+		},
+
+		{
+			Index:              15,
+			Name:               "location",
+			Comment:            `physical place of the event`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(512)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       512,
+			GoFieldName:        "Location",
+			GoFieldType:        "string",
+			JSONFieldName:      "location",
+			ProtobufFieldName:  "location",
+			ProtobufType:       "string",
+			ProtobufPos:        16,
+		},
+
+		{
+			Index:              16,
+			Name:               "online_url",
+			Comment:            `link to join the event online`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(512)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       512,
+			GoFieldName:        "OnlineURL",
+			GoFieldType:        "string",
+			JSONFieldName:      "online_url",
+			ProtobufFieldName:  "online_url",
+			ProtobufType:       "string",
+			ProtobufPos:        17,
+			Format:             FormatURL,
+		},
+
+		{
+			Index:              17,
+			Name:               "rrule",
+			Comment:            `RFC 5545 recurrence rule, empty for single events`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(512)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       512,
+			GoFieldName:        "RRule",
+			GoFieldType:        "string",
+			JSONFieldName:      "rrule",
+			ProtobufFieldName:  "rrule",
+			ProtobufType:       "string",
+			ProtobufPos:        18,
+		},
+
+		{
+			Index:              18,
+			Name:               "registration_open",
+			Comment:            `event takes registrations`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "tinyint",
+			DatabaseTypePretty: "tinyint(1)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "tinyint",
+			ColumnLength:       -1,
+			GoFieldName:        "RegistrationOpen",
+			GoFieldType:        "bool",
+			JSONFieldName:      "registration_open",
+			ProtobufFieldName:  "registration_open",
+			ProtobufType:       "bool",
+			ProtobufPos:        19,
+		},
+
+		{
+			Index:              19,
+			Name:               "capacity",
+			Comment:            `places for confirmed registrations, 0 when unlimited`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Capacity",
+			GoFieldType:        "int32",
+			JSONFieldName:      "capacity",
+			ProtobufFieldName:  "capacity",
+			ProtobufType:       "int32",
+			ProtobufPos:        20,
+		},
+
+		{
+			Index:              20,
+			Name:               "questions",
+			Comment:            `json list of the custom questions of the registration form`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "text",
+			DatabaseTypePretty: "text(65535)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "text",
+			ColumnLength:       65535,
+			GoFieldName:        "Questions",
+			GoFieldType:        "QuestionList",
+			JSONFieldName:      "questions",
+			ProtobufFieldName:  "questions",
+			ProtobufType:       "string",
+			ProtobufPos:        21,
+		},
 	},
 }
 
@@ -245,12 +574,153 @@ func (e *Events) BeforeSave() error {
 }
 
 // Prepare invoked before saving, can be used to populate fields etc.
+// Times are stored in UTC, all day events are stretched to the midnights around them in their time zone
+// and valid recurrence rules are brought into canonical form.
 func (e *Events) Prepare() {
+	preparePublishing(&e.Status, e.PublishAt)
+	SanitizeColumns(e)
+
+	if e.TimeZone == "" {
+		e.TimeZone = "UTC"
+	}
+	loc, err := LoadZone(e.TimeZone)
+	if err != nil {
+		return
+	}
+
+	if e.StartTime.Valid && e.AllDay {
+		start := e.StartTime.Time.In(loc)
+		start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+		end := start.AddDate(0, 0, 1)
+		if e.EndTime.Valid && e.EndTime.Time.After(start) {
+			end = e.EndTime.Time.In(loc)
+			if midnight := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc); midnight.Before(end) {
+				end = midnight.AddDate(0, 0, 1)
+			} else {
+				end = midnight
+			}
+		}
+		e.StartTime, e.EndTime = null.TimeFrom(start), null.TimeFrom(end)
+	}
+
+	if e.StartTime.Valid {
+		e.StartTime.Time = e.StartTime.Time.UTC()
+	}
+	if e.EndTime.Valid {
+		e.EndTime.Time = e.EndTime.Time.UTC()
+	}
+
+	if e.RRule != "" {
+		if rule, err := ParseRRule(e.RRule, loc); err == nil {
+			e.RRule = rule.Format(e.AllDay)
+		}
+	}
+
+	e.Questions.Prepare()
 }
 
 // Validate invoked before performing action, return an error if field is not populated.
 func (e *Events) Validate(action Action) error {
-	return nil
+	if action != Create && action != Update {
+		return nil
+	}
+
+	v := ValidateColumns(e)
+	validatePublishing(v, e.Status, e.PublishAt, e.UnpublishAt)
+
+	if !e.StartTime.Valid {
+		v.Add("start_time", ErrCodeRequired, "start_time is required")
+	} else if start := e.StartTime.Time.Unix(); start < minEventTime || start >= maxEventTime {
+		v.Add("start_time", ErrCodeOutOfRange, "start_time must be between 2000 and 2100")
+	}
+
+	if e.StartTime.Valid && e.EndTime.Valid && !e.EndTime.Time.After(e.StartTime.Time) {
+		v.Add("end_time", ErrCodeOutOfRange, "end_time must be after start_time")
+	}
+
+	loc, err := LoadZone(e.TimeZone)
+	if err != nil {
+		v.Add("time_zone", ErrCodeInvalid, "time_zone must be an IANA time zone such as Europe/London")
+		loc = time.UTC
+	}
+
+	if strings.HasPrefix(e.OnlineURL, "/") {
+		v.Add("online_url", ErrCodeInvalidURL, "online_url must be an absolute http(s) url")
+	}
+
+	if e.RRule != "" {
+		if _, err := ParseRRule(e.RRule, loc); err != nil {
+			v.Add("rrule", ErrCodeInvalid, "rrule %v", err)
+		}
+	}
+
+	if e.Capacity < 0 {
+		v.Add("capacity", ErrCodeOutOfRange, "capacity must not be negative")
+	}
+	e.Questions.Validate(v)
+
+	return v.Err()
+}
+
+// Zone returns the time zone of the event, UTC when it is unknown
+func (e *Events) Zone() *time.Location {
+	if loc, err := LoadZone(e.TimeZone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// Occurrences returns the occurrences of the event that overlap from to to, at most limit of them.
+// Events without end are taken to overlap when they start within the range.
+func (e *Events) Occurrences(from, to time.Time, limit int) []*Occurrence {
+	if !e.StartTime.Valid {
+		return nil
+	}
+
+	start := e.StartTime.Time
+	var length time.Duration
+	if e.EndTime.Valid {
+		length = e.EndTime.Time.Sub(start)
+	}
+
+	starts := []time.Time{start}
+	if e.RRule != "" {
+		if rule, err := ParseRRule(e.RRule, e.Zone()); err == nil {
+			starts = rule.Starts(start, length, from, to, limit)
+		}
+	} else if !start.Before(to) || !(start.Add(length).After(from) || (length == 0 && !start.Before(from))) {
+		return nil
+	}
+
+	days := int(length.Round(24*time.Hour) / (24 * time.Hour))
+	occurrences := make([]*Occurrence, 0, len(starts))
+	for _, s := range starts {
+		occurrence := &Occurrence{Start: s.UTC()}
+		switch {
+		case !e.EndTime.Valid:
+		case e.AllDay:
+			// whole days keep ending at midnight across daylight saving changes
+			occurrence.End = null.TimeFrom(s.In(e.Zone()).AddDate(0, 0, days).UTC())
+		default:
+			occurrence.End = null.TimeFrom(s.Add(length).UTC())
+		}
+		occurrences = append(occurrences, occurrence)
+	}
+	return occurrences
+}
+
+// AcceptsRegistrations reports whether the public can register for the event at now:
+// it is public, open for registration and it or one of its occurrences is yet to end
+func (e *Events) AcceptsRegistrations(now time.Time) bool {
+	if !e.RegistrationOpen || !e.IsPublic(now) {
+		return false
+	}
+	return len(e.Occurrences(now, now.AddDate(100, 0, 0), 1)) > 0
+}
+
+// IsPublic reports whether the record is visible to the public at now
+func (e *Events) IsPublic(now time.Time) bool {
+	return IsPublic(e.Status, e.PublishAt, e.UnpublishAt, now)
 }
 
 // TableInfo return table meta data
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/guregu/null"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+CREATE TABLE `events_tags` (
+  `events_id` int NOT NULL COMMENT 'id of the tagged events record',
+  `tag_id` int NOT NULL COMMENT 'id of the tag',
+  `position` int NOT NULL DEFAULT '0' COMMENT 'order of the tag on the record',
+  PRIMARY KEY (`events_id`, `tag_id`),
+  KEY `idx_events_tags_tag_id` (`tag_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='tags attached to events'
+
+JSON Sample
+-------------------------------------
+{    "events_id": 93,    "tag_id": 79,    "position": 20}
+
+
+
+*/
+
+// EventsTags struct is a row record of the events_tags table in the wcs database
+type EventsTags struct {
+	//[ 0] events_id                                      int                  null: false  primary: true   isArray: false  auto: false  col: int             len: -1      default: []
+	EventsID int32 `gorm:"primary_key;auto_increment:false;column:events_id;type:int;" json:"events_id"` // id of the tagged events record
+	//[ 1] tag_id                                         int                  null: false  primary: true   isArray: false  auto: false  col: int             len: -1      default: []
+	TagID int32 `gorm:"primary_key;auto_increment:false;column:tag_id;type:int;" json:"tag_id"` // id of the tag
+	//[ 2] position                                       int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	Position int32 `gorm:"column:position;type:int;default:0;not null;" json:"position"` // order of the tag on the record
+}
+
+var eventsTagsTableInfo = &TableInfo{
+	Name: "events_tags",
+	Columns: []*ColumnInfo{
+
+		{
+			Index:              0,
+			Name:               "events_id",
+			Comment:            `id of the tagged events record`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "EventsID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "events_id",
+			ProtobufFieldName:  "events_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		{
+			Index:              1,
+			Name:               "tag_id",
+			Comment:            `id of the tag`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "TagID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "tag_id",
+			ProtobufFieldName:  "tag_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        2,
+		},
+
+		{
+			Index:              2,
+			Name:               "position",
+			Comment:            `order of the tag on the record`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Position",
+			GoFieldType:        "int32",
+			JSONFieldName:      "position",
+			ProtobufFieldName:  "position",
+			ProtobufType:       "int32",
+			ProtobufPos:        3,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (j *EventsTags) TableName() string {
+	return "events_tags"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (j *EventsTags) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (j *EventsTags) Prepare() {
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (j *EventsTags) Validate(action Action) error {
+	return nil
+}
+
+// TableInfo return table meta data
+func (j *EventsTags) TableInfo() *TableInfo {
+	return eventsTagsTableInfo
+}
@@ -0,0 +1,403 @@
+package model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/guregu/null"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+CREATE TABLE `jobs` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `kind` varchar(64) NOT NULL COMMENT 'what the job does, selects its handler',
+  `payload` text COMMENT 'json arguments of the handler',
+  `unique_key` varchar(191) DEFAULT NULL COMMENT 'key a job is enqueued under at most once, null for jobs that may repeat',
+  `status` varchar(16) NOT NULL DEFAULT 'pending' COMMENT 'pending, running, done or failed',
+  `attempts` int NOT NULL DEFAULT '0' COMMENT 'times the job was started',
+  `max_attempts` int NOT NULL DEFAULT '5' COMMENT 'attempts after which a failing job is given up',
+  `run_at` datetime NOT NULL COMMENT 'time the job is due, pushed back after each failed attempt',
+  `locked_until` datetime DEFAULT NULL COMMENT 'end of the lease of the worker running the job, a crashed worker''s job is picked up again after it',
+  `last_error` text COMMENT 'error of the last failed attempt',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_jobs_unique_key` (`unique_key`),
+  KEY `idx_jobs_due` (`status`, `run_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='background jobs, run by the job scheduler'
+
+JSON Sample
+-------------------------------------
+{    "id": 7,    "kind": "reminder_mail",    "payload": "{\"event_id\":35,\"start\":\"2024-05-02T13:00:00Z\",\"registration_id\":12}",    "unique_key": "reminder_mail:35:1714654800:12",    "status": "pending",    "attempts": 1,    "max_attempts": 5,    "run_at": "2024-05-01T13:02:00Z",    "locked_until": null,    "last_error": "mailgun: connection refused",    "create_time": "2024-05-01T13:00:00Z",    "update_time": "2024-05-01T13:00:10Z"}
+
+
+
+*/
+
+// job statuses
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
+
+// DefaultMaxAttempts attempts of a job enqueued without a limit of its own
+const DefaultMaxAttempts = 5
+
+// bounds of the delay before a failed job is retried, doubling with every attempt
+const (
+	minRetryDelay = time.Minute
+	maxRetryDelay = 6 * time.Hour
+)
+
+// Jobs struct is a row record of the jobs table in the wcs database
+type Jobs struct {
+	//[ 0] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
+	ID int32 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] kind                                           varchar(64)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 64      default: []
+	Kind string `gorm:"column:kind;type:varchar(64);not null;" json:"kind"` // what the job does, selects its handler
+	//[ 2] payload                                        text(65535)          null: true   primary: false  isArray: false  auto: false  col: text            len: 65535   default: []
+	Payload string `gorm:"column:payload;type:text;" json:"payload"` // json arguments of the handler
+	//[ 3] unique_key                                     varchar(191)         null: true   primary: false  isArray: false  auto: false  col: varchar         len: 191     default: []
+	UniqueKey null.String `gorm:"column:unique_key;type:varchar(191);unique_index:idx_jobs_unique_key;" json:"unique_key"` // key a job is enqueued under at most once, null for jobs that may repeat
+	//[ 4] status                                         varchar(16)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 16      default: [pending]
+	Status string `gorm:"column:status;type:varchar(16);default:'pending';not null;index:idx_jobs_due;" json:"status"` // pending, running, done or failed
+	//[ 5] attempts                                       int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	Attempts int32 `gorm:"column:attempts;type:int;default:0;not null;" json:"attempts"` // times the job was started
+	//[ 6] max_attempts                                   int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [5]
+	MaxAttempts int32 `gorm:"column:max_attempts;type:int;default:5;not null;" json:"max_attempts"` // attempts after which a failing job is given up
+	//[ 7] run_at                                         datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	RunAt time.Time `gorm:"column:run_at;type:datetime;not null;index:idx_jobs_due;" json:"run_at"` // time the job is due, pushed back after each failed attempt
+	//[ 8] locked_until                                   datetime             null: true   primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	LockedUntil null.Time `gorm:"column:locked_until;type:datetime;" json:"locked_until"` // end of the lease of the worker running the job, a crashed worker's job is picked up again after it
+	//[ 9] last_error                                     text(65535)          null: true   primary: false  isArray: false  auto: false  col: text            len: 65535   default: []
+	LastError string `gorm:"column:last_error;type:text;" json:"last_error"` // error of the last failed attempt
+	//[10] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"create_time"`
+	//[11] update_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	UpdateTime time.Time `gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"update_time"`
+}
+
+var jobsTableInfo = &TableInfo{
+	Name: "jobs",
+	Columns: []*ColumnInfo{
+
+		{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		{
+			Index:              1,
+			Name:               "kind",
+			Comment:            `what the job does, selects its handler`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       64,
+			GoFieldName:        "Kind",
+			GoFieldType:        "string",
+			JSONFieldName:      "kind",
+			ProtobufFieldName:  "kind",
+			ProtobufType:       "string",
+			ProtobufPos:        2,
+			Required:           true,
+		},
+
+		{
+			Index:              2,
+			Name:               "payload",
+			Comment:            `json arguments of the handler`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "text",
+			DatabaseTypePretty: "text(65535)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "text",
+			ColumnLength:       65535,
+			GoFieldName:        "Payload",
+			GoFieldType:        "string",
+			JSONFieldName:      "payload",
+			ProtobufFieldName:  "payload",
+			ProtobufType:       "string",
+			ProtobufPos:        3,
+		},
+
+		{
+			Index:              3,
+			Name:               "unique_key",
+			Comment:            `key a job is enqueued under at most once, null for jobs that may repeat`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(191)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       191,
+			GoFieldName:        "UniqueKey",
+			GoFieldType:        "null.String",
+			JSONFieldName:      "unique_key",
+			ProtobufFieldName:  "unique_key",
+			ProtobufType:       "string",
+			ProtobufPos:        4,
+		},
+
+		{
+			Index:              4,
+			Name:               "status",
+			Comment:            `pending, running, done or failed`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(16)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       16,
+			GoFieldName:        "Status",
+			GoFieldType:        "string",
+			JSONFieldName:      "status",
+			ProtobufFieldName:  "status",
+			ProtobufType:       "string",
+			ProtobufPos:        5,
+		},
+
+		{
+			Index:              5,
+			Name:               "attempts",
+			Comment:            `times the job was started`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Attempts",
+			GoFieldType:        "int32",
+			JSONFieldName:      "attempts",
+			ProtobufFieldName:  "attempts",
+			ProtobufType:       "int32",
+			ProtobufPos:        6,
+		},
+
+		{
+			Index:              6,
+			Name:               "max_attempts",
+			Comment:            `attempts after which a failing job is given up`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "MaxAttempts",
+			GoFieldType:        "int32",
+			JSONFieldName:      "max_attempts",
+			ProtobufFieldName:  "max_attempts",
+			ProtobufType:       "int32",
+			ProtobufPos:        7,
+		},
+
+		{
+			Index:              7,
+			Name:               "run_at",
+			Comment:            `time the job is due, pushed back after each failed attempt`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "RunAt",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "run_at",
+			ProtobufFieldName:  "run_at",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        8,
+		},
+
+		{
+			Index:              8,
+			Name:               "locked_until",
+			Comment:            `end of the lease of the worker running the job, a crashed worker's job is picked up again after it`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "LockedUntil",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "locked_until",
+			ProtobufFieldName:  "locked_until",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        9,
+		},
+
+		{
+			Index:              9,
+			Name:               "last_error",
+			Comment:            `error of the last failed attempt`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "text",
+			DatabaseTypePretty: "text(65535)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "text",
+			ColumnLength:       65535,
+			GoFieldName:        "LastError",
+			GoFieldType:        "string",
+			JSONFieldName:      "last_error",
+			ProtobufFieldName:  "last_error",
+			ProtobufType:       "string",
+			ProtobufPos:        10,
+		},
+
+		{
+			Index:              10,
+			Name:               "create_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "CreateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "create_time",
+			ProtobufFieldName:  "create_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        11,
+		},
+
+		{
+			Index:              11,
+			Name:               "update_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "UpdateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "update_time",
+			ProtobufFieldName:  "update_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        12,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (j *Jobs) TableName() string {
+	return "jobs"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (j *Jobs) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (j *Jobs) Prepare() {
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (j *Jobs) Validate(action Action) error {
+	return nil
+}
+
+// TableInfo return table meta data
+func (j *Jobs) TableInfo() *TableInfo {
+	return jobsTableInfo
+}
+
+// NewJob returns a pending job of kind due at runAt, payload is stored as json
+func NewJob(kind string, payload interface{}, runAt time.Time) (*Jobs, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	return &Jobs{Kind: kind, Payload: string(data), Status: JobPending, MaxAttempts: DefaultMaxAttempts, RunAt: runAt.UTC()}, nil
+}
+
+// Decode unmarshals the payload of the job into v
+func (j *Jobs) Decode(v interface{}) error {
+	return json.Unmarshal([]byte(j.Payload), v)
+}
+
+// RetryAt returns when the job is due again after its latest attempt failed at now:
+// a minute after the first attempt, doubling with each further one up to six hours
+func (j *Jobs) RetryAt(now time.Time) time.Time {
+	delay := minRetryDelay
+	for i := int32(1); i < j.Attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return now.Add(delay).UTC()
+}
@@ -0,0 +1,364 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/guregu/null"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+CREATE TABLE `media` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `digest` varchar(64) NOT NULL COMMENT 'hex sha256 of the content',
+  `storage_key` varchar(255) NOT NULL COMMENT 'key of the blob in the media storage',
+  `filename` varchar(255) NOT NULL DEFAULT '' COMMENT 'name of the uploaded file',
+  `mime_type` varchar(64) NOT NULL COMMENT 'sniffed content type',
+  `size` bigint NOT NULL DEFAULT '0' COMMENT 'size in bytes',
+  `width` int NOT NULL DEFAULT '0' COMMENT 'image width in pixels, 0 for other files',
+  `height` int NOT NULL DEFAULT '0' COMMENT 'image height in pixels, 0 for other files',
+  `admin_id` int DEFAULT NULL COMMENT 'admin who uploaded the file',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `version` int NOT NULL DEFAULT '1' COMMENT 'row version used for optimistic concurrency',
+  `blurhash` varchar(64) NOT NULL DEFAULT '' COMMENT 'blurhash placeholder of an image, empty for other files',
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='uploaded files referenced by content rows'
+
+JSON Sample
+-------------------------------------
+{    "id": 91,    "digest": "csrHmfhUrHnkfbKfyOaHccvYq",    "storage_key": "LFFOKCUqiJXNXmEyNtkhefdGo",    "filename": "nmfHARVxuwxkXripittoPDmCb",    "mime_type": "PetKTHAXdLbbmNhrKcEgxqSls",    "size": 87,    "width": 87,    "height": 12,    "admin_id": 6,    "create_time": "2107-09-13T03:18:17.057513584+08:00",    "version": 68}
+
+
+
+*/
+
+// Media struct is a row record of the media table in the wcs database
+type Media struct {
+	//[ 0] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
+	ID int32 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] digest                                         varchar(64)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 64      default: []
+	Digest string `gorm:"column:digest;type:varchar(64);not null;unique_index:uniq_media_digest;" json:"digest"` // hex sha256 of the content
+	//[ 2] storage_key                                    varchar(255)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 255     default: []
+	StorageKey string `gorm:"column:storage_key;type:varchar(255);not null;" json:"storage_key"` // key of the blob in the media storage
+	//[ 3] filename                                       varchar(255)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 255     default: []
+	Filename string `gorm:"column:filename;type:varchar(255);not null;default:'';" json:"filename"` // name of the uploaded file
+	//[ 4] mime_type                                      varchar(64)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 64      default: []
+	MimeType string `gorm:"column:mime_type;type:varchar(64);not null;" json:"mime_type"` // sniffed content type
+	//[ 5] size                                           bigint               null: false  primary: false  isArray: false  auto: false  col: bigint          len: -1      default: [0]
+	Size int64 `gorm:"column:size;type:bigint;default:0;not null;" json:"size"` // size in bytes
+	//[ 6] width                                          int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	Width int32 `gorm:"column:width;type:int;default:0;not null;" json:"width"` // image width in pixels, 0 for other files
+	//[ 7] height                                         int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	Height int32 `gorm:"column:height;type:int;default:0;not null;" json:"height"` // image height in pixels, 0 for other files
+	//[ 8] admin_id                                       int                  null: true   primary: false  isArray: false  auto: false  col: int             len: -1      default: []
+	AdminID null.Int `gorm:"column:admin_id;type:int;" json:"admin_id"` // admin who uploaded the file
+	//[ 9] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"create_time"`
+	//[10] version                                        int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [1]
+	Version int32 `gorm:"column:version;type:int;default:1;not null;" json:"version"` // row version used for optimistic concurrency
+	//[11] blurhash                                       varchar(64)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 64      default: []
+	Blurhash string `gorm:"column:blurhash;type:varchar(64);not null;default:'';" json:"blurhash"` // blurhash placeholder of an image, empty for other files
+
+	// URL public url the content is served at, filled in by the dao
+	URL string `gorm:"-" json:"url"`
+
+	// Variants resized copies of an image, smallest first, filled in by the dao
+	Variants []*MediaVariants `gorm:"-" json:"variants"`
+}
+
+var mediaTableInfo = &TableInfo{
+	Name: "media",
+	Columns: []*ColumnInfo{
+
+		{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		{
+			Index:              1,
+			Name:               "digest",
+			Comment:            `hex sha256 of the content`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       64,
+			GoFieldName:        "Digest",
+			GoFieldType:        "string",
+			JSONFieldName:      "digest",
+			ProtobufFieldName:  "digest",
+			ProtobufType:       "string",
+			ProtobufPos:        2,
+			Required:           true,
+		},
+
+		{
+			Index:              2,
+			Name:               "storage_key",
+			Comment:            `key of the blob in the media storage`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       255,
+			GoFieldName:        "StorageKey",
+			GoFieldType:        "string",
+			JSONFieldName:      "storage_key",
+			ProtobufFieldName:  "storage_key",
+			ProtobufType:       "string",
+			ProtobufPos:        3,
+			Required:           true,
+		},
+
+		{
+			Index:              3,
+			Name:               "filename",
+			Comment:            `name of the uploaded file`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       255,
+			GoFieldName:        "Filename",
+			GoFieldType:        "string",
+			JSONFieldName:      "filename",
+			ProtobufFieldName:  "filename",
+			ProtobufType:       "string",
+			ProtobufPos:        4,
+		},
+
+		{
+			Index:              4,
+			Name:               "mime_type",
+			Comment:            `sniffed content type`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       64,
+			GoFieldName:        "MimeType",
+			GoFieldType:        "string",
+			JSONFieldName:      "mime_type",
+			ProtobufFieldName:  "mime_type",
+			ProtobufType:       "string",
+			ProtobufPos:        5,
+			Required:           true,
+		},
+
+		{
+			Index:              5,
+			Name:               "size",
+			Comment:            `size in bytes`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "bigint",
+			DatabaseTypePretty: "bigint",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "bigint",
+			ColumnLength:       -1,
+			GoFieldName:        "Size",
+			GoFieldType:        "int64",
+			JSONFieldName:      "size",
+			ProtobufFieldName:  "size",
+			ProtobufType:       "int64",
+			ProtobufPos:        6,
+		},
+
+		{
+			Index:              6,
+			Name:               "width",
+			Comment:            `image width in pixels, 0 for other files`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Width",
+			GoFieldType:        "int32",
+			JSONFieldName:      "width",
+			ProtobufFieldName:  "width",
+			ProtobufType:       "int32",
+			ProtobufPos:        7,
+		},
+
+		{
+			Index:              7,
+			Name:               "height",
+			Comment:            `image height in pixels, 0 for other files`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Height",
+			GoFieldType:        "int32",
+			JSONFieldName:      "height",
+			ProtobufFieldName:  "height",
+			ProtobufType:       "int32",
+			ProtobufPos:        8,
+		},
+
+		{
+			Index:              8,
+			Name:               "admin_id",
+			Comment:            `admin who uploaded the file`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "AdminID",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "admin_id",
+			ProtobufFieldName:  "admin_id",
+			ProtobufType:       "int64",
+			ProtobufPos:        9,
+		},
+
+		{
+			Index:              9,
+			Name:               "create_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "CreateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "create_time",
+			ProtobufFieldName:  "create_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        10,
+		},
+
+		{
+			Index:              10,
+			Name:               "version",
+			Comment:            `row version used for optimistic concurrency`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Version",
+			GoFieldType:        "int32",
+			JSONFieldName:      "version",
+			ProtobufFieldName:  "version",
+			ProtobufType:       "int32",
+			ProtobufPos:        11,
+		},
+
+		{
+			Index:              11,
+			Name:               "blurhash",
+			Comment:            `blurhash placeholder of an image, empty for other files`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       64,
+			GoFieldName:        "Blurhash",
+			GoFieldType:        "string",
+			JSONFieldName:      "blurhash",
+			ProtobufFieldName:  "blurhash",
+			ProtobufType:       "string",
+			ProtobufPos:        12,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (m *Media) TableName() string {
+	return "media"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (m *Media) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (m *Media) Prepare() {
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (m *Media) Validate(action Action) error {
+	return nil
+}
+
+// TableInfo return table meta data
+func (m *Media) TableInfo() *TableInfo {
+	return mediaTableInfo
+}
@@ -0,0 +1,265 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/guregu/null"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+CREATE TABLE `media_variants` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `media_id` int NOT NULL COMMENT 'media the variant was rendered from',
+  `width` int NOT NULL DEFAULT '0' COMMENT 'width in pixels',
+  `height` int NOT NULL DEFAULT '0' COMMENT 'height in pixels',
+  `mime_type` varchar(32) NOT NULL COMMENT 'content type of the variant',
+  `storage_key` varchar(255) NOT NULL COMMENT 'key of the blob in the media storage',
+  `size` bigint NOT NULL DEFAULT '0' COMMENT 'size in bytes',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_media_variants_media_id` (`media_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='resized copies of images in the media table'
+
+JSON Sample
+-------------------------------------
+{    "id": 10,    "media_id": 84,    "width": 23,    "height": 40,    "mime_type": "toJqTNGhlXlFsxQKpcmKAolex",    "storage_key": "RFrpvfkJvkAJxoeHbMmHscxDy",    "size": 94,    "create_time": "2247-06-26T00:24:10.973685332+08:00"}
+
+
+
+*/
+
+// MediaVariants struct is a row record of the media_variants table in the wcs database
+type MediaVariants struct {
+	//[ 0] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
+	ID int32 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] media_id                                       int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: []
+	MediaID int32 `gorm:"column:media_id;type:int;not null;index:idx_media_variants_media_id;" json:"media_id"` // media the variant was rendered from
+	//[ 2] width                                          int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	Width int32 `gorm:"column:width;type:int;default:0;not null;" json:"width"` // width in pixels
+	//[ 3] height                                         int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	Height int32 `gorm:"column:height;type:int;default:0;not null;" json:"height"` // height in pixels
+	//[ 4] mime_type                                      varchar(32)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 32      default: []
+	MimeType string `gorm:"column:mime_type;type:varchar(32);not null;" json:"mime_type"` // content type of the variant
+	//[ 5] storage_key                                    varchar(255)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 255     default: []
+	StorageKey string `gorm:"column:storage_key;type:varchar(255);not null;" json:"storage_key"` // key of the blob in the media storage
+	//[ 6] size                                           bigint               null: false  primary: false  isArray: false  auto: false  col: bigint          len: -1      default: [0]
+	Size int64 `gorm:"column:size;type:bigint;default:0;not null;" json:"size"` // size in bytes
+	//[ 7] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"create_time"`
+
+	// URL public url the variant is served at, filled in by the dao
+	URL string `gorm:"-" json:"url"`
+}
+
+var mediaVariantsTableInfo = &TableInfo{
+	Name: "media_variants",
+	Columns: []*ColumnInfo{
+
+		{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		{
+			Index:              1,
+			Name:               "media_id",
+			Comment:            `media the variant was rendered from`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "MediaID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "media_id",
+			ProtobufFieldName:  "media_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        2,
+		},
+
+		{
+			Index:              2,
+			Name:               "width",
+			Comment:            `width in pixels`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Width",
+			GoFieldType:        "int32",
+			JSONFieldName:      "width",
+			ProtobufFieldName:  "width",
+			ProtobufType:       "int32",
+			ProtobufPos:        3,
+		},
+
+		{
+			Index:              3,
+			Name:               "height",
+			Comment:            `height in pixels`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Height",
+			GoFieldType:        "int32",
+			JSONFieldName:      "height",
+			ProtobufFieldName:  "height",
+			ProtobufType:       "int32",
+			ProtobufPos:        4,
+		},
+
+		{
+			Index:              4,
+			Name:               "mime_type",
+			Comment:            `content type of the variant`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(32)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       32,
+			GoFieldName:        "MimeType",
+			GoFieldType:        "string",
+			JSONFieldName:      "mime_type",
+			ProtobufFieldName:  "mime_type",
+			ProtobufType:       "string",
+			ProtobufPos:        5,
+			Required:           true,
+		},
+
+		{
+			Index:              5,
+			Name:               "storage_key",
+			Comment:            `key of the blob in the media storage`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       255,
+			GoFieldName:        "StorageKey",
+			GoFieldType:        "string",
+			JSONFieldName:      "storage_key",
+			ProtobufFieldName:  "storage_key",
+			ProtobufType:       "string",
+			ProtobufPos:        6,
+			Required:           true,
+		},
+
+		{
+			Index:              6,
+			Name:               "size",
+			Comment:            `size in bytes`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "bigint",
+			DatabaseTypePretty: "bigint",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "bigint",
+			ColumnLength:       -1,
+			GoFieldName:        "Size",
+			GoFieldType:        "int64",
+			JSONFieldName:      "size",
+			ProtobufFieldName:  "size",
+			ProtobufType:       "int64",
+			ProtobufPos:        7,
+		},
+
+		{
+			Index:              7,
+			Name:               "create_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "CreateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "create_time",
+			ProtobufFieldName:  "create_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        8,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (m *MediaVariants) TableName() string {
+	return "media_variants"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (m *MediaVariants) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (m *MediaVariants) Prepare() {
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (m *MediaVariants) Validate(action Action) error {
+	return nil
+}
+
+// TableInfo return table meta data
+func (m *MediaVariants) TableInfo() *TableInfo {
+	return mediaVariantsTableInfo
+}
@@ -25,6 +25,8 @@ var (
 	FetchDDL = Action(5)
 
 	tables map[string]*TableInfo
+
+	records map[string]func() Model
 )
 
 func init() {
@@ -37,6 +39,31 @@ func init() {
 	tables["projects"] = projectsTableInfo
 	tables["resources"] = resourcesTableInfo
 	tables["staffs"] = staffsTableInfo
+	tables["tags"] = tagsTableInfo
+	tables["events_tags"] = eventsTagsTableInfo
+	tables["news_tags"] = newsTagsTableInfo
+	tables["status_history"] = statusHistoryTableInfo
+	tables["media"] = mediaTableInfo
+	tables["media_variants"] = mediaVariantsTableInfo
+	tables["registrations"] = registrationsTableInfo
+	tables["jobs"] = jobsTableInfo
+	tables["outbox"] = outboxTableInfo
+	tables["contact_messages"] = contactMessagesTableInfo
+	tables["subscribers"] = subscribersTableInfo
+	tables["digests"] = digestsTableInfo
+	tables["webhooks"] = webhooksTableInfo
+	tables["webhook_deliveries"] = webhookDeliveriesTableInfo
+
+	records = make(map[string]func() Model)
+
+	records["admin"] = func() Model { return &Admin{} }
+	records["events"] = func() Model { return &Events{} }
+	records["news"] = func() Model { return &News{} }
+	records["phds"] = func() Model { return &Phds{} }
+	records["projects"] = func() Model { return &Projects{} }
+	records["resources"] = func() Model { return &Resources{} }
+	records["staffs"] = func() Model { return &Staffs{} }
+	records["tags"] = func() Model { return &Tags{} }
 }
 
 // String describe the action
@@ -59,6 +86,16 @@ func (i Action) String() string {
 	}
 }
 
+// ParseAction returns the action named name, the inverse of Action.String
+func ParseAction(name string) (Action, bool) {
+	for _, action := range []Action{Create, RetrieveOne, RetrieveMany, Update, Delete, FetchDDL} {
+		if action.String() == name {
+			return action, true
+		}
+	}
+	return 0, false
+}
+
 // Model interface methods for database structs generated
 type Model interface {
 	TableName() string
@@ -95,6 +132,25 @@ type ColumnInfo struct {
 	ColumnType         string `json:"column_type"`
 	ColumnLength       int64  `json:"column_length"`
 	DefaultValue       string `json:"default_value"`
+
+	// Sanitizer name of the html policy the column is cleaned with on save, empty for columns stored as sent
+	Sanitizer string `json:"sanitizer,omitempty"`
+
+	// Required column must hold a non blank value, set for NOT NULL varchar columns without a default
+	Required bool `json:"is_required,omitempty"`
+
+	// Format value format checked by ValidateColumns, e.g. FormatURL, empty for free form columns
+	Format string `json:"format,omitempty"`
+}
+
+// IsServerManaged reports whether the column is maintained by the server and never taken from a request body
+func (c *ColumnInfo) IsServerManaged() bool {
+	switch c.Name {
+	case "create_time", "update_time", "version":
+		return true
+	}
+
+	return c.IsPrimaryKey || c.IsAutoIncrement
 }
 
 // GetTableInfo retrieve TableInfo for a table
@@ -102,3 +158,12 @@ func GetTableInfo(name string) (*TableInfo, bool) {
 	val, ok := tables[name]
 	return val, ok
 }
+
+// NewRecord returns an empty record of a table
+func NewRecord(name string) (Model, bool) {
+	newRecord, ok := records[name]
+	if !ok {
+		return nil, false
+	}
+	return newRecord(), true
+}
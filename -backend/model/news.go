@@ -26,14 +26,18 @@ CREATE TABLE `news` (
   `content` longtext NOT NULL,
   `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `update_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
-  `tags` varchar(128) NOT NULL,
   `cover` varchar(256) NOT NULL,
+  `version` int NOT NULL DEFAULT '1',
+  `status` varchar(16) NOT NULL DEFAULT 'published' COMMENT 'publishing state: draft, scheduled, published or archived',
+  `publish_at` datetime DEFAULT NULL COMMENT 'time a scheduled record goes public',
+  `unpublish_at` datetime DEFAULT NULL COMMENT 'time a published record is archived',
+  `cover_media_id` int DEFAULT NULL COMMENT 'id of the uploaded cover in the media table',
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB AUTO_INCREMENT=5 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci
 
 JSON Sample
 -------------------------------------
-{    "id": 76,    "title": "cGSfstycEikZjCWZYJhEuWwWC",    "content": "YkQrALQfQfpqwiSLOctWUkrOs",    "create_time": "2311-07-11T12:25:43.563373812+08:00",    "update_time": "2177-04-07T01:41:28.623684615+08:00",    "tags": "erGBbmpFXmOvpNmVsAOvLkCuF",    "cover": "kljHXlIKVdfpvdiQDEksfgyqH"}
+{    "id": 76,    "title": "cGSfstycEikZjCWZYJhEuWwWC",    "content": "YkQrALQfQfpqwiSLOctWUkrOs",    "create_time": "2311-07-11T12:25:43.563373812+08:00",    "update_time": "2177-04-07T01:41:28.623684615+08:00",    "cover": "kljHXlIKVdfpvdiQDEksfgyqH"}
 
 
 
@@ -51,10 +55,24 @@ type News struct {
 	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"create_time"`
 	//[ 4] update_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
 	UpdateTime time.Time `gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"update_time"`
-	//[ 5] tags                                           varchar(128)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 128     default: []
-	Tags string `gorm:"column:tags;type:varchar;size:128;" json:"tags"`
-	//[ 6] cover                                          varchar(256)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 256     default: []
+	//[ 5] cover                                          varchar(256)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 256     default: []
 	Cover string `gorm:"column:cover;type:varchar;size:256;" json:"cover"`
+	//[ 6] version                                        int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [1]
+	Version int32 `gorm:"column:version;type:int;default:1;not null;" json:"version"` // row version used for optimistic concurrency
+	//[ 7] status                                         varchar(16)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 16      default: [published]
+	Status string `gorm:"column:status;type:varchar(16);default:'published';not null;index:idx_news_status;" json:"status"` // publishing state: draft, scheduled, published or archived
+	//[ 8] publish_at                                     datetime             null: true   primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	PublishAt null.Time `gorm:"column:publish_at;type:datetime;" json:"publish_at"` // time a scheduled record goes public
+	//[ 9] unpublish_at                                   datetime             null: true   primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	UnpublishAt null.Time `gorm:"column:unpublish_at;type:datetime;" json:"unpublish_at"` // time a published record is archived
+	//[10] cover_media_id                                 int                  null: true   primary: false  isArray: false  auto: false  col: int             len: -1      default: []
+	CoverMediaID null.Int `gorm:"column:cover_media_id;type:int;" json:"cover_media_id"` // id of the uploaded cover in the media table
+
+	// Tags attached to the record, kept in the news_tags join table
+	Tags TagList `gorm:"-" json:"tags"`
+
+	// CoverMedia uploaded cover referenced by cover_media_id, filled in by the dao
+	CoverMedia *Media `gorm:"-" json:"cover_media,omitempty"`
 }
 
 var newsTableInfo = &TableInfo{
@@ -101,6 +119,7 @@ var newsTableInfo = &TableInfo{
 			ProtobufFieldName:  "title",
 			ProtobufType:       "string",
 			ProtobufPos:        2,
+			Required:           true,
 		},
 
 		{
@@ -122,6 +141,7 @@ var newsTableInfo = &TableInfo{
 			ProtobufFieldName:  "content",
 			ProtobufType:       "string",
 			ProtobufPos:        3,
+			Sanitizer:          SanitizeRichText,
 		},
 
 		{
@@ -168,44 +188,130 @@ var newsTableInfo = &TableInfo{
 
 		{
 			Index:              5,
-			Name:               "tags",
+			Name:               "cover",
 			Comment:            ``,
 			Notes:              ``,
 			Nullable:           false,
 			DatabaseTypeName:   "varchar",
-			DatabaseTypePretty: "varchar(128)",
+			DatabaseTypePretty: "varchar(256)",
 			IsPrimaryKey:       false,
 			IsAutoIncrement:    false,
 			IsArray:            false,
 			ColumnType:         "varchar",
-			ColumnLength:       128,
-			GoFieldName:        "Tags",
+			ColumnLength:       256,
+			GoFieldName:        "Cover",
 			GoFieldType:        "string",
-			JSONFieldName:      "tags",
-			ProtobufFieldName:  "tags",
+			JSONFieldName:      "cover",
+			ProtobufFieldName:  "cover",
 			ProtobufType:       "string",
 			ProtobufPos:        6,
+			Required:           true,
+			Format:             FormatURL,
 		},
 
 		{
 			Index:              6,
-			Name:               "cover",
-			Comment:            ``,
+			Name:               "version",
+			Comment:            `row version used for optimistic concurrency`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Version",
+			GoFieldType:        "int32",
+			JSONFieldName:      "version",
+			ProtobufFieldName:  "version",
+			ProtobufType:       "int32",
+			ProtobufPos:        7,
+		},
+
+		{
+			Index:              7,
+			Name:               "status",
+			Comment:            `publishing state: draft, scheduled, published or archived`,
 			Notes:              ``,
 			Nullable:           false,
 			DatabaseTypeName:   "varchar",
-			DatabaseTypePretty: "varchar(256)",
+			DatabaseTypePretty: "varchar(16)",
 			IsPrimaryKey:       false,
 			IsAutoIncrement:    false,
 			IsArray:            false,
 			ColumnType:         "varchar",
-			ColumnLength:       256,
-			GoFieldName:        "Cover",
+			ColumnLength:       16,
+			GoFieldName:        "Status",
 			GoFieldType:        "string",
-			JSONFieldName:      "cover",
-			ProtobufFieldName:  "cover",
+			JSONFieldName:      "status",
+			ProtobufFieldName:  "status",
 			ProtobufType:       "string",
-			ProtobufPos:        7,
+			ProtobufPos:        8,
+		},
+
+		{
+			Index:              8,
+			Name:               "publish_at",
+			Comment:            `time a scheduled record goes public`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "PublishAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "publish_at",
+			ProtobufFieldName:  "publish_at",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        9,
+		},
+
+		{
+			Index:              9,
+			Name:               "unpublish_at",
+			Comment:            `time a published record is archived`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "UnpublishAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "unpublish_at",
+			ProtobufFieldName:  "unpublish_at",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        10,
+		},
+
+		{
+			Index:              10,
+			Name:               "cover_media_id",
+			Comment:            `id of the uploaded cover in the media table`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "CoverMediaID",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "cover_media_id",
+			ProtobufFieldName:  "cover_media_id",
+			ProtobufType:       "int64",
+			ProtobufPos:        11,
 		},
 	},
 }
@@ -222,11 +328,25 @@ func (n *News) BeforeSave() error {
 
 // Prepare invoked before saving, can be used to populate fields etc.
 func (n *News) Prepare() {
+	preparePublishing(&n.Status, n.PublishAt)
+	SanitizeColumns(n)
 }
 
 // Validate invoked before performing action, return an error if field is not populated.
 func (n *News) Validate(action Action) error {
-	return nil
+	if action != Create && action != Update {
+		return nil
+	}
+
+	// the cover is optional, pages fall back to a stock image
+	v := ValidateColumns(n, "cover")
+	validatePublishing(v, n.Status, n.PublishAt, n.UnpublishAt)
+	return v.Err()
+}
+
+// IsPublic reports whether the record is visible to the public at now
+func (n *News) IsPublic(now time.Time) bool {
+	return IsPublic(n.Status, n.PublishAt, n.UnpublishAt, now)
 }
 
 // TableInfo return table meta data
@@ -0,0 +1,462 @@
+package model
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/guregu/null"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+CREATE TABLE `outbox` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `template` varchar(64) NOT NULL DEFAULT '' COMMENT 'mail template the message was rendered from',
+  `to_address` varchar(255) NOT NULL COMMENT 'recipient',
+  `reply_to` varchar(255) NOT NULL DEFAULT '' COMMENT 'address replies go to, empty for the sender',
+  `subject` varchar(255) NOT NULL,
+  `text_body` text COMMENT 'plain text body',
+  `html_body` mediumtext COMMENT 'html alternative of the body, empty for plain text mails',
+  `status` varchar(16) NOT NULL DEFAULT 'queued' COMMENT 'queued, sent or dead',
+  `attempts` int NOT NULL DEFAULT '0' COMMENT 'delivery attempts made',
+  `last_error` text COMMENT 'error of the last failed delivery attempt',
+  `deliveries` text COMMENT 'json list of the delivery attempts and their outcome',
+  `sent_at` datetime DEFAULT NULL COMMENT 'time the mail was handed to the mailer, null until then',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `unsubscribe` varchar(512) NOT NULL DEFAULT '' COMMENT 'one-click unsubscribe url of mailing list mails, empty for other mails',
+  PRIMARY KEY (`id`),
+  KEY `idx_outbox_status` (`status`, `create_time`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='outgoing mails, persisted before they are sent'
+
+JSON Sample
+-------------------------------------
+{    "id": 18,    "template": "contact",    "to_address": "wcs399@proton.me",    "reply_to": "ada@example.org",    "subject": "New Contact Message!",    "text_body": "user name: Ada ...",    "html_body": "<!DOCTYPE html>...",    "status": "sent",    "attempts": 2,    "last_error": "",    "deliveries": [{"at": "2024-05-01T13:00:01Z", "error": "mailgun: 503 service unavailable"}, {"at": "2024-05-01T13:01:02Z"}],    "sent_at": "2024-05-01T13:01:02Z",    "create_time": "2024-05-01T13:00:00Z",    "update_time": "2024-05-01T13:01:02Z",    "unsubscribe": ""}
+
+
+
+*/
+
+// outbox statuses, dead mails used up their delivery attempts
+const (
+	MailQueued = "queued"
+	MailSent   = "sent"
+	MailDead   = "dead"
+)
+
+// Outbox struct is a row record of the outbox table in the wcs database
+type Outbox struct {
+	//[ 0] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
+	ID int32 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] template                                       varchar(64)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 64      default: []
+	Template string `gorm:"column:template;type:varchar(64);default:'';not null;" json:"template"` // mail template the message was rendered from
+	//[ 2] to_address                                     varchar(255)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 255     default: []
+	ToAddress string `gorm:"column:to_address;type:varchar(255);not null;" json:"to_address"` // recipient
+	//[ 3] reply_to                                       varchar(255)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 255     default: []
+	ReplyTo string `gorm:"column:reply_to;type:varchar(255);default:'';not null;" json:"reply_to"` // address replies go to, empty for the sender
+	//[ 4] subject                                        varchar(255)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 255     default: []
+	Subject string `gorm:"column:subject;type:varchar(255);not null;" json:"subject"`
+	//[ 5] text_body                                      text(65535)          null: true   primary: false  isArray: false  auto: false  col: text            len: 65535   default: []
+	TextBody string `gorm:"column:text_body;type:text;" json:"text_body"` // plain text body
+	//[ 6] html_body                                      mediumtext(16777215) null: true   primary: false  isArray: false  auto: false  col: mediumtext      len: 16777215default: []
+	HTMLBody string `gorm:"column:html_body;type:mediumtext;" json:"html_body"` // html alternative of the body, empty for plain text mails
+	//[ 7] status                                         varchar(16)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 16      default: [queued]
+	Status string `gorm:"column:status;type:varchar(16);default:'queued';not null;index:idx_outbox_status;" json:"status"` // queued, sent or dead
+	//[ 8] attempts                                       int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	Attempts int32 `gorm:"column:attempts;type:int;default:0;not null;" json:"attempts"` // delivery attempts made
+	//[ 9] last_error                                     text(65535)          null: true   primary: false  isArray: false  auto: false  col: text            len: 65535   default: []
+	LastError string `gorm:"column:last_error;type:text;" json:"last_error"` // error of the last failed delivery attempt
+	//[10] deliveries                                     text(65535)          null: true   primary: false  isArray: false  auto: false  col: text            len: 65535   default: []
+	Deliveries DeliveryLog `gorm:"column:deliveries;type:text;" json:"deliveries"` // json list of the delivery attempts and their outcome
+	//[11] sent_at                                        datetime             null: true   primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	SentAt null.Time `gorm:"column:sent_at;type:datetime;" json:"sent_at"` // time the mail was handed to the mailer, null until then
+	//[12] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;index:idx_outbox_status;" json:"create_time"`
+	//[13] update_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	UpdateTime time.Time `gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"update_time"`
+	//[14] unsubscribe                                    varchar(512)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 512     default: []
+	Unsubscribe string `gorm:"column:unsubscribe;type:varchar(512);default:'';not null;" json:"unsubscribe"` // one-click unsubscribe url of mailing list mails, empty for other mails
+}
+
+var outboxTableInfo = &TableInfo{
+	Name: "outbox",
+	Columns: []*ColumnInfo{
+
+		{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		{
+			Index:              1,
+			Name:               "template",
+			Comment:            `mail template the message was rendered from`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       64,
+			GoFieldName:        "Template",
+			GoFieldType:        "string",
+			JSONFieldName:      "template",
+			ProtobufFieldName:  "template",
+			ProtobufType:       "string",
+			ProtobufPos:        2,
+		},
+
+		{
+			Index:              2,
+			Name:               "to_address",
+			Comment:            `recipient`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       255,
+			GoFieldName:        "ToAddress",
+			GoFieldType:        "string",
+			JSONFieldName:      "to_address",
+			ProtobufFieldName:  "to_address",
+			ProtobufType:       "string",
+			ProtobufPos:        3,
+			Required:           true,
+		},
+
+		{
+			Index:              3,
+			Name:               "reply_to",
+			Comment:            `address replies go to, empty for the sender`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       255,
+			GoFieldName:        "ReplyTo",
+			GoFieldType:        "string",
+			JSONFieldName:      "reply_to",
+			ProtobufFieldName:  "reply_to",
+			ProtobufType:       "string",
+			ProtobufPos:        4,
+		},
+
+		{
+			Index:              4,
+			Name:               "subject",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       255,
+			GoFieldName:        "Subject",
+			GoFieldType:        "string",
+			JSONFieldName:      "subject",
+			ProtobufFieldName:  "subject",
+			ProtobufType:       "string",
+			ProtobufPos:        5,
+			Required:           true,
+		},
+
+		{
+			Index:              5,
+			Name:               "text_body",
+			Comment:            `plain text body`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "text",
+			DatabaseTypePretty: "text(65535)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "text",
+			ColumnLength:       65535,
+			GoFieldName:        "TextBody",
+			GoFieldType:        "string",
+			JSONFieldName:      "text_body",
+			ProtobufFieldName:  "text_body",
+			ProtobufType:       "string",
+			ProtobufPos:        6,
+		},
+
+		{
+			Index:              6,
+			Name:               "html_body",
+			Comment:            `html alternative of the body, empty for plain text mails`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "mediumtext",
+			DatabaseTypePretty: "mediumtext(16777215)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "mediumtext",
+			ColumnLength:       16777215,
+			GoFieldName:        "HTMLBody",
+			GoFieldType:        "string",
+			JSONFieldName:      "html_body",
+			ProtobufFieldName:  "html_body",
+			ProtobufType:       "string",
+			ProtobufPos:        7,
+		},
+
+		{
+			Index:              7,
+			Name:               "status",
+			Comment:            `queued, sent or dead`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(16)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       16,
+			GoFieldName:        "Status",
+			GoFieldType:        "string",
+			JSONFieldName:      "status",
+			ProtobufFieldName:  "status",
+			ProtobufType:       "string",
+			ProtobufPos:        8,
+		},
+
+		{
+			Index:              8,
+			Name:               "attempts",
+			Comment:            `delivery attempts made`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Attempts",
+			GoFieldType:        "int32",
+			JSONFieldName:      "attempts",
+			ProtobufFieldName:  "attempts",
+			ProtobufType:       "int32",
+			ProtobufPos:        9,
+		},
+
+		{
+			Index:              9,
+			Name:               "last_error",
+			Comment:            `error of the last failed delivery attempt`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "text",
+			DatabaseTypePretty: "text(65535)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "text",
+			ColumnLength:       65535,
+			GoFieldName:        "LastError",
+			GoFieldType:        "string",
+			JSONFieldName:      "last_error",
+			ProtobufFieldName:  "last_error",
+			ProtobufType:       "string",
+			ProtobufPos:        10,
+		},
+
+		{
+			Index:              10,
+			Name:               "deliveries",
+			Comment:            `json list of the delivery attempts and their outcome`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "text",
+			DatabaseTypePretty: "text(65535)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "text",
+			ColumnLength:       65535,
+			GoFieldName:        "Deliveries",
+			GoFieldType:        "DeliveryLog",
+			JSONFieldName:      "deliveries",
+			ProtobufFieldName:  "deliveries",
+			ProtobufType:       "string",
+			ProtobufPos:        11,
+		},
+
+		{
+			Index:              11,
+			Name:               "sent_at",
+			Comment:            `time the mail was handed to the mailer, null until then`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "SentAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "sent_at",
+			ProtobufFieldName:  "sent_at",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        12,
+		},
+
+		{
+			Index:              12,
+			Name:               "create_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "CreateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "create_time",
+			ProtobufFieldName:  "create_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        13,
+		},
+
+		{
+			Index:              13,
+			Name:               "update_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "UpdateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "update_time",
+			ProtobufFieldName:  "update_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        14,
+		},
+
+		{
+			Index:              14,
+			Name:               "unsubscribe",
+			Comment:            `one-click unsubscribe url of mailing list mails, empty for other mails`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(512)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       512,
+			GoFieldName:        "Unsubscribe",
+			GoFieldType:        "string",
+			JSONFieldName:      "unsubscribe",
+			ProtobufFieldName:  "unsubscribe",
+			ProtobufType:       "string",
+			ProtobufPos:        15,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (o *Outbox) TableName() string {
+	return "outbox"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (o *Outbox) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (o *Outbox) Prepare() {
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (o *Outbox) Validate(action Action) error {
+	return nil
+}
+
+// TableInfo return table meta data
+func (o *Outbox) TableInfo() *TableInfo {
+	return outboxTableInfo
+}
+
+// Delivery a delivery attempt of an outgoing mail, Error is empty when the mailer accepted it
+type Delivery struct {
+	At    time.Time `json:"at"`
+	Error string    `json:"error,omitempty"`
+}
+
+// DeliveryLog delivery attempts of an outgoing mail, oldest first, stored as json
+type DeliveryLog []*Delivery
+
+// Value stores the log as json
+func (l DeliveryLog) Value() (driver.Value, error) {
+	if l == nil {
+		l = DeliveryLog{}
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
+// Scan reads the log from json
+func (l *DeliveryLog) Scan(src interface{}) error {
+	return scanJSON(src, l)
+}
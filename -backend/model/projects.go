@@ -25,6 +25,7 @@ CREATE TABLE `projects` (
   `name` varchar(512) NOT NULL,
   `intro` longtext NOT NULL,
   `link` varchar(128) NOT NULL DEFAULT '',
+  `version` int NOT NULL DEFAULT '1',
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci
 
@@ -46,6 +47,8 @@ type Projects struct {
 	Intro string `gorm:"column:intro;type:text;size:4294967295;" json:"intro"`
 	//[ 3] link                                           varchar(128)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 128     default: []
 	Link string `gorm:"column:link;type:varchar;size:128;" json:"link"`
+	//[ 4] version                                        int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [1]
+	Version int32 `gorm:"column:version;type:int;default:1;not null;" json:"version"` // row version used for optimistic concurrency
 }
 
 var projectsTableInfo = &TableInfo{
@@ -92,6 +95,7 @@ var projectsTableInfo = &TableInfo{
 			ProtobufFieldName:  "name",
 			ProtobufType:       "string",
 			ProtobufPos:        2,
+			Required:           true,
 		},
 
 		{
@@ -113,6 +117,7 @@ var projectsTableInfo = &TableInfo{
 			ProtobufFieldName:  "intro",
 			ProtobufType:       "string",
 			ProtobufPos:        3,
+			Sanitizer:          SanitizeRichText,
 		},
 
 		{
@@ -134,6 +139,28 @@ var projectsTableInfo = &TableInfo{
 			ProtobufFieldName:  "link",
 			ProtobufType:       "string",
 			ProtobufPos:        4,
+			Format:             FormatURL,
+		},
+
+		{
+			Index:              4,
+			Name:               "version",
+			Comment:            `row version used for optimistic concurrency`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Version",
+			GoFieldType:        "int32",
+			JSONFieldName:      "version",
+			ProtobufFieldName:  "version",
+			ProtobufType:       "int32",
+			ProtobufPos:        5,
 		},
 	},
 }
@@ -150,11 +177,16 @@ func (p *Projects) BeforeSave() error {
 
 // Prepare invoked before saving, can be used to populate fields etc.
 func (p *Projects) Prepare() {
+	SanitizeColumns(p)
 }
 
 // Validate invoked before performing action, return an error if field is not populated.
 func (p *Projects) Validate(action Action) error {
-	return nil
+	if action != Create && action != Update {
+		return nil
+	}
+
+	return ValidateColumns(p).Err()
 }
 
 // TableInfo return table meta data
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"time"
+
+	"github.com/guregu/null"
+)
+
+const (
+	// StatusDraft record is only visible to admins
+	StatusDraft = "draft"
+
+	// StatusScheduled record goes public at publish_at
+	StatusScheduled = "scheduled"
+
+	// StatusPublished record is public, until unpublish_at when set
+	StatusPublished = "published"
+
+	// StatusArchived record was taken down and is only visible to admins
+	StatusArchived = "archived"
+)
+
+var publishStatuses = map[string]bool{
+	StatusDraft:     true,
+	StatusScheduled: true,
+	StatusPublished: true,
+	StatusArchived:  true,
+}
+
+// IsPublishStatus reports whether status is one of the publishing states
+func IsPublishStatus(status string) bool {
+	return publishStatuses[status]
+}
+
+// IsPublic reports whether a record in the given publishing state is visible to the public at now.
+// A scheduled record past its publish_at counts as public even before the scheduler flipped it.
+func IsPublic(status string, publishAt, unpublishAt null.Time, now time.Time) bool {
+	switch status {
+	case StatusPublished:
+	case StatusScheduled:
+		if !publishAt.Valid || publishAt.Time.After(now) {
+			return false
+		}
+	default:
+		return false
+	}
+
+	return !unpublishAt.Valid || unpublishAt.Time.After(now)
+}
+
+// preparePublishing fills in the publishing state of a record that does not set one.
+// Records with a future publish_at are scheduled, everything else starts as a draft.
+func preparePublishing(status *string, publishAt null.Time) {
+	if *status != "" {
+		return
+	}
+
+	if publishAt.Valid && publishAt.Time.After(time.Now()) {
+		*status = StatusScheduled
+		return
+	}
+
+	*status = StatusDraft
+}
+
+// validatePublishing checks the publishing state and schedule of a record are consistent
+func validatePublishing(v *ValidationError, status string, publishAt, unpublishAt null.Time) {
+	if !IsPublishStatus(status) {
+		v.Add("status", ErrCodeInvalid, "unknown status %q", status)
+	}
+
+	if status == StatusScheduled && !publishAt.Valid {
+		v.Add("publish_at", ErrCodeRequired, "scheduled record requires publish_at")
+	}
+
+	if publishAt.Valid && unpublishAt.Valid && !unpublishAt.Time.After(publishAt.Time) {
+		v.Add("unpublish_at", ErrCodeOutOfRange, "unpublish_at must be after publish_at")
+	}
+}
@@ -0,0 +1,395 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/guregu/null"
+)
+
+// Occurrence single occurrence of an event, End is null for events without end
+type Occurrence struct {
+	Start time.Time `json:"start"`
+	End   null.Time `json:"end"`
+}
+
+// WeekdayNum BYDAY entry of a recurrence rule, N is the ordinal within the month, 0 for every such weekday
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// RRule recurrence rule of an event, the RFC 5545 subset of FREQ, INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and BYMONTH
+type RRule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []time.Month
+
+	loc *time.Location
+}
+
+const (
+	// maxRRulePeriods bounds the periods an expansion walks through, 50000 days are over a century
+	maxRRulePeriods = 50000
+
+	// icsUTC and icsLocal layouts of iCalendar date times, icsDate of dates
+	icsUTC   = "20060102T150405Z"
+	icsLocal = "20060102T150405"
+	icsDate  = "20060102"
+)
+
+var rruleFreqs = map[string]bool{"DAILY": true, "WEEKLY": true, "MONTHLY": true, "YEARLY": true}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// zones loaded time zones by name, LoadLocation reads the zone database on every call
+var zones sync.Map
+
+// LoadZone returns the IANA time zone name, cached
+func LoadZone(name string) (*time.Location, error) {
+	if loc, ok := zones.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	zones.Store(name, loc)
+	return loc, nil
+}
+
+// ParseRRule parses a recurrence rule such as FREQ=WEEKLY;BYDAY=MO,WE;COUNT=10, an optional RRULE: prefix is allowed.
+// Floating and date UNTIL values are read in loc, dates include the whole day.
+func ParseRRule(s string, loc *time.Location) (*RRule, error) {
+	r := &RRule{Interval: 1, loc: loc}
+	s = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "RRULE:")
+
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("malformed rule part %q", part)
+		}
+
+		key, value := kv[0], kv[1]
+		var err error
+		switch key {
+		case "FREQ":
+			if !rruleFreqs[value] {
+				return nil, fmt.Errorf("unsupported FREQ %s, use DAILY, WEEKLY, MONTHLY or YEARLY", value)
+			}
+			r.Freq = value
+		case "INTERVAL":
+			if r.Interval, err = strconv.Atoi(value); err != nil || r.Interval < 1 {
+				return nil, fmt.Errorf("INTERVAL must be a positive number")
+			}
+		case "COUNT":
+			if r.Count, err = strconv.Atoi(value); err != nil || r.Count < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive number")
+			}
+		case "UNTIL":
+			if r.Until, err = parseUntil(value, loc); err != nil {
+				return nil, err
+			}
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				if len(day) < 2 {
+					return nil, fmt.Errorf("unknown weekday in BYDAY %s", day)
+				}
+				wd, ok := weekdayCodes[day[len(day)-2:]]
+				if !ok {
+					return nil, fmt.Errorf("unknown weekday in BYDAY %s", day)
+				}
+				n := 0
+				if prefix := day[:len(day)-2]; prefix != "" {
+					if n, err = strconv.Atoi(prefix); err != nil || n == 0 || n < -5 || n > 5 {
+						return nil, fmt.Errorf("BYDAY ordinal must be between -5 and 5, got %s", day)
+					}
+				}
+				r.ByDay = append(r.ByDay, WeekdayNum{N: n, Day: wd})
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("BYMONTHDAY must be between -31 and 31, got %s", day)
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, month := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(month)
+				if err != nil || n < 1 || n > 12 {
+					return nil, fmt.Errorf("BYMONTH must be between 1 and 12, got %s", month)
+				}
+				r.ByMonth = append(r.ByMonth, time.Month(n))
+			}
+		case "WKST":
+			if value != "MO" {
+				return nil, fmt.Errorf("only WKST=MO is supported")
+			}
+		default:
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("COUNT and UNTIL can not be combined")
+	}
+	for _, day := range r.ByDay {
+		if day.N != 0 && r.Freq != "MONTHLY" && r.Freq != "YEARLY" {
+			return nil, fmt.Errorf("BYDAY ordinals are only allowed in MONTHLY and YEARLY rules")
+		}
+	}
+	if r.Freq == "YEARLY" && len(r.ByDay) > 0 && len(r.ByMonth) == 0 {
+		return nil, fmt.Errorf("BYDAY in YEARLY rules requires BYMONTH")
+	}
+	if r.Freq == "WEEKLY" && len(r.ByMonthDay) > 0 {
+		return nil, fmt.Errorf("BYMONTHDAY is not allowed in WEEKLY rules")
+	}
+
+	return r, nil
+}
+
+// parseUntil reads an UNTIL value, a UTC or floating date time or a date
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(icsUTC, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(icsLocal, value, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(icsDate, value, loc); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("UNTIL must be a date or date time such as 20240131 or 20240131T170000Z")
+}
+
+// Format returns the rule in canonical form, UNTIL as UTC date time or as date for rules of all day events
+func (r *RRule) Format(allDay bool) string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		if allDay {
+			parts = append(parts, "UNTIL="+r.Until.In(r.loc).Format(icsDate))
+		} else {
+			parts = append(parts, "UNTIL="+r.Until.UTC().Format(icsUTC))
+		}
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, m := range r.ByMonth {
+			months[i] = strconv.Itoa(int(m))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = strings.ToUpper(d.Day.String()[:2])
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Starts returns the starts of the occurrences of an event first starting at start that begin before to and end after from,
+// at most limit of them. Occurrences keep the wall clock time of start in the zone of the rule across daylight saving changes.
+func (r *RRule) Starts(start time.Time, length time.Duration, from, to time.Time, limit int) []time.Time {
+	start = start.In(r.loc)
+	var starts []time.Time
+	count := 0
+	for period := 0; period < maxRRulePeriods; period++ {
+		for _, t := range r.candidates(start, period*r.Interval) {
+			if t.Before(start) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return starts
+			}
+			if count++; r.Count > 0 && count > r.Count {
+				return starts
+			}
+			if !t.Before(to) {
+				return starts
+			}
+			if t.Add(length).After(from) || (length == 0 && !t.Before(from)) {
+				if starts = append(starts, t); len(starts) >= limit {
+					return starts
+				}
+			}
+		}
+	}
+	return starts
+}
+
+// candidates returns the sorted starts the rule yields in the period n frequency units after the one of start
+func (r *RRule) candidates(start time.Time, n int) []time.Time {
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, start.Hour(), start.Minute(), start.Second(), 0, r.loc)
+	}
+
+	var days []time.Time
+	switch r.Freq {
+	case "DAILY":
+		d := start.AddDate(0, 0, n)
+		if r.matchMonth(d.Month()) && r.matchMonthDay(d) && r.matchWeekday(d) {
+			days = append(days, at(d.Date()))
+		}
+	case "WEEKLY":
+		monday := start.AddDate(0, 0, -int((start.Weekday()+6)%7)+7*n)
+		weekdays := []time.Weekday{start.Weekday()}
+		if len(r.ByDay) > 0 {
+			weekdays = weekdays[:0]
+			for _, day := range r.ByDay {
+				weekdays = append(weekdays, day.Day)
+			}
+		}
+		for _, wd := range weekdays {
+			d := monday.AddDate(0, 0, int((wd+6)%7))
+			if r.matchMonth(d.Month()) {
+				days = append(days, at(d.Date()))
+			}
+		}
+	case "MONTHLY":
+		first := time.Date(start.Year(), start.Month()+time.Month(n), 1, 0, 0, 0, 0, r.loc)
+		if r.matchMonth(first.Month()) {
+			for _, d := range r.monthDays(first, start.Day()) {
+				days = append(days, at(first.Year(), first.Month(), d))
+			}
+		}
+	case "YEARLY":
+		months := r.ByMonth
+		if len(months) == 0 {
+			months = []time.Month{start.Month()}
+		}
+		for _, m := range months {
+			first := time.Date(start.Year()+n, m, 1, 0, 0, 0, 0, r.loc)
+			for _, d := range r.monthDays(first, start.Day()) {
+				days = append(days, at(first.Year(), first.Month(), d))
+			}
+		}
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	unique := days[:0]
+	for i, d := range days {
+		if i == 0 || !d.Equal(days[i-1]) {
+			unique = append(unique, d)
+		}
+	}
+	return unique
+}
+
+// monthDays returns the days of the month starting at first the rule selects, day when it selects none explicitly
+func (r *RRule) monthDays(first time.Time, day int) []int {
+	last := first.AddDate(0, 1, -1).Day()
+	var days []int
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = last + d + 1
+			}
+			if d >= 1 && d <= last && r.matchWeekday(first.AddDate(0, 0, d-1)) {
+				days = append(days, d)
+			}
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			offset := int(wd.Day-first.Weekday()+7) % 7
+			var matches []int
+			for d := 1 + offset; d <= last; d += 7 {
+				matches = append(matches, d)
+			}
+			switch {
+			case wd.N == 0:
+				days = append(days, matches...)
+			case wd.N > 0 && wd.N <= len(matches):
+				days = append(days, matches[wd.N-1])
+			case wd.N < 0 && -wd.N <= len(matches):
+				days = append(days, matches[len(matches)+wd.N])
+			}
+		}
+	case day <= last:
+		days = append(days, day)
+	}
+
+	return days
+}
+
+func (r *RRule) matchMonth(m time.Month) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, month := range r.ByMonth {
+		if month == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *RRule) matchMonthDay(d time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	last := d.AddDate(0, 1, -d.Day()).Day()
+	for _, day := range r.ByMonthDay {
+		if day == d.Day() || last+day+1 == d.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWeekday reports whether d falls on a weekday of BYDAY, ordinals are handled by monthDays
+func (r *RRule) matchWeekday(d time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, day := range r.ByDay {
+		if day.Day == d.Weekday() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,585 @@
+package model
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/guregu/null"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+CREATE TABLE `registrations` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `event_id` int NOT NULL COMMENT 'id of the event registered for',
+  `name` varchar(128) NOT NULL COMMENT 'name of the attendee',
+  `email` varchar(255) NOT NULL COMMENT 'email address of the attendee, lower case',
+  `answers` text COMMENT 'json object of the answers to the custom questions of the event, keyed by question id',
+  `status` varchar(16) NOT NULL DEFAULT 'confirmed' COMMENT 'confirmed, waitlisted or cancelled',
+  `token` varchar(64) NOT NULL COMMENT 'secret of the cancel link sent to the attendee',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `checked_in_at` datetime DEFAULT NULL COMMENT 'time the ticket was first scanned at the door, null until the attendee arrived',
+  `scan_count` int NOT NULL DEFAULT '0' COMMENT 'times the ticket was scanned, more than 1 for duplicate scans',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_registrations_token` (`token`),
+  KEY `idx_registrations_event` (`event_id`, `status`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='registrations of attendees for events'
+
+JSON Sample
+-------------------------------------
+{    "id": 12,    "event_id": 35,    "name": "Ada Lovelace",    "email": "ada@example.org",    "answers": {"dietary-requirements": "vegetarian"},    "status": "waitlisted",    "create_time": "2024-04-02T09:30:00Z",    "update_time": "2024-04-02T09:30:00Z",    "checked_in_at": null,    "scan_count": 0,    "position": 3}
+
+
+
+*/
+
+// registration statuses
+const (
+	RegistrationConfirmed  = "confirmed"
+	RegistrationWaitlisted = "waitlisted"
+	RegistrationCancelled  = "cancelled"
+)
+
+// limits of the custom questions of an event and of the answers to them
+const (
+	maxQuestions      = 20
+	maxQuestionLength = 255
+	maxAnswerLength   = 1000
+)
+
+// Registrations struct is a row record of the registrations table in the wcs database
+type Registrations struct {
+	//[ 0] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
+	ID int32 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] event_id                                       int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: []
+	EventID int32 `gorm:"column:event_id;type:int;not null;index:idx_registrations_event;" json:"event_id"` // id of the event registered for
+	//[ 2] name                                           varchar(128)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 128     default: []
+	Name string `gorm:"column:name;type:varchar(128);not null;" json:"name"` // name of the attendee
+	//[ 3] email                                          varchar(255)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 255     default: []
+	Email string `gorm:"column:email;type:varchar(255);not null;" json:"email"` // email address of the attendee, lower case
+	//[ 4] answers                                        text(65535)          null: true   primary: false  isArray: false  auto: false  col: text            len: 65535   default: []
+	Answers Answers `gorm:"column:answers;type:text;" json:"answers"` // json object of the answers to the custom questions of the event, keyed by question id
+	//[ 5] status                                         varchar(16)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 16      default: [confirmed]
+	Status string `gorm:"column:status;type:varchar(16);default:'confirmed';not null;index:idx_registrations_event;" json:"status"` // confirmed, waitlisted or cancelled
+	//[ 6] token                                          varchar(64)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 64      default: []
+	Token string `gorm:"column:token;type:varchar(64);not null;unique_index:idx_registrations_token;" json:"-"` // secret of the cancel link sent to the attendee
+	//[ 7] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"create_time"`
+	//[ 8] update_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	UpdateTime time.Time `gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"update_time"`
+	//[ 9] checked_in_at                                  datetime             null: true   primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	CheckedInAt null.Time `gorm:"column:checked_in_at;type:datetime;" json:"checked_in_at"` // time the ticket was first scanned at the door, null until the attendee arrived
+	//[10] scan_count                                     int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	ScanCount int32 `gorm:"column:scan_count;type:int;default:0;not null;" json:"scan_count"` // times the ticket was scanned, more than 1 for duplicate scans
+
+	// Position place of a waitlisted registration on the waitlist, starting at 1, filled in by the dao
+	Position int `gorm:"-" json:"position,omitempty"`
+
+	// Ticket signed ticket code of a confirmed registration, shown to the attendee who registered, filled in by the api
+	Ticket string `gorm:"-" json:"ticket,omitempty"`
+}
+
+var registrationsTableInfo = &TableInfo{
+	Name: "registrations",
+	Columns: []*ColumnInfo{
+
+		{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		{
+			Index:              1,
+			Name:               "event_id",
+			Comment:            `id of the event registered for`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "EventID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "event_id",
+			ProtobufFieldName:  "event_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        2,
+		},
+
+		{
+			Index:              2,
+			Name:               "name",
+			Comment:            `name of the attendee`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(128)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       128,
+			GoFieldName:        "Name",
+			GoFieldType:        "string",
+			JSONFieldName:      "name",
+			ProtobufFieldName:  "name",
+			ProtobufType:       "string",
+			ProtobufPos:        3,
+			Required:           true,
+		},
+
+		{
+			Index:              3,
+			Name:               "email",
+			Comment:            `email address of the attendee, lower case`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       255,
+			GoFieldName:        "Email",
+			GoFieldType:        "string",
+			JSONFieldName:      "email",
+			ProtobufFieldName:  "email",
+			ProtobufType:       "string",
+			ProtobufPos:        4,
+			Required:           true,
+		},
+
+		{
+			Index:              4,
+			Name:               "answers",
+			Comment:            `json object of the answers to the custom questions of the event, keyed by question id`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "text",
+			DatabaseTypePretty: "text(65535)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "text",
+			ColumnLength:       65535,
+			GoFieldName:        "Answers",
+			GoFieldType:        "Answers",
+			JSONFieldName:      "answers",
+			ProtobufFieldName:  "answers",
+			ProtobufType:       "string",
+			ProtobufPos:        5,
+		},
+
+		{
+			Index:              5,
+			Name:               "status",
+			Comment:            `confirmed, waitlisted or cancelled`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(16)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       16,
+			GoFieldName:        "Status",
+			GoFieldType:        "string",
+			JSONFieldName:      "status",
+			ProtobufFieldName:  "status",
+			ProtobufType:       "string",
+			ProtobufPos:        6,
+			DefaultValue:       "confirmed",
+		},
+
+		{
+			Index:              6,
+			Name:               "token",
+			Comment:            `secret of the cancel link sent to the attendee`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       64,
+			GoFieldName:        "Token",
+			GoFieldType:        "string",
+			JSONFieldName:      "token",
+			ProtobufFieldName:  "token",
+			ProtobufType:       "string",
+			ProtobufPos:        7,
+		},
+
+		{
+			Index:              7,
+			Name:               "create_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "CreateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "create_time",
+			ProtobufFieldName:  "create_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        8,
+			DefaultValue:       "CURRENT_TIMESTAMP",
+		},
+
+		{
+			Index:              8,
+			Name:               "update_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "UpdateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "update_time",
+			ProtobufFieldName:  "update_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        9,
+			DefaultValue:       "CURRENT_TIMESTAMP",
+		},
+
+		{
+			Index:              9,
+			Name:               "checked_in_at",
+			Comment:            `time the ticket was first scanned at the door, null until the attendee arrived`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "CheckedInAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "checked_in_at",
+			ProtobufFieldName:  "checked_in_at",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        10,
+		},
+
+		{
+			Index:              10,
+			Name:               "scan_count",
+			Comment:            `times the ticket was scanned, more than 1 for duplicate scans`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ScanCount",
+			GoFieldType:        "int32",
+			JSONFieldName:      "scan_count",
+			ProtobufFieldName:  "scan_count",
+			ProtobufType:       "int32",
+			ProtobufPos:        11,
+			DefaultValue:       "0",
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (r *Registrations) TableName() string {
+	return "registrations"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (r *Registrations) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+// Emails are kept in lower case so an attendee can not register twice with different spellings.
+func (r *Registrations) Prepare() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Email = strings.ToLower(strings.TrimSpace(r.Email))
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+// The answers are checked against the questions of the event by ValidateAnswers.
+func (r *Registrations) Validate(action Action) error {
+	if action != Create {
+		return nil
+	}
+
+	v := ValidateColumns(r)
+	if r.Email != "" {
+		if addr, err := mail.ParseAddress(r.Email); err != nil || addr.Address != r.Email {
+			v.Add("email", ErrCodeInvalid, "email must be an email address such as name@example.org")
+		}
+	}
+
+	return v.Err()
+}
+
+// TableInfo return table meta data
+func (r *Registrations) TableInfo() *TableInfo {
+	return registrationsTableInfo
+}
+
+// Attendance registration counts of an event
+type Attendance struct {
+	Confirmed  int `json:"confirmed" example:"28"`
+	Waitlisted int `json:"waitlisted" example:"0"`
+
+	// Available places left for confirmed registrations, null when the capacity is unlimited
+	Available null.Int `json:"available" swaggertype:"integer" example:"2"`
+}
+
+// NewAttendance returns the attendance of an event of capacity with the given registration counts
+func NewAttendance(capacity int32, confirmed, waitlisted int) *Attendance {
+	a := &Attendance{Confirmed: confirmed, Waitlisted: waitlisted}
+	if capacity > 0 {
+		available := int64(capacity) - int64(confirmed)
+		if available < 0 {
+			available = 0
+		}
+		a.Available = null.IntFrom(available)
+	}
+	return a
+}
+
+// AttendanceStats registrations and check-ins of an event
+type AttendanceStats struct {
+	EventID int32 `json:"event_id" example:"35"`
+
+	// Capacity places of the event, 0 when unlimited
+	Capacity int32 `json:"capacity" example:"30"`
+
+	Confirmed  int `json:"confirmed" example:"30"`
+	Waitlisted int `json:"waitlisted" example:"4"`
+	Cancelled  int `json:"cancelled" example:"2"`
+
+	// CheckedIn confirmed registrations whose ticket was scanned
+	CheckedIn int `json:"checked_in" example:"26"`
+
+	// NoShows confirmed registrations whose ticket was not scanned
+	NoShows int `json:"no_shows" example:"4"`
+
+	// DuplicateScans scans of tickets checked in already
+	DuplicateScans int `json:"duplicate_scans" example:"1"`
+
+	// CheckInRate share of the confirmed registrations checked in, 0 to 1
+	CheckInRate float64 `json:"check_in_rate" example:"0.87"`
+
+	FirstCheckIn null.Time `json:"first_check_in" swaggertype:"string" example:"2024-05-02T13:41:07Z"`
+	LastCheckIn  null.Time `json:"last_check_in" swaggertype:"string" example:"2024-05-02T14:12:55Z"`
+}
+
+// Add counts registration r in the stats
+func (s *AttendanceStats) Add(r *Registrations) {
+	switch r.Status {
+	case RegistrationConfirmed:
+		s.Confirmed++
+	case RegistrationWaitlisted:
+		s.Waitlisted++
+	case RegistrationCancelled:
+		s.Cancelled++
+	}
+
+	if r.ScanCount > 1 {
+		s.DuplicateScans += int(r.ScanCount) - 1
+	}
+
+	if r.Status != RegistrationConfirmed {
+		return
+	}
+	if !r.CheckedInAt.Valid {
+		s.NoShows++
+		return
+	}
+
+	s.CheckedIn++
+	s.CheckInRate = float64(s.CheckedIn) / float64(s.Confirmed)
+	if !s.FirstCheckIn.Valid || r.CheckedInAt.Time.Before(s.FirstCheckIn.Time) {
+		s.FirstCheckIn = r.CheckedInAt
+	}
+	if !s.LastCheckIn.Valid || r.CheckedInAt.Time.After(s.LastCheckIn.Time) {
+		s.LastCheckIn = r.CheckedInAt
+	}
+}
+
+// Question custom question of the registration form of an event
+type Question struct {
+	// ID key of the answer in the answers of a registration, derived from the label when empty
+	ID string `json:"id" example:"dietary-requirements"`
+
+	Label string `json:"label" example:"Dietary requirements"`
+
+	// Required registrations must answer the question
+	Required bool `json:"required" example:"false"`
+}
+
+// QuestionList custom questions of an event, stored as json in the questions column
+type QuestionList []*Question
+
+// Value stores the list as json
+func (l QuestionList) Value() (driver.Value, error) {
+	if l == nil {
+		l = QuestionList{}
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
+// Scan reads the list from its json column
+func (l *QuestionList) Scan(src interface{}) error {
+	return scanJSON(src, l)
+}
+
+// Prepare trims the labels and derives the missing ids from them
+func (l QuestionList) Prepare() {
+	for _, q := range l {
+		if q == nil {
+			continue
+		}
+		q.Label = strings.TrimSpace(q.Label)
+		if q.ID = strings.TrimSpace(q.ID); q.ID == "" {
+			q.ID = Slugify(q.Label)
+		}
+	}
+}
+
+// Validate records the failures of the questions in v: each needs a label and an id of its own
+func (l QuestionList) Validate(v *ValidationError) {
+	if len(l) > maxQuestions {
+		v.Add("questions", ErrCodeOutOfRange, "questions must hold at most %d questions", maxQuestions)
+		return
+	}
+
+	ids := make(map[string]bool)
+	for i, q := range l {
+		switch {
+		case q == nil || q.Label == "":
+			v.Add("questions", ErrCodeRequired, "label of question %d is required", i+1)
+		case utf8.RuneCountInString(q.Label) > maxQuestionLength:
+			v.Add("questions", ErrCodeTooLong, "label of question %d must be at most %d characters", i+1, maxQuestionLength)
+		case q.ID == "" || len(q.ID) > 64:
+			v.Add("questions", ErrCodeInvalid, "question %d needs an id of at most 64 characters", i+1)
+		case ids[q.ID]:
+			v.Add("questions", ErrCodeInvalid, "question id %s is used more than once", q.ID)
+		default:
+			ids[q.ID] = true
+		}
+	}
+}
+
+// Answers answers of a registration to the custom questions of its event, keyed by question id
+type Answers map[string]string
+
+// Value stores the answers as json
+func (a Answers) Value() (driver.Value, error) {
+	if a == nil {
+		a = Answers{}
+	}
+	data, err := json.Marshal(a)
+	return string(data), err
+}
+
+// Scan reads the answers from their json column
+func (a *Answers) Scan(src interface{}) error {
+	return scanJSON(src, a)
+}
+
+// ValidateAnswers checks the answers of r against the questions of its event: required questions must be answered
+// and answers must fit maxAnswerLength. Answers to unknown questions are dropped.
+func ValidateAnswers(r *Registrations, questions QuestionList) error {
+	v := &ValidationError{}
+	answers := Answers{}
+	for _, q := range questions {
+		answer := strings.TrimSpace(r.Answers[q.ID])
+		switch {
+		case answer == "" && q.Required:
+			v.Add("answers."+q.ID, ErrCodeRequired, "%s is required", q.Label)
+		case utf8.RuneCountInString(answer) > maxAnswerLength:
+			v.Add("answers."+q.ID, ErrCodeTooLong, "%s must be at most %d characters", q.Label, maxAnswerLength)
+		case answer != "":
+			answers[q.ID] = answer
+		}
+	}
+
+	r.Answers = answers
+	return v.Err()
+}
+
+// scanJSON decodes the json column value src into v, NULL and empty values leave v untouched
+func scanJSON(src interface{}, v interface{}) error {
+	var data []byte
+	switch s := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = s
+	case string:
+		data = []byte(s)
+	default:
+		return fmt.Errorf("can not scan %T into %T", src, v)
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,159 @@
+package model
+
+import (
+	"encoding/base64"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"golang.org/x/net/html"
+)
+
+const (
+	// SanitizeRichText policy of columns edited with the rich text editor: keeps formatting, links, images and tables
+	SanitizeRichText = "rich_text"
+
+	// SanitizeStrict policy removing every tag, leaving the text content
+	SanitizeStrict = "strict"
+)
+
+// sanitizer cleans an html fragment
+type sanitizer interface {
+	Sanitize(html string) string
+}
+
+// sanitizers html policies columns can refer to in ColumnInfo.Sanitizer
+var sanitizers = map[string]sanitizer{
+	SanitizeRichText: richText{richTextPolicy()},
+	SanitizeStrict:   bluemonday.StrictPolicy(),
+}
+
+// dataImagePrefix media types of the data urls of inline images, the raster formats the editor inserts
+var dataImagePrefix = regexp.MustCompile(`^image/(png|jpeg|gif|webp);base64,`)
+
+// richTextPolicy allows the markup produced by the editor of the admin pages.
+// Scripts, event handler attributes, javascript: urls and unknown css properties are dropped.
+// The editor inlines uploaded images as base64 data urls, those are kept for png, jpeg, gif and webp images,
+// every other data url is dropped.
+func richTextPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowURLSchemeWithCustomPolicy("data", isDataImage)
+
+	p.AllowStyles("color", "background-color", "text-align", "text-indent", "text-decoration",
+		"font-size", "font-family", "font-weight", "font-style", "line-height").
+		OnElements("p", "span", "div", "h1", "h2", "h3", "h4", "h5", "h6", "li", "blockquote", "td", "th")
+	p.AllowStyles("width", "height").OnElements("img", "table", "td", "th")
+
+	p.AllowAttrs("data-w-e-type").Matching(regexp.MustCompile(`^[a-z-]+$`)).OnElements("div", "span", "p")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w-]+$`)).OnElements("code")
+	p.AllowAttrs("colspan", "rowspan").Matching(bluemonday.Integer).OnElements("td", "th")
+	p.AllowAttrs("width").Matching(bluemonday.NumberOrPercent).OnElements("td", "th")
+
+	p.AllowAttrs("target").Matching(regexp.MustCompile(`^_blank$`)).OnElements("a")
+	p.RequireNoReferrerOnFullyQualifiedLinks(true)
+
+	return p
+}
+
+// isDataImage reports whether u is the data url of a png, jpeg, gif or webp image with valid base64 content
+func isDataImage(u *url.URL) bool {
+	if u.RawQuery != "" || u.Fragment != "" {
+		return false
+	}
+
+	prefix := dataImagePrefix.FindString(u.Opaque)
+	if prefix == "" {
+		return false
+	}
+
+	_, err := base64.StdEncoding.DecodeString(u.Opaque[len(prefix):])
+	return err == nil
+}
+
+// richText sanitizes with the rich text policy after dropping the data urls of every attribute but img src.
+// Url schemes are allowed for all link attributes of a policy at once, so the policy alone would keep them in a href too.
+type richText struct {
+	*bluemonday.Policy
+}
+
+// Sanitize cleans fragment, data urls are only kept as the src of an img
+func (rt richText) Sanitize(fragment string) string {
+	if !strings.Contains(strings.ToLower(fragment), "data:") {
+		return rt.Policy.Sanitize(fragment)
+	}
+
+	var b strings.Builder
+	tokenizer := html.NewTokenizer(strings.NewReader(fragment))
+	for {
+		if tokenizer.Next() == html.ErrorToken {
+			if tokenizer.Err() != io.EOF {
+				return ""
+			}
+			return rt.Policy.Sanitize(b.String())
+		}
+
+		token := tokenizer.Token()
+		if token.Type == html.StartTagToken || token.Type == html.SelfClosingTagToken {
+			attrs := token.Attr[:0]
+			for _, attr := range token.Attr {
+				if strings.HasPrefix(strings.ToLower(strings.TrimSpace(attr.Val)), "data:") && (token.Data != "img" || attr.Key != "src") {
+					continue
+				}
+				attrs = append(attrs, attr)
+			}
+			token.Attr = attrs
+		}
+		b.WriteString(token.String())
+	}
+}
+
+// Sanitize cleans html with the named policy, unknown names fall back to the strict policy
+func Sanitize(policy, html string) string {
+	p, ok := sanitizers[policy]
+	if !ok {
+		p = sanitizers[SanitizeStrict]
+	}
+	return p.Sanitize(html)
+}
+
+// SanitizeColumns cleans every column of record with a Sanitizer in place and reports the names of the columns that changed
+func SanitizeColumns(record Model) (changed []string) {
+	value := reflect.Indirect(reflect.ValueOf(record))
+	for _, col := range record.TableInfo().Columns {
+		if col.Sanitizer == "" {
+			continue
+		}
+
+		field := value.FieldByName(col.GoFieldName)
+		if !field.IsValid() || field.Kind() != reflect.String {
+			continue
+		}
+
+		if clean := Sanitize(col.Sanitizer, field.String()); clean != field.String() {
+			field.SetString(clean)
+			changed = append(changed, col.Name)
+		}
+	}
+
+	return changed
+}
+
+// SanitizedTables returns the names of the tables with at least one sanitized column, sorted
+func SanitizedTables() []string {
+	var names []string
+	for name, table := range tables {
+		for _, col := range table.Columns {
+			if col.Sanitizer != "" {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"strings"
+	"testing"
+)
+
+// editorImage a 2x2 png as the editor of the admin pages inlines an uploaded image
+const editorImage = "data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAIAAAACCAIAAAD91JpzAAAAEElEQVR4nGP4z8AARAwQCgAf7gP9i18U1AAAAABJRU5ErkJggg=="
+
+func TestSanitizeRichTextKeepsEditorImages(t *testing.T) {
+	// markup of a news stored by the editor, as in the database dump
+	content := `<p style="text-align: start;"><img src="` + editorImage + `" alt="riccardo.jpg" data-href="" style="width: 50%;"></p>` +
+		`<p style="text-align: start;">Last month, Ricciardo was linked to Mercedes as a reserve driver.</p>`
+
+	clean := Sanitize(SanitizeRichText, content)
+	if !strings.Contains(clean, `src="`+editorImage+`"`) {
+		t.Fatalf("inline image was dropped:\n%s", clean)
+	}
+	if !strings.Contains(clean, `style="width: 50%"`) || !strings.Contains(clean, `alt="riccardo.jpg"`) {
+		t.Errorf("image attributes were dropped:\n%s", clean)
+	}
+	if again := Sanitize(SanitizeRichText, clean); again != clean {
+		t.Errorf("sanitized content changes when sanitized again:\n%s\n%s", clean, again)
+	}
+
+	// the base64 of large images is wrapped over several lines
+	wrapped := `<img src="data:image/png;base64,iVBORw0KGgoAAAANSUhEUgAAAAIAAAACCAIAAAD91JpzAAAAEElEQVR4nGP4z8AARAwQCgAf7gP9i18U1AAAAAB` +
+		"\n" + `JRU5ErkJggg==">`
+	if clean := Sanitize(SanitizeRichText, wrapped); !strings.Contains(clean, `src="`+editorImage+`"`) {
+		t.Errorf("wrapped inline image was dropped:\n%s", clean)
+	}
+}
+
+func TestSanitizeRichTextDropsOtherDataURLs(t *testing.T) {
+	for _, test := range []struct {
+		name, html, dropped string
+	}{
+		{"svg image", `<img src="data:image/svg+xml;base64,PHN2ZyBvbmxvYWQ9ImFsZXJ0KDEpIi8+">`, "data:"},
+		{"html document", `<img src="data:text/html;base64,PHNjcmlwdD5hbGVydCgxKTwvc2NyaXB0Pg==">`, "data:"},
+		{"invalid base64", `<img src="data:image/png;base64,not*base64">`, "data:"},
+		{"not base64", `<img src="data:image/png,rawbytes">`, "data:"},
+		{"image link", `<a href="` + editorImage + `">open</a>`, "data:"},
+		{"image link in upper case", `<a href=" DATA:image/png;base64,iVBORw0KGgo=">open</a>`, "iVBORw0KGgo"},
+		{"quote source", `<blockquote cite="` + editorImage + `">quoted</blockquote>`, "data:"},
+		{"script", `<img src="` + editorImage + `" onerror="alert(1)"><script>alert(1)</script>`, "alert"},
+	} {
+		if clean := Sanitize(SanitizeRichText, test.html); strings.Contains(clean, test.dropped) {
+			t.Errorf("%s: %q kept %q", test.name, clean, test.dropped)
+		}
+	}
+}
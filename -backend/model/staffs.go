@@ -26,6 +26,8 @@ CREATE TABLE `staffs` (
   `job` varchar(512) NOT NULL,
   `intro` longtext NOT NULL,
   `avatar` varchar(512) NOT NULL,
+  `version` int NOT NULL DEFAULT '1',
+  `avatar_media_id` int DEFAULT NULL COMMENT 'id of the uploaded avatar in the media table',
   PRIMARY KEY (`id`)
 ) ENGINE=InnoDB AUTO_INCREMENT=4 DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci
 
@@ -49,6 +51,13 @@ type Staffs struct {
 	Intro string `gorm:"column:intro;type:text;size:4294967295;" json:"intro"`
 	//[ 4] avatar                                         varchar(512)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 512     default: []
 	Avatar string `gorm:"column:avatar;type:varchar;size:512;" json:"avatar"`
+	//[ 5] version                                        int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [1]
+	Version int32 `gorm:"column:version;type:int;default:1;not null;" json:"version"` // row version used for optimistic concurrency
+	//[ 6] avatar_media_id                                int                  null: true   primary: false  isArray: false  auto: false  col: int             len: -1      default: []
+	AvatarMediaID null.Int `gorm:"column:avatar_media_id;type:int;" json:"avatar_media_id"` // id of the uploaded avatar in the media table
+
+	// AvatarMedia uploaded avatar referenced by avatar_media_id, filled in by the dao
+	AvatarMedia *Media `gorm:"-" json:"avatar_media,omitempty"`
 }
 
 var staffsTableInfo = &TableInfo{
@@ -95,6 +104,7 @@ var staffsTableInfo = &TableInfo{
 			ProtobufFieldName:  "name",
 			ProtobufType:       "string",
 			ProtobufPos:        2,
+			Required:           true,
 		},
 
 		{
@@ -116,6 +126,7 @@ var staffsTableInfo = &TableInfo{
 			ProtobufFieldName:  "job",
 			ProtobufType:       "string",
 			ProtobufPos:        3,
+			Required:           true,
 		},
 
 		{
@@ -137,6 +148,7 @@ var staffsTableInfo = &TableInfo{
 			ProtobufFieldName:  "intro",
 			ProtobufType:       "string",
 			ProtobufPos:        4,
+			Sanitizer:          SanitizeRichText,
 		},
 
 		{
@@ -158,6 +170,50 @@ var staffsTableInfo = &TableInfo{
 			ProtobufFieldName:  "avatar",
 			ProtobufType:       "string",
 			ProtobufPos:        5,
+			Required:           true,
+			Format:             FormatURL,
+		},
+
+		{
+			Index:              5,
+			Name:               "version",
+			Comment:            `row version used for optimistic concurrency`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Version",
+			GoFieldType:        "int32",
+			JSONFieldName:      "version",
+			ProtobufFieldName:  "version",
+			ProtobufType:       "int32",
+			ProtobufPos:        6,
+		},
+
+		{
+			Index:              6,
+			Name:               "avatar_media_id",
+			Comment:            `id of the uploaded avatar in the media table`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "AvatarMediaID",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "avatar_media_id",
+			ProtobufFieldName:  "avatar_media_id",
+			ProtobufType:       "int64",
+			ProtobufPos:        7,
 		},
 	},
 }
@@ -174,11 +230,21 @@ func (s *Staffs) BeforeSave() error {
 
 // Prepare invoked before saving, can be used to populate fields etc.
 func (s *Staffs) Prepare() {
+	SanitizeColumns(s)
 }
 
 // Validate invoked before performing action, return an error if field is not populated.
 func (s *Staffs) Validate(action Action) error {
-	return nil
+	if action != Create && action != Update {
+		return nil
+	}
+
+	// the avatar url is filled in from an uploaded avatar
+	if s.AvatarMediaID.Valid {
+		return ValidateColumns(s, "avatar").Err()
+	}
+
+	return ValidateColumns(s).Err()
 }
 
 // TableInfo return table meta data
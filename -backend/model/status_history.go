@@ -0,0 +1,238 @@
+package model
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/guregu/null"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+CREATE TABLE `status_history` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `table_name` varchar(32) NOT NULL COMMENT 'table of the record, news or events',
+  `record_id` int NOT NULL COMMENT 'id of the record whose status changed',
+  `from_status` varchar(16) NOT NULL DEFAULT '' COMMENT 'status before the change, empty when the record was created',
+  `to_status` varchar(16) NOT NULL COMMENT 'status after the change',
+  `admin_id` int DEFAULT NULL COMMENT 'admin who made the change, null for the scheduler',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT 'time of the change',
+  PRIMARY KEY (`id`),
+  KEY `idx_status_history_record` (`table_name`, `record_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='publishing status changes of news and events'
+
+JSON Sample
+-------------------------------------
+{    "id": 69,    "table_name": "yjlXTMXCrOeUlZMXEzCojYadm",    "record_id": 51,    "from_status": "ABISduWFwDSzeUAOWmGheQVgs",    "to_status": "vJdsnXWJBBMZUkLPdNutOaJih",    "admin_id": 37,    "create_time": "2162-10-20T05:14:34.869447975+08:00"}
+
+
+
+*/
+
+// StatusHistory struct is a row record of the status_history table in the wcs database
+type StatusHistory struct {
+	//[ 0] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
+	ID int32 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] table_name                                     varchar(32)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 32      default: []
+	Table string `gorm:"column:table_name;type:varchar(32);not null;index:idx_status_history_record;" json:"table_name"` // table of the record, news or events
+	//[ 2] record_id                                      int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: []
+	RecordID int32 `gorm:"column:record_id;type:int;not null;index:idx_status_history_record;" json:"record_id"` // id of the record whose status changed
+	//[ 3] from_status                                    varchar(16)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 16      default: []
+	FromStatus string `gorm:"column:from_status;type:varchar(16);not null;default:'';" json:"from_status"` // status before the change, empty when the record was created
+	//[ 4] to_status                                      varchar(16)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 16      default: []
+	ToStatus string `gorm:"column:to_status;type:varchar(16);not null;" json:"to_status"` // status after the change
+	//[ 5] admin_id                                       int                  null: true   primary: false  isArray: false  auto: false  col: int             len: -1      default: []
+	AdminID null.Int `gorm:"column:admin_id;type:int;" json:"admin_id"` // admin who made the change, null for the scheduler
+	//[ 6] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"create_time"` // time of the change
+}
+
+var statusHistoryTableInfo = &TableInfo{
+	Name: "status_history",
+	Columns: []*ColumnInfo{
+
+		{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		{
+			Index:              1,
+			Name:               "table_name",
+			Comment:            `table of the record, news or events`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(32)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       32,
+			GoFieldName:        "Table",
+			GoFieldType:        "string",
+			JSONFieldName:      "table_name",
+			ProtobufFieldName:  "table_name",
+			ProtobufType:       "string",
+			ProtobufPos:        2,
+			Required:           true,
+		},
+
+		{
+			Index:              2,
+			Name:               "record_id",
+			Comment:            `id of the record whose status changed`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "RecordID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "record_id",
+			ProtobufFieldName:  "record_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        3,
+		},
+
+		{
+			Index:              3,
+			Name:               "from_status",
+			Comment:            `status before the change, empty when the record was created`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(16)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       16,
+			GoFieldName:        "FromStatus",
+			GoFieldType:        "string",
+			JSONFieldName:      "from_status",
+			ProtobufFieldName:  "from_status",
+			ProtobufType:       "string",
+			ProtobufPos:        4,
+		},
+
+		{
+			Index:              4,
+			Name:               "to_status",
+			Comment:            `status after the change`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(16)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       16,
+			GoFieldName:        "ToStatus",
+			GoFieldType:        "string",
+			JSONFieldName:      "to_status",
+			ProtobufFieldName:  "to_status",
+			ProtobufType:       "string",
+			ProtobufPos:        5,
+			Required:           true,
+		},
+
+		{
+			Index:              5,
+			Name:               "admin_id",
+			Comment:            `admin who made the change, null for the scheduler`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "AdminID",
+			GoFieldType:        "null.Int",
+			JSONFieldName:      "admin_id",
+			ProtobufFieldName:  "admin_id",
+			ProtobufType:       "int64",
+			ProtobufPos:        6,
+		},
+
+		{
+			Index:              6,
+			Name:               "create_time",
+			Comment:            `time of the change`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "CreateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "create_time",
+			ProtobufFieldName:  "create_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        7,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (s *StatusHistory) TableName() string {
+	return "status_history"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (s *StatusHistory) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (s *StatusHistory) Prepare() {
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (s *StatusHistory) Validate(action Action) error {
+	return nil
+}
+
+// TableInfo return table meta data
+func (s *StatusHistory) TableInfo() *TableInfo {
+	return statusHistoryTableInfo
+}
@@ -0,0 +1,306 @@
+package model
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/guregu/null"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+CREATE TABLE `subscribers` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `email` varchar(255) NOT NULL COMMENT 'email address of the subscriber, lower case',
+  `name` varchar(128) NOT NULL DEFAULT '' COMMENT 'name the subscriber gave, may be empty',
+  `status` varchar(16) NOT NULL DEFAULT 'pending' COMMENT 'pending until confirmed, active or unsubscribed',
+  `ip_address` varchar(64) NOT NULL DEFAULT '' COMMENT 'address the subscription was requested from',
+  `confirmed_at` datetime DEFAULT NULL COMMENT 'time the subscriber confirmed the address',
+  `unsubscribed_at` datetime DEFAULT NULL COMMENT 'time the subscriber unsubscribed',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uix_subscribers_email` (`email`),
+  KEY `idx_subscribers_status` (`status`, `id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='newsletter subscribers, confirmed by double opt-in'
+
+JSON Sample
+-------------------------------------
+{    "id": 4,    "email": "ada@example.org",    "name": "Ada",    "status": "active",    "ip_address": "203.0.113.7",    "confirmed_at": "2024-05-01T13:05:00Z",    "unsubscribed_at": null,    "create_time": "2024-05-01T13:00:00Z",    "update_time": "2024-05-01T13:05:00Z"}
+
+
+
+*/
+
+// subscriber statuses, only active subscribers get the newsletter
+const (
+	SubscriberPending      = "pending"
+	SubscriberActive       = "active"
+	SubscriberUnsubscribed = "unsubscribed"
+)
+
+// Subscribers struct is a row record of the subscribers table in the wcs database
+type Subscribers struct {
+	//[ 0] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
+	ID int32 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] email                                          varchar(255)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 255     default: []
+	Email string `gorm:"column:email;type:varchar(255);not null;unique_index:uix_subscribers_email;" json:"email"` // email address of the subscriber, lower case
+	//[ 2] name                                           varchar(128)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 128     default: []
+	Name string `gorm:"column:name;type:varchar(128);default:'';not null;" json:"name"` // name the subscriber gave, may be empty
+	//[ 3] status                                         varchar(16)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 16      default: [pending]
+	Status string `gorm:"column:status;type:varchar(16);default:'pending';not null;index:idx_subscribers_status;" json:"status"` // pending until confirmed, active or unsubscribed
+	//[ 4] ip_address                                     varchar(64)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 64      default: []
+	IPAddress string `gorm:"column:ip_address;type:varchar(64);default:'';not null;" json:"ip_address"` // address the subscription was requested from
+	//[ 5] confirmed_at                                   datetime             null: true   primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	ConfirmedAt null.Time `gorm:"column:confirmed_at;type:datetime;" json:"confirmed_at"` // time the subscriber confirmed the address
+	//[ 6] unsubscribed_at                                datetime             null: true   primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	UnsubscribedAt null.Time `gorm:"column:unsubscribed_at;type:datetime;" json:"unsubscribed_at"` // time the subscriber unsubscribed
+	//[ 7] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"create_time"`
+	//[ 8] update_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	UpdateTime time.Time `gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"update_time"`
+}
+
+var subscribersTableInfo = &TableInfo{
+	Name: "subscribers",
+	Columns: []*ColumnInfo{
+
+		{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		{
+			Index:              1,
+			Name:               "email",
+			Comment:            `email address of the subscriber, lower case`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       255,
+			GoFieldName:        "Email",
+			GoFieldType:        "string",
+			JSONFieldName:      "email",
+			ProtobufFieldName:  "email",
+			ProtobufType:       "string",
+			ProtobufPos:        2,
+			Required:           true,
+		},
+
+		{
+			Index:              2,
+			Name:               "name",
+			Comment:            `name the subscriber gave, may be empty`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(128)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       128,
+			GoFieldName:        "Name",
+			GoFieldType:        "string",
+			JSONFieldName:      "name",
+			ProtobufFieldName:  "name",
+			ProtobufType:       "string",
+			ProtobufPos:        3,
+		},
+
+		{
+			Index:              3,
+			Name:               "status",
+			Comment:            `pending until confirmed, active or unsubscribed`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(16)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       16,
+			GoFieldName:        "Status",
+			GoFieldType:        "string",
+			JSONFieldName:      "status",
+			ProtobufFieldName:  "status",
+			ProtobufType:       "string",
+			ProtobufPos:        4,
+		},
+
+		{
+			Index:              4,
+			Name:               "ip_address",
+			Comment:            `address the subscription was requested from`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       64,
+			GoFieldName:        "IPAddress",
+			GoFieldType:        "string",
+			JSONFieldName:      "ip_address",
+			ProtobufFieldName:  "ip_address",
+			ProtobufType:       "string",
+			ProtobufPos:        5,
+		},
+
+		{
+			Index:              5,
+			Name:               "confirmed_at",
+			Comment:            `time the subscriber confirmed the address`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "ConfirmedAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "confirmed_at",
+			ProtobufFieldName:  "confirmed_at",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        6,
+		},
+
+		{
+			Index:              6,
+			Name:               "unsubscribed_at",
+			Comment:            `time the subscriber unsubscribed`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "UnsubscribedAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "unsubscribed_at",
+			ProtobufFieldName:  "unsubscribed_at",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        7,
+		},
+
+		{
+			Index:              7,
+			Name:               "create_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "CreateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "create_time",
+			ProtobufFieldName:  "create_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        8,
+		},
+
+		{
+			Index:              8,
+			Name:               "update_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "UpdateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "update_time",
+			ProtobufFieldName:  "update_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        9,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (s *Subscribers) TableName() string {
+	return "subscribers"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (s *Subscribers) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+// Emails are kept in lower case so an address can not subscribe twice with different spellings.
+func (s *Subscribers) Prepare() {
+	s.Name = strings.TrimSpace(s.Name)
+	s.Email = strings.ToLower(strings.TrimSpace(s.Email))
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (s *Subscribers) Validate(action Action) error {
+	if action != Create {
+		return nil
+	}
+
+	v := ValidateColumns(s)
+	if s.Email != "" && !IsEmail(s.Email) {
+		v.Add("email", ErrCodeInvalid, "email must be an email address such as name@example.org")
+	}
+
+	return v.Err()
+}
+
+// TableInfo return table meta data
+func (s *Subscribers) TableInfo() *TableInfo {
+	return subscribersTableInfo
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var tagColorRegexp = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
+// TagList tags attached to a news or events record, stored in the news_tags and events_tags join tables.
+// It decodes from a list of tag objects, a list of tag names, or a legacy pipe delimited "a|b|c" string.
+type TagList []*Tags
+
+// UnmarshalJSON decode a tag list from any of its accepted forms
+func (l *TagList) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*l = TagList{}
+		return nil
+	}
+
+	var legacy string
+	if err := json.Unmarshal(data, &legacy); err == nil {
+		*l = ParseTagNames(legacy)
+		return nil
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	tags := make(TagList, 0, len(items))
+	for _, item := range items {
+		var name string
+		if err := json.Unmarshal(item, &name); err == nil {
+			tags = append(tags, &Tags{Name: name})
+			continue
+		}
+
+		tag := &Tags{}
+		if err := json.Unmarshal(item, tag); err != nil {
+			return err
+		}
+		tags = append(tags, tag)
+	}
+
+	*l = tags
+	return nil
+}
+
+// Names returns the display names of the tags in order
+func (l TagList) Names() []string {
+	names := make([]string, 0, len(l))
+	for _, tag := range l {
+		names = append(names, tag.Name)
+	}
+	return names
+}
+
+// ParseTagNames splits a legacy pipe delimited tag string into a tag list, dropping empty names
+func ParseTagNames(s string) TagList {
+	tags := TagList{}
+	for _, name := range strings.Split(s, "|") {
+		name = strings.TrimSpace(name)
+		if name != "" {
+			tags = append(tags, &Tags{Name: name})
+		}
+	}
+	return tags
+}
+
+// Slugify derive the url safe slug of a tag name, e.g. "Machine Learning" becomes "machine-learning"
+func Slugify(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+
+		if !dash && b.Len() > 0 {
+			b.WriteRune('-')
+			dash = true
+		}
+	}
+
+	slug := []rune(strings.TrimSuffix(b.String(), "-"))
+	if len(slug) > 64 {
+		slug = slug[:64]
+	}
+	return strings.TrimSuffix(string(slug), "-")
+}
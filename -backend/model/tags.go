@@ -0,0 +1,256 @@
+package model
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/guregu/null"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+CREATE TABLE `tags` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `name` varchar(64) NOT NULL COMMENT 'display name',
+  `slug` varchar(64) NOT NULL COMMENT 'url safe identifier',
+  `color` varchar(16) NOT NULL DEFAULT '' COMMENT 'display color, #rrggbb',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `version` int NOT NULL DEFAULT '1' COMMENT 'row version used for optimistic concurrency',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `uniq_tags_slug` (`slug`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='tags attached to news and events'
+
+JSON Sample
+-------------------------------------
+{    "id": 58,    "name": "LOjKjNusFahBrWysvNyGfXFMG",    "slug": "RsLhnnKPFfvyfVONdYgIQrscl",    "color": "ibybtEHERpcEomLHdyUSEJtrl",    "create_time": "2085-12-20T05:41:07.616997984+08:00",    "update_time": "2175-01-20T07:52:31.149011138+08:00",    "version": 1}
+
+
+
+*/
+
+// Tags struct is a row record of the tags table in the wcs database
+type Tags struct {
+	//[ 0] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
+	ID int32 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] name                                           varchar(64)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 64      default: []
+	Name string `gorm:"column:name;type:varchar(64);not null;" json:"name"` // display name
+	//[ 2] slug                                           varchar(64)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 64      default: []
+	Slug string `gorm:"column:slug;type:varchar(64);not null;unique_index:uniq_tags_slug;" json:"slug"` // url safe identifier
+	//[ 3] color                                          varchar(16)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 16      default: []
+	Color string `gorm:"column:color;type:varchar(16);not null;default:'';" json:"color"` // display color, #rrggbb
+	//[ 4] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"create_time"`
+	//[ 5] update_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	UpdateTime time.Time `gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"update_time"`
+	//[ 6] version                                        int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [1]
+	Version int32 `gorm:"column:version;type:int;default:1;not null;" json:"version"` // row version used for optimistic concurrency
+}
+
+var tagsTableInfo = &TableInfo{
+	Name: "tags",
+	Columns: []*ColumnInfo{
+
+		{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		{
+			Index:              1,
+			Name:               "name",
+			Comment:            `display name`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       64,
+			GoFieldName:        "Name",
+			GoFieldType:        "string",
+			JSONFieldName:      "name",
+			ProtobufFieldName:  "name",
+			ProtobufType:       "string",
+			ProtobufPos:        2,
+			Required:           true,
+		},
+
+		{
+			Index:              2,
+			Name:               "slug",
+			Comment:            `url safe identifier`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       64,
+			GoFieldName:        "Slug",
+			GoFieldType:        "string",
+			JSONFieldName:      "slug",
+			ProtobufFieldName:  "slug",
+			ProtobufType:       "string",
+			ProtobufPos:        3,
+			Required:           true,
+		},
+
+		{
+			Index:              3,
+			Name:               "color",
+			Comment:            `display color, #rrggbb`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(16)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       16,
+			GoFieldName:        "Color",
+			GoFieldType:        "string",
+			JSONFieldName:      "color",
+			ProtobufFieldName:  "color",
+			ProtobufType:       "string",
+			ProtobufPos:        4,
+		},
+
+		{
+			Index:              4,
+			Name:               "create_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "CreateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "create_time",
+			ProtobufFieldName:  "create_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        5,
+		},
+
+		{
+			Index:              5,
+			Name:               "update_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "UpdateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "update_time",
+			ProtobufFieldName:  "update_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        6,
+		},
+
+		{
+			Index:              6,
+			Name:               "version",
+			Comment:            `row version used for optimistic concurrency`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Version",
+			GoFieldType:        "int32",
+			JSONFieldName:      "version",
+			ProtobufFieldName:  "version",
+			ProtobufType:       "int32",
+			ProtobufPos:        7,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (t *Tags) TableName() string {
+	return "tags"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (t *Tags) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (t *Tags) Prepare() {
+	t.Name = strings.TrimSpace(t.Name)
+	if t.Slug == "" {
+		t.Slug = Slugify(t.Name)
+	}
+	t.Color = strings.ToLower(strings.TrimSpace(t.Color))
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (t *Tags) Validate(action Action) error {
+	if action != Create && action != Update {
+		return nil
+	}
+
+	v := ValidateColumns(t, "slug")
+	if t.Slug == "" {
+		v.Add("slug", ErrCodeRequired, "slug is required, a name without letters or digits gives none")
+	}
+	if t.Color != "" && !tagColorRegexp.MatchString(t.Color) {
+		v.Add("color", ErrCodeInvalid, "color must be #rgb or #rrggbb")
+	}
+
+	return v.Err()
+}
+
+// TableInfo return table meta data
+func (t *Tags) TableInfo() *TableInfo {
+	return tagsTableInfo
+}
@@ -0,0 +1,184 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/guregu/null"
+)
+
+// FormatURL column format of absolute http(s) urls and site relative paths such as /uploads/a.jpg
+const FormatURL = "url"
+
+// bounds of the event start times accepted as unix times, 2000-01-01 and 2100-01-01
+const (
+	minEventTime = 946684800
+	maxEventTime = 4102444800
+)
+
+// field error codes reported by validation
+const (
+	ErrCodeRequired   = "required"
+	ErrCodeTooLong    = "too_long"
+	ErrCodeInvalidURL = "invalid_url"
+	ErrCodeOutOfRange = "out_of_range"
+	ErrCodeInvalid    = "invalid"
+)
+
+// FieldError validation failure of a single field of a record
+type FieldError struct {
+	// Field json name of the field
+	Field string `json:"field" example:"title"`
+
+	// Code machine readable reason, e.g. required or too_long
+	Code string `json:"code" example:"required"`
+
+	Message string `json:"message" example:"title is required"`
+}
+
+// ValidationError lists every field of a record failing validation
+type ValidationError struct {
+	Fields []*FieldError `json:"errors"`
+}
+
+// Error joins the field messages
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Message)
+	}
+	return "validation failed: " + strings.Join(messages, ", ")
+}
+
+// Add records a failure of field
+func (e *ValidationError) Add(field, code, format string, args ...interface{}) {
+	e.Fields = append(e.Fields, &FieldError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err returns e when a failure was recorded, nil otherwise
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ValidateColumns checks the columns of record against its TableInfo: required columns must not be blank,
+// varchar values must fit ColumnLength and columns with a Format must match it.
+// Columns listed in except skip the required check, for models relaxing it with a rule of their own.
+func ValidateColumns(record Model, except ...string) *ValidationError {
+	v := &ValidationError{}
+	value := reflect.Indirect(reflect.ValueOf(record))
+
+columns:
+	for _, col := range record.TableInfo().Columns {
+		if col.IsServerManaged() {
+			continue
+		}
+
+		field := value.FieldByName(col.GoFieldName)
+		if !field.IsValid() {
+			continue
+		}
+
+		var text string
+		switch f := field.Interface().(type) {
+		case string:
+			text = f
+		default:
+			if s, ok := nullString(field); ok {
+				text = s
+			} else {
+				continue
+			}
+		}
+
+		if col.Required && strings.TrimSpace(text) == "" {
+			for _, name := range except {
+				if name == col.Name {
+					continue columns
+				}
+			}
+
+			v.Add(col.JSONFieldName, ErrCodeRequired, "%s is required", col.JSONFieldName)
+			continue
+		}
+
+		if col.ColumnType == "varchar" && col.ColumnLength > 0 && int64(utf8.RuneCountInString(text)) > col.ColumnLength {
+			v.Add(col.JSONFieldName, ErrCodeTooLong, "%s must be at most %d characters", col.JSONFieldName, col.ColumnLength)
+			continue
+		}
+
+		if col.Format == FormatURL && text != "" && !IsURL(text) {
+			v.Add(col.JSONFieldName, ErrCodeInvalidURL, "%s must be an http(s) url or a path starting with /", col.JSONFieldName)
+		}
+	}
+
+	return v
+}
+
+// nullString returns the value of a null.String or sql.NullString field, a null value reads as blank.
+// ok is false for fields of other types.
+func nullString(field reflect.Value) (string, bool) {
+	switch f := field.Interface().(type) {
+	case null.String:
+		return f.String, true
+	case sql.NullString:
+		return f.String, true
+	}
+	return "", false
+}
+
+// IsURL reports whether s is an absolute http(s) url with a host or a site relative path
+func IsURL(s string) bool {
+	if strings.HasPrefix(s, "/") && !strings.HasPrefix(s, "//") {
+		_, err := url.ParseRequestURI(s)
+		return err == nil
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// IsEmail reports whether s is a plain email address such as name@example.org, judged by its syntax alone:
+// no display name, a dotted domain of letters, digits and hyphens, and the length limits of RFC 5321
+func IsEmail(s string) bool {
+	if len(s) > 254 {
+		return false
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return false
+	}
+
+	at := strings.LastIndex(s, "@")
+	local, domain := s[:at], s[at+1:]
+	if len(local) > 64 || strings.HasPrefix(local, `"`) {
+		return false
+	}
+
+	labels := strings.Split(domain, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+	tld := labels[len(labels)-1]
+	return len(tld) >= 2 && strings.Trim(tld, "0123456789") != ""
+}
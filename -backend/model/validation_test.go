@@ -0,0 +1,74 @@
+package model
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/guregu/null"
+)
+
+// fieldCodes returns the failed fields of err mapped to their codes
+func fieldCodes(t *testing.T, err error) map[string]string {
+	t.Helper()
+	codes := make(map[string]string)
+	if err == nil {
+		return codes
+	}
+
+	var v *ValidationError
+	if !errors.As(err, &v) {
+		t.Fatalf("error %v is not a ValidationError", err)
+	}
+	for _, f := range v.Fields {
+		codes[f.Field] = f.Code
+	}
+	return codes
+}
+
+func TestValidateSQLNullString(t *testing.T) {
+	admin := &Admin{
+		Username: sql.NullString{String: strings.Repeat("a", 500), Valid: true},
+		Password: sql.NullString{String: "secret", Valid: true},
+	}
+	if got := fieldCodes(t, admin.Validate(Create)); got["username"] != ErrCodeTooLong {
+		t.Errorf("500 character username gave %v, want username %s", got, ErrCodeTooLong)
+	}
+
+	admin = &Admin{Username: sql.NullString{String: "root", Valid: true}, Password: sql.NullString{String: strings.Repeat("p", 257), Valid: true}}
+	if got := fieldCodes(t, admin.Validate(Update)); got["password"] != ErrCodeTooLong {
+		t.Errorf("257 character password gave %v, want password %s", got, ErrCodeTooLong)
+	}
+
+	got := fieldCodes(t, (&Admin{}).Validate(Create))
+	if got["username"] != ErrCodeRequired || got["password"] != ErrCodeRequired {
+		t.Errorf("blank admin gave %v, want username and password %s", got, ErrCodeRequired)
+	}
+
+	admin = &Admin{Username: sql.NullString{String: "root", Valid: true}, Password: sql.NullString{String: "secret", Valid: true}}
+	if err := admin.Validate(Create); err != nil {
+		t.Errorf("valid admin gave %v", err)
+	}
+}
+
+func TestNullString(t *testing.T) {
+	for _, c := range []struct {
+		value interface{}
+		text  string
+		ok    bool
+	}{
+		{null.StringFrom("a"), "a", true},
+		{null.String{}, "", true},
+		{sql.NullString{String: "b", Valid: true}, "b", true},
+		{sql.NullString{}, "", true},
+		{null.IntFrom(1), "", false},
+		{sql.NullInt64{Int64: 1, Valid: true}, "", false},
+	} {
+		text, ok := nullString(reflect.ValueOf(c.value))
+		if text != c.text || ok != c.ok {
+			t.Errorf("nullString(%#v) = %q, %v, want %q, %v", c.value, text, ok, c.text, c.ok)
+		}
+	}
+}
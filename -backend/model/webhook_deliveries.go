@@ -0,0 +1,414 @@
+package model
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/guregu/null"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+CREATE TABLE `webhook_deliveries` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `webhook_id` int NOT NULL COMMENT 'webhook the delivery is for',
+  `event` varchar(64) NOT NULL COMMENT 'table and action of the change, e.g. news.Update, webhook.Test for test deliveries',
+  `record_id` int NOT NULL DEFAULT '0' COMMENT 'id of the changed record',
+  `payload` mediumtext COMMENT 'json body posted to the webhook',
+  `status` varchar(16) NOT NULL DEFAULT 'pending' COMMENT 'pending, delivered or dead',
+  `attempts` int NOT NULL DEFAULT '0' COMMENT 'delivery attempts made',
+  `response_code` int NOT NULL DEFAULT '0' COMMENT 'http status of the last attempt, 0 when it got no response',
+  `last_error` text COMMENT 'error of the last failed delivery attempt',
+  `history` text COMMENT 'json list of the delivery attempts and their outcome',
+  `delivered_at` datetime DEFAULT NULL COMMENT 'time the webhook accepted the delivery, null until then',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`),
+  KEY `idx_webhook_deliveries_webhook` (`webhook_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='deliveries of the changes to the webhooks, with their attempts'
+
+JSON Sample
+-------------------------------------
+{    "id": 31,    "webhook_id": 2,    "event": "news.Update",    "record_id": 12,    "payload": "{\"id\":31,\"event\":\"news.Update\",...}",    "status": "delivered",    "attempts": 2,    "response_code": 204,    "last_error": "",    "history": [{"at": "2024-05-01T13:00:01Z", "status_code": 502, "error": "webhook answered 502 Bad Gateway", "duration_ms": 120}, {"at": "2024-05-01T13:01:02Z", "status_code": 204, "duration_ms": 85}],    "delivered_at": "2024-05-01T13:01:02Z",    "create_time": "2024-05-01T13:00:00Z",    "update_time": "2024-05-01T13:01:02Z"}
+
+
+
+*/
+
+// webhook delivery statuses, dead deliveries used up their attempts
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookDead      = "dead"
+)
+
+// WebhookDeliveries struct is a row record of the webhook_deliveries table in the wcs database
+type WebhookDeliveries struct {
+	//[ 0] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
+	ID int32 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] webhook_id                                     int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: []
+	WebhookID int32 `gorm:"column:webhook_id;type:int;not null;index:idx_webhook_deliveries_webhook;" json:"webhook_id"` // webhook the delivery is for
+	//[ 2] event                                          varchar(64)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 64      default: []
+	Event string `gorm:"column:event;type:varchar(64);not null;" json:"event"` // table and action of the change, e.g. news.Update, webhook.Test for test deliveries
+	//[ 3] record_id                                      int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	RecordID int32 `gorm:"column:record_id;type:int;default:0;not null;" json:"record_id"` // id of the changed record
+	//[ 4] payload                                        mediumtext(16777215) null: true   primary: false  isArray: false  auto: false  col: mediumtext      len: 16777215default: []
+	Payload string `gorm:"column:payload;type:mediumtext;" json:"payload"` // json body posted to the webhook
+	//[ 5] status                                         varchar(16)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 16      default: [pending]
+	Status string `gorm:"column:status;type:varchar(16);default:'pending';not null;" json:"status"` // pending, delivered or dead
+	//[ 6] attempts                                       int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	Attempts int32 `gorm:"column:attempts;type:int;default:0;not null;" json:"attempts"` // delivery attempts made
+	//[ 7] response_code                                  int                  null: false  primary: false  isArray: false  auto: false  col: int             len: -1      default: [0]
+	ResponseCode int32 `gorm:"column:response_code;type:int;default:0;not null;" json:"response_code"` // http status of the last attempt, 0 when it got no response
+	//[ 8] last_error                                     text(65535)          null: true   primary: false  isArray: false  auto: false  col: text            len: 65535   default: []
+	LastError string `gorm:"column:last_error;type:text;" json:"last_error"` // error of the last failed delivery attempt
+	//[ 9] history                                        text(65535)          null: true   primary: false  isArray: false  auto: false  col: text            len: 65535   default: []
+	History WebhookAttemptLog `gorm:"column:history;type:text;" json:"history"` // json list of the delivery attempts and their outcome
+	//[10] delivered_at                                   datetime             null: true   primary: false  isArray: false  auto: false  col: datetime        len: -1      default: []
+	DeliveredAt null.Time `gorm:"column:delivered_at;type:datetime;" json:"delivered_at"` // time the webhook accepted the delivery, null until then
+	//[11] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"create_time"`
+	//[12] update_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	UpdateTime time.Time `gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"update_time"`
+}
+
+var webhookDeliveriesTableInfo = &TableInfo{
+	Name: "webhook_deliveries",
+	Columns: []*ColumnInfo{
+
+		{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		{
+			Index:              1,
+			Name:               "webhook_id",
+			Comment:            `webhook the delivery is for`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "WebhookID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "webhook_id",
+			ProtobufFieldName:  "webhook_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        2,
+		},
+
+		{
+			Index:              2,
+			Name:               "event",
+			Comment:            `table and action of the change, e.g. news.Update, webhook.Test for test deliveries`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       64,
+			GoFieldName:        "Event",
+			GoFieldType:        "string",
+			JSONFieldName:      "event",
+			ProtobufFieldName:  "event",
+			ProtobufType:       "string",
+			ProtobufPos:        3,
+		},
+
+		{
+			Index:              3,
+			Name:               "record_id",
+			Comment:            `id of the changed record`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "RecordID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "record_id",
+			ProtobufFieldName:  "record_id",
+			ProtobufType:       "int32",
+			ProtobufPos:        4,
+		},
+
+		{
+			Index:              4,
+			Name:               "payload",
+			Comment:            `json body posted to the webhook`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "mediumtext",
+			DatabaseTypePretty: "mediumtext(16777215)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "mediumtext",
+			ColumnLength:       16777215,
+			GoFieldName:        "Payload",
+			GoFieldType:        "string",
+			JSONFieldName:      "payload",
+			ProtobufFieldName:  "payload",
+			ProtobufType:       "string",
+			ProtobufPos:        5,
+		},
+
+		{
+			Index:              5,
+			Name:               "status",
+			Comment:            `pending, delivered or dead`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(16)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       16,
+			GoFieldName:        "Status",
+			GoFieldType:        "string",
+			JSONFieldName:      "status",
+			ProtobufFieldName:  "status",
+			ProtobufType:       "string",
+			ProtobufPos:        6,
+		},
+
+		{
+			Index:              6,
+			Name:               "attempts",
+			Comment:            `delivery attempts made`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "Attempts",
+			GoFieldType:        "int32",
+			JSONFieldName:      "attempts",
+			ProtobufFieldName:  "attempts",
+			ProtobufType:       "int32",
+			ProtobufPos:        7,
+		},
+
+		{
+			Index:              7,
+			Name:               "response_code",
+			Comment:            `http status of the last attempt, 0 when it got no response`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ResponseCode",
+			GoFieldType:        "int32",
+			JSONFieldName:      "response_code",
+			ProtobufFieldName:  "response_code",
+			ProtobufType:       "int32",
+			ProtobufPos:        8,
+		},
+
+		{
+			Index:              8,
+			Name:               "last_error",
+			Comment:            `error of the last failed delivery attempt`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "text",
+			DatabaseTypePretty: "text(65535)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "text",
+			ColumnLength:       65535,
+			GoFieldName:        "LastError",
+			GoFieldType:        "string",
+			JSONFieldName:      "last_error",
+			ProtobufFieldName:  "last_error",
+			ProtobufType:       "string",
+			ProtobufPos:        9,
+		},
+
+		{
+			Index:              9,
+			Name:               "history",
+			Comment:            `json list of the delivery attempts and their outcome`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "text",
+			DatabaseTypePretty: "text(65535)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "text",
+			ColumnLength:       65535,
+			GoFieldName:        "History",
+			GoFieldType:        "WebhookAttemptLog",
+			JSONFieldName:      "history",
+			ProtobufFieldName:  "history",
+			ProtobufType:       "string",
+			ProtobufPos:        10,
+		},
+
+		{
+			Index:              10,
+			Name:               "delivered_at",
+			Comment:            `time the webhook accepted the delivery, null until then`,
+			Notes:              ``,
+			Nullable:           true,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "DeliveredAt",
+			GoFieldType:        "null.Time",
+			JSONFieldName:      "delivered_at",
+			ProtobufFieldName:  "delivered_at",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        11,
+		},
+
+		{
+			Index:              11,
+			Name:               "create_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "CreateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "create_time",
+			ProtobufFieldName:  "create_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        12,
+		},
+
+		{
+			Index:              12,
+			Name:               "update_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "UpdateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "update_time",
+			ProtobufFieldName:  "update_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        13,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (w *WebhookDeliveries) TableName() string {
+	return "webhook_deliveries"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (w *WebhookDeliveries) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+func (w *WebhookDeliveries) Prepare() {
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+func (w *WebhookDeliveries) Validate(action Action) error {
+	return nil
+}
+
+// TableInfo return table meta data
+func (w *WebhookDeliveries) TableInfo() *TableInfo {
+	return webhookDeliveriesTableInfo
+}
+
+// WebhookAttempt a delivery attempt of a webhook, Error is empty when the webhook accepted it
+type WebhookAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// WebhookAttemptLog delivery attempts of a webhook delivery, oldest first, stored as json
+type WebhookAttemptLog []*WebhookAttempt
+
+// Value stores the log as json
+func (l WebhookAttemptLog) Value() (driver.Value, error) {
+	if l == nil {
+		l = WebhookAttemptLog{}
+	}
+	data, err := json.Marshal(l)
+	return string(data), err
+}
+
+// Scan reads the log from json
+func (l *WebhookAttemptLog) Scan(src interface{}) error {
+	return scanJSON(src, l)
+}
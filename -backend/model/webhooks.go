@@ -0,0 +1,356 @@
+package model
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/guregu/null"
+	uuid "github.com/satori/go.uuid"
+)
+
+var (
+	_ = time.Second
+	_ = sql.LevelDefault
+	_ = null.Bool{}
+	_ = uuid.UUID{}
+)
+
+/*
+DB Table Details
+-------------------------------------
+
+
+CREATE TABLE `webhooks` (
+  `id` int NOT NULL AUTO_INCREMENT,
+  `url` varchar(512) NOT NULL COMMENT 'url the deliveries are posted to',
+  `secret` varchar(128) NOT NULL DEFAULT '' COMMENT 'key the deliveries are signed with, HMAC-SHA256',
+  `tables` varchar(255) NOT NULL DEFAULT '' COMMENT 'comma separated tables the webhook is called for, empty for every table',
+  `actions` varchar(64) NOT NULL DEFAULT '' COMMENT 'comma separated actions the webhook is called for: Create, Update, Delete, empty for all',
+  `description` varchar(255) NOT NULL DEFAULT '' COMMENT 'what the webhook is for',
+  `paused` tinyint(1) NOT NULL DEFAULT '0' COMMENT 'webhook is not called while paused',
+  `create_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `update_time` datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (`id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_0900_ai_ci COMMENT='outgoing webhooks called on changes of the content tables'
+
+JSON Sample
+-------------------------------------
+{    "id": 2,    "url": "https://faculty.example.org/hooks/wcs",    "secret": "Jx0c6rR1kq4WlVfS0pQm3w",    "tables": "news,events",    "actions": "Create,Update",    "description": "faculty website",    "paused": false,    "create_time": "2024-05-01T13:00:00Z",    "update_time": "2024-05-01T13:00:00Z"}
+
+
+
+*/
+
+// WebhookActions actions a webhook can be called for
+var WebhookActions = []Action{Create, Update, Delete}
+
+// Webhooks struct is a row record of the webhooks table in the wcs database
+type Webhooks struct {
+	//[ 0] id                                             int                  null: false  primary: true   isArray: false  auto: true   col: int             len: -1      default: []
+	ID int32 `gorm:"primary_key;AUTO_INCREMENT;column:id;" json:"id"`
+	//[ 1] url                                            varchar(512)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 512     default: []
+	URL string `gorm:"column:url;type:varchar(512);not null;" json:"url"` // url the deliveries are posted to
+	//[ 2] secret                                         varchar(128)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 128     default: []
+	Secret string `gorm:"column:secret;type:varchar(128);default:'';not null;" json:"secret"` // key the deliveries are signed with, HMAC-SHA256
+	//[ 3] tables                                         varchar(255)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 255     default: []
+	Tables string `gorm:"column:tables;type:varchar(255);default:'';not null;" json:"tables"` // comma separated tables the webhook is called for, empty for every table
+	//[ 4] actions                                        varchar(64)          null: false  primary: false  isArray: false  auto: false  col: varchar         len: 64      default: []
+	Actions string `gorm:"column:actions;type:varchar(64);default:'';not null;" json:"actions"` // comma separated actions the webhook is called for: Create, Update, Delete, empty for all
+	//[ 5] description                                    varchar(255)         null: false  primary: false  isArray: false  auto: false  col: varchar         len: 255     default: []
+	Description string `gorm:"column:description;type:varchar(255);default:'';not null;" json:"description"` // what the webhook is for
+	//[ 6] paused                                         tinyint              null: false  primary: false  isArray: false  auto: false  col: tinyint         len: -1      default: [0]
+	Paused bool `gorm:"column:paused;type:tinyint(1);default:0;not null;" json:"paused"` // webhook is not called while paused
+	//[ 7] create_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	CreateTime time.Time `gorm:"column:create_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"create_time"`
+	//[ 8] update_time                                    datetime             null: false  primary: false  isArray: false  auto: false  col: datetime        len: -1      default: [CURRENT_TIMESTAMP]
+	UpdateTime time.Time `gorm:"column:update_time;type:datetime;default:CURRENT_TIMESTAMP;" json:"update_time"`
+}
+
+var webhooksTableInfo = &TableInfo{
+	Name: "webhooks",
+	Columns: []*ColumnInfo{
+
+		{
+			Index:              0,
+			Name:               "id",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "int",
+			DatabaseTypePretty: "int",
+			IsPrimaryKey:       true,
+			IsAutoIncrement:    true,
+			IsArray:            false,
+			ColumnType:         "int",
+			ColumnLength:       -1,
+			GoFieldName:        "ID",
+			GoFieldType:        "int32",
+			JSONFieldName:      "id",
+			ProtobufFieldName:  "id",
+			ProtobufType:       "int32",
+			ProtobufPos:        1,
+		},
+
+		{
+			Index:              1,
+			Name:               "url",
+			Comment:            `url the deliveries are posted to`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(512)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       512,
+			GoFieldName:        "URL",
+			GoFieldType:        "string",
+			JSONFieldName:      "url",
+			ProtobufFieldName:  "url",
+			ProtobufType:       "string",
+			ProtobufPos:        2,
+			Required:           true,
+		},
+
+		{
+			Index:              2,
+			Name:               "secret",
+			Comment:            `key the deliveries are signed with, HMAC-SHA256`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(128)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       128,
+			GoFieldName:        "Secret",
+			GoFieldType:        "string",
+			JSONFieldName:      "secret",
+			ProtobufFieldName:  "secret",
+			ProtobufType:       "string",
+			ProtobufPos:        3,
+		},
+
+		{
+			Index:              3,
+			Name:               "tables",
+			Comment:            `comma separated tables the webhook is called for, empty for every table`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       255,
+			GoFieldName:        "Tables",
+			GoFieldType:        "string",
+			JSONFieldName:      "tables",
+			ProtobufFieldName:  "tables",
+			ProtobufType:       "string",
+			ProtobufPos:        4,
+		},
+
+		{
+			Index:              4,
+			Name:               "actions",
+			Comment:            `comma separated actions the webhook is called for: Create, Update, Delete, empty for all`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(64)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       64,
+			GoFieldName:        "Actions",
+			GoFieldType:        "string",
+			JSONFieldName:      "actions",
+			ProtobufFieldName:  "actions",
+			ProtobufType:       "string",
+			ProtobufPos:        5,
+		},
+
+		{
+			Index:              5,
+			Name:               "description",
+			Comment:            `what the webhook is for`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "varchar",
+			DatabaseTypePretty: "varchar(255)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "varchar",
+			ColumnLength:       255,
+			GoFieldName:        "Description",
+			GoFieldType:        "string",
+			JSONFieldName:      "description",
+			ProtobufFieldName:  "description",
+			ProtobufType:       "string",
+			ProtobufPos:        6,
+		},
+
+		{
+			Index:              6,
+			Name:               "paused",
+			Comment:            `webhook is not called while paused`,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "tinyint",
+			DatabaseTypePretty: "tinyint(1)",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "tinyint",
+			ColumnLength:       -1,
+			GoFieldName:        "Paused",
+			GoFieldType:        "bool",
+			JSONFieldName:      "paused",
+			ProtobufFieldName:  "paused",
+			ProtobufType:       "bool",
+			ProtobufPos:        7,
+		},
+
+		{
+			Index:              7,
+			Name:               "create_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "CreateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "create_time",
+			ProtobufFieldName:  "create_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        8,
+		},
+
+		{
+			Index:              8,
+			Name:               "update_time",
+			Comment:            ``,
+			Notes:              ``,
+			Nullable:           false,
+			DatabaseTypeName:   "datetime",
+			DatabaseTypePretty: "datetime",
+			IsPrimaryKey:       false,
+			IsAutoIncrement:    false,
+			IsArray:            false,
+			ColumnType:         "datetime",
+			ColumnLength:       -1,
+			GoFieldName:        "UpdateTime",
+			GoFieldType:        "time.Time",
+			JSONFieldName:      "update_time",
+			ProtobufFieldName:  "update_time",
+			ProtobufType:       "google.protobuf.Timestamp",
+			ProtobufPos:        9,
+		},
+	},
+}
+
+// TableName sets the insert table name for this struct type
+func (w *Webhooks) TableName() string {
+	return "webhooks"
+}
+
+// BeforeSave invoked before saving, return an error if field is not populated.
+func (w *Webhooks) BeforeSave() error {
+	return nil
+}
+
+// Prepare invoked before saving, can be used to populate fields etc.
+// The table and action lists are normalized to comma separated names without blanks.
+func (w *Webhooks) Prepare() {
+	w.URL = strings.TrimSpace(w.URL)
+	w.Secret = strings.TrimSpace(w.Secret)
+	w.Description = strings.TrimSpace(w.Description)
+	w.Tables = strings.Join(splitNames(w.Tables), ",")
+	w.Actions = strings.Join(splitNames(w.Actions), ",")
+}
+
+// Validate invoked before performing action, return an error if field is not populated.
+// The url must be absolute, the tables must be tables with records and the actions one of WebhookActions.
+func (w *Webhooks) Validate(action Action) error {
+	if action != Create && action != Update {
+		return nil
+	}
+
+	v := ValidateColumns(w)
+	if w.URL != "" && (strings.HasPrefix(w.URL, "/") || !IsURL(w.URL)) {
+		v.Add("url", ErrCodeInvalidURL, "url must be an absolute http(s) url")
+	}
+	for _, table := range splitNames(w.Tables) {
+		if _, ok := NewRecord(table); !ok || table == "admin" {
+			v.Add("tables", ErrCodeInvalid, "tables lists an unknown table %s", table)
+		}
+	}
+	for _, name := range splitNames(w.Actions) {
+		if a, ok := ParseAction(name); !ok || !isWebhookAction(a) {
+			v.Add("actions", ErrCodeInvalid, "actions must be some of Create, Update, Delete, not %s", name)
+		}
+	}
+
+	return v.Err()
+}
+
+// TableInfo return table meta data
+func (w *Webhooks) TableInfo() *TableInfo {
+	return webhooksTableInfo
+}
+
+// Watches reports whether the webhook is called when a record of table is changed by action
+func (w *Webhooks) Watches(table string, action Action) bool {
+	if w.Paused {
+		return false
+	}
+	return listed(w.Tables, table) && listed(w.Actions, action.String())
+}
+
+func isWebhookAction(action Action) bool {
+	for _, a := range WebhookActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// listed reports whether the comma separated list holds name, an empty list holds every name
+func listed(list, name string) bool {
+	names := splitNames(list)
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// splitNames splits a comma separated list of names, dropping blanks
+func splitNames(list string) []string {
+	names := make([]string, 0)
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
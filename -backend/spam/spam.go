@@ -0,0 +1,134 @@
+// Package spam scores the messages of the public forms for how likely they are spam. Scores add up simple content
+// heuristics and configurable blocklists, messages reaching the threshold of a Filter are to be quarantined for review.
+package spam
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DefaultThreshold score from which messages are quarantined
+const DefaultThreshold = 5
+
+// scores of the heuristics
+const (
+	blockedWordScore = 3
+	firstLinkScore   = 1
+	extraLinkScore   = 2
+	markupScore      = 3
+	linkInNameScore  = 3
+	allCapsScore     = 2
+)
+
+// minCapsLetters letters a text needs before it is judged for being all caps
+const minCapsLetters = 20
+
+// Filter scores messages. Blocked domains quarantine a message right away, blocked words add to its score.
+type Filter struct {
+	// Threshold score from which messages are quarantined
+	Threshold int
+
+	words   []string
+	domains []string
+}
+
+// Verdict score of a message and the reasons adding up to it
+type Verdict struct {
+	Score   int
+	Reasons []string
+	// Quarantine the score reached the threshold of the filter
+	Quarantine bool
+}
+
+// NewFilter returns a Filter with the blocklists words and domains, matched case insensitively.
+// Words may be phrases and match whole words only, domains match their subdomains too.
+func NewFilter(words, domains []string, threshold int) *Filter {
+	f := &Filter{Threshold: threshold}
+	for _, word := range words {
+		if word = strings.Join(tokens(word), " "); word != "" {
+			f.words = append(f.words, word)
+		}
+	}
+	for _, domain := range domains {
+		if domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), "@."); domain != "" {
+			f.domains = append(f.domains, domain)
+		}
+	}
+	return f
+}
+
+// Check scores the message text sent by name from email
+func (f *Filter) Check(name, email, text string) *Verdict {
+	v := &Verdict{}
+
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		domain := strings.ToLower(email[at+1:])
+		for _, blocked := range f.domains {
+			if domain == blocked || strings.HasSuffix(domain, "."+blocked) {
+				v.add(f.Threshold, "blocked domain %s", blocked)
+				break
+			}
+		}
+	}
+
+	words := " " + strings.Join(tokens(name+" "+text), " ") + " "
+	for _, blocked := range f.words {
+		if strings.Contains(words, " "+blocked+" ") {
+			v.add(blockedWordScore, "blocked word %q", blocked)
+		}
+	}
+
+	if links := countLinks(text); links > 0 {
+		v.add(firstLinkScore+extraLinkScore*(links-1), "%d links", links)
+	}
+
+	lower := strings.ToLower(text)
+	if strings.Contains(lower, "<a ") || strings.Contains(lower, "[url") || strings.Contains(lower, "[link") {
+		v.add(markupScore, "link markup")
+	}
+
+	if countLinks(name) > 0 {
+		v.add(linkInNameScore, "link in name")
+	}
+
+	if allCaps(text) {
+		v.add(allCapsScore, "all caps")
+	}
+
+	v.Quarantine = f.Threshold > 0 && v.Score >= f.Threshold
+	return v
+}
+
+func (v *Verdict) add(score int, format string, args ...interface{}) {
+	v.Score += score
+	v.Reasons = append(v.Reasons, fmt.Sprintf(format, args...))
+}
+
+// tokens returns the lower case words of s
+func tokens(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// countLinks counts the urls in s
+func countLinks(s string) int {
+	s = strings.ToLower(s)
+	return strings.Count(s, "http://") + strings.Count(s, "https://") + strings.Count(s, "www.") -
+		strings.Count(s, "://www.")
+}
+
+// allCaps reports whether s is long enough to judge and written in capital letters
+func allCaps(s string) bool {
+	letters, upper := 0, 0
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	return letters >= minCapsLetters && upper*10 >= letters*7
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Local stores blobs as files below a root directory, served by the web server at BaseURL
+type Local struct {
+	Root    string
+	BaseURL string
+}
+
+// NewLocal returns a Local storage rooted at dir, creating the directory when missing
+func NewLocal(dir, baseURL string) (*Local, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	return &Local{Root: dir, BaseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// Put writes the blob to a temporary file first so readers never see a partial blob
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(name), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), name)
+}
+
+// Open returns the file holding the blob
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	name, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil, ErrNotExist
+	}
+	return f, err
+}
+
+// Delete removes the file holding the blob
+func (l *Local) Delete(ctx context.Context, key string) error {
+	name, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	if err = os.Remove(name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// URL returns BaseURL joined with key
+func (l *Local) URL(key string) string {
+	return l.BaseURL + "/" + key
+}
+
+// path maps key below Root, rejecting keys escaping it
+func (l *Local) path(key string) (string, error) {
+	name := filepath.Join(l.Root, filepath.FromSlash(key))
+	if !strings.HasPrefix(name, filepath.Clean(l.Root)+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid storage key %q", key)
+	}
+	return name, nil
+}
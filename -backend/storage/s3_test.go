@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// s3StandIn is an in memory object store answering path style PUT, GET and DELETE Object requests.
+// Requests whose signature version 4 does not verify under secret are refused with 403.
+type s3StandIn struct {
+	bucket, region, accessKey, secret string
+
+	mu      sync.Mutex
+	objects map[string][]byte
+	types   map[string]string
+}
+
+func newS3StandIn(t *testing.T) (*s3StandIn, *httptest.Server) {
+	stand := &s3StandIn{
+		bucket:    "media",
+		region:    "us-east-1",
+		accessKey: "AKIDEXAMPLE",
+		secret:    "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		objects:   make(map[string][]byte),
+		types:     make(map[string]string),
+	}
+	srv := httptest.NewServer(stand)
+	t.Cleanup(srv.Close)
+	return stand, srv
+}
+
+func (s *s3StandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.verify(r) {
+		http.Error(w, "<Error><Code>SignatureDoesNotMatch</Code></Error>", http.StatusForbidden)
+		return
+	}
+
+	prefix := "/" + s.bucket + "/"
+	if !strings.HasPrefix(r.URL.Path, prefix) {
+		http.Error(w, "<Error><Code>NoSuchBucket</Code></Error>", http.StatusNotFound)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, prefix)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		s.objects[key] = body
+		s.types[key] = r.Header.Get("Content-Type")
+	case http.MethodGet:
+		body, ok := s.objects[key]
+		if !ok {
+			http.Error(w, "<Error><Code>NoSuchKey</Code></Error>", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", s.types[key])
+		w.Write(body)
+	case http.MethodDelete:
+		delete(s.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verify recomputes the signature version 4 of r from the headers it names as signed
+func (s *s3StandIn) verify(r *http.Request) bool {
+	auth := strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 ")
+	fields := make(map[string]string)
+	for _, part := range strings.Split(auth, ", ") {
+		if kv := strings.SplitN(part, "=", 2); len(kv) == 2 {
+			fields[kv[0]] = kv[1]
+		}
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	if len(amzDate) < 8 {
+		return false
+	}
+	scope := amzDate[:8] + "/" + s.region + "/s3/aws4_request"
+	if fields["Credential"] != s.accessKey+"/"+scope {
+		return false
+	}
+
+	names := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(names) {
+		return false
+	}
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		r.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		fields["SignedHeaders"],
+		r.Header.Get("X-Amz-Content-Sha256"),
+	}, "\n")
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hexSHA256(canonicalRequest)}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.secret), amzDate[:8])
+	key = hmacSHA256(key, s.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	want := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return hmac.Equal([]byte(fields["Signature"]), []byte(want))
+}
+
+func TestS3RoundTrip(t *testing.T) {
+	stand, srv := newS3StandIn(t)
+	store := &S3{Endpoint: srv.URL, Bucket: stand.bucket, Region: stand.region, AccessKey: stand.accessKey, SecretKey: stand.secret}
+	ctx := context.Background()
+	key := ContentKey("abcdef0123456789", ".jpg")
+	blob := []byte("not really a jpeg")
+
+	if err := store.Put(ctx, key, bytes.NewReader(blob), int64(len(blob)), "image/jpeg"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := stand.types[key]; got != "image/jpeg" {
+		t.Errorf("stored content type %q, want image/jpeg", got)
+	}
+
+	r, err := store.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	got, _ := io.ReadAll(r)
+	r.Close()
+	if !bytes.Equal(got, blob) {
+		t.Errorf("Open read %q, want %q", got, blob)
+	}
+
+	if want := srv.URL + "/media/ab/cd/abcdef0123456789.jpg"; store.URL(key) != want {
+		t.Errorf("URL %q, want %q", store.URL(key), want)
+	}
+
+	if err := store.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Open(ctx, key); err != ErrNotExist {
+		t.Errorf("Open of a deleted blob returned %v, want ErrNotExist", err)
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing blob returned %v, want nil", err)
+	}
+}
+
+func TestS3RejectedSignature(t *testing.T) {
+	stand, srv := newS3StandIn(t)
+	store := &S3{Endpoint: srv.URL, Bucket: stand.bucket, Region: stand.region, AccessKey: stand.accessKey, SecretKey: "wrong"}
+
+	err := store.Put(context.Background(), "a/b/c.png", strings.NewReader("x"), 1, "image/png")
+	if err == nil || !strings.Contains(err.Error(), "403") {
+		t.Fatalf("Put with a wrong secret returned %v, want a 403 error", err)
+	}
+	if len(stand.objects) != 0 {
+		t.Errorf("stand-in stored %d objects of a refused request", len(stand.objects))
+	}
+}
+
+func TestS3PublicURL(t *testing.T) {
+	store := &S3{Endpoint: "http://127.0.0.1:9000/", Bucket: "media", PublicURL: "https://cdn.example.org/media/"}
+	if got, want := store.URL("ab/cd/x.png"), "https://cdn.example.org/media/ab/cd/x.png"; got != want {
+		t.Errorf("URL %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,33 @@
+// Package storage keeps uploaded media blobs, on local disk or in an S3 compatible object store.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+)
+
+// ErrNotExist error when no blob is stored under a key
+var ErrNotExist = errors.New("blob does not exist")
+
+// Storage stores blobs under slash separated keys
+type Storage interface {
+	// Put stores size bytes read from r under key, replacing any previous blob
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+
+	// Open returns a reader of the blob stored under key, ErrNotExist when there is none
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the blob stored under key, deleting a missing blob is not an error
+	Delete(ctx context.Context, key string) error
+
+	// URL returns the public url the blob stored under key is served at
+	URL(key string) string
+}
+
+// ContentKey returns the content addressed key of a blob with the given hex encoded sha256 digest,
+// e.g. "ab/cd/abcd0123...ef.jpg". Blobs with the same content share a key.
+func ContentKey(digest, ext string) string {
+	return path.Join(digest[0:2], digest[2:4], digest+ext)
+}
@@ -0,0 +1,140 @@
+// Package stream fans out change notifications to the Server-Sent Events clients of this server. Published events
+// are numbered and kept in a bounded buffer, so a client reconnecting with the id of the last event it got resumes
+// without missing any. The hub lives in memory: every server of a cluster only streams the changes made through it.
+package stream
+
+import (
+	"sync"
+	"time"
+)
+
+// Event a notification handed to the clients watching its table
+type Event struct {
+	ID    uint64
+	Table string
+	Data  interface{}
+}
+
+// Hub buffers published events and hands them to the subscribed clients
+type Hub struct {
+	mu      sync.Mutex
+	size    int
+	queue   int
+	buffer  []*Event
+	lastID  uint64
+	clients map[*Client]struct{}
+}
+
+// Client a subscriber of a Hub. Events is closed when the client is unsubscribed or dropped for lagging behind.
+type Client struct {
+	// Events events to send to the client, in id order
+	Events <-chan *Event
+	// LastID id of the last event published before the client subscribed, events replayed on resume included
+	LastID uint64
+
+	events  chan *Event
+	tables  map[string]bool
+	dropped bool
+}
+
+// NewHub returns a Hub keeping the last size events for resuming clients. Every client may have queue events
+// waiting to be sent, a client falling further behind is dropped.
+func NewHub(size, queue int) *Hub {
+	return &Hub{
+		size:  size,
+		queue: queue,
+		// ids of a restarted server start past the ones it handed out before, so old ids are never resumed
+		lastID:  uint64(time.Now().UnixMilli()),
+		clients: make(map[*Client]struct{}),
+	}
+}
+
+// Publish numbers an event of table carrying data, buffers it and queues it for every client watching table.
+// Publish never blocks: a client whose queue is full is dropped, it reconnects and resumes from the buffer.
+func (h *Hub) Publish(table string, data interface{}) *Event {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event := &Event{ID: h.lastID, Table: table, Data: data}
+	h.buffer = append(h.buffer, event)
+	if len(h.buffer) > h.size {
+		h.buffer = append(h.buffer[:0], h.buffer[len(h.buffer)-h.size:]...)
+	}
+
+	for c := range h.clients {
+		if !c.watches(table) {
+			continue
+		}
+		select {
+		case c.events <- event:
+		default:
+			c.dropped = true
+			h.remove(c)
+		}
+	}
+
+	return event
+}
+
+// Subscribe registers a client for the events of tables, of every table when tables is empty. A client resuming
+// after lastID gets the buffered events it missed queued first; resumed is false when lastID is unknown or older
+// than the buffer, the client then missed events and has to reload what it shows.
+func (h *Hub) Subscribe(tables []string, lastID uint64) (c *Client, resumed bool) {
+	c = &Client{}
+	if len(tables) > 0 {
+		c.tables = make(map[string]bool, len(tables))
+		for _, table := range tables {
+			c.tables[table] = true
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var replay []*Event
+	if lastID > 0 && lastID <= h.lastID && (lastID == h.lastID || len(h.buffer) > 0 && lastID >= h.buffer[0].ID-1) {
+		resumed = true
+		for _, event := range h.buffer {
+			if event.ID > lastID && c.watches(event.Table) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	// the replay does not count against the queue of the client
+	c.events = make(chan *Event, h.queue+len(replay))
+	c.Events = c.events
+	c.LastID = h.lastID
+	for _, event := range replay {
+		c.events <- event
+	}
+
+	h.clients[c] = struct{}{}
+	return c, resumed
+}
+
+// Unsubscribe removes c from the hub and closes its Events
+func (h *Hub) Unsubscribe(c *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.remove(c)
+}
+
+// Dropped tells whether c was dropped for lagging behind, valid once its Events is closed
+func (c *Client) Dropped() bool {
+	return c.dropped
+}
+
+func (h *Hub) remove(c *Client) {
+	if _, ok := h.clients[c]; !ok {
+		return
+	}
+	delete(h.clients, c)
+	close(c.events)
+}
+
+func (c *Client) watches(table string) bool {
+	return c.tables == nil || c.tables[table]
+}
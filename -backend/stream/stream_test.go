@@ -0,0 +1,140 @@
+package stream
+
+import (
+	"testing"
+	"time"
+)
+
+// drain returns the ids of the events queued for c without waiting for more
+func drain(c *Client) (ids []uint64, open bool) {
+	for {
+		select {
+		case event, ok := <-c.Events:
+			if !ok {
+				return ids, false
+			}
+			ids = append(ids, event.ID)
+		default:
+			return ids, true
+		}
+	}
+}
+
+func equalIDs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSubscribeResume(t *testing.T) {
+	h := NewHub(4, 8)
+	first := h.Publish("news", 1)
+	second := h.Publish("events", 2)
+	third := h.Publish("news", 3)
+
+	c, resumed := h.Subscribe(nil, first.ID)
+	if !resumed || c.LastID != third.ID {
+		t.Fatalf("resume after %d: resumed %v, last id %d, want true and %d", first.ID, resumed, c.LastID, third.ID)
+	}
+	fourth := h.Publish("news", 4)
+	if ids, _ := drain(c); !equalIDs(ids, []uint64{second.ID, third.ID, fourth.ID}) {
+		t.Errorf("resumed client got %v, want the missed events then the new one", ids)
+	}
+
+	current, resumed := h.Subscribe(nil, fourth.ID)
+	if ids, _ := drain(current); !resumed || len(ids) != 0 {
+		t.Errorf("resume from the latest event: resumed %v with %v, want true with nothing replayed", resumed, ids)
+	}
+}
+
+func TestSubscribeTables(t *testing.T) {
+	h := NewHub(8, 8)
+	start := h.Publish("news", nil)
+	event := h.Publish("events", nil)
+	news := h.Publish("news", nil)
+
+	c, resumed := h.Subscribe([]string{"news"}, start.ID)
+	if !resumed {
+		t.Fatal("not resumed")
+	}
+	h.Publish("events", nil)
+	live := h.Publish("news", nil)
+
+	if ids, _ := drain(c); !equalIDs(ids, []uint64{news.ID, live.ID}) {
+		t.Errorf("news client got %v, want only the news events %d and %d, not %d", ids, news.ID, live.ID, event.ID)
+	}
+}
+
+func TestSubscribeReset(t *testing.T) {
+	h := NewHub(2, 8)
+	var ids []uint64
+	for i := 0; i < 5; i++ {
+		ids = append(ids, h.Publish("news", i).ID)
+	}
+
+	for _, test := range []struct {
+		name   string
+		lastID uint64
+		want   bool
+	}{
+		{"new client", 0, false},
+		{"last event before the buffer, nothing missed", ids[2], true},
+		{"older event, the next one is gone", ids[1], false},
+		{"event pushed out of the buffer", ids[0], false},
+		{"id the hub never handed out", ids[4] + 10, false},
+	} {
+		c, resumed := h.Subscribe(nil, test.lastID)
+		replayed, _ := drain(c)
+		if resumed != test.want {
+			t.Errorf("%s: resumed %v, want %v", test.name, resumed, test.want)
+		}
+		if !resumed && len(replayed) != 0 {
+			t.Errorf("%s: client that has to reload got %v replayed", test.name, replayed)
+		}
+		h.Unsubscribe(c)
+	}
+}
+
+func TestSubscribeAfterRestart(t *testing.T) {
+	before := NewHub(8, 8)
+	lastID := before.Publish("news", nil).ID
+
+	time.Sleep(5 * time.Millisecond)
+	restarted := NewHub(8, 8)
+	if _, resumed := restarted.Subscribe(nil, lastID); resumed {
+		t.Errorf("id %d of the previous server resumed on a restarted one", lastID)
+	}
+}
+
+func TestPublishDropsLaggingClient(t *testing.T) {
+	h := NewHub(8, 2)
+	slow, _ := h.Subscribe(nil, 0)
+	fast, _ := h.Subscribe(nil, 0)
+
+	for i := 0; i < 3; i++ {
+		h.Publish("news", i)
+		if i < 2 {
+			drain(fast)
+		}
+	}
+
+	ids, open := drain(slow)
+	if open || len(ids) != 2 || !slow.Dropped() {
+		t.Errorf("lagging client got %v, open %v, dropped %v, want its queue of 2 then closed and dropped", ids, open, slow.Dropped())
+	}
+	if ids, open := drain(fast); !open || len(ids) != 1 || fast.Dropped() {
+		t.Errorf("client keeping up got %v, open %v, dropped %v", ids, open, fast.Dropped())
+	}
+
+	h.Unsubscribe(fast)
+	h.Unsubscribe(fast)
+	if _, open := drain(fast); open || fast.Dropped() {
+		t.Errorf("unsubscribed client is open %v, dropped %v, want closed and not dropped", open, fast.Dropped())
+	}
+}
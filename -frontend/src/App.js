@@ -10,6 +10,10 @@ import { Link, Route, Routes, BrowserRouter } from "react-router-dom";
 import { Home } from 'pages/home'
 import { Contact } from 'pages/contact'
 import { Events } from 'pages/events'
+import { RegistrationCancel } from 'pages/registrationCancel'
+import { CheckIn } from 'pages/checkIn'
+import { NewsletterConfirm } from 'pages/newsletterConfirm'
+import { NewsletterUnsubscribe } from 'pages/newsletterUnsubscribe'
 import { News } from 'pages/news'
 import { Projects } from 'pages/projects'
 import { Resources } from 'pages/resuorces'
@@ -146,6 +150,10 @@ function App () {
         <Route path='/home' element={<Home />} />
         <Route path='/contact' element={<Contact />} />
         <Route path='/events' element={<Events />} />
+        <Route path='/registrations/cancel' element={<RegistrationCancel />} />
+        <Route path='/checkin' element={<CheckIn />} />
+        <Route path='/newsletter/confirm' element={<NewsletterConfirm />} />
+        <Route path='/newsletter/unsubscribe' element={<NewsletterUnsubscribe />} />
         <Route path='/news' element={<News />} />
         <Route path='/projects' element={<Projects />} />
         <Route path='/resuorces' element={<Resources />} />
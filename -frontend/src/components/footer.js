@@ -1,10 +1,14 @@
-import { Grid, Link, Typography, Space } from '@arco-design/web-react';
+import React, { useState } from 'react';
+import { Grid, Link, Typography, Space, Input, Button, Message } from '@arco-design/web-react';
+import { subscribe } from 'utils/request';
 const Row = Grid.Row;
 const Col = Grid.Col;
 const { Title, Paragraph, Text } = Typography;
 
 
 export function Footer () {
+    const [email, setEmail] = useState('');
+
     return (
         <div style={{
             width: '100%',
@@ -39,6 +43,26 @@ export function Footer () {
                         </Space>
                     </Typography>
                 </Col>
+                <Col flex={1}>
+                </Col>
+                <Col flex={3}>
+                    <Typography style={{ marginTop: 10 }}>
+                        <Title heading={5}>Newsletter</Title>
+                        <Space direction='horizontal'>
+                            <Input value={email} onChange={setEmail} placeholder='your email address' />
+                            <Button type='primary' onClick={() => {
+                                subscribe(email, '').then(res => {
+                                    if (res.code != 0) {
+                                        Message.error(res.msg);
+                                        return
+                                    }
+                                    Message.info('Check your inbox to confirm the subscription');
+                                    setEmail('');
+                                })
+                            }}>Subscribe</Button>
+                        </Space>
+                    </Typography>
+                </Col>
             </Row>
         </div>
     );
@@ -0,0 +1,55 @@
+import React, { useState } from 'react';
+import { Typography, Input, Form, Button, Message } from '@arco-design/web-react';
+import { registerForEvent } from 'utils/request';
+const { Title, Paragraph } = Typography;
+const FormItem = Form.Item;
+
+// RegistrationForm sign up form of an event open for registration, with a field per custom question
+export function RegistrationForm (props) {
+  const { event } = props;
+  const [form] = Form.useForm();
+  const [registration, setRegistration] = useState(null);
+
+  if (registration) {
+    return (
+      <Paragraph>
+        {registration.status == 'waitlisted'
+          ? `The event is full, you are number ${registration.position} on the waitlist. We will email ${registration.email} when a place becomes free.`
+          : `You are registered, a confirmation was sent to ${registration.email}. Show this ticket at the door.`}
+        {registration.ticket && <img alt='ticket' src={`/api/tickets/${registration.ticket}.png`} style={{ display: 'block', width: 240 }} />}
+      </Paragraph>
+    )
+  }
+
+  return (
+    <>
+      <Title heading={4}>Register</Title>
+      <Form form={form} style={{ width: 600 }} autoComplete='off'>
+        <FormItem label='Your Name' field='name' rules={[{ required: true }]}>
+          <Input required type='text' placeholder='please enter your name...' />
+        </FormItem>
+        <FormItem label='Email' field='email' rules={[{ required: true, type: 'email' }]}>
+          <Input required type='email' placeholder='please enter your email...' />
+        </FormItem>
+        {(event.questions || []).map(q => (
+          <FormItem key={q.id} label={q.label} field={`answers.${q.id}`} rules={[{ required: q.required }]}>
+            <Input type='text' />
+          </FormItem>
+        ))}
+        <FormItem wrapperCol={{ offset: 5 }}>
+          <Button type='primary' onClick={() => {
+            form.validate().then(values => {
+              registerForEvent(event.id, values.name, values.email, values.answers).then(res => {
+                if (res.code != 0) {
+                  Message.error(res.msg);
+                  return
+                }
+                setRegistration(res.data);
+              })
+            })
+          }}>Register</Button>
+        </FormItem>
+      </Form>
+    </>
+  );
+}
@@ -0,0 +1,79 @@
+import React, { useState, useEffect, useRef } from 'react';
+import { useSearchParams } from 'react-router-dom';
+import { Typography, Input, Button, Alert } from '@arco-design/web-react';
+import { checkInTicket } from 'utils/request';
+const { Title, Paragraph } = Typography;
+
+// CheckIn scanner page of volunteers at the door, reached by the link carrying the check-in key of an event.
+// Uses the camera where the browser supports BarcodeDetector, codes can always be typed in.
+export function CheckIn () {
+  const [params] = useSearchParams();
+  const eventId = params.get('event');
+  const key = params.get('key');
+  const [code, setCode] = useState('');
+  const [result, setResult] = useState(null);
+  const video = useRef(null);
+  const last = useRef('');
+
+  const submit = (value) => {
+    checkInTicket(eventId, key, value.trim()).then(res => {
+      if (res.code != 0) {
+        setResult({ type: 'error', content: res.msg });
+        return
+      }
+      const r = res.data;
+      setResult(r.duplicate
+        ? { type: 'warning', content: `${r.name} was already checked in at ${new Date(r.checked_in_at).toLocaleTimeString()} (scan ${r.scan_count})` }
+        : { type: 'success', content: `${r.name} checked in` });
+    })
+  }
+
+  useEffect(() => {
+    if (!('BarcodeDetector' in window) || !navigator.mediaDevices) {
+      return
+    }
+    const detector = new window.BarcodeDetector({ formats: ['qr_code'] });
+    let stream = null;
+    let timer = null;
+    navigator.mediaDevices.getUserMedia({ video: { facingMode: 'environment' } }).then(s => {
+      stream = s;
+      video.current.srcObject = s;
+      video.current.play();
+      timer = setInterval(() => {
+        detector.detect(video.current).then(codes => {
+          // the same ticket stays in front of the camera for a while, scan it once
+          if (codes.length > 0 && codes[0].rawValue != last.current) {
+            last.current = codes[0].rawValue;
+            submit(codes[0].rawValue);
+          }
+        })
+      }, 500);
+    }).catch(() => { })
+    return () => {
+      clearInterval(timer);
+      stream && stream.getTracks().forEach(t => t.stop());
+    }
+  }, [eventId, key])
+
+  return (
+    <Typography style={{ marginTop: 10 }}>
+      <Title heading={4}>Check-in</Title>
+      <video ref={video} muted playsInline style={{ width: 320, display: 'BarcodeDetector' in window ? 'block' : 'none' }} />
+      <Paragraph>
+        <Input.Search
+          value={code}
+          onChange={setCode}
+          searchButton='Check in'
+          placeholder='scan a ticket or type its code...'
+          style={{ width: 400 }}
+          onSearch={value => {
+            last.current = value;
+            submit(value);
+            setCode('');
+          }}
+        />
+      </Paragraph>
+      {result && <Alert type={result.type} content={result.content} />}
+    </Typography>
+  );
+}
@@ -1,11 +1,23 @@
+import { useEffect, useState } from 'react';
 import { Typography, Input, Form, Button, InputNumber, Message } from '@arco-design/web-react';
-import { notifyContact } from 'utils/request';
+import { getContactToken, notifyContact } from 'utils/request';
 const { Title, Paragraph, Text } = Typography;
 const TextArea = Input.TextArea;
 const FormItem = Form.Item;
 
 export function Contact () {
   const [form] = Form.useForm();
+  const [token, setToken] = useState('');
+
+  const loadToken = () => {
+    getContactToken().then(res => {
+      if (res.code == 0) {
+        setToken(res.data.token);
+      }
+    })
+  }
+
+  useEffect(loadToken, []);
 
   return (
     <Typography style={{ marginTop: 10 }}>
@@ -20,14 +32,22 @@ export function Contact () {
         <FormItem label='Feedback' field='feedback' rules={[{ required: true, minLength: 10 }]}>
           <TextArea required minLength={10} placeholder='Please enter ...' style={{ minHeight: 64, width: 350 }} />
         </FormItem>
+        {/* honeypot, hidden from people and filled in by bots */}
+        <FormItem field='website' style={{ position: 'absolute', left: -10000 }} aria-hidden='true'>
+          <Input tabIndex={-1} autoComplete='off' />
+        </FormItem>
         <FormItem wrapperCol={{ offset: 5 }}>
           <Button type='primary' onClick={() => {
             form.validate().then(values => {
-              const { name, email, feedback } = values;
-              notifyContact(name, email, feedback).then(res => {
+              const { name, email, feedback, website } = values;
+              notifyContact(name, email, feedback, token, website).then(res => {
                 if (res.code == 0) {
                   Message.success('Submit success');
+                  form.resetFields();
+                } else {
+                  Message.error(res.msg);
                 }
+                loadToken();
               })
             })
             form.getFields()
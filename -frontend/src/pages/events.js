@@ -10,7 +10,8 @@ import { Typography, Space, Image, Card, Tag, Grid, Link, Button, Message } from
 import { Menu, Trigger } from '@arco-design/web-react';
 import { IconMessage, IconClose, IconBug, IconBulb } from '@arco-design/web-react/icon';
 import { checkIsAdminLogin, eventList } from 'utils/request';
-import { dateFormat } from 'utils/util';
+import { dateFormat, srcSet } from 'utils/util';
+import { RegistrationForm } from 'components/registrationForm';
 const { Title, Paragraph } = Typography;
 const MenuItem = Menu.Item;
 const Row = Grid.Row;
@@ -40,13 +41,13 @@ export function Events () {
 
       res.data.data.forEach((item, index) => {
         // parse tags
-        item.tags = item.tags.split('|').filter(item => item != '');
+        item.tags = (item.tags || []).map(tag => tag.name);
         res.data.data[index] = item;
 
         if (item.id == params.get('id')) {
           // get event by special id
           setEvent(item);
-        } else if (params.get('id') == undefined && (index == 0 || new Date(item.event_time * 1000) > new Date())) {
+        } else if (params.get('id') == undefined && (index == 0 || new Date(item.end_time || item.start_time) > new Date())) {
           // get first event
           setEvent(item);
         }
@@ -77,7 +78,14 @@ export function Events () {
         marginRight: '100px',
       }}>
         <Title heading={1}>{event.title}</Title>
-        <Title heading={6}>{dateFormat(new Date(event.event_time * 1000))} </Title>
+        <Title heading={6}>
+          {event.start_time && dateFormat(new Date(event.start_time))}
+          {event.end_time && ` - ${dateFormat(new Date(event.end_time))}`}
+          {event.time_zone && event.time_zone != 'UTC' && ` (${event.time_zone})`}
+        </Title>
+        {event.location && <Title heading={6}>{event.location}</Title>}
+        {event.online_url && <><Link href={event.online_url} target='_blank'>Join online</Link><br /></>}
+        {event.id && <Link href={`/api/events/${event.id}.ics`}>Add to calendar</Link>}
         <br />
         <Space size='medium' style={{
           marginTop: '15px'
@@ -91,6 +99,7 @@ export function Events () {
 
         <br />
         <div dangerouslySetInnerHTML={{ __html: event.content }} />
+        {event.id && event.registration_open && <RegistrationForm key={event.id} event={event} />}
 
         <Title heading={2}>More Events</Title>
         <Row style={{
@@ -115,6 +124,8 @@ export function Events () {
                               width: '100%',
                             }}
                             src={ev.cover || "/events.jpeg"}
+                            srcSet={srcSet(ev.cover_media)}
+                            sizes='300px'
                           />
                         </div>
                       }
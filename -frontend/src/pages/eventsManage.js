@@ -2,8 +2,8 @@ import React, {
     useState,
     useEffect,
 } from 'react';
-import { Button, Message, Table, Input, Select, Form, Space, Tag, Trigger, Typography, DatePicker } from '@arco-design/web-react';
-import { addEvent, checkIsAdminLogin, deleteEvent, editEvent, eventList } from 'utils/request';
+import { Button, Message, Table, Input, Select, Form, Space, Tag, Trigger, Typography, DatePicker, Upload, Switch } from '@arco-design/web-react';
+import { addEvent, attendanceStats, checkIsAdminLogin, deleteEvent, editEvent, eventList, getCheckInKey, uploadMedia, watchChanges } from 'utils/request';
 import { WYSISYGEditor } from 'components/editor';
 import { EditableCell } from 'components/editableRowCell';
 import { EditableRow } from 'components/editableRowCell';
@@ -58,6 +58,30 @@ export function EventsManage () {
             dataIndex: 'cover',
             editable: true
         },
+        {
+            title: 'Upload Cover',
+            dataIndex: 'cover_media_id',
+            editable: false,
+            render: (_, record) => (
+                <Upload
+                    accept='image/*'
+                    showUploadList={false}
+                    customRequest={({ file, onSuccess, onError }) => {
+                        uploadMedia(file).then(res => {
+                            if (res.code != 0) {
+                                Message.error(res.msg);
+                                onError(res.msg);
+                                return
+                            }
+                            onSuccess(res.data);
+                            record.cover_media_id = res.data.id
+                            record.cover = res.data.url
+                            handleSave(record)
+                        })
+                    }}
+                />
+            ),
+        },
         {
             title: 'Tags',
             dataIndex: 'tags',
@@ -76,24 +100,165 @@ export function EventsManage () {
             ),
         },
         {
-            title: 'Event Time',
-            dataIndex: 'event_time',
+            title: 'Starts',
+            dataIndex: 'start_time',
             editable: false,
             render: (_, record) => (
-                <>
-                    <DatePicker
-                        showTime
-                        allowClear={false}
-                        // timezone={Intl.DateTimeFormat().resolvedOptions().timeZone}
-                        value={new Date(record.event_time * 1000)}
-                        onOk={(v, vd) => {
-                            if (vd) {
-                                record.event_time = vd.toDate().getTime() / 1000
+                <DatePicker
+                    showTime={!record.all_day}
+                    allowClear={false}
+                    value={new Date(record.start_time)}
+                    onChange={(v, vd) => {
+                        if (vd) {
+                            record.start_time = vd.toDate().toISOString()
+                        }
+                        handleSave(record)
+                    }}
+                />
+            ),
+        },
+        {
+            title: 'Ends',
+            dataIndex: 'end_time',
+            editable: false,
+            render: (_, record) => (
+                <DatePicker
+                    showTime={!record.all_day}
+                    value={record.end_time ? new Date(record.end_time) : undefined}
+                    onChange={(v, vd) => {
+                        record.end_time = vd ? vd.toDate().toISOString() : null
+                        handleSave(record)
+                    }}
+                />
+            ),
+        },
+        {
+            title: 'All Day',
+            dataIndex: 'all_day',
+            editable: false,
+            render: (_, record) => (
+                <Switch
+                    checked={record.all_day}
+                    onChange={(v) => {
+                        record.all_day = v
+                        handleSave(record)
+                    }}
+                />
+            ),
+        },
+        {
+            title: 'Time Zone',
+            dataIndex: 'time_zone',
+            editable: true,
+        },
+        {
+            title: 'Location',
+            dataIndex: 'location',
+            editable: true,
+        },
+        {
+            title: 'Online URL',
+            dataIndex: 'online_url',
+            editable: true,
+        },
+        {
+            title: 'Repeats (RRULE)',
+            dataIndex: 'rrule',
+            editable: true,
+        },
+        {
+            title: 'Registration',
+            dataIndex: 'registration_open',
+            editable: false,
+            render: (_, record) => (
+                <Switch
+                    checked={record.registration_open}
+                    onChange={(v) => {
+                        record.registration_open = v
+                        handleSave(record)
+                    }}
+                />
+            ),
+        },
+        {
+            title: 'Capacity (0 = unlimited)',
+            dataIndex: 'capacity',
+            editable: true,
+        },
+        {
+            title: 'Questions (end with * if required)',
+            dataIndex: 'question_labels',
+            type: 'tags',
+            editable: true,
+            render: (_, record) => (
+                <Space size='medium'>
+                    {
+                        record.question_labels && record.question_labels.map((label, idx) => (
+                            <Tag key={idx}>{label}</Tag>
+                        ))
+                    }
+                </Space>
+            ),
+        },
+        {
+            title: 'Attendees',
+            dataIndex: 'attendees',
+            editable: false,
+            render: (_, record) => (
+                record.id && record.registration_open &&
+                <Space direction='vertical'>
+                    <a href={`/api/events/${record.id}/registrations?format=csv`}>Export CSV</a>
+                    <Button type='text' size='mini' onClick={() => {
+                        getCheckInKey(record.id).then(res => {
+                            if (res.code != 0) {
+                                Message.error(res.msg);
+                                return
                             }
-                            handleSave(record)
-                        }}
-                    />
-                </>
+                            window.open(res.data.url, '_blank');
+                        })
+                    }}>Check-in</Button>
+                    <Button type='text' size='mini' onClick={() => {
+                        attendanceStats(record.id).then(res => {
+                            if (res.code != 0) {
+                                Message.error(res.msg);
+                                return
+                            }
+                            const s = res.data;
+                            Message.info(`${s.checked_in}/${s.confirmed} checked in, ${s.no_shows} no-shows, ${s.duplicate_scans} duplicate scans`);
+                        })
+                    }}>Attendance</Button>
+                </Space>
+            ),
+        },
+        {
+            title: 'Status',
+            dataIndex: 'status',
+            editable: false,
+            render: (_, record) => (
+                <Select
+                    style={{ width: 120 }}
+                    value={record.status}
+                    options={['draft', 'scheduled', 'published', 'archived']}
+                    onChange={(v) => {
+                        record.status = v
+                        handleSave(record)
+                    }}
+                />
+            ),
+        },
+        {
+            title: 'Publish At',
+            dataIndex: 'publish_at',
+            editable: false,
+            render: (_, record) => (
+                <DatePicker
+                    showTime
+                    value={record.publish_at ? new Date(record.publish_at) : undefined}
+                    onChange={(v, vd) => {
+                        record.publish_at = vd ? vd.toDate().toISOString() : null
+                        handleSave(record)
+                    }}
+                />
             ),
         },
         {
@@ -118,12 +283,21 @@ export function EventsManage () {
 
     let parseEvent = (event) => {
         event.key = `old_${event.id}`;
-        event.tags = event.tags ? event.tags.split('|').filter(item => item != '') : [];
+        event.tags = (event.tags || []).map(tag => tag.name);
         event.cover = event.cover || '/events.jpeg';
+        event.question_labels = (event.questions || []).map(q => q.required ? `${q.label} *` : q.label);
         return event
     }
 
-    useEffect(() => {
+    // questions of the labels edited in the table, a trailing * marks a required question
+    let toQuestions = (row) => (row.question_labels || []).map(label => {
+        let required = label.trim().endsWith('*');
+        label = label.trim().replace(/\s*\*$/, '');
+        let current = (row.questions || []).find(q => q.label === label);
+        return { id: current ? current.id : '', label: label, required: required };
+    })
+
+    function load () {
         eventList().then(res => {
             if (res.code != 0) {
                 Message.error(res.msg);
@@ -136,9 +310,17 @@ export function EventsManage () {
             });
             setData(res.data.data);
         })
+    }
+
+    useEffect(() => {
+        load();
+
+        // reload the table when another admin changes it
+        return watchChanges('events', load);
     }, []);
 
     function handleSave (row) {
+        row.questions = toQuestions(row)
         let newRow = row
 
         let update = () => {
@@ -152,7 +334,7 @@ export function EventsManage () {
 
         if (row.new) {
             // create new
-            addEvent(row.title, row.content, row.tags, row.cover, row.event_time).then(res => {
+            addEvent(row.title, row.content, row.tags, row.cover, row, row.status, row.publish_at, row.unpublish_at, row.cover_media_id).then(res => {
                 if (res.code != 0) {
                     Message.error(res.msg);
                     return
@@ -162,7 +344,7 @@ export function EventsManage () {
             })
         } else {
             // update event
-            editEvent(row.id, row.title, row.content, row.tags, row.cover, row.event_time).then(res => {
+            editEvent(row.id, row.title, row.content, row.tags, row.cover, row, row.status, row.publish_at, row.unpublish_at, row.cover_media_id).then(res => {
                 if (res.code != 0) {
                     Message.error(res.msg);
                     return
@@ -192,7 +374,12 @@ export function EventsManage () {
                 title: 'new event',
                 content: '',
                 tags: [],
-                event_time: new Date().getTime() / 1000,
+                start_time: new Date().toISOString(),
+                time_zone: Intl.DateTimeFormat().resolvedOptions().timeZone || 'UTC',
+                all_day: false,
+                registration_open: false,
+                capacity: 0,
+                question_labels: [],
                 cover: '/events.jpeg',
             })
         );
@@ -4,6 +4,7 @@ import React, {
 } from 'react';
 import { Carousel, Typography, Image, Space, Link, Message } from '@arco-design/web-react';
 import { eventList, newsList } from 'utils/request';
+import { srcSet } from 'utils/util';
 const newsMap = [
   {
     name: 'News 1',
@@ -51,7 +52,7 @@ export function Home () {
 
       res.data.data.forEach((item, index) => {
         // parse tags
-        item.tags = item.tags.split('|').filter(item => item != '');
+        item.tags = (item.tags || []).map(tag => tag.name);
         res.data.data[index] = item;
       });
 
@@ -66,7 +67,7 @@ export function Home () {
 
       res.data.data.forEach((item, index) => {
         // parse tags
-        item.tags = item.tags.split('|').filter(item => item != '');
+        item.tags = (item.tags || []).map(tag => tag.name);
         res.data.data[index] = item;
       });
 
@@ -97,7 +98,8 @@ export function Home () {
                 <Image style={{
                   width: '80%',
                   height: 350
-                }} preview={false} src={n.cover || "/news.jpeg"} title={n.title} />
+                }} preview={false} src={n.cover || "/news.jpeg"} title={n.title}
+                  imgAttributes={{ srcSet: srcSet(n.cover_media), sizes: '50vw' }} />
               </Link>
             </div>
           )
@@ -117,7 +119,8 @@ export function Home () {
                 <Image style={{
                   width: '80%',
                   height: 350
-                }} preview={false} src={event.cover || "/events.jpeg"} title={event.title} />
+                }} preview={false} src={event.cover || "/events.jpeg"} title={event.title}
+                  imgAttributes={{ srcSet: srcSet(event.cover_media), sizes: '50vw' }} />
               </Link>
             </div>
           )
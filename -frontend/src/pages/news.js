@@ -10,7 +10,7 @@ import { Typography, Space, Image, Card, Tag, Grid, Link, Button, Message } from
 import { Menu, Trigger } from '@arco-design/web-react';
 import { IconMessage, IconClose, IconBug, IconBulb } from '@arco-design/web-react/icon';
 import { checkIsAdminLogin, newsList } from 'utils/request';
-import { dateFormat } from 'utils/util';
+import { dateFormat, srcSet } from 'utils/util';
 const { Title, Paragraph } = Typography;
 const MenuItem = Menu.Item;
 const Row = Grid.Row;
@@ -40,7 +40,7 @@ export function News () {
 
       res.data.data.forEach((item, index) => {
         // parse tags
-        item.tags = item.tags.split('|').filter(item => item != '');
+        item.tags = (item.tags || []).map(tag => tag.name);
         res.data.data[index] = item;
 
         if (item.id == params.get('id')) {
@@ -115,6 +115,8 @@ export function News () {
                               width: '100%',
                             }}
                             src={ev.cover || "/news.jpeg"}
+                            srcSet={srcSet(ev.cover_media)}
+                            sizes='300px'
                           />
                         </div>
                       }
@@ -2,8 +2,8 @@ import React, {
     useState,
     useEffect,
 } from 'react';
-import { Button, Message, Table, Input, Select, Form, Space, Tag, Trigger, Typography } from '@arco-design/web-react';
-import { addNews, deleteNews, editNews, newsList } from 'utils/request';
+import { Button, Message, Table, Input, Select, Form, Space, Tag, Trigger, Typography, DatePicker, Upload } from '@arco-design/web-react';
+import { addNews, deleteNews, editNews, newsList, sendDigest, uploadMedia, watchChanges } from 'utils/request';
 import { WYSISYGEditor } from 'components/editor';
 import { EditableCell } from 'components/editableRowCell';
 import { EditableRow } from 'components/editableRowCell';
@@ -57,6 +57,30 @@ export function NewsManage () {
             dataIndex: 'cover',
             editable: true
         },
+        {
+            title: 'Upload Cover',
+            dataIndex: 'cover_media_id',
+            editable: false,
+            render: (_, record) => (
+                <Upload
+                    accept='image/*'
+                    showUploadList={false}
+                    customRequest={({ file, onSuccess, onError }) => {
+                        uploadMedia(file).then(res => {
+                            if (res.code != 0) {
+                                Message.error(res.msg);
+                                onError(res.msg);
+                                return
+                            }
+                            onSuccess(res.data);
+                            record.cover_media_id = res.data.id
+                            record.cover = res.data.url
+                            handleSave(record)
+                        })
+                    }}
+                />
+            ),
+        },
         {
             title: 'Tags',
             dataIndex: 'tags',
@@ -74,6 +98,37 @@ export function NewsManage () {
                 </Space>
             ),
         },
+        {
+            title: 'Status',
+            dataIndex: 'status',
+            editable: false,
+            render: (_, record) => (
+                <Select
+                    style={{ width: 120 }}
+                    value={record.status}
+                    options={['draft', 'scheduled', 'published', 'archived']}
+                    onChange={(v) => {
+                        record.status = v
+                        handleSave(record)
+                    }}
+                />
+            ),
+        },
+        {
+            title: 'Publish At',
+            dataIndex: 'publish_at',
+            editable: false,
+            render: (_, record) => (
+                <DatePicker
+                    showTime
+                    value={record.publish_at ? new Date(record.publish_at) : undefined}
+                    onChange={(v, vd) => {
+                        record.publish_at = vd ? vd.toDate().toISOString() : null
+                        handleSave(record)
+                    }}
+                />
+            ),
+        },
         {
             title: 'Operation',
             dataIndex: 'op',
@@ -96,12 +151,12 @@ export function NewsManage () {
 
     let parseNews = (news) => {
         news.key = `old_${news.id}`;
-        news.tags = news.tags ? news.tags.split('|').filter(item => item != '') : [];
+        news.tags = (news.tags || []).map(tag => tag.name);
         news.cover = news.cover || '/news.jpeg';
         return news
     }
 
-    useEffect(() => {
+    function load () {
         newsList().then(res => {
             if (res.code != 0) {
                 Message.error(res.msg);
@@ -114,6 +169,13 @@ export function NewsManage () {
             });
             setData(res.data.data);
         })
+    }
+
+    useEffect(() => {
+        load();
+
+        // reload the table when another admin changes it
+        return watchChanges('news', load);
     }, []);
 
     function handleSave (row) {
@@ -130,7 +192,7 @@ export function NewsManage () {
 
         if (row.new) {
             // create new
-            addNews(row.title, row.content, row.tags, row.cover).then(res => {
+            addNews(row.title, row.content, row.tags, row.cover, row.status, row.publish_at, row.unpublish_at, row.cover_media_id).then(res => {
                 if (res.code != 0) {
                     Message.error(res.msg);
                     return
@@ -140,7 +202,7 @@ export function NewsManage () {
             })
         } else {
             // update news
-            editNews(row.id, row.title, row.content, row.tags, row.cover).then(res => {
+            editNews(row.id, row.title, row.content, row.tags, row.cover, row.status, row.publish_at, row.unpublish_at, row.cover_media_id).then(res => {
                 if (res.code != 0) {
                     Message.error(res.msg);
                     return
@@ -170,6 +232,7 @@ export function NewsManage () {
                 title: 'new news',
                 content: '',
                 tags: [],
+                status: 'draft',
             })
         );
     }
@@ -188,6 +251,23 @@ export function NewsManage () {
             >
                 Add
             </Button>
+            <Button
+                style={{
+                    marginBottom: 10,
+                    marginLeft: 10,
+                }}
+                onClick={() => {
+                    sendDigest('').then(res => {
+                        if (res.code != 0) {
+                            Message.error(res.msg);
+                            return
+                        }
+                        Message.info('Sending the newsletter: ' + res.data.subject);
+                    })
+                }}
+            >
+                Send newsletter
+            </Button>
             <Table
                 data={data}
                 components={{
@@ -0,0 +1,29 @@
+import React, { useState } from 'react';
+import { useSearchParams } from 'react-router-dom';
+import { Typography, Button, Message } from '@arco-design/web-react';
+import { confirmSubscription } from 'utils/request';
+const { Title, Paragraph } = Typography;
+
+// NewsletterConfirm target of the confirmation link mailed to new subscribers, confirms only once the reader clicks
+export function NewsletterConfirm () {
+  const [params] = useSearchParams();
+  const [confirmed, setConfirmed] = useState(false);
+
+  return (
+    <Typography style={{ marginTop: 10 }}>
+      <Title heading={4}>Newsletter Subscription</Title>
+      {confirmed
+        ? <Paragraph>Thank you, you are subscribed to the newsletter.</Paragraph>
+        : <Button type='primary' onClick={() => {
+          confirmSubscription(params.get('token')).then(res => {
+            if (res.code != 0) {
+              Message.error(res.msg);
+              return
+            }
+            setConfirmed(true);
+          })
+        }}>Confirm my subscription</Button>
+      }
+    </Typography>
+  );
+}
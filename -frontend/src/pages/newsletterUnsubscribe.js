@@ -0,0 +1,29 @@
+import React, { useState } from 'react';
+import { useSearchParams } from 'react-router-dom';
+import { Typography, Button, Message } from '@arco-design/web-react';
+import { unsubscribe } from 'utils/request';
+const { Title, Paragraph } = Typography;
+
+// NewsletterUnsubscribe target of the unsubscribe link at the bottom of the newsletter
+export function NewsletterUnsubscribe () {
+  const [params] = useSearchParams();
+  const [unsubscribed, setUnsubscribed] = useState(false);
+
+  return (
+    <Typography style={{ marginTop: 10 }}>
+      <Title heading={4}>Unsubscribe</Title>
+      {unsubscribed
+        ? <Paragraph>You will not get the newsletter anymore.</Paragraph>
+        : <Button type='primary' status='danger' onClick={() => {
+          unsubscribe(params.get('token')).then(res => {
+            if (res.code != 0) {
+              Message.error(res.msg);
+              return
+            }
+            setUnsubscribed(true);
+          })
+        }}>Unsubscribe from the newsletter</Button>
+      }
+    </Typography>
+  );
+}
@@ -2,8 +2,8 @@ import React, {
     useState,
     useEffect,
 } from 'react';
-import { Button, Message, Table, Input, Select, Form, Space, Tag, Trigger, Typography } from '@arco-design/web-react';
-import { addNews, addPhd, deleteNews, deletePhd, editNews, editPhd, newsList, phdList } from 'utils/request';
+import { Button, Message, Table, Input, Select, Form, Space, Tag, Trigger, Typography, Upload } from '@arco-design/web-react';
+import { addNews, addPhd, deleteNews, deletePhd, editNews, editPhd, newsList, phdList, uploadMedia, watchChanges } from 'utils/request';
 import { WYSISYGEditor } from 'components/editor';
 import { EditableCell } from 'components/editableRowCell';
 import { EditableRow } from 'components/editableRowCell';
@@ -63,6 +63,30 @@ export function PhdsManage () {
             dataIndex: 'avatar',
             editable: true
         },
+        {
+            title: 'Upload Avatar',
+            dataIndex: 'avatar_media_id',
+            editable: false,
+            render: (_, record) => (
+                <Upload
+                    accept='image/*'
+                    showUploadList={false}
+                    customRequest={({ file, onSuccess, onError }) => {
+                        uploadMedia(file).then(res => {
+                            if (res.code != 0) {
+                                Message.error(res.msg);
+                                onError(res.msg);
+                                return
+                            }
+                            onSuccess(res.data);
+                            record.avatar_media_id = res.data.id
+                            record.avatar = res.data.url
+                            handleSave(record)
+                        })
+                    }}
+                />
+            ),
+        },
         {
             title: 'Operation',
             dataIndex: 'op',
@@ -88,7 +112,7 @@ export function PhdsManage () {
         return phd
     }
 
-    useEffect(() => {
+    function load () {
         phdList().then(res => {
             if (res.code != 0) {
                 Message.error(res.msg);
@@ -101,6 +125,13 @@ export function PhdsManage () {
             });
             setData(res.data.data);
         })
+    }
+
+    useEffect(() => {
+        load();
+
+        // reload the table when another admin changes it
+        return watchChanges('phds', load);
     }, []);
 
     function handleSave (row) {
@@ -117,7 +148,7 @@ export function PhdsManage () {
 
         if (row.new) {
             // create new
-            addPhd(row.name, row.job, row.intro, row.avatar).then(res => {
+            addPhd(row.name, row.job, row.intro, row.avatar, row.avatar_media_id).then(res => {
                 if (res.code != 0) {
                     Message.error(res.msg);
                     return
@@ -127,7 +158,7 @@ export function PhdsManage () {
             })
         } else {
             // update phd
-            editPhd(row.id, row.name, row.job, row.intro, row.avatar).then(res => {
+            editPhd(row.id, row.name, row.job, row.intro, row.avatar, row.avatar_media_id).then(res => {
                 if (res.code != 0) {
                     Message.error(res.msg);
                     return
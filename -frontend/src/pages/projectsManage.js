@@ -3,7 +3,7 @@ import React, {
     useEffect,
 } from 'react';
 import { Button, Message, Table, Input, Select, Form, Space, Tag, Trigger, Typography } from '@arco-design/web-react';
-import { addNews, addProject, deleteNews, deleteProject, editNews, editProject, newsList, projectList } from 'utils/request';
+import { addNews, addProject, deleteNews, deleteProject, editNews, editProject, newsList, projectList, watchChanges } from 'utils/request';
 import { WYSISYGEditor } from 'components/editor';
 import { EditableCell } from 'components/editableRowCell';
 import { EditableRow } from 'components/editableRowCell';
@@ -78,7 +78,7 @@ export function ProjectsManage () {
         return project
     }
 
-    useEffect(() => {
+    function load () {
         projectList().then(res => {
             if (res.code != 0) {
                 Message.error(res.msg);
@@ -91,6 +91,13 @@ export function ProjectsManage () {
             });
             setData(res.data.data);
         })
+    }
+
+    useEffect(() => {
+        load();
+
+        // reload the table when another admin changes it
+        return watchChanges('projects', load);
     }, []);
 
     function handleSave (row) {
@@ -117,7 +124,7 @@ export function ProjectsManage () {
             })
         } else {
             // update project
-            editProject(row.id, row.name, row.intro).then(res => {
+            editProject(row.id, row.name, row.intro, row.link).then(res => {
                 if (res.code != 0) {
                     Message.error(res.msg);
                     return
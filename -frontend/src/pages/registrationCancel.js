@@ -0,0 +1,29 @@
+import React, { useState } from 'react';
+import { useSearchParams } from 'react-router-dom';
+import { Typography, Button, Message } from '@arco-design/web-react';
+import { cancelRegistration } from 'utils/request';
+const { Title, Paragraph } = Typography;
+
+// RegistrationCancel target of the cancel link mailed to attendees, cancels only once the attendee confirms
+export function RegistrationCancel () {
+  const [params] = useSearchParams();
+  const [cancelled, setCancelled] = useState(false);
+
+  return (
+    <Typography style={{ marginTop: 10 }}>
+      <Title heading={4}>Cancel Registration</Title>
+      {cancelled
+        ? <Paragraph>Your registration is cancelled.</Paragraph>
+        : <Button type='primary' status='danger' onClick={() => {
+          cancelRegistration(params.get('token')).then(res => {
+            if (res.code != 0) {
+              Message.error(res.msg);
+              return
+            }
+            setCancelled(true);
+          })
+        }}>Cancel my registration</Button>
+      }
+    </Typography>
+  );
+}
@@ -3,7 +3,7 @@ import React, {
     useEffect,
 } from 'react';
 import { Button, Message, Table, Input, Select, Form, Space, Tag, Trigger, Typography } from '@arco-design/web-react';
-import { addNews, addResource, deleteNews, deleteResource, editNews, editResource, newsList, resourceList } from 'utils/request';
+import { addNews, addResource, deleteNews, deleteResource, editNews, editResource, newsList, resourceList, watchChanges } from 'utils/request';
 import { WYSISYGEditor } from 'components/editor';
 import { EditableCell } from 'components/editableRowCell';
 import { EditableRow } from 'components/editableRowCell';
@@ -83,7 +83,7 @@ export function ResourcesManage () {
         return resource
     }
 
-    useEffect(() => {
+    function load () {
         resourceList().then(res => {
             if (res.code != 0) {
                 Message.error(res.msg);
@@ -96,6 +96,13 @@ export function ResourcesManage () {
             });
             setData(res.data.data);
         })
+    }
+
+    useEffect(() => {
+        load();
+
+        // reload the table when another admin changes it
+        return watchChanges('resources', load);
     }, []);
 
     function handleSave (row) {
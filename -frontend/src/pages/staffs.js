@@ -4,6 +4,7 @@ import React, {
 } from 'react';
 import { List, Avatar, Grid, Link, Button, Image, Message } from '@arco-design/web-react';
 import { checkIsAdminLogin, staffList } from 'utils/request';
+import { srcSet } from 'utils/util';
 const Row = Grid.Row;
 const Col = Grid.Col;
 
@@ -59,6 +60,7 @@ export function Staffs () {
                 <Image
                   width={200}
                   src={item.avatar}
+                  imgAttributes={{ srcSet: srcSet(item.avatar_media), sizes: '200px' }}
                   footerPosition='outer'
                   alt='picture'
                 />
@@ -2,8 +2,8 @@ import React, {
     useState,
     useEffect,
 } from 'react';
-import { Button, Message, Table, Input, Select, Form, Space, Tag, Trigger, Typography } from '@arco-design/web-react';
-import { addNews, addStaff, deleteNews, deleteStaff, editNews, editStaff, newsList, staffList } from 'utils/request';
+import { Button, Message, Table, Input, Select, Form, Space, Tag, Trigger, Typography, Upload } from '@arco-design/web-react';
+import { addNews, addStaff, deleteNews, deleteStaff, editNews, editStaff, newsList, staffList, uploadMedia, watchChanges } from 'utils/request';
 import { WYSISYGEditor } from 'components/editor';
 import { EditableCell } from 'components/editableRowCell';
 import { EditableRow } from 'components/editableRowCell';
@@ -63,6 +63,30 @@ export function StaffsManage () {
             dataIndex: 'avatar',
             editable: true
         },
+        {
+            title: 'Upload Avatar',
+            dataIndex: 'avatar_media_id',
+            editable: false,
+            render: (_, record) => (
+                <Upload
+                    accept='image/*'
+                    showUploadList={false}
+                    customRequest={({ file, onSuccess, onError }) => {
+                        uploadMedia(file).then(res => {
+                            if (res.code != 0) {
+                                Message.error(res.msg);
+                                onError(res.msg);
+                                return
+                            }
+                            onSuccess(res.data);
+                            record.avatar_media_id = res.data.id
+                            record.avatar = res.data.url
+                            handleSave(record)
+                        })
+                    }}
+                />
+            ),
+        },
         {
             title: 'Operation',
             dataIndex: 'op',
@@ -88,7 +112,7 @@ export function StaffsManage () {
         return staff
     }
 
-    useEffect(() => {
+    function load () {
         staffList().then(res => {
             if (res.code != 0) {
                 Message.error(res.msg);
@@ -101,6 +125,13 @@ export function StaffsManage () {
             });
             setData(res.data.data);
         })
+    }
+
+    useEffect(() => {
+        load();
+
+        // reload the table when another admin changes it
+        return watchChanges('staffs', load);
     }, []);
 
     function handleSave (row) {
@@ -117,7 +148,7 @@ export function StaffsManage () {
 
         if (row.new) {
             // create new
-            addStaff(row.name, row.job, row.intro, row.avatar).then(res => {
+            addStaff(row.name, row.job, row.intro, row.avatar, row.avatar_media_id).then(res => {
                 if (res.code != 0) {
                     Message.error(res.msg);
                     return
@@ -127,7 +158,7 @@ export function StaffsManage () {
             })
         } else {
             // update staff
-            editStaff(row.id, row.name, row.job, row.intro, row.avatar).then(res => {
+            editStaff(row.id, row.name, row.job, row.intro, row.avatar, row.avatar_media_id).then(res => {
                 if (res.code != 0) {
                     Message.error(res.msg);
                     return
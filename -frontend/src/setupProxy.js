@@ -2,7 +2,7 @@ const { createProxyMiddleware } = require('http-proxy-middleware');
 
 module.exports = function (app) {
     app.use(
-        '/api',
+        ['/api', '/uploads'],
         createProxyMiddleware({
             target: 'http://localhost:8080',
             changeOrigin: true,
@@ -7,9 +7,54 @@ const instance = axios.create({
     withCredentials: true,
 });
 
+// version of every record loaded from the api by path, e.g. versions['/events/3'] = 2. Edits and deletes send it
+// back as If-Match, so a change based on a copy someone else modified meanwhile is refused instead of overwriting theirs.
+const versions = {}
+
+function recordPath (url, id) {
+    let table = url.split('?')[0].split('/').filter(part => part)[0]
+    return `/${table}/${id}`
+}
+
+function rememberVersions (url, data) {
+    let records = Array.isArray(data) ? data : (data && Array.isArray(data.data) ? data.data : [data])
+    for (let record of records) {
+        if (record && record.id !== undefined && record.version !== undefined) {
+            versions[recordPath(url, record.id)] = record.version
+        }
+    }
+}
+
+instance.interceptors.request.use(config => {
+    let method = (config.method || '').toLowerCase()
+    let version = versions[config.url.split('?')[0]]
+    if (['put', 'patch', 'delete'].includes(method) && version !== undefined) {
+        config.headers['If-Match'] = `"${version}"`
+    }
+    return config
+});
+
+instance.interceptors.response.use(res => {
+    rememberVersions(res.config.url, res.data)
+    return res
+});
+
+// surface the field errors of a rejected record (422) instead of the bare status code
+instance.interceptors.response.use(res => res, err => {
+    let problem = err.response && err.response.data
+    if (err.response && err.response.status === 412) {
+        err.message = 'someone else changed this record since it was loaded, reload the page and apply your changes again'
+    } else if (problem && problem.errors && problem.errors.length) {
+        err.message = problem.errors.map(e => e.message).join('; ')
+    } else if (problem && problem.detail) {
+        err.message = problem.detail
+    }
+    return Promise.reject(err)
+});
+
 export async function eventList () {
     try {
-        let res = await instance.get("/events?order=event_time desc")
+        let res = await instance.get("/events?order=start_time desc")
 
         if (res.status != 200) {
             return {
@@ -31,14 +76,27 @@ export async function eventList () {
     }
 }
 
-export async function addEvent (title, content, tags, cover, event_time) {
+export async function addEvent (title, content, tags, cover, details, status, publishAt, unpublishAt, coverMediaId) {
     try {
         let res = await instance.post("/events", {
             "title": title,
             "content": content,
-            "tags": tags.join('|'),
+            "tags": tags,
             "cover": cover,
-            "event_time": event_time | 0,
+            "start_time": details.start_time,
+            "end_time": details.end_time || null,
+            "time_zone": details.time_zone || 'UTC',
+            "all_day": !!details.all_day,
+            "location": details.location || '',
+            "online_url": details.online_url || '',
+            "rrule": details.rrule || '',
+            "registration_open": !!details.registration_open,
+            "capacity": parseInt(details.capacity) || 0,
+            "questions": details.questions || [],
+            "status": status,
+            "publish_at": publishAt || null,
+            "unpublish_at": unpublishAt || null,
+            "cover_media_id": coverMediaId || null,
         })
 
         if (res.status != 200) {
@@ -61,14 +119,27 @@ export async function addEvent (title, content, tags, cover, event_time) {
     }
 }
 
-export async function editEvent (eventId, title, content, tags, cover, event_time) {
+export async function editEvent (eventId, title, content, tags, cover, details, status, publishAt, unpublishAt, coverMediaId) {
     try {
         let res = await instance.put(`/events/${eventId}`, {
             "title": title,
             "content": content,
-            "tags": tags.join('|'),
+            "tags": tags,
             "cover": cover,
-            "event_time": event_time | 0,
+            "start_time": details.start_time,
+            "end_time": details.end_time || null,
+            "time_zone": details.time_zone || 'UTC',
+            "all_day": !!details.all_day,
+            "location": details.location || '',
+            "online_url": details.online_url || '',
+            "rrule": details.rrule || '',
+            "registration_open": !!details.registration_open,
+            "capacity": parseInt(details.capacity) || 0,
+            "questions": details.questions || [],
+            "status": status,
+            "publish_at": publishAt || null,
+            "unpublish_at": unpublishAt || null,
+            "cover_media_id": coverMediaId || null,
         })
 
         if (res.status != 200) {
@@ -139,13 +210,17 @@ export async function newsList () {
     }
 }
 
-export async function addNews (title, content, tags, cover) {
+export async function addNews (title, content, tags, cover, status, publishAt, unpublishAt, coverMediaId) {
     try {
         let res = await instance.post("/news", {
             "title": title,
             "content": content,
-            "tags": tags.join('|'),
+            "tags": tags,
             "cover": cover,
+            "status": status,
+            "publish_at": publishAt || null,
+            "unpublish_at": unpublishAt || null,
+            "cover_media_id": coverMediaId || null,
         })
 
         if (res.status != 200) {
@@ -168,13 +243,17 @@ export async function addNews (title, content, tags, cover) {
     }
 }
 
-export async function editNews (newsId, title, content, tags, cover) {
+export async function editNews (newsId, title, content, tags, cover, status, publishAt, unpublishAt, coverMediaId) {
     try {
         let res = await instance.put(`/news/${newsId}`, {
             "title": title,
             "content": content,
-            "tags": tags.join('|'),
+            "tags": tags,
             "cover": cover,
+            "status": status,
+            "publish_at": publishAt || null,
+            "unpublish_at": unpublishAt || null,
+            "cover_media_id": coverMediaId || null,
         })
 
         if (res.status != 200) {
@@ -245,13 +324,14 @@ export async function phdList () {
     }
 }
 
-export async function addPhd (name, job, intro, avatar) {
+export async function addPhd (name, job, intro, avatar, avatarMediaId) {
     try {
         let res = await instance.post("/phds", {
             name: name,
             job: job,
             intro: intro,
             avatar: avatar,
+            avatar_media_id: avatarMediaId || null,
         })
 
         if (res.status != 200) {
@@ -274,13 +354,14 @@ export async function addPhd (name, job, intro, avatar) {
     }
 }
 
-export async function editPhd (phdId, name, job, intro, avatar) {
+export async function editPhd (phdId, name, job, intro, avatar, avatarMediaId) {
     try {
         let res = await instance.put(`/phds/${phdId}`, {
             name: name,
             job: job,
             intro: intro,
             avatar: avatar,
+            avatar_media_id: avatarMediaId || null,
         })
 
         if (res.status != 200) {
@@ -351,13 +432,14 @@ export async function staffList () {
     }
 }
 
-export async function addStaff (name, job, intro, avatar) {
+export async function addStaff (name, job, intro, avatar, avatarMediaId) {
     try {
         let res = await instance.post("/staffs", {
             name: name,
             job: job,
             intro: intro,
             avatar: avatar,
+            avatar_media_id: avatarMediaId || null,
         })
 
         if (res.status != 200) {
@@ -380,13 +462,14 @@ export async function addStaff (name, job, intro, avatar) {
     }
 }
 
-export async function editStaff (staffId, name, job, intro, avatar) {
+export async function editStaff (staffId, name, job, intro, avatar, avatarMediaId) {
     try {
         let res = await instance.put(`/staffs/${staffId}`, {
             name: name,
             job: job,
             intro: intro,
             avatar: avatar,
+            avatar_media_id: avatarMediaId || null,
         })
 
         if (res.status != 200) {
@@ -484,11 +567,12 @@ export async function addProject (name, intro) {
     }
 }
 
-export async function editProject (projectId, name, intro) {
+export async function editProject (projectId, name, intro, link) {
     try {
         let res = await instance.put(`/projects/${projectId}`, {
             name: name,
             intro: intro,
+            link: link,
         })
 
         if (res.status != 200) {
@@ -639,6 +723,32 @@ export async function deleteResource (resourceId) {
     }
 }
 
+export async function uploadMedia (file) {
+    try {
+        let form = new FormData();
+        form.append('file', file);
+        let res = await instance.post("/media", form)
+
+        if (res.status != 200 && res.status != 201) {
+            return {
+                code: 1,
+                msg: 'request failed with status code ' + res.status
+            }
+        }
+
+        return {
+            code: 0,
+            data: res.data
+        }
+    }
+    catch (err) {
+        return {
+            code: 1,
+            msg: err,
+        }
+    }
+}
+
 export async function checkIsAdminLogin () {
     try {
         let res = await instance.get(`/isAdminLogin`)
@@ -711,9 +821,123 @@ export async function adminLogout () {
     }
 }
 
-export async function notifyContact (name, email, feedback) {
+export async function registerForEvent (eventId, name, email, answers) {
+    try {
+        let res = await instance.post(`/events/${eventId}/register`, {
+            "name": name,
+            "email": email,
+            "answers": answers || {},
+        })
+
+        return {
+            code: 0,
+            data: res.data
+        }
+    }
+    catch (err) {
+        return {
+            code: 1,
+            msg: err.message,
+        }
+    }
+}
+
+export async function cancelRegistration (token) {
+    try {
+        let res = await instance.post('/registrations/cancel', {
+            "token": token,
+        })
+
+        return {
+            code: 0,
+            data: res.data
+        }
+    }
+    catch (err) {
+        return {
+            code: 1,
+            msg: err.message,
+        }
+    }
+}
+
+export async function checkInTicket (eventId, key, code) {
+    try {
+        let res = await instance.post(`/events/${eventId}/checkin`, {
+            "code": code,
+        }, {
+            headers: key ? { 'X-Checkin-Key': key } : {},
+        })
+
+        return {
+            code: 0,
+            data: res.data
+        }
+    }
+    catch (err) {
+        return {
+            code: 1,
+            msg: (err.response && err.response.data && err.response.data.detail) || err.message,
+        }
+    }
+}
+
+export async function getCheckInKey (eventId) {
+    try {
+        let res = await instance.get(`/events/${eventId}/checkin-key`)
+
+        return {
+            code: 0,
+            data: res.data
+        }
+    }
+    catch (err) {
+        return {
+            code: 1,
+            msg: err.message,
+        }
+    }
+}
+
+export async function attendanceStats (eventId) {
     try {
-        let res = await instance.post(`/notifyContact?name=${name}&email=${email}&feedback=${feedback}`)
+        let res = await instance.get(`/events/${eventId}/attendance`)
+
+        return {
+            code: 0,
+            data: res.data
+        }
+    }
+    catch (err) {
+        return {
+            code: 1,
+            msg: err.message,
+        }
+    }
+}
+
+export async function getContactToken () {
+    try {
+        let res = await instance.get('/contactToken')
+
+        return {
+            code: 0,
+            data: res.data
+        }
+    }
+    catch (err) {
+        return {
+            code: 1,
+            msg: err.message,
+        }
+    }
+}
+
+export async function notifyContact (name, email, feedback, token, website) {
+    try {
+        let res = await instance.post('/notifyContact', null, {
+            params: { name, email, feedback, token, website }
+        })
 
         if (res.status != 200) {
             return {
@@ -730,7 +954,104 @@ export async function notifyContact (name, email, feedback) {
     catch (err) {
         return {
             code: 1,
-            msg: err,
+            msg: (err.response && err.response.data && err.response.data.detail) || err.message,
+        }
+    }
+}
+
+export async function subscribe (email, name) {
+    try {
+        let res = await instance.post('/subscribe', {
+            "email": email,
+            "name": name,
+        })
+
+        return {
+            code: 0,
+            data: res.data
+        }
+    }
+    catch (err) {
+        return {
+            code: 1,
+            msg: (err.response && err.response.data && err.response.data.detail) || err.message,
         }
     }
+}
+
+export async function confirmSubscription (token) {
+    try {
+        let res = await instance.post('/subscribe/confirm', {
+            "token": token,
+        })
+
+        return {
+            code: 0,
+            data: res.data
+        }
+    }
+    catch (err) {
+        return {
+            code: 1,
+            msg: (err.response && err.response.data && err.response.data.detail) || err.message,
+        }
+    }
+}
+
+export async function unsubscribe (token) {
+    try {
+        let res = await instance.post('/unsubscribe', {
+            "token": token,
+        })
+
+        return {
+            code: 0,
+            data: res.data
+        }
+    }
+    catch (err) {
+        return {
+            code: 1,
+            msg: (err.response && err.response.data && err.response.data.detail) || err.message,
+        }
+    }
+}
+
+export async function sendDigest (subject) {
+    try {
+        let res = await instance.post('/digests', {
+            "subject": subject,
+        })
+
+        return {
+            code: 0,
+            data: res.data
+        }
+    }
+    catch (err) {
+        return {
+            code: 1,
+            msg: (err.response && err.response.data && err.response.data.detail) || err.message,
+        }
+    }
+}
+
+// watchChanges calls onChange whenever another admin creates, updates or deletes a record of tables, and after
+// a reconnect that missed changes. It returns a function closing the stream, to be used as an effect cleanup.
+export function watchChanges (tables, onChange) {
+    let source = new EventSource(instance.defaults.baseURL + '/stream?tables=' + [].concat(tables).join(','), { withCredentials: true })
+    let adminId = null
+
+    source.addEventListener('ready', e => {
+        adminId = JSON.parse(e.data).admin_id
+    })
+    source.addEventListener('reset', () => onChange(null))
+    source.addEventListener('change', e => {
+        let change = JSON.parse(e.data)
+        if (change.admin_id !== adminId) {
+            onChange(change)
+        }
+    })
+
+    return () => source.close()
 }
\ No newline at end of file
@@ -9,4 +9,16 @@ export function dateFormat (d) {
         day = '0' + day;
 
     return [day, month, year].join('/');
-}
\ No newline at end of file
+}
+
+// srcSet builds an img srcset from the resized variants of an uploaded image, the original being the widest candidate
+export function srcSet (media) {
+    if (!media || !media.variants || media.variants.length === 0) {
+        return undefined;
+    }
+
+    return media.variants
+        .map(variant => `${variant.url} ${variant.width}w`)
+        .concat(`${media.url} ${media.width}w`)
+        .join(', ');
+}